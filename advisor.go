@@ -0,0 +1,283 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// IndexSuggestion is an index which would avoid full table scans.
+type IndexSuggestion struct {
+	Table   string
+	Columns []string
+	SQL     string   // CREATE INDEX statement
+	Queries []string // statements using the index
+	Benefit int64    // estimated number of rows not scanned anymore (table rows count for each query)
+}
+
+const advisorIndex = "gosqlite_advisor_candidate"
+
+// AdviseIndexes analyses the specified statements (with EXPLAIN QUERY PLAN) and suggests
+// indexes on the tables of the main database which are fully scanned.
+// Candidate indexes (on the columns referenced by the statements) are created in a savepoint,
+// which is always rolled back, to check that the query planner would use them:
+// the connection must be writable and must not be used concurrently.
+// Statements are not executed. Suggestions are ordered by decreasing benefit.
+func (c *Conn) AdviseIndexes(queries ...string) ([]IndexSuggestion, error) {
+	tables, err := c.Tables("")
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]string, len(tables))
+	for _, table := range tables {
+		known[strings.ToLower(table)] = table
+	}
+	scope, err := c.SavepointScope("gosqlite_advisor")
+	if err != nil {
+		return nil, err
+	}
+	defer scope.Rollback()
+	var suggestions []*IndexSuggestion
+	byKey := make(map[string]*IndexSuggestion)
+	rows := make(map[string]int64)
+	for _, query := range queries {
+		plan, err := c.queryPlan(query)
+		if err != nil {
+			return nil, err
+		}
+		tokens := sqlIdentifiers(query)
+		aliases := tableAliases(tokens, known)
+		for _, detail := range plan {
+			alias := scannedTable(detail)
+			table, ok := aliases[strings.ToLower(alias)]
+			if !ok {
+				continue // subquery, view, virtual table...
+			}
+			columns, err := c.advise(query, alias, table, tokens)
+			if err != nil {
+				return nil, err
+			} else if len(columns) == 0 {
+				continue
+			}
+			key := strings.ToLower(table + "(" + strings.Join(columns, ",") + ")")
+			suggestion := byKey[key]
+			if suggestion == nil {
+				suggestion = &IndexSuggestion{Table: table, Columns: columns, SQL: createIndexSQL(table, columns)}
+				byKey[key] = suggestion
+				suggestions = append(suggestions, suggestion)
+			}
+			if n := len(suggestion.Queries); n == 0 || suggestion.Queries[n-1] != query {
+				suggestion.Queries = append(suggestion.Queries, query)
+			}
+			count, ok := rows[table]
+			if !ok {
+				if err = c.OneValue(fmt.Sprintf("SELECT count(*) FROM %s", QualifiedName("main", table)), &count); err != nil {
+					return nil, err
+				}
+				rows[table] = count
+			}
+			suggestion.Benefit += count
+		}
+	}
+	result := make([]IndexSuggestion, len(suggestions))
+	for i, suggestion := range suggestions {
+		result[i] = *suggestion
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Benefit > result[j].Benefit })
+	return result, nil
+}
+
+// advise returns the columns of the best candidate index for the table (alias) scanned by the query.
+func (c *Conn) advise(query, alias, table string, tokens []string) ([]string, error) {
+	columns, err := c.Columns("main", table)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		referenced[strings.ToLower(token)] = true
+	}
+	var equalities, ranges []string
+	best, bestConstraints := []string(nil), 0
+	for _, column := range columns {
+		if !referenced[strings.ToLower(column.Name)] {
+			continue
+		}
+		detail, err := c.tryIndex(query, alias, table, []string{column.Name})
+		if err != nil {
+			return nil, err
+		} else if len(detail) == 0 {
+			continue
+		}
+		if strings.Contains(detail, column.Name+"=?") {
+			equalities = append(equalities, column.Name)
+		} else {
+			ranges = append(ranges, column.Name)
+		}
+		if n := strings.Count(detail, "?"); n > bestConstraints {
+			best, bestConstraints = []string{column.Name}, n
+		}
+	}
+	// equality constraints first, then at most one range constraint
+	composite := make([]string, 0, len(equalities)+1)
+	composite = append(composite, equalities...)
+	if len(ranges) > 0 {
+		composite = append(composite, ranges[0])
+	}
+	if len(composite) > 1 {
+		detail, err := c.tryIndex(query, alias, table, composite)
+		if err != nil {
+			return nil, err
+		}
+		if n := strings.Count(detail, "?"); n > bestConstraints {
+			best = composite
+		}
+	}
+	return best, nil
+}
+
+// tryIndex creates a candidate index and returns the plan detail of the table scan if the index is used.
+func (c *Conn) tryIndex(query, alias, table string, columns []string) (string, error) {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = QuoteIdentifier(column)
+	}
+	if err := c.FastExec(fmt.Sprintf("CREATE INDEX main.%s ON %s(%s)", advisorIndex, QuoteIdentifier(table), strings.Join(quoted, ", "))); err != nil {
+		return "", err
+	}
+	plan, err := c.queryPlan(query)
+	if dropErr := c.FastExec("DROP INDEX main." + advisorIndex); err == nil {
+		err = dropErr
+	}
+	if err != nil {
+		return "", err
+	}
+	prefix := "SEARCH " + alias + " USING "
+	for _, detail := range plan {
+		if strings.HasPrefix(detail, prefix) && strings.Contains(detail, advisorIndex) {
+			return detail, nil
+		}
+	}
+	return "", nil
+}
+
+// queryPlan returns the details of the EXPLAIN QUERY PLAN output.
+func (c *Conn) queryPlan(query string) ([]string, error) {
+	s, err := c.prepare("EXPLAIN QUERY PLAN " + query)
+	if err != nil {
+		return nil, err
+	}
+	defer s.finalize()
+	var details []string
+	err = s.Select(func(s *Stmt) error {
+		detail, _ := s.ScanText(3)
+		details = append(details, detail)
+		return nil
+	})
+	return details, err
+}
+
+// scannedTable returns the table (or alias) fully scanned (without index) or "".
+func scannedTable(detail string) string {
+	if !strings.HasPrefix(detail, "SCAN ") || strings.Contains(detail, " USING ") {
+		return ""
+	}
+	fields := strings.Fields(strings.TrimPrefix(detail[5:], "TABLE "))
+	if len(fields) == 0 {
+		return ""
+	}
+	if len(fields) >= 3 && fields[1] == "AS" { // SQLite < 3.36: SCAN TABLE t AS a
+		return fields[2]
+	}
+	return fields[0]
+}
+
+// tableAliases maps table names and their aliases (lower case) to the (known) table names.
+func tableAliases(tokens []string, known map[string]string) map[string]string {
+	aliases := make(map[string]string)
+	for i, token := range tokens {
+		table, ok := known[strings.ToLower(token)]
+		if !ok {
+			continue
+		}
+		aliases[strings.ToLower(token)] = table
+		j := i + 1
+		if j < len(tokens) && strings.EqualFold(tokens[j], "AS") {
+			j++
+		}
+		if j < len(tokens) && !IsKeyword(tokens[j]) && !isPunctuation(tokens[j]) {
+			aliases[strings.ToLower(tokens[j])] = table
+		}
+	}
+	return aliases
+}
+
+// sqlIdentifiers returns the identifiers (unquoted), keywords and punctuation of the SQL text, skipping literals.
+func sqlIdentifiers(sql string) []string {
+	var tokens []string
+	for i := 0; i < len(sql); {
+		switch ch := sql[i]; {
+		case ch == '\'':
+			i = skipQuoted(sql, i, '\'')
+		case ch == '"' || ch == '`' || ch == '[':
+			end := ch
+			if ch == '[' {
+				end = ']'
+			}
+			j := skipQuoted(sql, i, end)
+			if j-1 > i+1 {
+				tokens = append(tokens, strings.Replace(sql[i+1:j-1], string(end)+string(end), string(end), -1))
+			}
+			i = j
+		case ch == '_' || ch >= 0x80 || unicode.IsLetter(rune(ch)):
+			j := i
+			for j < len(sql) && (sql[j] == '_' || sql[j] == '$' || sql[j] >= 0x80 || unicode.IsLetter(rune(sql[j])) || unicode.IsDigit(rune(sql[j]))) {
+				j++
+			}
+			tokens = append(tokens, sql[i:j])
+			i = j
+		case ch >= '0' && ch <= '9':
+			for i < len(sql) && (sql[i] == '.' || unicode.IsLetter(rune(sql[i])) || unicode.IsDigit(rune(sql[i]))) {
+				i++
+			}
+		case unicode.IsSpace(rune(ch)):
+			i++
+		default: // punctuation
+			tokens = append(tokens, sql[i:i+1])
+			i++
+		}
+	}
+	return tokens
+}
+
+func isPunctuation(token string) bool {
+	return len(token) == 1 && token[0] != '_' && !unicode.IsLetter(rune(token[0]))
+}
+
+// skipQuoted returns the index after the closing quote (doubled quotes are escaped).
+func skipQuoted(sql string, start int, end byte) int {
+	for i := start + 1; i < len(sql); i++ {
+		if sql[i] == end {
+			if i+1 < len(sql) && sql[i+1] == end && end != ']' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+func createIndexSQL(table string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = QuoteIdentifier(column)
+	}
+	name := strings.ToLower(table + "_" + strings.Join(columns, "_") + "_idx")
+	return fmt.Sprintf("CREATE INDEX %s ON %s(%s)", QuoteIdentifier(name), QuoteIdentifier(table), strings.Join(quoted, ", "))
+}
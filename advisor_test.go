@@ -0,0 +1,49 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestAdviseIndexes(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.FastExec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, age INT, city TEXT);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INT, total REAL);
+		CREATE INDEX orders_total ON orders(total);
+		INSERT INTO users (name, age, city) VALUES ('a', 20, 'x'), ('b', 30, 'y'), ('c', 40, 'x');`)
+	checkNoError(t, err, "couldn't create tables: %s")
+
+	suggestions, err := db.AdviseIndexes(
+		"SELECT * FROM users AS u WHERE u.city = 'it''s' AND age > ?",
+		"SELECT o.total FROM orders o JOIN users ON users.id = o.user_id WHERE o.user_id = 1",
+		"SELECT * FROM users WHERE city = ? AND age > 18",
+		"SELECT * FROM orders WHERE total > 10",
+		"SELECT count(*) FROM users",
+	)
+	checkNoError(t, err, "couldn't advise indexes: %s")
+	assert.Equal(t, 2, len(suggestions))
+	assert.Equal(t, "users", suggestions[0].Table)
+	assert.Equal(t, []string{"city", "age"}, suggestions[0].Columns)
+	assert.Equal(t, `CREATE INDEX "users_city_age_idx" ON "users"("city", "age")`, suggestions[0].SQL)
+	assert.Equal(t, 2, len(suggestions[0].Queries))
+	assert.Equal(t, int64(6), suggestions[0].Benefit)
+	assert.Equal(t, "orders", suggestions[1].Table)
+	assert.Equal(t, []string{"user_id"}, suggestions[1].Columns)
+	assert.Equal(t, int64(0), suggestions[1].Benefit)
+
+	// nothing has been changed
+	indexes, err := db.Indexes("")
+	checkNoError(t, err, "couldn't list indexes: %s")
+	assert.Equal(t, 1, len(indexes))
+	assert.T(t, !db.InTransaction(), "savepoint expected to be released")
+
+	_, err = db.AdviseIndexes("SELECT * FROM unknown")
+	assert.T(t, err != nil, "error expected")
+}
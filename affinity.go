@@ -0,0 +1,99 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// AffinityViolation is reported when a connection is used by a goroutine
+// while another goroutine has a transaction or a statement in progress.
+type AffinityViolation struct {
+	Owner     int64  // goroutine which last used the connection
+	Goroutine int64  // offending goroutine
+	Op        string // offending operation
+}
+
+func (v *AffinityViolation) Error() string {
+	return fmt.Sprintf("sqlite connection used by goroutine %d (%s) while goroutine %d has a transaction or a statement in progress", v.Goroutine, v.Op, v.Owner)
+}
+
+type affinityCheck struct {
+	mu     sync.Mutex
+	owner  int64
+	report func(v *AffinityViolation)
+}
+
+// EnableAffinityCheck turns on a debug mode where the goroutine which last used the connection is recorded:
+// when another goroutine prepares or steps a statement while the connection is in a transaction
+// or has a statement which has not been reset, the violation is reported (panic when report is nil).
+// Connections (and statements) must not be used concurrently by multiple goroutines:
+// such misuses otherwise manifest as mysterious ErrMisuse or corrupted results.
+// It is expensive (the goroutine id is retrieved from the stack trace) and should be used only for debugging.
+func (c *Conn) EnableAffinityCheck(report func(v *AffinityViolation)) {
+	c.affinity = &affinityCheck{report: report}
+}
+
+// DisableAffinityCheck turns off the goroutine-affinity debug mode.
+func (c *Conn) DisableAffinityCheck() {
+	c.affinity = nil
+}
+
+func (c *Conn) checkAffinity(op string) {
+	a := c.affinity
+	if a == nil {
+		return
+	}
+	gid := goroutineID()
+	a.mu.Lock()
+	owner := a.owner
+	violation := owner != 0 && owner != gid && c.inUse()
+	if !violation {
+		a.owner = gid
+	}
+	a.mu.Unlock()
+	if violation {
+		v := &AffinityViolation{Owner: owner, Goroutine: gid, Op: op}
+		if a.report == nil {
+			panic(v)
+		}
+		a.report(v)
+	}
+}
+
+// inUse tells if the connection is in a transaction or has a busy statement.
+func (c *Conn) inUse() bool {
+	if C.sqlite3_get_autocommit(c.db) == 0 {
+		return true
+	}
+	for s := C.sqlite3_next_stmt(c.db, nil); s != nil; s = C.sqlite3_next_stmt(c.db, s) {
+		if C.sqlite3_stmt_busy(s) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+func goroutineID() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
+}
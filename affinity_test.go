@@ -0,0 +1,69 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestAffinityCheck(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	checkNoError(t, db.FastExec("CREATE TABLE test (x); INSERT INTO test VALUES (1), (2)"), "couldn't create table: %s")
+
+	var violations []*AffinityViolation
+	db.EnableAffinityCheck(func(v *AffinityViolation) {
+		violations = append(violations, v)
+	})
+	inOtherGoroutine := func(f func()) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			f()
+		}()
+		<-done
+	}
+
+	checkNoError(t, db.Begin(), "couldn't begin: %s")
+	inOtherGoroutine(func() {
+		_ = db.FastExec("INSERT INTO test VALUES (3)")
+	})
+	assert.Equal(t, 1, len(violations))
+	assert.Equal(t, "Conn.FastExec", violations[0].Op)
+	checkNoError(t, db.Rollback(), "couldn't rollback: %s")
+
+	// no transaction nor statement in progress
+	inOtherGoroutine(func() {
+		_ = db.FastExec("INSERT INTO test VALUES (3)")
+	})
+	assert.Equal(t, 1, len(violations))
+
+	s, err := db.Prepare("SELECT x FROM test")
+	checkNoError(t, err, "couldn't prepare: %s")
+	defer checkFinalize(s, t)
+	ok, err := s.Next()
+	checkNoError(t, err, "couldn't step: %s")
+	assert.T(t, ok)
+	inOtherGoroutine(func() {
+		_, _ = s.Next()
+	})
+	assert.Equal(t, 2, len(violations))
+	checkNoError(t, s.Reset(), "couldn't reset: %s")
+
+	db.EnableAffinityCheck(nil)
+	checkNoError(t, db.Begin(), "couldn't begin: %s")
+	inOtherGoroutine(func() {
+		defer func() {
+			_, ok := recover().(*AffinityViolation)
+			assert.T(t, ok, "panic expected")
+		}()
+		_ = db.FastExec("SELECT 1")
+	})
+	db.DisableAffinityCheck()
+	checkNoError(t, db.Rollback(), "couldn't rollback: %s")
+}
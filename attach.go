@@ -0,0 +1,168 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AttachManager manages the databases attached to a connection (one per tenant for example):
+// schema names are allocated, the number of attached databases is checked against LimitAttached
+// and the specified pragmas are applied to each newly attached database.
+//
+//	m := NewAttachManager(db, "journal_mode=WAL", "synchronous=NORMAL")
+//	schema, err := m.Attach("tenant1.db")
+//	tables, err := db.Tables(schema)
+//	err = m.Detach(schema)
+type AttachManager struct {
+	c       *Conn
+	prefix  string
+	pragmas []string
+	next    int
+	files   map[string]string // file name by schema name
+}
+
+// NewAttachManager creates a manager of the databases attached to c.
+// pragmas (like "journal_mode=WAL") are applied to each database attached by the manager.
+func NewAttachManager(c *Conn, pragmas ...string) *AttachManager {
+	return &AttachManager{c: c, prefix: "db", pragmas: pragmas, files: make(map[string]string)}
+}
+
+// SetPrefix changes the prefix of the allocated schema names ("db" by default: db1, db2, ...).
+func (m *AttachManager) SetPrefix(prefix string) {
+	m.prefix = prefix
+}
+
+// Attach attaches the specified database file with an allocated schema name which is returned.
+// If the file has already been attached by the manager, its schema name is returned.
+// (See http://sqlite.org/lang_attach.html)
+func (m *AttachManager) Attach(filename string) (string, error) {
+	if schema, ok := m.Schema(filename); ok {
+		return schema, nil
+	}
+	databases, err := m.c.Databases()
+	if err != nil {
+		return "", err
+	}
+	var schema string
+	for {
+		m.next++
+		schema = fmt.Sprintf("%s%d", m.prefix, m.next)
+		if !containsSchema(databases, schema) {
+			break
+		}
+	}
+	return schema, m.attach(filename, schema, databases)
+}
+
+// AttachAs attaches the specified database file with the specified schema name.
+func (m *AttachManager) AttachAs(filename, schema string) error {
+	databases, err := m.c.Databases()
+	if err != nil {
+		return err
+	}
+	if containsSchema(databases, schema) {
+		return m.c.specificError("database %q is already in use", schema)
+	}
+	return m.attach(filename, schema, databases)
+}
+
+func (m *AttachManager) attach(filename, schema string, databases map[string]string) error {
+	if max := int(m.c.Limit(LimitAttached)); attachedCount(databases) >= max {
+		return m.c.specificError("too many attached databases (max %d)", max)
+	}
+	if err := m.c.FastExec("ATTACH DATABASE " + QuoteLiteral(filename) + " AS " + QuoteIdentifier(schema)); err != nil {
+		return err
+	}
+	for _, p := range m.pragmas {
+		if err := m.c.FastExec(pragma(schema, p)); err != nil {
+			_ = m.c.FastExec("DETACH DATABASE " + QuoteIdentifier(schema))
+			return err
+		}
+	}
+	m.files[schema] = filename
+	return nil
+}
+
+// Detach detaches the database with the specified schema name.
+func (m *AttachManager) Detach(schema string) error {
+	if err := m.c.FastExec("DETACH DATABASE " + QuoteIdentifier(schema)); err != nil {
+		return err
+	}
+	for name := range m.files {
+		if strings.EqualFold(name, schema) {
+			delete(m.files, name)
+		}
+	}
+	return nil
+}
+
+// DetachAll detaches all the databases attached by the manager.
+func (m *AttachManager) DetachAll() error {
+	for schema := range m.files {
+		if err := m.Detach(schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Schema returns the schema name of the specified file (when attached by the manager).
+func (m *AttachManager) Schema(filename string) (string, bool) {
+	for schema, file := range m.files {
+		if file == filename {
+			return schema, true
+		}
+	}
+	return "", false
+}
+
+// Attached returns the file name by schema name of the databases attached by the manager
+// (databases detached directly with the DETACH statement are forgotten).
+func (m *AttachManager) Attached() (map[string]string, error) {
+	databases, err := m.c.Databases()
+	if err != nil {
+		return nil, err
+	}
+	attached := make(map[string]string, len(m.files))
+	for schema, file := range m.files {
+		if containsSchema(databases, schema) {
+			attached[schema] = file
+		} else {
+			delete(m.files, schema)
+		}
+	}
+	return attached, nil
+}
+
+// Available returns how many databases can still be attached (see LimitAttached).
+func (m *AttachManager) Available() (int, error) {
+	databases, err := m.c.Databases()
+	if err != nil {
+		return 0, err
+	}
+	return int(m.c.Limit(LimitAttached)) - attachedCount(databases), nil
+}
+
+func containsSchema(databases map[string]string, schema string) bool {
+	for name := range databases {
+		if strings.EqualFold(name, schema) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachedCount returns the number of attached databases (main and temp excluded).
+func attachedCount(databases map[string]string) int {
+	n := len(databases)
+	for _, name := range []string{"main", "temp"} {
+		if _, ok := databases[name]; ok {
+			n--
+		}
+	}
+	return n
+}
@@ -0,0 +1,61 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestAttachManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosqlite-attach")
+	checkNoError(t, err, "couldn't create temp dir: %s")
+	defer os.RemoveAll(dir)
+	db := open(t)
+	defer checkClose(db, t)
+	db.SetLimit(LimitAttached, 2)
+
+	m := NewAttachManager(db, "journal_mode=WAL")
+	f1 := filepath.Join(dir, "it's.db")
+	s1, err := m.Attach(f1)
+	checkNoError(t, err, "attach error: %s")
+	assert.Equal(t, "db1", s1)
+	s, err := m.Attach(f1)
+	checkNoError(t, err, "attach error: %s")
+	assert.Equal(t, s1, s)
+	mode, err := db.JournalMode(s1)
+	checkNoError(t, err, "journal mode error: %s")
+	assert.Equal(t, "wal", mode)
+
+	checkNoError(t, db.FastExec(`CREATE TABLE db1.t (x)`), "create error: %s")
+	tables, err := db.Tables(s1)
+	checkNoError(t, err, "tables error: %s")
+	assert.Equal(t, []string{"t"}, tables)
+
+	err = m.AttachAs(filepath.Join(dir, "other.db"), "DB1")
+	assert.T(t, err != nil, "name already in use")
+	checkNoError(t, m.AttachAs(filepath.Join(dir, "two.db"), `we"ird`), "attach error: %s")
+	n, err := m.Available()
+	checkNoError(t, err, "available error: %s")
+	assert.Equal(t, 0, n)
+	_, err = m.Attach(filepath.Join(dir, "three.db"))
+	assert.T(t, err != nil, "too many attached databases")
+
+	checkNoError(t, db.FastExec(`DETACH DATABASE "we""ird"`), "detach error: %s")
+	attached, err := m.Attached()
+	checkNoError(t, err, "attached error: %s")
+	assert.Equal(t, map[string]string{"db1": f1}, attached)
+
+	checkNoError(t, m.DetachAll(), "detach error: %s")
+	databases, err := db.Databases()
+	checkNoError(t, err, "databases error: %s")
+	_, ok := databases["db1"]
+	assert.T(t, !ok, "db1 detached")
+}
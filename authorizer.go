@@ -0,0 +1,178 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"strings"
+)
+
+// DenyActions returns an authorizer which denies the specified actions and allows all others.
+//
+//	db.SetAuthorizer(DenyActions(DropTable, Attach, Detach), nil)
+func DenyActions(actions ...Action) Authorizer {
+	denied := make(map[Action]bool, len(actions))
+	for _, a := range actions {
+		denied[a] = true
+	}
+	return func(udp interface{}, action Action, arg1, arg2, dbName, triggerName string) Auth {
+		if denied[action] {
+			return AuthDeny
+		}
+		return AuthOk
+	}
+}
+
+// readOnlyPragmas are the pragmas which never change the database nor the connection,
+// whatever their argument (a table or index name usually).
+var readOnlyPragmas = map[string]bool{
+	"collation_list": true, "compile_options": true, "data_version": true, "database_list": true,
+	"foreign_key_check": true, "foreign_key_list": true, "freelist_count": true, "function_list": true,
+	"index_info": true, "index_list": true, "index_xinfo": true, "integrity_check": true,
+	"module_list": true, "page_count": true, "pragma_list": true, "quick_check": true,
+	"table_info": true, "table_list": true, "table_xinfo": true,
+}
+
+// queryPragmas are the pragmas whose value can be queried without argument (but changed with one).
+var queryPragmas = map[string]bool{
+	"analysis_limit": true, "application_id": true, "auto_vacuum": true, "automatic_index": true,
+	"busy_timeout": true, "cache_size": true, "cache_spill": true, "cell_size_check": true,
+	"checkpoint_fullfsync": true, "defer_foreign_keys": true, "encoding": true, "foreign_keys": true,
+	"fullfsync": true, "hard_heap_limit": true, "ignore_check_constraints": true, "journal_mode": true,
+	"journal_size_limit": true, "legacy_alter_table": true, "locking_mode": true, "max_page_count": true,
+	"mmap_size": true, "page_size": true, "query_only": true, "read_uncommitted": true,
+	"recursive_triggers": true, "reverse_unordered_selects": true, "schema_version": true,
+	"secure_delete": true, "soft_heap_limit": true, "synchronous": true, "temp_store": true,
+	"threads": true, "trusted_schema": true, "user_version": true, "wal_autocheckpoint": true,
+}
+
+// ReadOnlyAuthorizer returns an authorizer which only allows reading:
+// SELECT statements (including recursive common table expressions), function calls,
+// transaction control, introspection pragmas (like table_info) and pragmas used to query (not to change) a value.
+// Other pragmas (like incremental_vacuum, wal_checkpoint or optimize) are denied.
+func ReadOnlyAuthorizer() Authorizer {
+	return func(udp interface{}, action Action, arg1, arg2, dbName, triggerName string) Auth {
+		switch action {
+		case Select, Read, Function, Recursive, Transaction, Savepoint:
+			return AuthOk
+		case Pragma:
+			name := strings.ToLower(arg1)
+			if readOnlyPragmas[name] || (queryPragmas[name] && len(arg2) == 0) {
+				return AuthOk
+			}
+		}
+		return AuthDeny
+	}
+}
+
+// AllowTables returns an authorizer which denies reading or writing any table except the specified ones.
+// The database name can be empty (tables are allowed in any database), "main", "temp" or the name of an attached database.
+// Table names are compared case insensitively.
+// Other actions are allowed (they may be restricted by chaining with another authorizer).
+// See ChainAuthorizers
+func AllowTables(dbName string, tables []string) Authorizer {
+	allowed := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		allowed[strings.ToLower(table)] = true
+	}
+	return func(udp interface{}, action Action, arg1, arg2, db, triggerName string) Auth {
+		switch action {
+		case Read, Insert, Update, Delete:
+			if !allowed[strings.ToLower(arg1)] || (len(dbName) > 0 && !strings.EqualFold(dbName, db)) {
+				return AuthDeny
+			}
+		}
+		return AuthOk
+	}
+}
+
+// ChainAuthorizers returns an authorizer which combines the specified ones:
+// the most restrictive result wins (AuthDeny over AuthIgnore over AuthOk).
+// Authorizers are evaluated in order until one denies the action.
+func ChainAuthorizers(authorizers ...Authorizer) Authorizer {
+	return func(udp interface{}, action Action, arg1, arg2, dbName, triggerName string) Auth {
+		result := AuthOk
+		for _, f := range authorizers {
+			switch f(udp, action, arg1, arg2, dbName, triggerName) {
+			case AuthDeny:
+				return AuthDeny
+			case AuthIgnore:
+				result = AuthIgnore
+			}
+		}
+		return result
+	}
+}
+
+// AuthRequest describes an authorization request with named fields
+// (instead of the positional arguments whose meaning depends on the action).
+// Only the fields relevant to the action are set.
+type AuthRequest struct {
+	Action        Action
+	Database      string // database name ("main", "temp" or attached name); the detached database for Detach
+	TriggerOrView string // innermost trigger or view responsible for the access (empty for top-level SQL)
+	Table         string // CreateTable, DropTable, CreateIndex, DropIndex, CreateTrigger, DropTrigger, Insert, Delete, Update, Read, AlterTable, Analyze, CreateVTable, DropVTable
+	Column        string // Read, Update
+	Index         string // CreateIndex, DropIndex, Reindex
+	Trigger       string // CreateTrigger, DropTrigger
+	View          string // CreateView, DropView
+	Module        string // CreateVTable, DropVTable
+	Pragma        string // Pragma name
+	PragmaArg     string // Pragma argument (empty when the pragma value is queried)
+	Function      string // Function
+	Operation     string // Transaction and Savepoint: BEGIN, COMMIT, RELEASE or ROLLBACK
+	Savepoint     string // Savepoint
+	Filename      string // Attach
+}
+
+// NewAuthRequest maps the authorizer arguments to an AuthRequest.
+// (See http://sqlite.org/c3ref/c_alter_table.html)
+func NewAuthRequest(action Action, arg1, arg2, dbName, triggerName string) AuthRequest {
+	r := AuthRequest{Action: action, Database: dbName, TriggerOrView: triggerName}
+	switch action {
+	case CreateIndex, CreateTempIndex, DropIndex, DropTempIndex:
+		r.Index, r.Table = arg1, arg2
+	case CreateTable, CreateTempTable, DropTable, DropTempTable, Insert, Delete, Analyze:
+		r.Table = arg1
+	case CreateTrigger, CreateTempTrigger, DropTrigger, DropTempTrigger:
+		r.Trigger, r.Table = arg1, arg2
+	case CreateView, CreateTempView, DropView, DropTempView:
+		r.View = arg1
+	case Read, Update:
+		r.Table, r.Column = arg1, arg2
+	case Pragma:
+		r.Pragma, r.PragmaArg = arg1, arg2
+	case Transaction:
+		r.Operation = arg1
+	case Savepoint:
+		r.Operation, r.Savepoint = arg1, arg2
+	case Attach:
+		r.Filename = arg1
+	case Detach:
+		r.Database = arg1
+	case AlterTable:
+		r.Database, r.Table = arg1, arg2
+	case Reindex:
+		r.Index = arg1
+	case CreateVTable, DropVTable:
+		r.Table, r.Module = arg1, arg2
+	case Function:
+		r.Function = arg2
+	}
+	return r
+}
+
+// RequestAuthorizer adapts f, which receives structured requests, to the Authorizer signature.
+//
+//	db.SetAuthorizer(RequestAuthorizer(func(r AuthRequest) Auth {
+//		if r.Action == Read && r.Column == "password" {
+//			return AuthIgnore // NULL is read instead
+//		}
+//		return AuthOk
+//	}), nil)
+func RequestAuthorizer(f func(r AuthRequest) Auth) Authorizer {
+	return func(udp interface{}, action Action, arg1, arg2, dbName, triggerName string) Auth {
+		return f(NewAuthRequest(action, arg1, arg2, dbName, triggerName))
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestAuthorizerPolicies(t *testing.T) {
+	deny := DenyActions(DropTable, Attach)
+	assert.Equal(t, AuthDeny, deny(nil, DropTable, "test", "", "main", ""))
+	assert.Equal(t, AuthOk, deny(nil, Read, "test", "a", "main", ""))
+
+	ro := ReadOnlyAuthorizer()
+	assert.Equal(t, AuthOk, ro(nil, Read, "test", "a", "main", ""))
+	assert.Equal(t, AuthOk, ro(nil, Pragma, "user_version", "", "", ""))
+	assert.Equal(t, AuthDeny, ro(nil, Pragma, "user_version", "1", "", ""))
+	assert.Equal(t, AuthOk, ro(nil, Pragma, "TABLE_INFO", "test", "", ""))
+	assert.Equal(t, AuthDeny, ro(nil, Pragma, "incremental_vacuum", "", "", ""))
+	assert.Equal(t, AuthDeny, ro(nil, Pragma, "wal_checkpoint", "", "", ""))
+	assert.Equal(t, AuthDeny, ro(nil, Pragma, "optimize", "", "", ""))
+	assert.Equal(t, AuthDeny, ro(nil, Insert, "test", "", "main", ""))
+
+	tables := AllowTables("main", []string{"Test"})
+	assert.Equal(t, AuthOk, tables(nil, Read, "test", "a", "main", ""))
+	assert.Equal(t, AuthDeny, tables(nil, Read, "test", "a", "temp", ""))
+	assert.Equal(t, AuthDeny, tables(nil, Update, "other", "a", "main", ""))
+	assert.Equal(t, AuthOk, tables(nil, DropTable, "other", "", "main", ""))
+
+	ignore := func(udp interface{}, action Action, arg1, arg2, dbName, triggerName string) Auth {
+		return AuthIgnore
+	}
+	assert.Equal(t, AuthIgnore, ChainAuthorizers(tables, ignore)(nil, Read, "test", "a", "main", ""))
+	assert.Equal(t, AuthDeny, ChainAuthorizers(ignore, ro)(nil, Delete, "test", "", "main", ""))
+	assert.Equal(t, AuthOk, ChainAuthorizers()(nil, Delete, "test", "", "main", ""))
+}
+
+func TestReadOnlyAuthorizer(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+	checkNoError(t, db.FastExec("CREATE TABLE other (data TEXT)"), "%s")
+
+	checkNoError(t, db.SetAuthorizer(ChainAuthorizers(ReadOnlyAuthorizer(), AllowTables("", []string{"test"})), nil), "%s")
+	var n int
+	checkNoError(t, db.OneValue("SELECT count(*) FROM test", &n), "read error: %s")
+	err := db.OneValue("SELECT count(*) FROM other", &n)
+	assert.T(t, err != nil, "error expected")
+	err = db.Exec("INSERT INTO test (a_string) VALUES ('denied')")
+	assert.T(t, err != nil, "error expected")
+	err = db.Exec("DROP TABLE test")
+	assert.T(t, err != nil, "error expected")
+	checkNoError(t, db.Select("PRAGMA table_info(test)", func(s *Stmt) error { return nil }), "introspection error: %s")
+	err = db.Exec("PRAGMA incremental_vacuum")
+	assert.T(t, err != nil, "error expected")
+	checkNoError(t, db.SetAuthorizer(nil, nil), "%s")
+}
+
+func TestAuthRequest(t *testing.T) {
+	r := NewAuthRequest(Read, "test", "a_string", "main", "")
+	assert.Equal(t, AuthRequest{Action: Read, Database: "main", Table: "test", Column: "a_string"}, r)
+	r = NewAuthRequest(Function, "", "upper", "", "")
+	assert.Equal(t, "upper", r.Function)
+	r = NewAuthRequest(CreateIndex, "idx", "test", "main", "")
+	assert.Equal(t, "idx", r.Index)
+	assert.Equal(t, "test", r.Table)
+	r = NewAuthRequest(Detach, "aux", "", "", "")
+	assert.Equal(t, "aux", r.Database)
+
+	skipIfCgoCheckActive(t)
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+	checkNoError(t, db.FastExec("INSERT INTO test (a_string) VALUES ('secret')"), "%s")
+	checkNoError(t, db.SetAuthorizer(RequestAuthorizer(func(r AuthRequest) Auth {
+		if r.Action == Read && r.Column == "a_string" {
+			return AuthIgnore
+		}
+		return AuthOk
+	}), nil), "%s")
+	defer db.SetAuthorizer(nil, nil)
+	var s interface{}
+	checkNoError(t, db.OneValue("SELECT a_string FROM test", &s), "read error: %s")
+	assert.Equal(t, nil, s)
+}
@@ -36,6 +36,14 @@ type BlobReadWriter struct {
 // (See http://sqlite.org/lang_corefunc.html#zeroblob)
 type ZeroBlobLength int32
 
+// ZeroBlobLength64 is like ZeroBlobLength but with a 64-bit length
+// (the length is still limited by LimitLength).
+//
+//	stmt.Bind(..., ZeroBlobLength64(1<<31), ...)
+//
+// (See http://sqlite.org/c3ref/bind_blob.html)
+type ZeroBlobLength64 int64
+
 // NewBlobReader opens a BLOB for incremental I/O in read-only mode.
 //
 // (See http://sqlite.org/c3ref/blob_open.html)
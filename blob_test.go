@@ -7,6 +7,7 @@ package sqlite_test
 import (
 	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/bmizerany/assert"
@@ -127,3 +128,36 @@ func TestZeroLengthBlob(t *testing.T) {
 	checkNoError(t, err, "select error: %s")
 	assert.T(t, blob == nil, "nil blob expected")
 }
+
+func TestBlobTooBig(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	db.SetLimit(LimitLength, 10)
+
+	var n int
+	err := db.OneValue("SELECT length(?)", &n, ZeroBlobLength64(10))
+	checkNoError(t, err, "error while binding zeroblob: %s")
+	assert.Equal(t, 10, n)
+
+	for _, v := range []interface{}{make([]byte, 11), "01234567890", ZeroBlobLength64(1 << 32)} {
+		err = db.OneValue("SELECT length(?)", &n, v)
+		assert.T(t, err != nil, "too big error expected")
+		assert.T(t, strings.Contains(err.Error(), "too big"), err.Error())
+	}
+}
+
+func TestResultZeroblobTooBig(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	db.SetLimit(LimitLength, 10)
+
+	var n int
+	err := db.CreateScalarFunction("big", 0, true, nil, func(ctx *ScalarContext, nArg int) {
+		ctx.ResultZeroblob64(ZeroBlobLength64(11))
+	}, nil)
+	checkNoError(t, err, "couldn't create function: %s")
+	err = db.OneValue("SELECT length(big())", &n)
+	assert.T(t, err != nil, "too big error expected")
+}
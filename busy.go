@@ -0,0 +1,148 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+#include <stdlib.h>
+
+// Busy timeout handler counting its invocations and the time spent sleeping
+// (without calling Go).
+typedef struct {
+	int ms;
+	sqlite3_int64 nCall;
+	sqlite3_int64 msWait;
+} goSqlite3BusyTimer;
+
+// Same delays as the default busy handler (see sqliteDefaultBusyCallback).
+static int goSqlite3TimerBusyHandler(void *p, int count) {
+	static const unsigned char delays[] = { 1, 2, 5, 10, 15, 20, 25, 25, 25, 50, 50, 100 };
+	static const unsigned char totals[] = { 0, 1, 3, 8, 18, 33, 53, 78, 103, 128, 178, 228 };
+	goSqlite3BusyTimer *t = p;
+	int delay, prior;
+	t->nCall++;
+	if (count < 12) {
+		delay = delays[count];
+		prior = totals[count];
+	} else {
+		delay = delays[11];
+		prior = totals[11] + delay * (count - 11);
+	}
+	if (prior + delay > t->ms) {
+		delay = t->ms - prior;
+		if (delay <= 0) {
+			return 0;
+		}
+	}
+	t->msWait += sqlite3_sleep(delay);
+	return 1;
+}
+
+static int goSqlite3BusyTimeout(sqlite3 *db, goSqlite3BusyTimer *t, int ms) {
+	if (ms <= 0) {
+		if (t) {
+			t->ms = 0;
+		}
+		return sqlite3_busy_timeout(db, 0);
+	}
+	t->ms = ms;
+	return sqlite3_busy_handler(db, goSqlite3TimerBusyHandler, t);
+}
+*/
+import "C"
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// BusyStats quantifies lock contention.
+// See Conn.BusyStats and TotalBusyStats.
+type BusyStats struct {
+	Busy  int64         // number of SQLITE_BUSY errors
+	Calls int64         // number of busy handler (or busy timeout) invocations
+	Wait  time.Duration // cumulative time spent in the busy handler (or sleeping in the busy timeout)
+}
+
+// busyCounters are updated atomically so that they can be read by a monitoring goroutine.
+type busyCounters struct {
+	busy  int64
+	calls int64
+	wait  int64
+}
+
+var totalBusy busyCounters
+
+func (b *busyCounters) add(busy, calls int64, wait time.Duration) {
+	for _, b := range []*busyCounters{b, &totalBusy} {
+		atomic.AddInt64(&b.busy, busy)
+		atomic.AddInt64(&b.calls, calls)
+		atomic.AddInt64(&b.wait, int64(wait))
+	}
+}
+
+func (b *busyCounters) stats(reset bool) BusyStats {
+	load := atomic.LoadInt64
+	if reset {
+		load = func(addr *int64) int64 { return atomic.SwapInt64(addr, 0) }
+	}
+	return BusyStats{Busy: load(&b.busy), Calls: load(&b.calls), Wait: time.Duration(load(&b.wait))}
+}
+
+// BusyStats returns the lock contention counters of the connection (and resets them when reset is true).
+// It may be called from another goroutine than the one using the connection.
+// The busy timeout invocations are accounted when the current statement step (or exec) ends.
+func (c *Conn) BusyStats(reset bool) BusyStats {
+	return c.busyCounters.stats(reset)
+}
+
+// TotalBusyStats returns the lock contention counters aggregated over all connections
+// (and resets them when reset is true).
+func TotalBusyStats(reset bool) BusyStats {
+	return totalBusy.stats(reset)
+}
+
+// countBusy accounts rv when it is an SQLITE_BUSY error
+// and the busy timeout invocations since the last call.
+func (c *Conn) countBusy(rv C.int) {
+	var busy, calls int64
+	var wait time.Duration
+	if rv&0xff == C.SQLITE_BUSY {
+		busy = 1
+	}
+	if t := (*C.goSqlite3BusyTimer)(c.busyTimer); t != nil && t.nCall > 0 {
+		calls, wait = int64(t.nCall), time.Duration(t.msWait)*time.Millisecond
+		t.nCall, t.msWait = 0, 0
+	}
+	if busy > 0 || calls > 0 {
+		c.busyCounters.add(busy, calls, wait)
+	}
+}
+
+// busyTimerTimeout returns the timeout of the counting handler (zero when it is not installed).
+func (c *Conn) busyTimerTimeout() time.Duration {
+	if t := (*C.goSqlite3BusyTimer)(c.busyTimer); t != nil {
+		return time.Duration(t.ms) * time.Millisecond
+	}
+	return 0
+}
+
+// setBusyTimeout replaces the SQLite busy timeout by an equivalent handler whose invocations are counted.
+func (c *Conn) setBusyTimeout(d time.Duration) C.int {
+	if d > 0 && c.busyTimer == nil {
+		c.busyTimer = C.calloc(1, C.sizeof_goSqlite3BusyTimer)
+		if c.busyTimer == nil {
+			return C.SQLITE_NOMEM
+		}
+	}
+	return C.goSqlite3BusyTimeout(c.db, (*C.goSqlite3BusyTimer)(c.busyTimer), C.int(d/time.Millisecond))
+}
+
+func (c *Conn) freeBusyTimer() {
+	if c.busyTimer != nil {
+		C.free(c.busyTimer)
+		c.busyTimer = nil
+	}
+}
@@ -38,6 +38,47 @@ func TestInterrupt(t *testing.T) {
 	}
 }
 
+func TestCloseWithTimeout(t *testing.T) {
+	db := open(t)
+	s, err := db.Prepare("WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c) SELECT x FROM c")
+	checkNoError(t, err, "couldn't prepare stmt: %#v")
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		var err error
+		var ok bool
+		for i := 0; err == nil; i++ {
+			if ok, err = s.Next(); !ok {
+				break
+			}
+			if i == 0 {
+				close(started)
+			}
+		}
+		done <- err
+	}()
+	<-started
+	checkNoError(t, db.CloseWithTimeout(time.Second), "error while closing connection: %s")
+	assert.T(t, db.IsClosed())
+	err = <-done
+	if se, ok := err.(StmtError); !ok || se.Code() != ErrInterrupt {
+		t.Errorf("got %#v; want interrupt", err)
+	}
+}
+
+func TestCloseWithTimeoutNotReset(t *testing.T) {
+	db := open(t)
+	s, err := db.Prepare("SELECT 1 UNION ALL SELECT 2")
+	checkNoError(t, err, "couldn't prepare stmt: %#v")
+	ok, err := s.Next()
+	checkNoError(t, err, "couldn't step: %s")
+	assert.T(t, ok)
+	start := time.Now()
+	checkNoError(t, db.CloseWithTimeout(20*time.Millisecond), "error while closing connection: %s")
+	assert.T(t, time.Since(start) >= 20*time.Millisecond, "timeout expected")
+	assert.T(t, db.IsClosed())
+}
+
 func openTwoConnSameDb(t *testing.T) (*os.File, *Conn, *Conn) {
 	f, err := ioutil.TempFile("", "gosqlite-test")
 	checkNoError(t, err, "couldn't create temp file: %s")
@@ -86,6 +127,79 @@ func TestBusyTimeout(t *testing.T) {
 	//<- join
 }
 
+func TestGetBusyTimeout(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	d, err := db.GetBusyTimeout()
+	checkNoError(t, err, "couldn't get busy timeout: %s")
+	assert.Equal(t, time.Duration(0), d)
+
+	checkNoError(t, db.BusyTimeout(1500*time.Millisecond), "couldn't set busy timeout: %s")
+	d, err = db.GetBusyTimeout()
+	checkNoError(t, err, "couldn't get busy timeout: %s")
+	assert.Equal(t, 1500*time.Millisecond, d)
+
+	checkNoError(t, db.BusyTimeout(0), "couldn't clear busy timeout: %s")
+	d, err = db.GetBusyTimeout()
+	checkNoError(t, err, "couldn't get busy timeout: %s")
+	assert.Equal(t, time.Duration(0), d)
+}
+
+func TestStmtWithBusyTimeout(t *testing.T) {
+	f, db1, db2 := openTwoConnSameDb(t)
+	defer os.Remove(f.Name())
+	defer checkClose(db1, t)
+	defer checkClose(db2, t)
+	checkNoError(t, db2.BusyTimeout(10*time.Millisecond), "couldn't set busy timeout: %s")
+	s, err := db2.Prepare("SELECT count(*) FROM sqlite_master")
+	checkNoError(t, err, "couldn't prepare stmt: %#v")
+	defer checkFinalize(s, t)
+	checkNoError(t, db1.BeginTransaction(Exclusive), "couldn't begin transaction: %s")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		db1.Rollback()
+	}()
+
+	var n int
+	_, err = s.WithBusyTimeout(5 * time.Second).SelectOneRow(&n)
+	checkNoError(t, err, "couldn't select: %#v")
+	d, err := db2.GetBusyTimeout()
+	checkNoError(t, err, "couldn't get busy timeout: %s")
+	assert.Equal(t, 10*time.Millisecond, d)
+}
+
+func TestBeginTransactionTimeout(t *testing.T) {
+	f, db1, db2 := openTwoConnSameDb(t)
+	defer os.Remove(f.Name())
+	defer checkClose(db1, t)
+	defer checkClose(db2, t)
+	checkNoError(t, db2.BusyTimeout(10*time.Millisecond), "couldn't set busy timeout: %s")
+	checkNoError(t, db1.BeginTransaction(Exclusive), "couldn't begin transaction: %s")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		db1.Rollback()
+	}()
+
+	checkNoError(t, db2.BeginTransactionTimeout(Immediate, 5*time.Second), "couldn't begin transaction: %s")
+	d, err := db2.GetBusyTimeout()
+	checkNoError(t, err, "couldn't get busy timeout: %s")
+	assert.Equal(t, 5*time.Second, d)
+	s, err := db2.Prepare("SELECT 1")
+	checkNoError(t, err, "couldn't prepare stmt: %s")
+	var n int
+	_, err = s.WithBusyTimeout(time.Second).SelectOneRow(&n)
+	checkNoError(t, err, "couldn't select: %s")
+	checkNoError(t, s.Finalize(), "couldn't finalize stmt: %s")
+	d, err = db2.GetBusyTimeout()
+	checkNoError(t, err, "couldn't get busy timeout: %s")
+	assert.Equal(t, 5*time.Second, d)
+	checkNoError(t, db2.Commit(), "couldn't commit: %s")
+	d, err = db2.GetBusyTimeout()
+	checkNoError(t, err, "couldn't get busy timeout: %s")
+	assert.Equal(t, 10*time.Millisecond, d)
+}
+
 func TestBusyHandler(t *testing.T) {
 	skipIfCgoCheckActive(t)
 
@@ -117,3 +231,37 @@ func TestBusyHandler(t *testing.T) {
 	checkNoError(t, err, "couldn't query schema version: %#v")
 	assert.T(t, called, "expected busy handler to be called")
 }
+
+func TestBusyStats(t *testing.T) {
+	skipIfCgoCheckActive(t)
+	f, db1, db2 := openTwoConnSameDb(t)
+	defer os.Remove(f.Name())
+	defer checkClose(db1, t)
+	defer checkClose(db2, t)
+	db2.BusyStats(true)
+	total := TotalBusyStats(false)
+
+	checkNoError(t, db1.BeginTransaction(Exclusive), "couldn't begin transaction: %s")
+	checkNoError(t, db2.BusyTimeout(20*time.Millisecond), "couldn't set busy timeout: %s")
+	_, err := db2.SchemaVersion("")
+	assert.T(t, err != nil, "busy error expected")
+	stats := db2.BusyStats(false)
+	assert.Equal(t, int64(1), stats.Busy)
+	assert.Tf(t, stats.Calls > 1, "got %d busy timeout calls", stats.Calls)
+	assert.Tf(t, stats.Wait >= 15*time.Millisecond, "got %s waiting", stats.Wait)
+
+	checkNoError(t, db2.BusyHandler(func(udp interface{}, count int) bool {
+		time.Sleep(time.Millisecond)
+		return count < 2
+	}, nil), "couldn't set busy handler: %s")
+	_, err = db2.SchemaVersion("")
+	assert.T(t, err != nil, "busy error expected")
+	stats = db2.BusyStats(true)
+	assert.Equal(t, int64(2), stats.Busy)
+	assert.Tf(t, stats.Calls >= 3+2, "got %d busy handler calls", stats.Calls)
+
+	totalAfter := TotalBusyStats(false)
+	assert.Tf(t, totalAfter.Busy >= total.Busy+2, "got %d total busy errors", totalAfter.Busy)
+	assert.Equal(t, BusyStats{}, db2.BusyStats(false))
+	checkNoError(t, db1.Rollback(), "couldn't rollback transaction: %s")
+}
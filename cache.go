@@ -60,6 +60,7 @@ func (c *cache) release(s *Stmt) error {
 		s.finalize()
 		return nil
 	}
+	s.busyTimeout = 0
 	c.m.Lock()
 	defer c.m.Unlock()
 	c.l.PushFront(s)
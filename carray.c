@@ -0,0 +1,205 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Table-valued function carray(?) returning the content of an array bound with sqlite3_bind_pointer.
+// Adapted from ext/misc/carray.c (but only the single argument form is supported).
+
+#include <sqlite3.h>
+#include <stdlib.h>
+#include <string.h>
+
+#define GO_CARRAY_INT64  0
+#define GO_CARRAY_DOUBLE 1
+#define GO_CARRAY_TEXT   2
+
+static void goCArrayFreeData(void *aData, int nData, int eType) {
+	int i;
+	if (eType == GO_CARRAY_TEXT && aData != 0) {
+		for (i = 0; i < nData; i++) {
+			free(((char **)aData)[i]);
+		}
+	}
+	free(aData);
+}
+
+#if SQLITE_VERSION_NUMBER >= 3020000
+
+static const char *goCArrayPointerType = "gosqlite_carray";
+
+typedef struct goCArray goCArray;
+struct goCArray {
+	void *aData;   /* malloc'ed content */
+	int nData;     /* Number of elements */
+	int eType;     /* GO_CARRAY_INT64, GO_CARRAY_DOUBLE or GO_CARRAY_TEXT */
+};
+
+typedef struct carray_cursor carray_cursor;
+struct carray_cursor {
+	sqlite3_vtab_cursor base;  /* Base class */
+	goCArray *pArray;          /* Bound array (may be NULL) */
+	sqlite3_int64 iRowid;      /* The rowid (starting at 1) */
+};
+
+static void goCArrayFree(void *p) {
+	goCArray *pArray = (goCArray *)p;
+	if (pArray == 0) {
+		return;
+	}
+	goCArrayFreeData(pArray->aData, pArray->nData, pArray->eType);
+	free(pArray);
+}
+
+static int carrayConnect(sqlite3 *db, void *pAux, int argc, const char *const *argv, sqlite3_vtab **ppVtab, char **pzErr) {
+	sqlite3_vtab *pNew;
+	int rc = sqlite3_declare_vtab(db, "CREATE TABLE x(value, pointer HIDDEN)");
+	if (rc != SQLITE_OK) {
+		return rc;
+	}
+	pNew = *ppVtab = sqlite3_malloc(sizeof(*pNew));
+	if (pNew == 0) {
+		return SQLITE_NOMEM;
+	}
+	memset(pNew, 0, sizeof(*pNew));
+	return SQLITE_OK;
+}
+
+static int carrayDisconnect(sqlite3_vtab *pVtab) {
+	sqlite3_free(pVtab);
+	return SQLITE_OK;
+}
+
+static int carrayOpen(sqlite3_vtab *p, sqlite3_vtab_cursor **ppCursor) {
+	carray_cursor *pCur = sqlite3_malloc(sizeof(*pCur));
+	if (pCur == 0) {
+		return SQLITE_NOMEM;
+	}
+	memset(pCur, 0, sizeof(*pCur));
+	*ppCursor = &pCur->base;
+	return SQLITE_OK;
+}
+
+static int carrayClose(sqlite3_vtab_cursor *cur) {
+	sqlite3_free(cur);
+	return SQLITE_OK;
+}
+
+static int carrayNext(sqlite3_vtab_cursor *cur) {
+	((carray_cursor *)cur)->iRowid++;
+	return SQLITE_OK;
+}
+
+static int carrayColumn(sqlite3_vtab_cursor *cur, sqlite3_context *ctx, int i) {
+	carray_cursor *pCur = (carray_cursor *)cur;
+	int idx = (int)(pCur->iRowid - 1);
+	if (i != 0) {
+		return SQLITE_OK; /* pointer is NULL */
+	}
+	switch (pCur->pArray->eType) {
+	case GO_CARRAY_INT64:
+		sqlite3_result_int64(ctx, ((sqlite3_int64 *)pCur->pArray->aData)[idx]);
+		break;
+	case GO_CARRAY_DOUBLE:
+		sqlite3_result_double(ctx, ((double *)pCur->pArray->aData)[idx]);
+		break;
+	case GO_CARRAY_TEXT:
+		sqlite3_result_text(ctx, ((char **)pCur->pArray->aData)[idx], -1, SQLITE_STATIC);
+		break;
+	}
+	return SQLITE_OK;
+}
+
+static int carrayRowid(sqlite3_vtab_cursor *cur, sqlite_int64 *pRowid) {
+	*pRowid = ((carray_cursor *)cur)->iRowid;
+	return SQLITE_OK;
+}
+
+static int carrayEof(sqlite3_vtab_cursor *cur) {
+	carray_cursor *pCur = (carray_cursor *)cur;
+	return pCur->pArray == 0 || pCur->iRowid > pCur->pArray->nData;
+}
+
+static int carrayFilter(sqlite3_vtab_cursor *cur, int idxNum, const char *idxStr, int argc, sqlite3_value **argv) {
+	carray_cursor *pCur = (carray_cursor *)cur;
+	pCur->pArray = 0;
+	if (idxNum == 1) {
+		pCur->pArray = (goCArray *)sqlite3_value_pointer(argv[0], goCArrayPointerType);
+	}
+	pCur->iRowid = 1;
+	return SQLITE_OK;
+}
+
+static int carrayBestIndex(sqlite3_vtab *tab, sqlite3_index_info *pIdxInfo) {
+	int i;
+	const struct sqlite3_index_constraint *pConstraint = pIdxInfo->aConstraint;
+	for (i = 0; i < pIdxInfo->nConstraint; i++, pConstraint++) {
+		if (pConstraint->iColumn == 1 && pConstraint->op == SQLITE_INDEX_CONSTRAINT_EQ) {
+			if (!pConstraint->usable) {
+				return SQLITE_CONSTRAINT;
+			}
+			pIdxInfo->aConstraintUsage[i].argvIndex = 1;
+			pIdxInfo->aConstraintUsage[i].omit = 1;
+			pIdxInfo->estimatedCost = (double)1;
+			pIdxInfo->estimatedRows = 100;
+			pIdxInfo->idxNum = 1;
+			return SQLITE_OK;
+		}
+	}
+	pIdxInfo->estimatedCost = (double)2147483647;
+	pIdxInfo->estimatedRows = 2147483647;
+	pIdxInfo->idxNum = 0;
+	return SQLITE_OK;
+}
+
+static sqlite3_module carrayModule = {
+	0,                         /* iVersion */
+	0,                         /* xCreate (eponymous only) */
+	carrayConnect,             /* xConnect */
+	carrayBestIndex,           /* xBestIndex */
+	carrayDisconnect,          /* xDisconnect */
+	0,                         /* xDestroy */
+	carrayOpen,                /* xOpen */
+	carrayClose,               /* xClose */
+	carrayFilter,              /* xFilter */
+	carrayNext,                /* xNext */
+	carrayEof,                 /* xEof */
+	carrayColumn,              /* xColumn */
+	carrayRowid,               /* xRowid */
+	0,                         /* xUpdate */
+	0,                         /* xBegin */
+	0,                         /* xSync */
+	0,                         /* xCommit */
+	0,                         /* xRollback */
+	0,                         /* xFindMethod */
+	0,                         /* xRename */
+};
+
+int goSqlite3CreateCArrayModule(sqlite3 *db) {
+	return sqlite3_create_module(db, "carray", &carrayModule, 0);
+}
+
+// Takes ownership of aData (even on failure).
+int goSqlite3BindCArray(sqlite3_stmt *pStmt, int i, void *aData, int nData, int eType) {
+	goCArray *pArray = malloc(sizeof(*pArray));
+	if (pArray == 0) {
+		goCArrayFreeData(aData, nData, eType);
+		return SQLITE_NOMEM;
+	}
+	pArray->aData = aData;
+	pArray->nData = nData;
+	pArray->eType = eType;
+	return sqlite3_bind_pointer(pStmt, i, pArray, goCArrayPointerType, goCArrayFree);
+}
+
+#else
+
+int goSqlite3CreateCArrayModule(sqlite3 *db) {
+	return SQLITE_ERROR;
+}
+
+int goSqlite3BindCArray(sqlite3_stmt *pStmt, int i, void *aData, int nData, int eType) {
+	goCArrayFreeData(aData, nData, eType);
+	return SQLITE_ERROR;
+}
+
+#endif
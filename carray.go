@@ -0,0 +1,90 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+#include <stdlib.h>
+
+int goSqlite3CreateCArrayModule(sqlite3 *db);
+int goSqlite3BindCArray(sqlite3_stmt *pStmt, int i, void *aData, int nData, int eType);
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+const (
+	carrayInt64  = 0
+	carrayDouble = 1
+	carrayText   = 2
+)
+
+// LoadCArrayModule registers the carray table-valued function.
+// It is a cheaper alternative to IntArray for IN (...) queries:
+//
+//	SELECT * FROM t WHERE x IN carray(?)
+//
+// The array is bound with Stmt.BindInt64Array, Stmt.BindFloat64Array or Stmt.BindTextArray
+// (a statement whose array parameter is not bound returns no row).
+// The module must be loaded before the statements using carray are prepared.
+// (See http://sqlite.org/carray.html and http://sqlite.org/bindptr.html)
+func LoadCArrayModule(db *Conn) error {
+	if !VersionAtLeast(3020000) {
+		return db.specificError("carray requires SQLite >= 3.20.0")
+	}
+	if rv := C.goSqlite3CreateCArrayModule(db.db); rv != C.SQLITE_OK {
+		return db.error(rv, "LoadCArrayModule")
+	}
+	return nil
+}
+
+// BindInt64Array binds an array of integers to the carray(?) parameter at the specified index.
+// The values are copied: the slice can be modified once bound.
+// The copy is released when the parameter is rebound or cleared or when the statement is finalized.
+func (s *Stmt) BindInt64Array(index int, values []int64) error {
+	p := carrayAlloc(len(values), unsafe.Sizeof(C.sqlite3_int64(0)))
+	a := (*[1 << 28]C.sqlite3_int64)(p)[:len(values):len(values)]
+	for i, v := range values {
+		a[i] = C.sqlite3_int64(v)
+	}
+	return s.bindCArray(index, p, len(values), carrayInt64, "Stmt.BindInt64Array")
+}
+
+// BindFloat64Array binds an array of floats to the carray(?) parameter at the specified index.
+// The values are copied: the slice can be modified once bound.
+func (s *Stmt) BindFloat64Array(index int, values []float64) error {
+	p := carrayAlloc(len(values), unsafe.Sizeof(C.double(0)))
+	a := (*[1 << 28]C.double)(p)[:len(values):len(values)]
+	for i, v := range values {
+		a[i] = C.double(v)
+	}
+	return s.bindCArray(index, p, len(values), carrayDouble, "Stmt.BindFloat64Array")
+}
+
+// BindTextArray binds an array of strings to the carray(?) parameter at the specified index.
+// The values are copied: the slice can be modified once bound.
+func (s *Stmt) BindTextArray(index int, values []string) error {
+	p := carrayAlloc(len(values), unsafe.Sizeof((*C.char)(nil)))
+	a := (*[1 << 28]*C.char)(p)[:len(values):len(values)]
+	for i, v := range values {
+		a[i] = C.CString(v)
+	}
+	return s.bindCArray(index, p, len(values), carrayText, "Stmt.BindTextArray")
+}
+
+func carrayAlloc(n int, size uintptr) unsafe.Pointer {
+	if n == 0 {
+		n = 1
+	}
+	return C.malloc(C.size_t(uintptr(n) * size))
+}
+
+// bindCArray transfers the ownership of p to the statement (even on failure).
+func (s *Stmt) bindCArray(index int, p unsafe.Pointer, n int, eType C.int, method string) error {
+	rv := C.goSqlite3BindCArray(s.stmt, C.int(index), p, C.int(n), eType)
+	return s.error(rv, method)
+}
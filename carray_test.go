@@ -0,0 +1,65 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestCArray(t *testing.T) {
+	if !VersionAtLeast(3020000) {
+		t.Skipf("SQLite version too old (%d < 3020000)", VersionNumber())
+	}
+	db := open(t)
+	defer checkClose(db, t)
+	checkNoError(t, LoadCArrayModule(db), "couldn't load carray module: %s")
+	checkNoError(t, db.FastExec("CREATE TABLE test (id INTEGER PRIMARY KEY, name TEXT, score REAL);"+
+		"INSERT INTO test VALUES (1, 'a', 0.5), (2, 'b', 1.5), (3, 'c', 2.5), (4, 'd', 3.5)"), "couldn't create table: %s")
+
+	ids := func(s *Stmt) []int64 {
+		var result []int64
+		err := s.Select(func(s *Stmt) error {
+			id, _, err := s.ScanInt64(0)
+			result = append(result, id)
+			return err
+		})
+		checkNoError(t, err, "couldn't select: %s")
+		return result
+	}
+
+	s, err := db.Prepare("SELECT id FROM test WHERE id IN carray(?) ORDER BY id")
+	checkNoError(t, err, "couldn't prepare: %s")
+	defer checkFinalize(s, t)
+	values := []int64{4, 2, 5}
+	checkNoError(t, s.BindInt64Array(1, values), "couldn't bind array: %s")
+	values[0] = 1 // copied
+	assert.Equal(t, []int64{2, 4}, ids(s))
+
+	checkNoError(t, s.BindInt64Array(1, nil), "couldn't bind array: %s")
+	assert.Equal(t, 0, len(ids(s)))
+	checkNoError(t, s.BindInt64Array(1, []int64{1, 3}), "couldn't rebind array: %s")
+	assert.Equal(t, []int64{1, 3}, ids(s))
+	checkNoError(t, s.ClearBindings(), "couldn't clear bindings: %s")
+	assert.Equal(t, 0, len(ids(s)))
+
+	s2, err := db.Prepare("SELECT id FROM test WHERE name IN carray(?) ORDER BY id")
+	checkNoError(t, err, "couldn't prepare: %s")
+	defer checkFinalize(s2, t)
+	checkNoError(t, s2.BindTextArray(1, []string{"c", "a", "z"}), "couldn't bind array: %s")
+	assert.Equal(t, []int64{1, 3}, ids(s2))
+
+	s3, err := db.Prepare("SELECT id FROM test WHERE score IN carray(?) ORDER BY id")
+	checkNoError(t, err, "couldn't prepare: %s")
+	defer checkFinalize(s3, t)
+	checkNoError(t, s3.BindFloat64Array(1, []float64{1.5, 3.5}), "couldn't bind array: %s")
+	assert.Equal(t, []int64{2, 4}, ids(s3))
+
+	var n int
+	checkNoError(t, db.OneValue("SELECT count(*) FROM carray(?)", &n, 1), "couldn't count: %s")
+	assert.Equal(t, 0, n) // not a carray pointer
+}
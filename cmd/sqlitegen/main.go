@@ -0,0 +1,79 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command sqlitegen generates Go structs from the schema of an SQLite database.
+//
+// Usage:
+//
+//	sqlitegen -db schema.db [-schema schema.sql] [-pkg model] [-tables t1,t2] [-o model.go]
+//
+// With -schema, the SQL script is executed in an in-memory database (and -db is ignored).
+// Typical go:generate usage:
+//
+//	//go:generate go run github.com/gwenn/gosqlite/cmd/sqlitegen -schema schema.sql -pkg model -o model_gen.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gwenn/gosqlite"
+	"github.com/gwenn/gosqlite/gen"
+)
+
+func main() {
+	dbFile := flag.String("db", "", "database file")
+	schemaFile := flag.String("schema", "", "SQL script creating the schema (instead of -db)")
+	pkg := flag.String("pkg", "model", "package name")
+	tables := flag.String("tables", "", "comma-separated list of tables (default is all tables)")
+	output := flag.String("o", "", "output file (default is stdout)")
+	flag.Parse()
+
+	if err := run(*dbFile, *schemaFile, *pkg, *tables, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "sqlitegen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dbFile, schemaFile, pkg, tables, output string) error {
+	var db *sqlite.Conn
+	var err error
+	if len(schemaFile) > 0 {
+		schema, err := ioutil.ReadFile(schemaFile)
+		if err != nil {
+			return err
+		}
+		if db, err = sqlite.Open(":memory:"); err != nil {
+			return err
+		}
+		defer db.Close()
+		if err = db.FastExec(string(schema)); err != nil {
+			return err
+		}
+	} else if len(dbFile) > 0 {
+		if db, err = sqlite.Open(dbFile, sqlite.OpenReadOnly); err != nil {
+			return err
+		}
+		defer db.Close()
+	} else {
+		return fmt.Errorf("-db or -schema must be specified")
+	}
+	opts := gen.Options{Package: pkg}
+	if len(tables) > 0 {
+		opts.Tables = strings.Split(tables, ",")
+	}
+	var b bytes.Buffer
+	if err = gen.Generate(&b, db, opts); err != nil {
+		return err
+	}
+	if len(output) == 0 {
+		_, err = os.Stdout.Write(b.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(output, b.Bytes(), 0644)
+}
@@ -13,12 +13,30 @@ static inline int my_db_config(sqlite3 *db, int op, int v, int *ok) {
 	return sqlite3_db_config(db, op, v, ok);
 }
 
+// Missing in old sqlite3.h (sqlite3_db_config then fails)
+#ifndef SQLITE_DBCONFIG_ENABLE_LOAD_EXTENSION
+#define SQLITE_DBCONFIG_ENABLE_LOAD_EXTENSION 1005
+#endif
+#ifndef SQLITE_DBCONFIG_DEFENSIVE
+#define SQLITE_DBCONFIG_DEFENSIVE 1010
+#endif
+#ifndef SQLITE_DBCONFIG_DQS_DML
+#define SQLITE_DBCONFIG_DQS_DML 1013
+#define SQLITE_DBCONFIG_DQS_DDL 1014
+#endif
+#ifndef SQLITE_DBCONFIG_TRUSTED_SCHEMA
+#define SQLITE_DBCONFIG_TRUSTED_SCHEMA 1017
+#endif
+
 int goSqlite3ConfigThreadMode(int mode);
 int goSqlite3Config(int op, int mode);
 */
 import "C"
 
-import "unsafe"
+import (
+	"sync"
+	"unsafe"
+)
 
 // ThreadingMode enumerates SQLite threading mode
 // See ConfigThreadingMode
@@ -105,6 +123,43 @@ func (c *Conn) AreTriggersEnabled() (bool, error) {
 	return c.queryOrSetEnableDbConfig(C.SQLITE_DBCONFIG_ENABLE_TRIGGER, -1)
 }
 
+// EnableDefensive enables or disables the defensive mode which prevents SQL statements
+// from deliberately corrupting the database file (by writing the schema or shadow tables for example).
+// Calls sqlite3_db_config(db, SQLITE_DBCONFIG_DEFENSIVE, b).
+//
+// (See http://sqlite.org/c3ref/c_dbconfig_defensive.html)
+func (c *Conn) EnableDefensive(b bool) (bool, error) {
+	return c.queryOrSetEnableDbConfig(C.SQLITE_DBCONFIG_DEFENSIVE, btocint(b))
+}
+
+// EnableTrustedSchema enables or disables the use of SQL functions and virtual tables
+// (not tagged as innocuous) from within the schema (triggers, views, CHECK constraints...).
+// Calls sqlite3_db_config(db, SQLITE_DBCONFIG_TRUSTED_SCHEMA, b).
+// Another way is PRAGMA trusted_schema = boolean;
+//
+// (See http://sqlite.org/c3ref/c_dbconfig_defensive.html)
+func (c *Conn) EnableTrustedSchema(b bool) (bool, error) {
+	return c.queryOrSetEnableDbConfig(C.SQLITE_DBCONFIG_TRUSTED_SCHEMA, btocint(b))
+}
+
+// EnableDQS enables or disables the double-quoted string literals misfeature (for both DML and DDL statements).
+// Calls sqlite3_db_config(db, SQLITE_DBCONFIG_DQS_DML, b) and sqlite3_db_config(db, SQLITE_DBCONFIG_DQS_DDL, b).
+//
+// (See http://sqlite.org/quirks.html#dblquote)
+func (c *Conn) EnableDQS(b bool) (bool, error) {
+	if _, err := c.queryOrSetEnableDbConfig(C.SQLITE_DBCONFIG_DQS_DDL, btocint(b)); err != nil {
+		return false, err
+	}
+	return c.queryOrSetEnableDbConfig(C.SQLITE_DBCONFIG_DQS_DML, btocint(b))
+}
+
+// disableLoadExtension disables the sqlite3_load_extension C API
+// and so the load_extension() SQL function (contrary to EnableLoadExtension, it is available with SQLITE_OMIT_LOAD_EXTENSION).
+func (c *Conn) disableLoadExtension() error {
+	_, err := c.queryOrSetEnableDbConfig(C.SQLITE_DBCONFIG_ENABLE_LOAD_EXTENSION, 0)
+	return err
+}
+
 func (c *Conn) queryOrSetEnableDbConfig(key, i C.int) (bool, error) {
 	var ok C.int
 	rv := C.my_db_config(c.db, key, i, &ok)
@@ -127,3 +182,63 @@ func CompileOptionUsed(optName string) bool {
 	defer C.free(unsafe.Pointer(cOptName))
 	return C.sqlite3_compileoption_used(cOptName) == 1
 }
+
+// CompileOptions returns the list of options that were defined at compile time (without the "SQLITE_" prefix).
+// (See http://sqlite.org/c3ref/compileoption_get.html)
+func CompileOptions() []string {
+	var options []string
+	for i := C.int(0); ; i++ {
+		p := C.sqlite3_compileoption_get(i)
+		if p == nil {
+			break
+		}
+		options = append(options, C.GoString(p))
+	}
+	return options
+}
+
+// Features reports optional extensions and APIs available in the linked SQLite library.
+// Extensions loaded at run-time are not detected.
+// See GetFeatures
+type Features struct {
+	HasColumnMetadata bool // ENABLE_COLUMN_METADATA
+	HasDbStat         bool // ENABLE_DBSTAT_VTAB
+	HasFTS3           bool // ENABLE_FTS3 or ENABLE_FTS4
+	HasFTS5           bool // ENABLE_FTS5
+	HasGeopoly        bool // ENABLE_GEOPOLY
+	HasJSON1          bool // built-in since 3.38 (unless OMIT_JSON) or ENABLE_JSON1
+	HasLoadExtension  bool // not OMIT_LOAD_EXTENSION
+	HasMathFunctions  bool // ENABLE_MATH_FUNCTIONS
+	HasPreupdateHook  bool // ENABLE_PREUPDATE_HOOK
+	HasRTree          bool // ENABLE_RTREE
+	HasSession        bool // ENABLE_SESSION
+	HasUnlockNotify   bool // ENABLE_UNLOCK_NOTIFY
+}
+
+var (
+	features     Features
+	featuresOnce sync.Once
+)
+
+// GetFeatures returns the optional features available in the linked SQLite library.
+// Compile options are checked only once.
+func GetFeatures() Features {
+	featuresOnce.Do(func() {
+		features = Features{
+			HasColumnMetadata: CompileOptionUsed("ENABLE_COLUMN_METADATA"),
+			HasDbStat:         CompileOptionUsed("ENABLE_DBSTAT_VTAB"),
+			HasFTS3:           CompileOptionUsed("ENABLE_FTS3") || CompileOptionUsed("ENABLE_FTS4"),
+			HasFTS5:           CompileOptionUsed("ENABLE_FTS5"),
+			HasGeopoly:        CompileOptionUsed("ENABLE_GEOPOLY"),
+			HasJSON1: CompileOptionUsed("ENABLE_JSON1") ||
+				(VersionNumber() >= 3038000 && !CompileOptionUsed("OMIT_JSON")),
+			HasLoadExtension: !CompileOptionUsed("OMIT_LOAD_EXTENSION"),
+			HasMathFunctions: CompileOptionUsed("ENABLE_MATH_FUNCTIONS"),
+			HasPreupdateHook: CompileOptionUsed("ENABLE_PREUPDATE_HOOK"),
+			HasRTree:         CompileOptionUsed("ENABLE_RTREE"),
+			HasSession:       CompileOptionUsed("ENABLE_SESSION"),
+			HasUnlockNotify:  CompileOptionUsed("ENABLE_UNLOCK_NOTIFY"),
+		}
+	})
+	return features
+}
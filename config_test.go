@@ -6,6 +6,7 @@ package sqlite_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/bmizerany/assert"
@@ -71,3 +72,17 @@ func TestCompileOptionUsed(t *testing.T) {
 	}
 	//assert.T(t, b, "COLUMN_METADATA disabled")
 }
+
+func TestCompileOptions(t *testing.T) {
+	options := CompileOptions()
+	assert.T(t, len(options) > 0, "compile options expected")
+	for _, option := range options {
+		name := option
+		if i := strings.IndexByte(option, '='); i >= 0 {
+			name = option[:i]
+		}
+		assert.Tf(t, CompileOptionUsed(name), "%s", option)
+	}
+	assert.Equal(t, CompileOptionUsed("ENABLE_RTREE"), GetFeatures().HasRTree)
+	assert.Equal(t, GetFeatures(), GetFeatures())
+}
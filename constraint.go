@@ -0,0 +1,333 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckConstraint is the description of one CHECK constraint.
+// See Conn.TableConstraints
+type CheckConstraint struct {
+	Name   string // empty when the constraint is not named
+	Column string // empty for a table constraint
+	Expr   string // expression (as declared)
+}
+
+// UniqueConstraint is the description of one UNIQUE or PRIMARY KEY constraint.
+// See Conn.TableConstraints
+type UniqueConstraint struct {
+	Name       string // empty when the constraint is not named
+	Columns    []string
+	PrimaryKey bool
+	OnConflict string // ROLLBACK, ABORT, FAIL, IGNORE, REPLACE or empty (default is ABORT)
+}
+
+// NotNullConstraint is the description of one NOT NULL constraint.
+// See Conn.TableConstraints
+type NotNullConstraint struct {
+	Name       string // empty when the constraint is not named
+	Column     string
+	OnConflict string // ROLLBACK, ABORT, FAIL, IGNORE, REPLACE or empty (default is ABORT)
+}
+
+// TableConstraints gathers the constraints declared by one table (at column or table level).
+type TableConstraints struct {
+	Checks   []CheckConstraint
+	Uniques  []UniqueConstraint
+	NotNulls []NotNullConstraint
+}
+
+// TableConstraints returns the CHECK, UNIQUE, PRIMARY KEY and NOT NULL constraints (with their conflict clauses)
+// declared by the specified table.
+// As they are not exposed by pragmas, they are parsed from the CREATE TABLE statement stored in 'sqlite_master'.
+// The database name can be empty, "main", "temp" or the name of an attached database.
+func (c *Conn) TableConstraints(dbName, table string) (*TableConstraints, error) {
+	var sql string
+	if len(dbName) == 0 {
+		sql = "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ? COLLATE NOCASE"
+	} else if strings.EqualFold("temp", dbName) {
+		sql = "SELECT sql FROM sqlite_temp_master WHERE type = 'table' AND name = ? COLLATE NOCASE"
+	} else {
+		sql = fmt.Sprintf("SELECT sql FROM %s.sqlite_master WHERE type = 'table' AND name = ? COLLATE NOCASE", doubleQuote(dbName))
+	}
+	s, err := c.prepare(sql, table)
+	if err != nil {
+		return nil, err
+	}
+	defer s.finalize()
+	if ok, err := s.Next(); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, c.specificError("no such table: %q", table)
+	}
+	var ddl string
+	if err = s.Scan(&ddl); err != nil {
+		return nil, err
+	}
+	constraints, err := parseTableConstraints(ddl)
+	if err != nil {
+		return nil, c.specificError("%s", err)
+	}
+	return constraints, nil
+}
+
+// token is a lexical unit of a CREATE TABLE statement.
+type token struct {
+	val    string // unquoted value for identifiers
+	quoted bool
+	pos    int // start offset in the statement
+	end    int // end offset in the statement
+}
+
+func (t token) is(keyword string) bool {
+	return !t.quoted && strings.EqualFold(t.val, keyword)
+}
+
+// tokenize splits sql into words, quoted identifiers, strings and single punctuation characters.
+// Comments and spaces are skipped.
+func tokenize(sql string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			if j := strings.IndexByte(sql[i:], '\n'); j < 0 {
+				i = len(sql)
+			} else {
+				i += j + 1
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			if j := strings.Index(sql[i+2:], "*/"); j < 0 {
+				i = len(sql)
+			} else {
+				i += j + 4
+			}
+		case c == '"' || c == '`' || c == '[' || c == '\'':
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			var b []byte
+			j := i + 1
+			for ; j < len(sql); j++ {
+				if sql[j] == end {
+					if end != ']' && j+1 < len(sql) && sql[j+1] == end {
+						b = append(b, end)
+						j++
+						continue
+					}
+					break
+				}
+				b = append(b, sql[j])
+			}
+			if j >= len(sql) {
+				return nil, fmt.Errorf("unterminated quoted string at %d", i)
+			}
+			tokens = append(tokens, token{val: string(b), quoted: true, pos: i, end: j + 1})
+			i = j + 1
+		case c == '_' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9'):
+			j := i + 1
+			for j < len(sql) && (sql[j] == '_' || sql[j] == '$' || sql[j] == '.' || sql[j] >= 0x80 ||
+				('a' <= sql[j] && sql[j] <= 'z') || ('A' <= sql[j] && sql[j] <= 'Z') || ('0' <= sql[j] && sql[j] <= '9')) {
+				j++
+			}
+			tokens = append(tokens, token{val: sql[i:j], pos: i, end: j})
+			i = j
+		default:
+			tokens = append(tokens, token{val: sql[i : i+1], pos: i, end: i + 1})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// splitDefinitions splits tokens on top-level commas.
+func splitDefinitions(tokens []token) [][]token {
+	var defs [][]token
+	depth, start := 0, 0
+	for i, t := range tokens {
+		if t.quoted {
+			continue
+		}
+		switch t.val {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				defs = append(defs, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(defs, tokens[start:])
+}
+
+// parenthesized returns the index of the token closing the parenthesis opened at tokens[i].
+func parenthesized(tokens []token, i int) (int, error) {
+	if i >= len(tokens) || tokens[i].quoted || tokens[i].val != "(" {
+		return 0, fmt.Errorf("'(' expected")
+	}
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		if tokens[j].quoted {
+			continue
+		}
+		switch tokens[j].val {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return j, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unbalanced parentheses")
+}
+
+// conflictClause parses an optional ON CONFLICT clause at tokens[i].
+func conflictClause(tokens []token, i int) (string, int) {
+	if i+2 < len(tokens) && tokens[i].is("ON") && tokens[i+1].is("CONFLICT") {
+		return strings.ToUpper(tokens[i+2].val), i + 3
+	}
+	return "", i
+}
+
+func parseTableConstraints(sql string) (*TableConstraints, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	// skip CREATE [TEMP] TABLE [IF NOT EXISTS] name
+	start := -1
+	for i, t := range tokens {
+		if !t.quoted && t.val == "(" {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("no column definition in %q", sql)
+	}
+	end, err := parenthesized(tokens, start)
+	if err != nil {
+		return nil, err
+	}
+	constraints := &TableConstraints{}
+	for _, def := range splitDefinitions(tokens[start+1 : end]) {
+		if len(def) == 0 {
+			continue
+		}
+		if isTableConstraint(def) {
+			err = constraints.parseTableConstraint(sql, def)
+		} else {
+			err = constraints.parseColumnConstraints(sql, def)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return constraints, nil
+}
+
+func isTableConstraint(def []token) bool {
+	t := def[0]
+	return t.is("CONSTRAINT") || t.is("PRIMARY") || t.is("UNIQUE") || t.is("CHECK") || t.is("FOREIGN")
+}
+
+func (tc *TableConstraints) parseTableConstraint(sql string, def []token) error {
+	var name string
+	i := 0
+	if def[0].is("CONSTRAINT") && len(def) > 1 {
+		name = def[1].val
+		i = 2
+	}
+	if i >= len(def) {
+		return fmt.Errorf("invalid table constraint: %q", sql[def[0].pos:def[len(def)-1].end])
+	}
+	switch {
+	case def[i].is("PRIMARY") || def[i].is("UNIQUE"):
+		u := UniqueConstraint{Name: name, PrimaryKey: def[i].is("PRIMARY")}
+		i++
+		if u.PrimaryKey {
+			i++ // KEY
+		}
+		end, err := parenthesized(def, i)
+		if err != nil {
+			return err
+		}
+		for _, col := range splitDefinitions(def[i+1 : end]) {
+			if len(col) > 0 {
+				u.Columns = append(u.Columns, col[0].val)
+			}
+		}
+		u.OnConflict, _ = conflictClause(def, end+1)
+		tc.Uniques = append(tc.Uniques, u)
+	case def[i].is("CHECK"):
+		end, err := parenthesized(def, i+1)
+		if err != nil {
+			return err
+		}
+		tc.Checks = append(tc.Checks, CheckConstraint{Name: name, Expr: sql[def[i+1].end:def[end].pos]})
+	}
+	// FOREIGN KEY constraints are exposed by Conn.ForeignKeys
+	return nil
+}
+
+func (tc *TableConstraints) parseColumnConstraints(sql string, def []token) error {
+	column := def[0].val
+	var name string
+	for i := 1; i < len(def); i++ {
+		t := def[i]
+		switch {
+		case t.quoted:
+			continue
+		case t.val == "(": // type arguments, DEFAULT (expr), ...
+			end, err := parenthesized(def, i)
+			if err != nil {
+				return err
+			}
+			i = end
+		case t.is("CONSTRAINT") && i+1 < len(def):
+			name = def[i+1].val
+			i++
+		case t.is("PRIMARY") || t.is("UNIQUE"):
+			u := UniqueConstraint{Name: name, Columns: []string{column}, PrimaryKey: t.is("PRIMARY")}
+			if u.PrimaryKey {
+				i++ // KEY
+				if i+1 < len(def) && (def[i+1].is("ASC") || def[i+1].is("DESC")) {
+					i++
+				}
+			}
+			u.OnConflict, i = conflictClause(def, i+1)
+			i--
+			tc.Uniques = append(tc.Uniques, u)
+			name = ""
+		case t.is("NOT") && i+1 < len(def) && def[i+1].is("NULL"):
+			n := NotNullConstraint{Name: name, Column: column}
+			n.OnConflict, i = conflictClause(def, i+2)
+			i--
+			tc.NotNulls = append(tc.NotNulls, n)
+			name = ""
+		case t.is("CHECK"):
+			end, err := parenthesized(def, i+1)
+			if err != nil {
+				return err
+			}
+			tc.Checks = append(tc.Checks, CheckConstraint{Name: name, Column: column, Expr: sql[def[i+1].end:def[end].pos]})
+			i = end
+			name = ""
+		case t.is("DEFAULT") || t.is("COLLATE") || t.is("REFERENCES") || t.is("GENERATED") || t.is("AS"):
+			name = "" // named constraint not reported
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestTableConstraints(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	err := db.FastExec(`CREATE TABLE "my table" (
+		id INTEGER PRIMARY KEY ON CONFLICT REPLACE, -- comment, with comma
+		code TEXT CONSTRAINT code_uk UNIQUE ON CONFLICT IGNORE CONSTRAINT code_nn NOT NULL,
+		price NUMERIC(10, 2) DEFAULT (0) CHECK (price >= 0),
+		"a, b" TEXT NOT NULL ON CONFLICT FAIL,
+		CONSTRAINT pair UNIQUE ("a, b", code COLLATE NOCASE) ON CONFLICT ROLLBACK,
+		CHECK (length(code) > 2 AND code <> ')')
+	)`)
+	checkNoError(t, err, "error creating table: %s")
+
+	c, err := db.TableConstraints("main", "My Table")
+	checkNoError(t, err, "error looking for constraints: %s")
+
+	assert.Equal(t, 2, len(c.Checks), "check count")
+	assert.Equal(t, "price", c.Checks[0].Column, "column check")
+	assert.Equal(t, "price >= 0", c.Checks[0].Expr, "column check expression")
+	assert.Equal(t, "", c.Checks[1].Column, "table check")
+	assert.Equal(t, "length(code) > 2 AND code <> ')'", c.Checks[1].Expr, "table check expression")
+
+	assert.Equal(t, 3, len(c.Uniques), "unique count")
+	assert.Equal(t, []string{"id"}, c.Uniques[0].Columns, "primary key columns")
+	assert.T(t, c.Uniques[0].PrimaryKey, "primary key")
+	assert.Equal(t, "REPLACE", c.Uniques[0].OnConflict, "primary key conflict clause")
+	assert.Equal(t, "code_uk", c.Uniques[1].Name, "unique name")
+	assert.Equal(t, "IGNORE", c.Uniques[1].OnConflict, "unique conflict clause")
+	assert.Equal(t, "pair", c.Uniques[2].Name, "unique name")
+	assert.Equal(t, []string{"a, b", "code"}, c.Uniques[2].Columns, "unique columns")
+	assert.Equal(t, "ROLLBACK", c.Uniques[2].OnConflict, "unique conflict clause")
+
+	assert.Equal(t, 2, len(c.NotNulls), "not null count")
+	assert.Equal(t, "code_nn", c.NotNulls[0].Name, "not null name")
+	assert.Equal(t, "a, b", c.NotNulls[1].Column, "not null column")
+	assert.Equal(t, "FAIL", c.NotNulls[1].OnConflict, "not null conflict clause")
+
+	_, err = db.TableConstraints("", "unknown")
+	assert.T(t, err != nil, "expected error with unknown table")
+}
@@ -0,0 +1,153 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy specifies how a transaction is retried when the database is busy.
+// See Conn.TransactionContext
+type RetryPolicy struct {
+	MaxRetries int           // maximum number of retries (zero means no retry)
+	Delay      time.Duration // delay before the first retry, doubled after each retry
+	MaxDelay   time.Duration // upper bound of the delay between two retries (ignored when zero)
+}
+
+// TransactionContext is like Conn.Transaction but honors ctx cancellation:
+// the statements being executed are interrupted when ctx is done and ctx.Err() is returned.
+// When retry is not nil, the whole transaction (including f) is retried on SQLITE_BUSY/SQLITE_LOCKED,
+// only when it is the outermost transaction (a nested one cannot release the locks held by its parent).
+// (See http://sqlite.org/c3ref/interrupt.html)
+func (c *Conn) TransactionContext(ctx context.Context, t TransactionType, retry *RetryPolicy, f func(c *Conn) error) error {
+	var delay time.Duration
+	if retry != nil {
+		delay = retry.Delay
+	}
+	for i := 0; ; i++ {
+		err := c.withContext(ctx, func() error {
+			return c.Transaction(t, f)
+		})
+		if err != nil && ctx.Err() != nil {
+			return err
+		}
+		if err == nil || retry == nil || i >= retry.MaxRetries || c.nTransaction > 0 || !isBusy(err) {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+		if retry.MaxDelay > 0 && delay > retry.MaxDelay {
+			delay = retry.MaxDelay
+		}
+	}
+}
+
+// ExistsContext is like Conn.Exists but the query is interrupted when ctx is done (ctx.Err() is returned).
+func (c *Conn) ExistsContext(ctx context.Context, query string, args ...interface{}) (exists bool, err error) {
+	err = c.withContext(ctx, func() (err error) {
+		exists, err = c.Exists(query, args...)
+		return
+	})
+	return
+}
+
+// OneValueContext is like Conn.OneValue but the query is interrupted when ctx is done (ctx.Err() is returned).
+func (c *Conn) OneValueContext(ctx context.Context, query string, value interface{}, args ...interface{}) error {
+	return c.withContext(ctx, func() error {
+		return c.OneValue(query, value, args...)
+	})
+}
+
+// SelectContext is like Conn.Select but the query is interrupted when ctx is done (ctx.Err() is returned).
+// ctx is also checked between each row so that the iteration stops even if the callback does not use the connection.
+func (c *Conn) SelectContext(ctx context.Context, query string, rowCallbackHandler func(s *Stmt) error, args ...interface{}) error {
+	return c.withContext(ctx, func() error {
+		return c.Select(query, func(s *Stmt) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return rowCallbackHandler(s)
+		}, args...)
+	})
+}
+
+// withContext executes f, interrupting it when ctx is done.
+func (c *Conn) withContext(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stop := c.interruptOnDone(ctx)
+	err := f()
+	stop()
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// InterruptOnDone interrupts the statements being executed when ctx is done,
+// for the remaining lifetime of the connection (the watch ends when the connection is closed).
+// It can be called many times with different contexts.
+// (See http://sqlite.org/c3ref/interrupt.html)
+func (c *Conn) InterruptOnDone(ctx context.Context) {
+	if ctx.Done() == nil { // never cancelled
+		return
+	}
+	c.interruptMu.Lock()
+	if c.closing || c.db == nil {
+		c.interruptMu.Unlock()
+		return
+	}
+	if c.closed == nil {
+		c.closed = make(chan struct{})
+	}
+	closed := c.closed
+	c.interruptMu.Unlock()
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Interrupt()
+		case <-closed:
+		}
+	}()
+}
+
+// interruptOnDone interrupts the statements executed by c when ctx is done.
+// The returned function must be called to stop watching ctx.
+func (c *Conn) interruptOnDone(ctx context.Context) (stop func()) {
+	if ctx.Done() == nil { // never cancelled
+		return func() {}
+	}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		select {
+		case <-ctx.Done():
+			c.Interrupt()
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+func isBusy(err error) bool {
+	if e, ok := err.(interface {
+		Code() Errno
+	}); ok {
+		return e.Code() == ErrBusy || e.Code() == ErrLocked
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestTransactionContextRetry(t *testing.T) {
+	f, db1, db2 := openTwoConnSameDb(t)
+	defer os.Remove(f.Name())
+	defer checkClose(db1, t)
+	defer checkClose(db2, t)
+	checkNoError(t, db1.BeginTransaction(Exclusive), "couldn't begin transaction: %s")
+
+	err := db2.TransactionContext(context.Background(), Immediate, nil, func(c *Conn) error {
+		return nil
+	})
+	assert.T(t, err != nil, "busy error expected")
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		db1.Rollback()
+	}()
+	var calls int
+	err = db2.TransactionContext(context.Background(), Immediate, &RetryPolicy{MaxRetries: 20, Delay: 5 * time.Millisecond, MaxDelay: 20 * time.Millisecond}, func(c *Conn) error {
+		calls++
+		return c.FastExec("CREATE TABLE test (data TEXT)")
+	})
+	checkNoError(t, err, "couldn't execute transaction: %s")
+	assert.Equal(t, 1, calls)
+}
+
+func TestTransactionContextCancel(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := db.TransactionContext(ctx, Deferred, nil, func(c *Conn) error {
+		t.Error("unexpected call")
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = db.TransactionContext(ctx, Deferred, nil, func(c *Conn) error {
+		var n int64
+		return c.OneValue("SELECT count(*) FROM ("+infiniteQuery+")", &n)
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.T(t, !db.InTransaction(), "no transaction expected")
+	checkNoError(t, db.FastExec("SELECT 1"), "connection unusable after interruption: %s")
+}
+
+const infiniteQuery = "WITH RECURSIVE cnt(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM cnt) SELECT x FROM cnt"
+
+func TestQueryContext(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	ctx := context.Background()
+	exists, err := db.ExistsContext(ctx, "SELECT 1 WHERE 1 = ?", 1)
+	checkNoError(t, err, "exists error: %s")
+	assert.T(t, exists, "row expected")
+	var n int
+	err = db.OneValueContext(ctx, "SELECT ?", &n, 42)
+	checkNoError(t, err, "one value error: %s")
+	assert.Equal(t, 42, n)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = db.OneValueContext(ctx, "SELECT count(*) FROM ("+infiniteQuery+")", &n)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	_, err = db.ExistsContext(ctx, "SELECT 1")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	var rows int
+	err = db.SelectContext(ctx, infiniteQuery, func(s *Stmt) error {
+		rows++
+		if rows == 10 {
+			cancel()
+		}
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 10, rows)
+}
+
+func TestInterruptOnDone(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	db.InterruptOnDone(ctx)
+	var n int64
+	err := db.OneValue("SELECT count(*) FROM ("+infiniteQuery+")", &n)
+	if se, ok := err.(StmtError); !ok || se.Code() != ErrInterrupt {
+		t.Errorf("got %#v; want interrupt", err)
+	}
+	checkNoError(t, db.FastExec("SELECT 1"), "connection unusable after interruption: %s")
+}
+
+func TestInterruptWhileClosing(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		db := open(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		db.InterruptOnDone(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for j := 0; j < 100; j++ {
+				db.Interrupt()
+			}
+		}()
+		checkNoError(t, db.Close(), "close error: %s")
+		cancel()
+		<-done
+		db.Interrupt() // no-op
+	}
+}
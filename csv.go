@@ -2,6 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// +build !noyacr
+
 package sqlite
 
 import (
@@ -117,26 +119,21 @@ func (m csvModule) Create(c *Conn, args []string) (VTab, error) {
 	 * that is successful, call sqlite3_declare_vtab() to configure
 	 * the csv table schema.
 	 */
-	sql := "CREATE TABLE x("
-	tail := ", "
+	schema := VTabSchema{Columns: make([]VTabColumn, len(vTab.cols))}
 	for i, col := range vTab.cols {
-		if i == len(vTab.cols)-1 {
-			tail = ");"
-		}
-		colType := ""
-		if len(types) > i {
-			colType = " " + types[i]
-		}
 		if named {
 			if len(col) == 0 {
 				return nil, errors.New("no column name found")
 			}
-			sql = fmt.Sprintf("%s\"%s\"%s%s", sql, col, colType, tail)
+			schema.Columns[i].Name = col
 		} else {
-			sql = fmt.Sprintf("%scol%d%s%s", sql, i+1, colType, tail)
+			schema.Columns[i].Name = fmt.Sprintf("col%d", i+1)
+		}
+		if len(types) > i {
+			schema.Columns[i].Affinity = Affinity(types[i])
 		}
 	}
-	if err = c.DeclareVTab(sql); err != nil {
+	if err = c.DeclareVTabSchema(schema); err != nil {
 		return nil, err
 	}
 
@@ -208,6 +205,39 @@ func (v *csvTab) readRow(r *yacr.Reader) error {
 func (v *csvTab) BestIndex() error {
 	return nil
 }
+
+// BestIndexInfo pushes down the LIMIT (to stop reading the file early)
+// when there is no other constraint (WHERE terms are not handled) and no ORDER BY.
+// The OFFSET is left to SQLite but the rows it skips must be read.
+func (v *csvTab) BestIndexInfo(info *IndexInfo) error {
+	if len(info.OrderBy) > 0 { // ORDER BY is not consumed
+		return nil
+	}
+	var limit, offset int
+	for i, c := range info.Constraints {
+		switch {
+		case c.Op == IndexConstraintLimit:
+			if c.Usable {
+				limit = i + 1
+			}
+		case c.Op == IndexConstraintOffset:
+			if c.Usable {
+				offset = i + 1
+			}
+		default: // rows must be filtered by SQLite before the LIMIT is applied
+			return nil
+		}
+	}
+	if limit > 0 {
+		info.Constraints[limit-1].ArgvIndex = 1
+		info.IdxNum = 1
+		if offset > 0 {
+			info.Constraints[offset-1].ArgvIndex = 2
+			info.IdxNum = 2
+		}
+	}
+	return nil
+}
 func (v *csvTab) Disconnect() error {
 	return nil
 }
@@ -219,7 +249,7 @@ func (v *csvTab) Open() (VTabCursor, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &csvTabCursor{vTab: v, f: f, rowNumber: 0}, nil
+	return &csvTabCursor{vTab: v, f: f, rowNumber: 0, limit: -1}, nil
 }
 
 type csvTabCursor struct {
@@ -227,13 +257,28 @@ type csvTabCursor struct {
 	f         *os.File
 	r         *yacr.Reader
 	rowNumber int64
+	limit     int64 // -1 when no LIMIT has been pushed down
 }
 
 func (vc *csvTabCursor) Close() error {
 	return vc.f.Close()
 }
 func (vc *csvTabCursor) Filter() error {
+	return vc.FilterIndex(0, "", nil)
+}
+func (vc *csvTabCursor) FilterIndex(idxNum int, idxStr string, args []interface{}) error {
 	v := vc.vTab
+	vc.limit = -1
+	if idxNum > 0 {
+		if limit, ok := args[0].(int64); ok && limit >= 0 {
+			vc.limit = limit
+			if idxNum > 1 {
+				if offset, ok := args[1].(int64); ok && offset > 0 {
+					vc.limit += offset
+				}
+			}
+		}
+	}
 	/* seek back to start of first zRow */
 	v.eof = false
 	if _, err := vc.f.Seek(v.offsetFirstRow, os.SEEK_SET); err != nil {
@@ -250,6 +295,10 @@ func (vc *csvTabCursor) Next() error {
 	if v.eof {
 		return io.EOF
 	}
+	if vc.limit >= 0 && vc.rowNumber >= vc.limit {
+		v.eof = true
+		return nil
+	}
 	if vc.r == nil {
 		vc.r = yacr.NewReader(vc.f, v.sep, v.quoted, false)
 	}
@@ -299,16 +348,17 @@ func LoadCsvModule(db *Conn) error {
 }
 
 // ExportTableToCSV exports table or view content to CSV.
+// (See ExportTableToCSVWriter to export without the yacr dependency.)
 // 'headers' flag turns output of headers on or off.
 // NULL values are output as specified by 'nullvalue' parameter.
 func (db *Conn) ExportTableToCSV(dbName, table string, nullvalue string, headers bool, w *yacr.Writer) error {
-	var sql string
-	if len(dbName) == 0 {
-		sql = fmt.Sprintf(`SELECT * FROM "%s"`, escapeQuote(table))
-	} else {
-		sql = fmt.Sprintf(`SELECT * FROM %s."%s"`, doubleQuote(dbName), escapeQuote(table))
-	}
-	s, err := db.prepare(sql)
+	return db.ExportTableToCSVWithOptions(dbName, table, nullvalue, headers, w, nil)
+}
+
+// ExportTableToCSVWithOptions exports the rows and columns of a table or view selected by o to CSV.
+// The whole table is exported when o is nil.
+func (db *Conn) ExportTableToCSVWithOptions(dbName, table string, nullvalue string, headers bool, w *yacr.Writer, o *ExportOptions) error {
+	s, err := db.prepareTableSelect(dbName, table, o)
 	if err != nil {
 		return err
 	}
@@ -356,6 +406,84 @@ type ImportConfig struct {
 	Headers   bool       // skip headers (first line)
 	Types     []Affinity // optional, when target table does not exist, specify columns type
 	Log       io.Writer  // optional, used to trace lines in error
+	// DryRun parses and checks the whole input without writing anything (the table is not even created):
+	// records whose column count does not match and values which cannot be converted to
+	// their column affinity are reported through Log and returned as ImportErrors.
+	DryRun     bool
+	OnConflict ImportConflict // optional, how duplicate keys are handled (the import is aborted by default)
+	Keys       []string       // columns of the unique/primary key used by ImportUpsert
+	Truncate   bool           // optional, delete the content of the target table first (in the same transaction)
+}
+
+// ImportConflict specifies how ImportCSV handles records violating a uniqueness constraint.
+type ImportConflict uint8
+
+// Import conflict modes
+const (
+	ImportAbort   ImportConflict = iota // INSERT: the whole import is aborted
+	ImportIgnore                        // INSERT OR IGNORE: the record is skipped
+	ImportReplace                       // INSERT OR REPLACE: the existing row is deleted and the record inserted
+	ImportUpsert                        // INSERT ... ON CONFLICT (Keys) DO UPDATE: the other columns of the existing row are updated
+)
+
+// insertSQL returns the statement used to insert one record into table (already quoted) whose columns are names.
+func (ic ImportConfig) insertSQL(table string, names []string) (string, error) {
+	verb := "INSERT"
+	switch ic.OnConflict {
+	case ImportIgnore:
+		verb = "INSERT OR IGNORE"
+	case ImportReplace:
+		verb = "INSERT OR REPLACE"
+	}
+	sql := fmt.Sprintf(`%s INTO %s VALUES (?%s)`, verb, table, strings.Repeat(", ?", len(names)-1))
+	if ic.OnConflict != ImportUpsert {
+		return sql, nil
+	}
+	if len(ic.Keys) == 0 {
+		return "", errors.New("no key specified for upsert")
+	}
+	keys := make(map[string]bool, len(ic.Keys))
+	quoted := make([]string, len(ic.Keys))
+	for i, key := range ic.Keys {
+		keys[strings.ToLower(key)] = true
+		quoted[i] = QuoteIdentifier(key)
+	}
+	var set []string
+	for _, name := range names {
+		if !keys[strings.ToLower(name)] {
+			set = append(set, fmt.Sprintf("%s = excluded.%s", QuoteIdentifier(name), QuoteIdentifier(name)))
+		}
+	}
+	sql += " ON CONFLICT (" + strings.Join(quoted, ", ") + ")"
+	if len(set) == 0 {
+		return sql + " DO NOTHING", nil
+	}
+	return sql + " DO UPDATE SET " + strings.Join(set, ", "), nil
+}
+
+// ImportError reports an invalid record (or value) found by ImportCSV in DryRun mode.
+type ImportError struct {
+	Name   string // the name of the input (see ImportConfig.Name)
+	Line   int    // line number where the record starts
+	Column int    // index of the value in the record, starting at 1 (0 when the whole record is invalid)
+	Msg    string
+}
+
+func (e ImportError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%s:%d: column %d: %s", e.Name, e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Name, e.Line, e.Msg)
+}
+
+// ImportErrors is the error returned by ImportCSV in DryRun mode when the input is invalid.
+type ImportErrors []ImportError
+
+func (e ImportErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e[0].Error(), len(e)-1)
 }
 
 func (ic ImportConfig) getType(i int) string {
@@ -385,6 +513,12 @@ func (db *Conn) ImportCSV(in io.Reader, ic ImportConfig, dbName, table string) e
 	r.Trim = ic.Trim
 	r.Comment = ic.Comment
 	nCol := len(columns)
+	affinities := make([]Affinity, nCol)
+	names := make([]string, nCol)
+	for i, column := range columns {
+		affinities[i] = typeAffinity(column.DataType)
+		names[i] = column.Name
+	}
 	if nCol == 0 { // table does not exist, let's create it
 		var sql string
 		if len(dbName) == 0 {
@@ -402,6 +536,8 @@ func (db *Conn) ImportCSV(in io.Reader, ic ImportConfig, dbName, table string) e
 			sql += fmt.Sprintf("%c\n  \"%s\" %s", sep, r.Text(), ic.getType(i))
 			sep = ','
 			nCol++
+			affinities = append(affinities, typeAffinity(ic.getType(i)))
+			names = append(names, r.Text())
 			if r.EndOfRecord() {
 				break
 			}
@@ -413,8 +549,10 @@ func (db *Conn) ImportCSV(in io.Reader, ic ImportConfig, dbName, table string) e
 			return errors.New("empty file/input")
 		}
 		sql += "\n)"
-		if err = db.FastExec(sql); err != nil {
-			return err
+		if !ic.DryRun {
+			if err = db.FastExec(sql); err != nil {
+				return err
+			}
 		}
 	} else if ic.Headers { // skip headers line
 		for r.Scan() {
@@ -426,12 +564,19 @@ func (db *Conn) ImportCSV(in io.Reader, ic ImportConfig, dbName, table string) e
 			return err
 		}
 	}
+	if ic.DryRun {
+		return validateCSV(r, ic, affinities)
+	}
 
-	var sql string
+	var target string
 	if len(dbName) == 0 {
-		sql = fmt.Sprintf(`INSERT INTO "%s" VALUES (?%s)`, escapeQuote(table), strings.Repeat(", ?", nCol-1))
+		target = fmt.Sprintf(`"%s"`, escapeQuote(table))
 	} else {
-		sql = fmt.Sprintf(`INSERT INTO %s."%s" VALUES (?%s)`, doubleQuote(dbName), escapeQuote(table), strings.Repeat(", ?", nCol-1))
+		target = fmt.Sprintf(`%s."%s"`, doubleQuote(dbName), escapeQuote(table))
+	}
+	sql, err := ic.insertSQL(target, names)
+	if err != nil {
+		return err
 	}
 	s, err := db.prepare(sql)
 	if err != nil {
@@ -449,6 +594,11 @@ func (db *Conn) ImportCSV(in io.Reader, ic ImportConfig, dbName, table string) e
 			_ = db.Rollback()
 		}
 	}()
+	if ic.Truncate {
+		if err = db.FastExec("DELETE FROM " + target); err != nil {
+			return err
+		}
+	}
 	startLine := r.LineNumber()
 	for i := 1; r.Scan(); i++ {
 		if i == 1 && r.EndOfRecord() && len(r.Bytes()) == 0 { // empty line
@@ -491,3 +641,55 @@ func (db *Conn) ImportCSV(in io.Reader, ic ImportConfig, dbName, table string) e
 	}
 	return nil
 }
+
+// validateCSV checks the records read by r against the column affinities (see ImportConfig.DryRun).
+func validateCSV(r *yacr.Reader, ic ImportConfig, affinities []Affinity) error {
+	var errs ImportErrors
+	report := func(line, column int, format string, a ...interface{}) {
+		e := ImportError{Name: ic.Name, Line: line, Column: column, Msg: fmt.Sprintf(format, a...)}
+		if ic.Log != nil {
+			fmt.Fprintln(ic.Log, e.Error())
+		}
+		errs = append(errs, e)
+	}
+	nCol := len(affinities)
+	startLine := r.LineNumber()
+	for i := 1; r.Scan(); i++ {
+		if i == 1 && r.EndOfRecord() && len(r.Bytes()) == 0 { // empty line
+			i = 0
+			startLine = r.LineNumber()
+			continue
+		}
+		if i <= nCol && !convertible(r.Text(), affinities[i-1]) {
+			report(startLine, i, "%q cannot be converted to %s", r.Text(), affinities[i-1])
+		}
+		if r.EndOfRecord() {
+			if i != nCol {
+				report(startLine, 0, "expected %d columns but found %d", nCol, i)
+			}
+			i = 0
+			startLine = r.LineNumber()
+		}
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// convertible tells if value is stored with the type matching the affinity
+// (otherwise SQLite stores it as text).
+func convertible(value string, affinity Affinity) bool {
+	switch affinity {
+	case Integral:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case Real, Numerical:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	}
+	return true
+}
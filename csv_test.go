@@ -2,6 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// +build !noyacr
+
 package sqlite_test
 
 import (
@@ -54,6 +56,17 @@ func TestCsvModule(t *testing.T) {
 	})
 	checkNoError(t, err, "couldn't select from CSV virtual table: %s")
 
+	var rowids string
+	err = db.OneValue("SELECT group_concat(rowid) FROM (SELECT rowid FROM vtab LIMIT 2 OFFSET 1)", &rowids)
+	checkNoError(t, err, "couldn't select from CSV virtual table: %s")
+	assert.Equal(t, "2,3", rowids, "limit pushed down")
+	err = db.OneValue("SELECT colC FROM vtab ORDER BY colC DESC LIMIT 1", &col3)
+	checkNoError(t, err, "couldn't select from CSV virtual table: %s")
+	assert.Equal(t, "c,d", col3, "limit not pushed down before sort")
+	err = db.OneValue("SELECT rowid FROM vtab WHERE colC = 'c,d' LIMIT 1", &rowids)
+	checkNoError(t, err, "couldn't select from CSV virtual table: %s")
+	assert.Equal(t, "5", rowids, "limit not pushed down before filtering")
+
 	err = db.Exec("DROP TABLE vtab")
 	checkNoError(t, err, "couldn't drop CSV virtual table: %s")
 }
@@ -195,6 +208,96 @@ func TestImportCSV(t *testing.T) {
 	checkNoError(t, err, "error while importing CSV file: %s")
 }
 
+func TestImportCSVDryRun(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	ic := ImportConfig{
+		Name:      "test",
+		Separator: ',',
+		Headers:   true,
+		Types:     []Affinity{Textual, Integral, Real},
+		DryRun:    true,
+	}
+	err := db.ImportCSV(strings.NewReader("t,i,r\na,1,1.5\nb,x,2\nc,3\nd,4,y,z\n"), ic, "", "test")
+	assert.T(t, err != nil, "error expected")
+	errs, ok := err.(ImportErrors)
+	assert.Tf(t, ok, "got %#v; want ImportErrors", err)
+	assert.Equal(t, ImportErrors{
+		{Name: "test", Line: 3, Column: 2, Msg: `"x" cannot be converted to INTEGER`},
+		{Name: "test", Line: 4, Msg: "expected 3 columns but found 2"},
+		{Name: "test", Line: 5, Column: 3, Msg: `"y" cannot be converted to REAL`},
+		{Name: "test", Line: 5, Msg: "expected 3 columns but found 4"},
+	}, errs)
+	assert.Equal(t, "test:3: column 2: \"x\" cannot be converted to INTEGER (and 3 more errors)", err.Error())
+	exists, err := db.TableExists("", "test")
+	checkNoError(t, err, "%s")
+	assert.T(t, !exists, "no table expected in dry-run mode")
+
+	createTable(db, t)
+	ic.Types = nil
+	err = db.ImportCSV(strings.NewReader("id,float_num,int_num,a_string\n1,2.5,3,a\n"), ic, "", "test")
+	checkNoError(t, err, "error while validating CSV input: %s")
+	var count int
+	checkNoError(t, db.OneValue("SELECT count(*) FROM test", &count), "%s")
+	assert.Equal(t, 0, count)
+}
+
+func TestImportCSVConflict(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	err := db.FastExec("CREATE TABLE test (id INTEGER PRIMARY KEY, name TEXT, n INT); INSERT INTO test VALUES (1, 'a', 1)")
+	checkNoError(t, err, "error creating table: %s")
+	input := "id,name,n\n1,b,2\n2,c,3\n"
+	ic := ImportConfig{Name: "test", Separator: ',', Headers: true}
+
+	err = db.ImportCSV(strings.NewReader(input), ic, "", "test")
+	assert.T(t, err != nil, "constraint violation expected")
+
+	content := func() string {
+		var rows []string
+		err := db.Select("SELECT id, name, n FROM test ORDER BY id", func(s *Stmt) error {
+			var id, n int
+			var name string
+			if err := s.Scan(&id, &name, &n); err != nil {
+				return err
+			}
+			rows = append(rows, fmt.Sprintf("%d:%s:%d", id, name, n))
+			return nil
+		})
+		checkNoError(t, err, "error selecting: %s")
+		return strings.Join(rows, ",")
+	}
+	assert.Equal(t, "1:a:1", content())
+
+	for _, test := range []struct {
+		mode     ImportConflict
+		keys     []string
+		expected string
+	}{
+		{ImportIgnore, nil, "1:a:1,2:c:3"},
+		{ImportReplace, nil, "1:b:2,2:c:3"},
+		{ImportUpsert, []string{"id"}, "1:b:2,2:c:3"},
+	} {
+		checkNoError(t, db.FastExec("DELETE FROM test WHERE id > 1; UPDATE test SET name = 'a', n = 1"), "%s")
+		ic.OnConflict = test.mode
+		ic.Keys = test.keys
+		err = db.ImportCSV(strings.NewReader(input), ic, "", "test")
+		checkNoError(t, err, "error while importing CSV: %s")
+		assert.Equal(t, test.expected, content())
+	}
+
+	ic.Keys = nil
+	err = db.ImportCSV(strings.NewReader(input), ic, "", "test")
+	assert.T(t, err != nil, "error expected without keys")
+
+	ic.OnConflict = ImportAbort
+	ic.Truncate = true
+	err = db.ImportCSV(strings.NewReader("id,name,n\n3,d,4\n"), ic, "", "test")
+	checkNoError(t, err, "error while importing CSV: %s")
+	assert.Equal(t, "3:d:4", content())
+}
+
 func TestImportAffinity(t *testing.T) {
 	db := open(t)
 	defer checkClose(db, t)
@@ -243,6 +346,23 @@ line"), (3.33, 2, 'test')`)
 line"
 3,3.33,2,test
 `, b.String())
+
+	b.Reset()
+	w = yacr.NewWriter(&b, ',', true)
+	err = db.ExportTableToCSVWithOptions("main", "test", "NULL", false, w, &ExportOptions{
+		Columns: []string{"a_string", "float_num"},
+		Where:   "int_num < ?",
+		Args:    []interface{}{2},
+		OrderBy: "id DESC",
+	})
+	checkNoError(t, err, "error while exporting CSV file: %s")
+	assert.Equal(t, `"new
+line",NULL
+"qu""ote",1.23
+`, b.String())
+
+	err = db.ExportTableToCSVWithOptions("", "test", "", true, w, &ExportOptions{Where: "unknown = 1"})
+	assert.T(t, err != nil, "error expected with invalid filter")
 }
 
 func TestExportToCSV(t *testing.T) {
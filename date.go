@@ -16,6 +16,37 @@ const (
 	dayInSeconds = 60 * 60 * 24
 )
 
+// TimeLayouts is a list of the common text formats of timestamps (ISO-8601 with or without fraction/timezone,
+// SQLite date and time functions output, RFC3339) which can be used as Conn.ScanTimeLayouts:
+//
+//	c.ScanTimeLayouts = TimeLayouts
+var TimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999Z07:00", // RFC3339 with fraction
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseTime parses a text timestamp with the first matching layout of ScanTimeLayouts
+// (or with the DefaultTimeLayout when no layout is specified).
+// If no timezone is specified, UTC is used.
+func (c *Conn) parseTime(txt string) (time.Time, error) {
+	if len(c.ScanTimeLayouts) == 0 {
+		return time.Parse(c.DefaultTimeLayout, txt)
+	}
+	var err error
+	for _, layout := range c.ScanTimeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, txt); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
 // JulianDayToUTC transforms a julian day number into an UTC Time.
 func JulianDayToUTC(jd float64) time.Time {
 	jd -= julianDay
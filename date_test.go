@@ -75,6 +75,31 @@ func TestScanTime(t *testing.T) {
 	}
 }
 
+func TestScanTimeLayouts(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	db.ScanTimeLayouts = TimeLayouts
+
+	for txt, expected := range map[string]time.Time{
+		"2017-05-10":                     time.Date(2017, 5, 10, 0, 0, 0, 0, time.UTC),
+		"2017-05-10 14:30":               time.Date(2017, 5, 10, 14, 30, 0, 0, time.UTC),
+		"2017-05-10 14:30:15":            time.Date(2017, 5, 10, 14, 30, 15, 0, time.UTC),
+		"2017-05-10T14:30:15.123":        time.Date(2017, 5, 10, 14, 30, 15, 123000000, time.UTC),
+		"2017-05-10T14:30:15Z":           time.Date(2017, 5, 10, 14, 30, 15, 0, time.UTC),
+		"2017-05-10 14:30:15.5+02:00":    time.Date(2017, 5, 10, 12, 30, 15, 500000000, time.UTC),
+		"2017-05-10T14:30:15.123456789Z": time.Date(2017, 5, 10, 14, 30, 15, 123456789, time.UTC),
+	} {
+		var tm time.Time
+		err := db.OneValue("SELECT ?", &tm, txt)
+		checkNoError(t, err, "Error reading time: %#v")
+		assert.Tf(t, expected.Equal(tm), "%s: got %s; want %s", txt, tm, expected)
+	}
+
+	var tm time.Time
+	err := db.OneValue("SELECT '10/05/2017'", &tm)
+	assert.T(t, err != nil, "parse error expected")
+}
+
 func TestScanNullTime(t *testing.T) {
 	db := open(t)
 	defer checkClose(db, t)
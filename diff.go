@@ -0,0 +1,189 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaObject is the description of one table, index, trigger or view.
+type SchemaObject struct {
+	Type  string // "table", "index", "trigger" or "view"
+	Name  string
+	Table string // table the object is attached to (same as Name for tables and views)
+	SQL   string // CREATE statement
+}
+
+// SchemaChange describes an object whose definition differs between two schemas.
+// Column changes are reported only for tables.
+type SchemaChange struct {
+	Old, New       SchemaObject
+	AddedColumns   []string
+	RemovedColumns []string
+	ChangedColumns []string // type, constraint or default value changed
+}
+
+// SchemaDiff reports the differences between two schemas (from a to b).
+type SchemaDiff struct {
+	Added   []SchemaObject // objects present only in b
+	Removed []SchemaObject // objects present only in a
+	Changed []SchemaChange // objects present in both but with different definitions
+}
+
+// Empty returns true when both schemas are the same.
+func (d SchemaDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffSchemas compares tables, columns, indexes, triggers and views between two connections.
+// The database name can be empty, "main", "temp" or the name of an attached database (in both connections).
+// Internal objects (sqlite_%) are ignored.
+func DiffSchemas(a, b *Conn, dbName string) (SchemaDiff, error) {
+	return diffSchemas(a, dbName, b, dbName)
+}
+
+// DiffDeclaredSchema compares the schema of the connection c (from) with a declarative schema (to).
+// The declarative schema is a list of CREATE statements separated by semicolons
+// which is loaded into a temporary in-memory database.
+func DiffDeclaredSchema(c *Conn, dbName string, schema string) (SchemaDiff, error) {
+	decl, err := Open(":memory:")
+	if err != nil {
+		return SchemaDiff{}, err
+	}
+	defer decl.Close()
+	if err = decl.FastExec(schema); err != nil {
+		return SchemaDiff{}, err
+	}
+	return diffSchemas(c, dbName, decl, "main")
+}
+
+func diffSchemas(a *Conn, aName string, b *Conn, bName string) (SchemaDiff, error) {
+	var diff SchemaDiff
+	oldObjects, err := a.schemaObjects(aName)
+	if err != nil {
+		return diff, err
+	}
+	newObjects, err := b.schemaObjects(bName)
+	if err != nil {
+		return diff, err
+	}
+	for key, o := range oldObjects {
+		n, ok := newObjects[key]
+		if !ok {
+			diff.Removed = append(diff.Removed, o)
+			continue
+		}
+		change := SchemaChange{Old: o, New: n}
+		if o.Type == "table" {
+			if err = change.diffColumns(a, aName, b, bName); err != nil {
+				return diff, err
+			}
+		}
+		if len(change.AddedColumns) > 0 || len(change.RemovedColumns) > 0 || len(change.ChangedColumns) > 0 ||
+			normalizeSQL(o.SQL) != normalizeSQL(n.SQL) {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for key, n := range newObjects {
+		if _, ok := oldObjects[key]; !ok {
+			diff.Added = append(diff.Added, n)
+		}
+	}
+	sort.Sort(schemaObjects(diff.Added))
+	sort.Sort(schemaObjects(diff.Removed))
+	sort.Sort(schemaChanges(diff.Changed))
+	return diff, nil
+}
+
+// schemaObjects returns objects from 'sqlite_master'/'sqlite_temp_master' indexed by type and lower-cased name.
+func (c *Conn) schemaObjects(dbName string) (map[string]SchemaObject, error) {
+	var sql string
+	if len(dbName) == 0 {
+		sql = "SELECT type, name, tbl_name, sql FROM sqlite_master WHERE name NOT LIKE 'sqlite_%'"
+	} else if strings.EqualFold("temp", dbName) {
+		sql = "SELECT type, name, tbl_name, sql FROM sqlite_temp_master WHERE name NOT LIKE 'sqlite_%'"
+	} else {
+		sql = fmt.Sprintf("SELECT type, name, tbl_name, sql FROM %s.sqlite_master WHERE name NOT LIKE 'sqlite_%%'", doubleQuote(dbName))
+	}
+	s, err := c.prepare(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer s.finalize()
+	var objects = make(map[string]SchemaObject)
+	err = s.Select(func(s *Stmt) (err error) {
+		o := SchemaObject{}
+		if err = s.Scan(&o.Type, &o.Name, &o.Table, &o.SQL); err != nil {
+			return
+		}
+		objects[o.Type+"."+strings.ToLower(o.Name)] = o
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (change *SchemaChange) diffColumns(a *Conn, aName string, b *Conn, bName string) error {
+	oldColumns, err := a.Columns(aName, change.Old.Name)
+	if err != nil {
+		return err
+	}
+	newColumns, err := b.Columns(bName, change.New.Name)
+	if err != nil {
+		return err
+	}
+	news := make(map[string]Column, len(newColumns))
+	for _, c := range newColumns {
+		news[strings.ToLower(c.Name)] = c
+	}
+	olds := make(map[string]bool, len(oldColumns))
+	for _, o := range oldColumns {
+		key := strings.ToLower(o.Name)
+		olds[key] = true
+		n, ok := news[key]
+		if !ok {
+			change.RemovedColumns = append(change.RemovedColumns, o.Name)
+		} else if !strings.EqualFold(o.DataType, n.DataType) || o.NotNull != n.NotNull || o.DfltValue != n.DfltValue || o.Pk != n.Pk {
+			change.ChangedColumns = append(change.ChangedColumns, o.Name)
+		}
+	}
+	for _, n := range newColumns {
+		if !olds[strings.ToLower(n.Name)] {
+			change.AddedColumns = append(change.AddedColumns, n.Name)
+		}
+	}
+	return nil
+}
+
+// normalizeSQL collapses white spaces to make definitions comparable.
+func normalizeSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+type schemaObjects []SchemaObject
+
+func (s schemaObjects) Len() int      { return len(s) }
+func (s schemaObjects) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s schemaObjects) Less(i, j int) bool {
+	if s[i].Type != s[j].Type {
+		return s[i].Type < s[j].Type
+	}
+	return s[i].Name < s[j].Name
+}
+
+type schemaChanges []SchemaChange
+
+func (s schemaChanges) Len() int      { return len(s) }
+func (s schemaChanges) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s schemaChanges) Less(i, j int) bool {
+	if s[i].Old.Type != s[j].Old.Type {
+		return s[i].Old.Type < s[j].Old.Type
+	}
+	return s[i].Old.Name < s[j].Old.Name
+}
@@ -0,0 +1,62 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	a := open(t)
+	defer checkClose(a, t)
+	b := open(t)
+	defer checkClose(b, t)
+
+	checkNoError(t, a.FastExec(`CREATE TABLE t1 (id INTEGER PRIMARY KEY, name TEXT, old TEXT);
+		CREATE TABLE t2 (x);
+		CREATE INDEX t1_name ON t1 (name);
+		CREATE VIEW v AS SELECT 1;`), "error creating schema: %s")
+	checkNoError(t, b.FastExec(`CREATE TABLE t1 (id INTEGER PRIMARY KEY, name TEXT NOT NULL, new TEXT);
+		CREATE TABLE t3 (y);
+		CREATE INDEX t1_name ON t1 (name);
+		CREATE VIEW v AS
+		SELECT 1;`), "error creating schema: %s")
+
+	diff, err := DiffSchemas(a, b, "main")
+	checkNoError(t, err, "error while comparing schemas: %s")
+	assert.T(t, !diff.Empty(), "expected differences")
+	assert.Equal(t, 1, len(diff.Added), "added objects")
+	assert.Equal(t, "t3", diff.Added[0].Name, "added table")
+	assert.Equal(t, 1, len(diff.Removed), "removed objects")
+	assert.Equal(t, "t2", diff.Removed[0].Name, "removed table")
+	assert.Equal(t, 1, len(diff.Changed), "changed objects")
+	change := diff.Changed[0]
+	assert.Equal(t, "t1", change.Old.Name, "changed table")
+	assert.Equal(t, []string{"new"}, change.AddedColumns, "added columns")
+	assert.Equal(t, []string{"old"}, change.RemovedColumns, "removed columns")
+	assert.Equal(t, []string{"name"}, change.ChangedColumns, "changed columns")
+
+	diff, err = DiffSchemas(a, a, "")
+	checkNoError(t, err, "error while comparing schemas: %s")
+	assert.T(t, diff.Empty(), "expected no difference")
+}
+
+func TestDiffDeclaredSchema(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+	checkNoError(t, db.FastExec("ATTACH ':memory:' AS aux; CREATE TABLE aux.t (x)"), "%s")
+
+	diff, err := DiffDeclaredSchema(db, "aux", "CREATE TABLE t (x, y)")
+	checkNoError(t, err, "error while comparing schemas: %s")
+	assert.Equal(t, 1, len(diff.Changed), "changed objects")
+	assert.Equal(t, []string{"y"}, diff.Changed[0].AddedColumns, "added columns")
+
+	_, err = DiffDeclaredSchema(db, "main", "CREATE TABLE")
+	assert.T(t, err != nil, "expected syntax error")
+}
@@ -14,6 +14,8 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -33,9 +35,11 @@ type impl struct {
 	configure func(*Conn) error
 }
 type conn struct {
-	c *Conn
+	c    *Conn
+	inTx bool // true between Begin and Commit/Rollback
 }
 type stmt struct {
+	c            *conn
 	s            *Stmt
 	rowsRef      bool // true if there is a rowsImpl associated to this statement that has not been closed.
 	pendingClose bool
@@ -44,6 +48,9 @@ type rowsImpl struct {
 	s           *stmt
 	columnNames []string // cache
 	ctx         context.Context
+	declKinds   []declKind // cache (when Conn.ScanDeclaredTypes is set)
+	// stops the goroutine interrupting the statement when ctx is done
+	stopInterrupt func()
 }
 
 type result struct {
@@ -70,11 +77,22 @@ func NewDriver(open func(name string) (*Conn, error), configure func(*Conn) erro
 }
 
 var defaultOpen = func(name string) (*Conn, error) {
+	// An encryption key can be specified with an URI parameter: file:test.db?_key=secret
+	name, key := keyFromDSN(name)
 	// OpenNoMutex == multi-thread mode (http://sqlite.org/compile.html#threadsafe and http://sqlite.org/threadsafe.html)
-	c, err := Open(name, OpenURI, OpenNoMutex, OpenReadWrite, OpenCreate)
+	c, err := openVfs(name, "", OpenURI, OpenNoMutex, OpenReadWrite, OpenCreate)
 	if err != nil {
 		return nil, err
 	}
+	if key != nil {
+		if err = c.Key("main", key); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+	}
+	if c, err = c.autoRegistered(); err != nil {
+		return nil, err
+	}
 	c.BusyTimeout(10 * time.Second)
 	//c.DefaultTimeLayout = "2006-01-02 15:04:05.999999999"
 	c.ScanNumericalAsTime = true
@@ -95,7 +113,7 @@ func (d *impl) Open(name string) (driver.Conn, error) {
 			return nil, err
 		}
 	}
-	return &conn{c}, nil
+	return &conn{c: c}, nil
 }
 
 // Unwrap gives access to underlying driver connection.
@@ -133,17 +151,17 @@ func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, e
 	if err != nil {
 		return nil, err
 	}
-	return &stmt{s: s}, nil
+	return &stmt{c: c, s: s}, nil
 }
 
 func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
 	if c.c.IsClosed() {
 		return nil, driver.ErrBadConn
 	}
-	if ctx.Done() != nil {
-		c.c.ProgressHandler(progressHandler, 100, ctx)
-		defer c.c.ProgressHandler(nil, 0, nil)
+	if err := c.checkTx(); err != nil {
+		return nil, err
 	}
+	defer c.c.interruptOnDone(ctx)()
 	if len(args) == 0 {
 		if query == "unwrap" {
 			return nil, ConnError{c: c.c}
@@ -190,11 +208,14 @@ func (c *conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 	if c.c.IsClosed() {
 		return nil, driver.ErrBadConn
 	}
+	if err := c.checkTx(); err != nil {
+		return nil, err
+	}
 	st, err := c.c.Prepare(query)
 	if err != nil {
 		return nil, err
 	}
-	s := &stmt{s: st}
+	s := &stmt{c: c, s: st}
 	return s.QueryContext(ctx, args)
 }
 
@@ -209,6 +230,7 @@ func (c *conn) Begin() (driver.Tx, error) {
 	if err := c.c.Begin(); err != nil {
 		return nil, err
 	}
+	c.inTx = true
 	return c, nil
 }
 
@@ -238,12 +260,32 @@ func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, e
 }
 
 func (c *conn) Commit() error {
+	if err := c.checkTx(); err != nil {
+		c.inTx = false
+		return err
+	}
+	c.inTx = false
 	return c.c.Commit()
 }
 func (c *conn) Rollback() error {
+	if c.checkTx() != nil { // nothing left to roll back
+		c.inTx = false
+		return nil
+	}
+	c.inTx = false
 	return c.c.Rollback()
 }
 
+// checkTx detects transactions rolled back by SQLite itself
+// (ON CONFLICT ROLLBACK, SQLITE_FULL, ...) while the database/sql Tx is still open:
+// the following statements would be silently executed in autocommit mode.
+func (c *conn) checkTx() error {
+	if c.inTx && c.c.GetAutocommit() {
+		return c.c.specificError("transaction has been rolled back automatically (after an error)")
+	}
+	return nil
+}
+
 func (s *stmt) Close() error {
 	if s.rowsRef { // Currently, it never happens because the sql.Stmt doesn't call driver.Stmt in this case
 		s.pendingClose = true
@@ -265,13 +307,13 @@ func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
 }
 
 func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	if err := s.s.bindNamedValue(args); err != nil {
+	if err := s.c.checkTx(); err != nil {
 		return nil, err
 	}
-	if ctx.Done() != nil {
-		s.s.c.ProgressHandler(progressHandler, 100, ctx)
-		defer s.s.c.ProgressHandler(nil, 0, nil)
+	if err := s.s.bindNamedValue(args); err != nil {
+		return nil, err
 	}
+	defer s.s.c.interruptOnDone(ctx)()
 	if err := s.s.exec(); err != nil {
 		return nil, ctxError(ctx, err)
 	}
@@ -282,14 +324,14 @@ func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driv
 	if s.rowsRef {
 		return nil, errors.New("previously returned Rows still not closed")
 	}
+	if err := s.c.checkTx(); err != nil {
+		return nil, err
+	}
 	if err := s.s.bindNamedValue(args); err != nil {
 		return nil, err
 	}
 	s.rowsRef = true
-	if ctx.Done() != nil {
-		s.s.c.ProgressHandler(progressHandler, 100, ctx)
-	}
-	return &rowsImpl{s, nil, ctx}, nil
+	return &rowsImpl{s: s, ctx: ctx, stopInterrupt: s.s.c.interruptOnDone(ctx)}, nil
 }
 
 func (s *stmt) bind(args []driver.Value) error {
@@ -322,12 +364,96 @@ func (r *rowsImpl) Next(dest []driver.Value) error {
 			panic("Invalid type returned by ScanValue")
 		}*/
 	}
+	if r.s.s.c.ScanDeclaredTypes {
+		for i := range dest {
+			dest[i] = r.s.s.c.convertDeclared(r.declKind(i), dest[i])
+		}
+	}
 	return nil
 }
 
+// declKind tells how a value is converted according to its column declared type (see Conn.ScanDeclaredTypes).
+type declKind uint8
+
+const (
+	declNone declKind = iota
+	declBool
+	declTime
+	declDecimal
+)
+
+func declaredKind(declType string) declKind {
+	declType = strings.ToUpper(declType)
+	if i := strings.IndexByte(declType, '('); i >= 0 { // DECIMAL(10,2)
+		declType = strings.TrimSpace(declType[:i])
+	}
+	switch declType {
+	case "BOOLEAN", "BOOL":
+		return declBool
+	case "DATE", "DATETIME", "TIMESTAMP":
+		return declTime
+	case "DECIMAL":
+		return declDecimal
+	}
+	return declNone
+}
+
+func (r *rowsImpl) declKind(index int) declKind {
+	if r.declKinds == nil {
+		r.declKinds = make([]declKind, r.s.s.ColumnCount())
+		for i := range r.declKinds {
+			r.declKinds[i] = declaredKind(r.s.s.ColumnDeclaredType(i))
+		}
+	}
+	return r.declKinds[index]
+}
+
+// convertDeclared converts a value scanned by the driver according to its column declared type.
+// The value is returned unchanged when it cannot be converted.
+func (c *Conn) convertDeclared(kind declKind, value interface{}) interface{} {
+	switch kind {
+	case declBool:
+		switch v := value.(type) {
+		case int64:
+			return v != 0
+		case float64:
+			return v != 0
+		case []byte:
+			if b, err := strconv.ParseBool(string(v)); err == nil {
+				return b
+			}
+		}
+	case declTime:
+		switch v := value.(type) {
+		case int64:
+			return time.Unix(v, 0)
+		case []byte:
+			if c.DefaultTimeLayout == "" && len(c.ScanTimeLayouts) == 0 {
+				break
+			}
+			t, err := c.parseTime(string(v))
+			if err == nil {
+				return t
+			}
+			Log(-1, err.Error())
+		}
+	case declDecimal:
+		switch v := value.(type) {
+		case int64:
+			return strconv.FormatInt(v, 10)
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case []byte:
+			return string(v)
+		}
+	}
+	return value
+}
+
 func (r *rowsImpl) Close() error {
-	if r.ctx.Done() != nil {
-		r.s.s.c.ProgressHandler(nil, 0, nil)
+	if r.stopInterrupt != nil {
+		r.stopInterrupt()
+		r.stopInterrupt = nil
 	}
 	r.s.rowsRef = false
 	if r.s.pendingClose {
@@ -365,10 +491,21 @@ func (r *rowsImpl) NextResultSet() error {
 		return err
 	}
 	r.s.s = nextStmt
+	r.declKinds = nil
 	return nil
 }
 
 func (r *rowsImpl) ColumnTypeScanType(index int) reflect.Type {
+	if r.s.s.c.ScanDeclaredTypes {
+		switch r.declKind(index) {
+		case declBool:
+			return reflect.TypeOf(false)
+		case declTime:
+			return reflect.TypeOf(time.Time{})
+		case declDecimal:
+			return reflect.TypeOf("")
+		}
+	}
 	switch r.s.s.ColumnType(index) {
 	case Integer:
 		return reflect.TypeOf(int64(0))
@@ -416,19 +553,6 @@ func (s *Stmt) bindNamedValue(args []driver.NamedValue) error {
 	return nil
 }
 
-func progressHandler(p interface{}) bool {
-	if ctx, ok := p.(context.Context); ok {
-		select {
-		case <-ctx.Done():
-			// Cancelled
-			return true
-		default:
-			return false
-		}
-	}
-	return false
-}
-
 func ctxError(ctx context.Context, err error) error {
 	ctxErr := ctx.Err()
 	if ctxErr != nil {
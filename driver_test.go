@@ -10,6 +10,7 @@ import (
 	"database/sql"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
@@ -161,6 +162,26 @@ func TestSqlTx(t *testing.T) {
 	checkNoError(t, err, "Error while committing tx: %s")
 }
 
+func TestSqlTxRolledBackBySQLite(t *testing.T) {
+	db := sqlCreate("DROP TABLE IF EXISTS tx_rollback; CREATE TABLE tx_rollback (x UNIQUE ON CONFLICT ROLLBACK)", t)
+	defer checkSqlDbClose(db, t)
+
+	tx, err := db.Begin()
+	checkNoError(t, err, "Error while beginning tx: %s")
+	_, err = tx.Exec("INSERT INTO tx_rollback VALUES (?)", 1)
+	checkNoError(t, err, "Error while inserting: %s")
+	_, err = tx.Exec("INSERT INTO tx_rollback VALUES (?)", 1)
+	assert.T(t, err != nil, "constraint violation expected")
+	_, err = tx.Exec("INSERT INTO tx_rollback VALUES (?)", 2)
+	assert.T(t, err != nil, "transaction rolled back expected")
+	err = tx.Commit()
+	assert.T(t, err != nil, "transaction rolled back expected")
+
+	var n int
+	checkNoError(t, db.QueryRow("SELECT count(*) FROM tx_rollback").Scan(&n), "%s")
+	assert.Equal(t, 0, n)
+}
+
 func TestSqlPrepare(t *testing.T) {
 	db := sqlCreate(ddl+dml, t)
 	defer checkSqlDbClose(db, t)
@@ -271,6 +292,39 @@ func TestScanTimeFromView(t *testing.T) {
 	assert.Tf(t, err != nil, "scan error expected")
 }
 
+func TestScanDeclaredTypes(t *testing.T) {
+	db := sqlCreate("DROP TABLE IF EXISTS decl; CREATE TABLE decl (b BOOLEAN, tic DATETIME, d DECIMAL(10,2));"+
+		"DROP VIEW IF EXISTS decl_v; CREATE VIEW decl_v AS SELECT * FROM decl;"+
+		"INSERT INTO decl VALUES (1, '2017-05-10 14:30:00.123', '12.50')", t)
+	defer checkSqlDbClose(db, t)
+	db.SetMaxOpenConns(1)
+
+	conn := sqlite.Unwrap(db)
+	conn.DefaultTimeLayout = "2006-01-02 15:04:05.000"
+	conn.ScanDeclaredTypes = true
+	defer func() {
+		conn.ScanDeclaredTypes = false
+	}()
+
+	var b bool
+	var tic time.Time
+	var d string
+	err := db.QueryRow("SELECT * FROM decl_v").Scan(&b, &tic, &d)
+	checkNoError(t, err, "Error while scanning view: %s")
+	assert.T(t, b, "bool expected")
+	assert.Equal(t, time.Date(2017, 5, 10, 14, 30, 0, 123000000, time.UTC), tic)
+	assert.Equal(t, "12.5", d)
+
+	rows, err := db.Query("SELECT * FROM decl")
+	checkNoError(t, err, "%s")
+	defer checkSqlRowsClose(rows, t)
+	types, err := rows.ColumnTypes()
+	checkNoError(t, err, "%s")
+	assert.Equal(t, reflect.TypeOf(false), types[0].ScanType())
+	assert.Equal(t, reflect.TypeOf(time.Time{}), types[1].ScanType())
+	assert.Equal(t, reflect.TypeOf(""), types[2].ScanType())
+}
+
 func TestScanNumericalAsTime(t *testing.T) {
 	db := sqlOpen(t)
 	defer checkSqlDbClose(db, t)
@@ -459,6 +513,27 @@ func TestCancel(t *testing.T) {
 	}
 }
 
+func TestCancelQuery(t *testing.T) {
+	db := sqlOpen(t)
+	defer checkSqlDbClose(db, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, "WITH RECURSIVE cnt(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM cnt) SELECT count(*) FROM cnt")
+	checkNoError(t, err, "Error while querying: %s")
+	for rows.Next() {
+	}
+	if err = rows.Err(); err != context.DeadlineExceeded {
+		t.Errorf("Query expected to fail with DeadlineExceeded but it returned %v", err)
+	}
+	checkSqlRowsClose(rows, t)
+
+	// connection should be usable after timeout
+	var val int64
+	err = db.QueryRow("SELECT 1").Scan(&val)
+	checkNoError(t, err, "Scan failed with %s")
+}
+
 func TestNilAndEmptyBytes(t *testing.T) {
 	db := sqlOpen(t)
 	defer checkSqlDbClose(db, t)
@@ -515,6 +590,39 @@ func TestNilAndEmptyBytes(t *testing.T) {
 	}
 }
 
+func TestNullAndEmptyBlobOptions(t *testing.T) {
+	sql.Register("sqlite3NullBlob", sqlite.NewDriver(nil, func(c *sqlite.Conn) error {
+		c.NullIfEmptyBlob = true
+		c.ScanEmptyBlobAsNil = true
+		return nil
+	}))
+	db, err := sql.Open("sqlite3NullBlob", ":memory:")
+	checkNoError(t, err, "Error while opening customized db: %s")
+	defer checkSqlDbClose(db, t)
+	db.SetMaxOpenConns(1)
+
+	var isNull bool
+	err = db.QueryRow("SELECT ? IS NULL", []byte{}).Scan(&isNull)
+	checkNoError(t, err, "Error while binding empty blob: %s")
+	assert.T(t, isNull, "empty blob bound as null expected")
+
+	var b []byte
+	err = db.QueryRow("SELECT zeroblob(0)").Scan(&b)
+	checkNoError(t, err, "Error while scanning empty blob: %s")
+	assert.T(t, b == nil, "nil expected")
+
+	conn := sqlite.Unwrap(db)
+	assert.T(t, conn != nil, "unwrap failed")
+	conn.NullIfEmptyBlob = false
+	conn.ScanEmptyBlobAsNil = false
+	err = db.QueryRow("SELECT ? IS NULL", []byte{}).Scan(&isNull)
+	checkNoError(t, err, "Error while binding empty blob: %s")
+	assert.T(t, !isNull, "zero-length blob expected")
+	err = db.QueryRow("SELECT zeroblob(0)").Scan(&b)
+	checkNoError(t, err, "Error while scanning empty blob: %s")
+	assert.T(t, b != nil && len(b) == 0, "empty slice expected")
+}
+
 func TestMultipleResultSets(t *testing.T) {
 	db := sqlOpen(t)
 	defer checkSqlDbClose(db, t)
@@ -0,0 +1,120 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ExpandIn rewrites a query whose parameters are named with ?name (like "x IN (?list)")
+// into a query with anonymous parameters, expanding slice arguments into the right number of placeholders
+// and flattening the arguments accordingly:
+//
+//	sql, args, err := ExpandIn("SELECT * FROM t WHERE x IN (?list) AND y > ?min",
+//		map[string]interface{}{"list": []int64{1, 2, 3}, "min": 0})
+//	// sql == "SELECT * FROM t WHERE x IN (?, ?, ?) AND y > ?"
+//	// args == []interface{}{int64(1), int64(2), int64(3), 0}
+//
+// An empty slice is replaced by NULL (so that "x IN (NULL)" matches nothing).
+// []byte arguments are not expanded (they are bound as blob).
+// Anonymous (?), numbered (?NNN) and other named parameters (:AAA, @AAA, $AAA) are not supported.
+// The number of parameters is checked against the default SQLITE_MAX_VARIABLE_NUMBER
+// (see Conn.ExpandIn to check against the connection limit).
+func ExpandIn(sql string, args map[string]interface{}) (string, []interface{}, error) {
+	max := 999
+	if VersionAtLeast(3032000) {
+		max = 32766
+	}
+	return expandIn(sql, args, max)
+}
+
+// ExpandIn is like the ExpandIn function but the number of parameters is checked against
+// the connection limit (LimitVariableNumber).
+func (c *Conn) ExpandIn(sql string, args map[string]interface{}) (string, []interface{}, error) {
+	return expandIn(sql, args, int(c.Limit(LimitVariableNumber)))
+}
+
+func expandIn(sql string, args map[string]interface{}, max int) (string, []interface{}, error) {
+	var b strings.Builder
+	var flat []interface{}
+	start := 0
+	for i := 0; i < len(sql); {
+		switch ch := sql[i]; {
+		case ch == '\'' || ch == '"' || ch == '`':
+			i = skipQuoted(sql, i, ch)
+		case ch == '[':
+			i = skipQuoted(sql, i, ']')
+		case ch == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			if j := strings.IndexByte(sql[i:], '\n'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(sql)
+			}
+		case ch == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			if j := strings.Index(sql[i+2:], "*/"); j >= 0 {
+				i += j + 4
+			} else {
+				i = len(sql)
+			}
+		case ch == '?':
+			j := i + 1
+			for j < len(sql) && isIdentifierChar(sql[j]) {
+				j++
+			}
+			name := sql[i+1 : j]
+			if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+				return "", nil, fmt.Errorf("unsupported parameter %q at offset %d (?name expected)", "?"+name, i)
+			}
+			arg, ok := args[name]
+			if !ok {
+				return "", nil, fmt.Errorf("missing argument for parameter %q", name)
+			}
+			b.WriteString(sql[start:i])
+			flat = expandArg(&b, flat, arg)
+			start, i = j, j
+		case ch == ':' || ch == '@' || ch == '$':
+			if i+1 < len(sql) && isIdentifierChar(sql[i+1]) {
+				return "", nil, fmt.Errorf("unsupported parameter at offset %d (?name expected)", i)
+			}
+			i++
+		default:
+			i++
+		}
+	}
+	b.WriteString(sql[start:])
+	if len(flat) > max {
+		return "", nil, fmt.Errorf("too many SQL variables: %d > %d", len(flat), max)
+	}
+	return b.String(), flat, nil
+}
+
+func expandArg(b *strings.Builder, flat []interface{}, arg interface{}) []interface{} {
+	if arg != nil {
+		if _, blob := arg.([]byte); !blob {
+			if v := reflect.ValueOf(arg); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+				if v.Len() == 0 {
+					b.WriteString("NULL")
+					return flat
+				}
+				for i := 0; i < v.Len(); i++ {
+					if i > 0 {
+						b.WriteString(", ")
+					}
+					b.WriteByte('?')
+					flat = append(flat, v.Index(i).Interface())
+				}
+				return flat
+			}
+		}
+	}
+	b.WriteByte('?')
+	return append(flat, arg)
+}
+
+func isIdentifierChar(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= 0x80 || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
@@ -0,0 +1,46 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestExpandIn(t *testing.T) {
+	sql, args, err := ExpandIn("SELECT * FROM t WHERE x IN (?list) AND y > ?min AND z <> '?list' -- ?list",
+		map[string]interface{}{"list": []int64{1, 2, 3}, "min": 0})
+	checkNoError(t, err, "couldn't expand: %s")
+	assert.Equal(t, "SELECT * FROM t WHERE x IN (?, ?, ?) AND y > ? AND z <> '?list' -- ?list", sql)
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(3), 0}, args)
+
+	sql, args, err = ExpandIn("SELECT ?b WHERE 1 IN (?empty)", map[string]interface{}{"b": []byte("x"), "empty": []string{}})
+	checkNoError(t, err, "couldn't expand: %s")
+	assert.Equal(t, "SELECT ? WHERE 1 IN (NULL)", sql)
+	assert.Equal(t, []interface{}{[]byte("x")}, args)
+
+	for _, query := range []string{"SELECT ?", "SELECT ?1", "SELECT :a", "SELECT ?missing"} {
+		_, _, err = ExpandIn(query, map[string]interface{}{"a": 1})
+		assert.Tf(t, err != nil, "error expected for %q", query)
+	}
+}
+
+func TestConnExpandIn(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	checkNoError(t, db.FastExec("CREATE TABLE t (x INT); INSERT INTO t VALUES (1), (2), (3), (4)"), "couldn't create table: %s")
+
+	sql, args, err := db.ExpandIn("SELECT count(*) FROM t WHERE x IN (?list)", map[string]interface{}{"list": []int{1, 3, 5}})
+	checkNoError(t, err, "couldn't expand: %s")
+	var n int
+	checkNoError(t, db.OneValue(sql, &n, args...), "couldn't count: %s")
+	assert.Equal(t, 2, n)
+
+	db.SetLimit(LimitVariableNumber, 2)
+	_, _, err = db.ExpandIn("SELECT count(*) FROM t WHERE x IN (?list)", map[string]interface{}{"list": []int{1, 3, 5}})
+	assert.T(t, err != nil, "error expected")
+}
@@ -0,0 +1,197 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// RecordWriter is the minimal interface used to export records
+// (implemented by *csv.Writer from the standard encoding/csv package).
+// The yacr based CSV features (csv module, ImportCSV, ExportToCSV) can be excluded
+// with the noyacr build tag.
+type RecordWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// ExportTableToCSVWriter exports table or view content to w (a *csv.Writer for example).
+// 'headers' flag turns output of headers on or off.
+// NULL values are output as specified by 'nullvalue' parameter.
+func (db *Conn) ExportTableToCSVWriter(dbName, table string, nullvalue string, headers bool, w RecordWriter) error {
+	return db.ExportTableToCSVWriterWithOptions(dbName, table, nullvalue, headers, w, nil)
+}
+
+// ExportTableToCSVWriterWithOptions exports the rows and columns of a table or view selected by o to w.
+// The whole table is exported when o is nil.
+func (db *Conn) ExportTableToCSVWriterWithOptions(dbName, table string, nullvalue string, headers bool, w RecordWriter, o *ExportOptions) error {
+	s, err := db.prepareTableSelect(dbName, table, o)
+	if err != nil {
+		return err
+	}
+	defer s.finalize()
+	return s.ExportToCSVWriter(nullvalue, headers, w)
+}
+
+// ExportToCSVWriter exports statement result to w (a *csv.Writer for example).
+// 'headers' flag turns output of headers on or off.
+// NULL values are output as specified by 'nullvalue' parameter.
+func (s *Stmt) ExportToCSVWriter(nullvalue string, headers bool, w RecordWriter) error {
+	if headers {
+		if err := w.Write(s.ColumnNames()); err != nil {
+			return err
+		}
+	}
+	record := make([]string, s.ColumnCount())
+	err := s.Select(func(s *Stmt) error {
+		for i := range record {
+			rb, null := s.ScanRawBytes(i)
+			if null {
+				record[i] = nullvalue
+			} else {
+				record[i] = string(rb)
+			}
+		}
+		return w.Write(record)
+	})
+	w.Flush()
+	if err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// TableOptions specifies how results are rendered by ExportToMarkdown and ExportToHTML.
+type TableOptions struct {
+	Headers   bool   // output column names (a Markdown table always has a header row, left blank when false)
+	NullValue string // text used for NULL values
+	MaxWidth  int    // values longer than MaxWidth characters are truncated with an ellipsis (no limit when zero)
+}
+
+// ExportToMarkdown exports statement result as a (GitHub flavored) Markdown table.
+// Pipes and backslashes are escaped and new lines are replaced by <br>.
+// Headers are output and NULL values are empty when o is nil.
+func (s *Stmt) ExportToMarkdown(w io.Writer, o *TableOptions) error {
+	if o == nil {
+		o = &TableOptions{Headers: true}
+	}
+	bw := bufio.NewWriter(w)
+	n := s.ColumnCount()
+	bw.WriteByte('|')
+	for _, name := range s.ColumnNames() {
+		if !o.Headers {
+			name = ""
+		}
+		bw.WriteString(" " + escapeMarkdown(o.truncate(name)) + " |")
+	}
+	bw.WriteString("\n|" + strings.Repeat(" --- |", n) + "\n")
+	err := s.Select(func(s *Stmt) error {
+		bw.WriteByte('|')
+		for i := 0; i < n; i++ {
+			bw.WriteString(" " + escapeMarkdown(o.value(s, i)) + " |")
+		}
+		_, err := bw.WriteString("\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ExportToHTML exports statement result as an HTML table (values are escaped).
+// Headers are output and NULL values are empty when o is nil.
+func (s *Stmt) ExportToHTML(w io.Writer, o *TableOptions) error {
+	if o == nil {
+		o = &TableOptions{Headers: true}
+	}
+	bw := bufio.NewWriter(w)
+	n := s.ColumnCount()
+	bw.WriteString("<table>\n")
+	if o.Headers {
+		bw.WriteString("<tr>")
+		for _, name := range s.ColumnNames() {
+			bw.WriteString("<th>" + html.EscapeString(o.truncate(name)) + "</th>")
+		}
+		bw.WriteString("</tr>\n")
+	}
+	err := s.Select(func(s *Stmt) error {
+		bw.WriteString("<tr>")
+		for i := 0; i < n; i++ {
+			bw.WriteString("<td>" + html.EscapeString(o.value(s, i)) + "</td>")
+		}
+		_, err := bw.WriteString("</tr>\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteString("</table>\n")
+	return bw.Flush()
+}
+
+// value returns the (truncated) text of the specified column.
+func (o *TableOptions) value(s *Stmt, index int) string {
+	rb, null := s.ScanRawBytes(index)
+	if null {
+		return o.NullValue
+	}
+	return o.truncate(string(rb))
+}
+
+func (o *TableOptions) truncate(v string) string {
+	if o.MaxWidth <= 0 || utf8.RuneCountInString(v) <= o.MaxWidth {
+		return v
+	}
+	runes := []rune(v)
+	return string(runes[:o.MaxWidth-1]) + "…"
+}
+
+var markdownEscaper = strings.NewReplacer("\\", "\\\\", "|", "\\|", "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+func escapeMarkdown(v string) string {
+	return markdownEscaper.Replace(v)
+}
+
+// ExportOptions restricts the rows and columns exported by ExportTableToCSVWithOptions and ExportTableToCSVWriterWithOptions.
+type ExportOptions struct {
+	Columns []string      // exported columns (all when empty)
+	Where   string        // optional filter, like "id > ? AND name IS NOT NULL"
+	Args    []interface{} // values bound to the parameters of the Where clause
+	OrderBy string        // optional sort, like "name, id DESC"
+}
+
+func (db *Conn) prepareTableSelect(dbName, table string, o *ExportOptions) (*Stmt, error) {
+	if o == nil {
+		o = &ExportOptions{}
+	}
+	columns := "*"
+	if len(o.Columns) > 0 {
+		quoted := make([]string, len(o.Columns))
+		for i, column := range o.Columns {
+			quoted[i] = QuoteIdentifier(column)
+		}
+		columns = strings.Join(quoted, ", ")
+	}
+	var sql string
+	if len(dbName) == 0 {
+		sql = fmt.Sprintf(`SELECT %s FROM "%s"`, columns, escapeQuote(table))
+	} else {
+		sql = fmt.Sprintf(`SELECT %s FROM %s."%s"`, columns, doubleQuote(dbName), escapeQuote(table))
+	}
+	if len(o.Where) > 0 {
+		sql += " WHERE " + o.Where
+	}
+	if len(o.OrderBy) > 0 {
+		sql += " ORDER BY " + o.OrderBy
+	}
+	return db.prepare(sql, o.Args...)
+}
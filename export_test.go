@@ -0,0 +1,78 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestExportTableToCSVWriter(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+	err := db.FastExec(`INSERT INTO test (float_num, int_num, a_string) VALUES (1.23, 0, 'qu"ote'), (NULL, 1, "new
+line"), (3.33, 2, 'test')`)
+	checkNoError(t, err, "error while inserting data: %s")
+
+	var b bytes.Buffer
+	err = db.ExportTableToCSVWriter("", "test", "NULL", true, csv.NewWriter(&b))
+	checkNoError(t, err, "error while exporting CSV file: %s")
+	assert.Equal(t, `id,float_num,int_num,a_string
+1,1.23,0,"qu""ote"
+2,NULL,1,"new
+line"
+3,3.33,2,test
+`, b.String())
+
+	s, err := db.Prepare("SELECT int_num, a_string FROM test where id > ?", 2)
+	checkNoError(t, err, "error while preparing stmt: %s")
+	defer checkFinalize(s, t)
+	b.Reset()
+	w := csv.NewWriter(&b)
+	w.Comma = ';'
+	err = s.ExportToCSVWriter("", false, w)
+	checkNoError(t, err, "error while exporting CSV file: %s")
+	assert.Equal(t, "2;test\n", b.String())
+}
+
+func TestExportToMarkdown(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	s, err := db.Prepare(`SELECT 1 AS "a|b", 'x\y' AS c, NULL AS d UNION ALL SELECT 2, 'new
+line', 'abcdefghij'`)
+	checkNoError(t, err, "error while preparing stmt: %s")
+	defer checkFinalize(s, t)
+	var b bytes.Buffer
+	err = s.ExportToMarkdown(&b, &TableOptions{Headers: true, NullValue: "NULL", MaxWidth: 5})
+	checkNoError(t, err, "error while exporting to Markdown: %s")
+	assert.Equal(t, `| a\|b | c | d |
+| --- | --- | --- |
+| 1 | x\\y | NULL |
+| 2 | new<br>… | abcd… |
+`, b.String())
+}
+
+func TestExportToHTML(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	s, err := db.Prepare(`SELECT '<b>' AS "x&y", NULL AS z`)
+	checkNoError(t, err, "error while preparing stmt: %s")
+	defer checkFinalize(s, t)
+	var b bytes.Buffer
+	err = s.ExportToHTML(&b, nil)
+	checkNoError(t, err, "error while exporting to HTML: %s")
+	assert.Equal(t, `<table>
+<tr><th>x&amp;y</th><th>z</th></tr>
+<tr><td>&lt;b&gt;</td><td></td></tr>
+</table>
+`, b.String())
+}
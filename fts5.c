@@ -0,0 +1,36 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include <sqlite3.h>
+// warning: incompatible pointer types passing
+//#include "_cgo_export.h"
+
+extern void goXFts5Func(void *api, void *fts, void *ctx, int argc, void *argv, void *udp);
+extern void goXFts5Destroy(void *udp);
+
+static void cXFts5Func(const Fts5ExtensionApi *pApi, Fts5Context *pFts, sqlite3_context *pCtx, int nVal, sqlite3_value **apVal) {
+	goXFts5Func((void *)pApi, pFts, pCtx, nVal, apVal, pApi->xUserData(pFts));
+}
+
+// See http://sqlite.org/fts5.html#extending_fts5
+static fts5_api *fts5Api(sqlite3 *db) {
+	fts5_api *pRet = 0;
+#if SQLITE_VERSION_NUMBER >= 3020000
+	sqlite3_stmt *pStmt = 0;
+	if (SQLITE_OK == sqlite3_prepare_v2(db, "SELECT fts5(?1)", -1, &pStmt, 0)) {
+		sqlite3_bind_pointer(pStmt, 1, (void *)&pRet, "fts5_api_ptr", 0);
+		sqlite3_step(pStmt);
+	}
+	sqlite3_finalize(pStmt);
+#endif
+	return pRet;
+}
+
+int goSqlite3CreateFts5Function(sqlite3 *db, const char *zName, void *udp) {
+	fts5_api *pApi = fts5Api(db);
+	if (pApi == 0) {
+		return SQLITE_ERROR;
+	}
+	return pApi->xCreateFunction(pApi, zName, udp, cXFts5Func, goXFts5Destroy);
+}
@@ -0,0 +1,435 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+#include <stdlib.h>
+
+static inline int my_fts5_column_count(const Fts5ExtensionApi *api, Fts5Context *fts) {
+	return api->xColumnCount(fts);
+}
+static inline int my_fts5_row_count(const Fts5ExtensionApi *api, Fts5Context *fts, sqlite3_int64 *pnRow) {
+	return api->xRowCount(fts, pnRow);
+}
+static inline int my_fts5_column_total_size(const Fts5ExtensionApi *api, Fts5Context *fts, int iCol, sqlite3_int64 *pnToken) {
+	return api->xColumnTotalSize(fts, iCol, pnToken);
+}
+static inline sqlite3_int64 my_fts5_rowid(const Fts5ExtensionApi *api, Fts5Context *fts) {
+	return api->xRowid(fts);
+}
+static inline int my_fts5_column_text(const Fts5ExtensionApi *api, Fts5Context *fts, int iCol, const char **pz, int *pn) {
+	return api->xColumnText(fts, iCol, pz, pn);
+}
+static inline int my_fts5_column_size(const Fts5ExtensionApi *api, Fts5Context *fts, int iCol, int *pnToken) {
+	return api->xColumnSize(fts, iCol, pnToken);
+}
+static inline int my_fts5_phrase_count(const Fts5ExtensionApi *api, Fts5Context *fts) {
+	return api->xPhraseCount(fts);
+}
+static inline int my_fts5_phrase_size(const Fts5ExtensionApi *api, Fts5Context *fts, int iPhrase) {
+	return api->xPhraseSize(fts, iPhrase);
+}
+static inline int my_fts5_inst_count(const Fts5ExtensionApi *api, Fts5Context *fts, int *pnInst) {
+	return api->xInstCount(fts, pnInst);
+}
+static inline int my_fts5_inst(const Fts5ExtensionApi *api, Fts5Context *fts, int iIdx, int *piPhrase, int *piCol, int *piOff) {
+	return api->xInst(fts, iIdx, piPhrase, piCol, piOff);
+}
+
+int goSqlite3CreateFts5Function(sqlite3 *db, const char *zName, void *udp);
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// FTS5Detail is the amount of information stored in the full-text index
+// (See http://sqlite.org/fts5.html#the_detail_option)
+type FTS5Detail string
+
+// FTS5 detail options
+const (
+	FTS5DetailFull   FTS5Detail = "full"
+	FTS5DetailColumn FTS5Detail = "column"
+	FTS5DetailNone   FTS5Detail = "none"
+)
+
+// FTS5Options customizes the creation of an FTS5 table.
+// (See http://sqlite.org/fts5.html#fts5_table_creation_and_initialization)
+type FTS5Options struct {
+	Tokenizer    string // optional, like "porter unicode61 remove_diacritics 2"
+	Prefix       []int  // optional, prefix indexes sizes
+	Content      string // optional, external content table
+	ContentRowID string // optional, rowid column of the external content table
+	Contentless  bool   // content=''
+	// NoColumnSize disables the storage of the size of each column (columnsize=0)
+	NoColumnSize bool
+	Detail       FTS5Detail // optional
+	Unindexed    []string   // optional, columns which are stored but not indexed
+}
+
+// CreateFTS5Table creates a full-text table with the specified columns.
+func (c *Conn) CreateFTS5Table(dbName, table string, columns []string, opts *FTS5Options) error {
+	if len(columns) == 0 {
+		return c.specificError("no column specified for FTS5 table %q", table)
+	}
+	if opts == nil {
+		opts = &FTS5Options{}
+	}
+	if opts.Contentless && len(opts.Content) > 0 {
+		return c.specificError("FTS5 table %q cannot be both contentless and external content", table)
+	}
+	args := make([]string, 0, len(columns)+7)
+	for _, column := range columns {
+		arg := QuoteIdentifier(column)
+		for _, unindexed := range opts.Unindexed {
+			if strings.EqualFold(column, unindexed) {
+				arg += " UNINDEXED"
+				break
+			}
+		}
+		args = append(args, arg)
+	}
+	if len(opts.Tokenizer) > 0 {
+		args = append(args, "tokenize = "+QuoteLiteral(opts.Tokenizer))
+	}
+	if len(opts.Prefix) > 0 {
+		prefixes := make([]string, len(opts.Prefix))
+		for i, p := range opts.Prefix {
+			prefixes[i] = fmt.Sprintf("%d", p)
+		}
+		args = append(args, "prefix = "+QuoteLiteral(strings.Join(prefixes, " ")))
+	}
+	if opts.Contentless {
+		args = append(args, "content = ''")
+	} else if len(opts.Content) > 0 {
+		args = append(args, "content = "+QuoteLiteral(opts.Content))
+		if len(opts.ContentRowID) > 0 {
+			args = append(args, "content_rowid = "+QuoteLiteral(opts.ContentRowID))
+		}
+	}
+	if opts.NoColumnSize {
+		args = append(args, "columnsize = 0")
+	}
+	if len(opts.Detail) > 0 {
+		args = append(args, "detail = "+string(opts.Detail))
+	}
+	return c.FastExec(fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(%s)", QualifiedName(dbName, table), strings.Join(args, ", ")))
+}
+
+// fts5Command executes a special INSERT command on an FTS5 table.
+// (See http://sqlite.org/fts5.html#special_insert_commands)
+func (c *Conn) fts5Command(dbName, table, command string, args ...interface{}) error {
+	name := QuoteIdentifier(table)
+	if len(args) == 0 {
+		return c.FastExec(fmt.Sprintf("INSERT INTO %s(%s) VALUES (%s)", QualifiedName(dbName, table), name, QuoteLiteral(command)))
+	}
+	return c.Exec(fmt.Sprintf("INSERT INTO %s(%s, rank) VALUES (%s, ?)", QualifiedName(dbName, table), name, QuoteLiteral(command)), args...)
+}
+
+// FTS5Rebuild discards the full-text index and rebuilds it from the content table.
+func (c *Conn) FTS5Rebuild(dbName, table string) error {
+	return c.fts5Command(dbName, table, "rebuild")
+}
+
+// FTS5Optimize merges all index b-trees into a single one.
+func (c *Conn) FTS5Optimize(dbName, table string) error {
+	return c.fts5Command(dbName, table, "optimize")
+}
+
+// FTS5Merge performs incremental merges of the index b-trees (up to pages pages are written).
+// A negative value merges only the segments of the current level.
+func (c *Conn) FTS5Merge(dbName, table string, pages int) error {
+	return c.fts5Command(dbName, table, "merge", pages)
+}
+
+// FTS5SetConfig sets a persistent configuration option like "automerge", "crisismerge" or "pgsz".
+// (See http://sqlite.org/fts5.html#fts5_configuration_options_config_table_)
+func (c *Conn) FTS5SetConfig(dbName, table, option string, value interface{}) error {
+	return c.fts5Command(dbName, table, option, value)
+}
+
+// FTS5SyncTriggers returns the statements creating the triggers which keep the external content FTS5 table
+// in sync with its content table (See http://sqlite.org/fts5.html#external_content_tables).
+// The indexed columns are those of the FTS5 table. contentRowID defaults to "rowid".
+// The triggers are named after the FTS5 table with the suffixes _ai, _ad and _au.
+func (c *Conn) FTS5SyncTriggers(dbName, ftsTable, contentTable, contentRowID string) ([]string, error) {
+	columns, err := c.Columns(dbName, ftsTable)
+	if err != nil {
+		return nil, err
+	} else if len(columns) == 0 {
+		return nil, c.specificError("no such FTS5 table: %q", ftsTable)
+	}
+	if len(contentRowID) == 0 {
+		contentRowID = "rowid"
+	}
+	names := make([]string, len(columns))
+	newValues := make([]string, len(columns))
+	oldValues := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = QuoteIdentifier(column.Name)
+		newValues[i] = "new." + names[i]
+		oldValues[i] = "old." + names[i]
+	}
+	fts := QuoteIdentifier(ftsTable)
+	content := QuoteIdentifier(contentTable)
+	rowid := QuoteIdentifier(contentRowID)
+	insert := fmt.Sprintf("INSERT INTO %s(rowid, %s) VALUES (new.%s, %s);", fts, strings.Join(names, ", "), rowid, strings.Join(newValues, ", "))
+	del := fmt.Sprintf("INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.%s, %s);", fts, fts, strings.Join(names, ", "), rowid, strings.Join(oldValues, ", "))
+	trigger := func(suffix, event, body string) string {
+		return fmt.Sprintf("CREATE TRIGGER %s AFTER %s ON %s BEGIN\n  %s\nEND", QualifiedName(dbName, ftsTable+suffix), event, content, body)
+	}
+	return []string{
+		trigger("_ai", "INSERT", insert),
+		trigger("_ad", "DELETE", del),
+		trigger("_au", "UPDATE", del+"\n  "+insert),
+	}, nil
+}
+
+// CreateFTS5SyncTriggers creates the triggers returned by FTS5SyncTriggers (in a transaction).
+// The FTS5 table should be rebuilt (see FTS5Rebuild) if the content table is not empty.
+func (c *Conn) CreateFTS5SyncTriggers(dbName, ftsTable, contentTable, contentRowID string) error {
+	triggers, err := c.FTS5SyncTriggers(dbName, ftsTable, contentTable, contentRowID)
+	if err != nil {
+		return err
+	}
+	return c.Transaction(Immediate, func(c *Conn) error {
+		for _, trigger := range triggers {
+			if err := c.FastExec(trigger); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FTS5IntegrityCheck verifies that the full-text index is internally consistent
+// and, for external content tables with SQLite >= 3.44, that it matches the content table.
+// An error is returned when the check fails (ErrCorrupt extended code).
+func (c *Conn) FTS5IntegrityCheck(dbName, table string) error {
+	if VersionAtLeast(3044000) {
+		return c.fts5Command(dbName, table, "integrity-check", 1)
+	}
+	return c.fts5Command(dbName, table, "integrity-check")
+}
+
+// FTS5Repair runs an integrity check and rebuilds the full-text index if it is corrupt.
+// Returns true when the index has been rebuilt.
+func (c *Conn) FTS5Repair(dbName, table string) (rebuilt bool, err error) {
+	err = c.FTS5IntegrityCheck(dbName, table)
+	if err == nil {
+		return false, nil
+	}
+	if cerr, ok := err.(ConnError); !ok || cerr.Code() != ErrCorrupt {
+		return false, err
+	}
+	if err = c.FTS5Rebuild(dbName, table); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FTS5Phrase quotes s as an FTS5 string so that it is matched as a phrase
+// (and FTS5 query syntax in user input is not interpreted).
+func FTS5Phrase(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// FTS5Query specifies a full-text search ranked with the bm25 function.
+type FTS5Query struct {
+	DbName  string
+	Table   string
+	Match   string    // FTS5 query expression (see FTS5Phrase)
+	Columns []string  // optional, columns selected after rowid and rank (default is all columns)
+	Weights []float64 // optional, bm25 weight of each column (in table order)
+	Limit   int       // optional
+	Offset  int       // optional
+}
+
+// FTS5Search prepares the full-text query.
+// The statement returns the rowid, the bm25 rank (lower is better) and the selected columns,
+// ordered by rank.
+func (c *Conn) FTS5Search(q FTS5Query) (*Stmt, error) {
+	if len(q.Table) == 0 {
+		return nil, errors.New("no FTS5 table specified")
+	}
+	columns := "*"
+	if len(q.Columns) > 0 {
+		quoted := make([]string, len(q.Columns))
+		for i, column := range q.Columns {
+			quoted[i] = QuoteIdentifier(column)
+		}
+		columns = strings.Join(quoted, ", ")
+	}
+	name := QuoteIdentifier(q.Table)
+	rank := fmt.Sprintf("bm25(%s", name)
+	for _, w := range q.Weights {
+		rank += fmt.Sprintf(", %g", w)
+	}
+	rank += ")"
+	sql := fmt.Sprintf("SELECT rowid, %s AS rank, %s FROM %s WHERE %s MATCH ? ORDER BY rank",
+		rank, columns, QualifiedName(q.DbName, q.Table), name)
+	if q.Limit > 0 || q.Offset > 0 {
+		limit := q.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		sql += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, q.Offset)
+	}
+	return c.Prepare(sql, q.Match)
+}
+
+// FTS5Context is used to represent the context of an FTS5 auxiliary function.
+// (See http://sqlite.org/fts5.html#custom_auxiliary_functions)
+type FTS5Context struct {
+	FunctionContext
+	api *C.Fts5ExtensionApi
+	fts *C.Fts5Context
+	udf *fts5Function
+}
+
+// FTS5Function is the expected signature of FTS5 auxiliary function implemented in Go.
+// nArg is the number of arguments after the table name.
+type FTS5Function func(ctx *FTS5Context, nArg int)
+
+type fts5Function struct {
+	f    FTS5Function
+	d    DestroyDataFunction
+	pApp interface{}
+}
+
+//export goXFts5Func
+func goXFts5Func(api, fts, scp unsafe.Pointer, argc C.int, argv, udp unsafe.Pointer) {
+	udf := (*fts5Function)(udp)
+	c := &FTS5Context{
+		FunctionContext: FunctionContext{sc: (*Context)(scp), argv: (**C.sqlite3_value)(argv)},
+		api:             (*C.Fts5ExtensionApi)(api),
+		fts:             (*C.Fts5Context)(fts),
+		udf:             udf,
+	}
+	udf.f(c, int(argc))
+}
+
+//export goXFts5Destroy
+func goXFts5Destroy(udp unsafe.Pointer) {
+	udf := (*fts5Function)(udp)
+	if udf.d != nil {
+		udf.d(udf.pApp)
+	}
+}
+
+// CreateFTS5Function creates or redefines an FTS5 auxiliary function
+// which can be used in full-text queries: SELECT name(fts_table, ...) FROM fts_table WHERE fts_table MATCH ?.
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+// (See http://sqlite.org/fts5.html#custom_auxiliary_functions)
+func (c *Conn) CreateFTS5Function(functionName string, pApp interface{}, f FTS5Function, d DestroyDataFunction) error {
+	if f == nil {
+		return c.specificError("no FTS5 function specified for %q", functionName)
+	}
+	fname := C.CString(functionName)
+	defer C.free(unsafe.Pointer(fname))
+	// To make sure it is not gced, keep a reference in the connection.
+	udf := &fts5Function{f, d, pApp}
+	if len(c.fts5Functions) == 0 {
+		c.fts5Functions = make(map[string]*fts5Function)
+	}
+	c.fts5Functions[functionName] = udf
+	rv := C.goSqlite3CreateFts5Function(c.db, fname, unsafe.Pointer(udf))
+	if rv == C.SQLITE_ERROR {
+		delete(c.fts5Functions, functionName)
+		return c.specificError("FTS5 API not available (SQLite version %s, FTS5 enabled: %t)", Version(), GetFeatures().HasFTS5)
+	}
+	return c.error(rv, fmt.Sprintf("Conn.CreateFTS5Function(%q)", functionName))
+}
+
+// UserData returns the user data specified when the FTS5 function was created.
+func (c *FTS5Context) UserData() interface{} {
+	return c.udf.pApp
+}
+
+// ColumnCount returns the number of columns in the FTS5 table.
+func (c *FTS5Context) ColumnCount() int {
+	return int(C.my_fts5_column_count(c.api, c.fts))
+}
+
+// RowCount returns the number of rows in the FTS5 table.
+func (c *FTS5Context) RowCount() (int64, error) {
+	var n C.sqlite3_int64
+	if rv := C.my_fts5_row_count(c.api, c.fts, &n); rv != C.SQLITE_OK {
+		return 0, Errno(rv)
+	}
+	return int64(n), nil
+}
+
+// ColumnTotalSize returns the total number of tokens in the specified column
+// (or in all columns when col is negative) of the FTS5 table.
+func (c *FTS5Context) ColumnTotalSize(col int) (int64, error) {
+	var n C.sqlite3_int64
+	if rv := C.my_fts5_column_total_size(c.api, c.fts, C.int(col), &n); rv != C.SQLITE_OK {
+		return 0, Errno(rv)
+	}
+	return int64(n), nil
+}
+
+// RowID returns the rowid of the current row.
+func (c *FTS5Context) RowID() int64 {
+	return int64(C.my_fts5_rowid(c.api, c.fts))
+}
+
+// ColumnText returns the text of the specified column of the current row.
+func (c *FTS5Context) ColumnText(col int) (string, error) {
+	var p *C.char
+	var n C.int
+	if rv := C.my_fts5_column_text(c.api, c.fts, C.int(col), &p, &n); rv != C.SQLITE_OK {
+		return "", Errno(rv)
+	}
+	if p == nil {
+		return "", nil
+	}
+	return C.GoStringN(p, n), nil
+}
+
+// ColumnSize returns the number of tokens in the specified column
+// (or in all columns when col is negative) of the current row.
+func (c *FTS5Context) ColumnSize(col int) (int, error) {
+	var n C.int
+	if rv := C.my_fts5_column_size(c.api, c.fts, C.int(col), &n); rv != C.SQLITE_OK {
+		return 0, Errno(rv)
+	}
+	return int(n), nil
+}
+
+// PhraseCount returns the number of phrases in the current query expression.
+func (c *FTS5Context) PhraseCount() int {
+	return int(C.my_fts5_phrase_count(c.api, c.fts))
+}
+
+// PhraseSize returns the number of tokens in the specified phrase.
+func (c *FTS5Context) PhraseSize(phrase int) int {
+	return int(C.my_fts5_phrase_size(c.api, c.fts, C.int(phrase)))
+}
+
+// InstCount returns the number of phrase instances in the current row.
+func (c *FTS5Context) InstCount() (int, error) {
+	var n C.int
+	if rv := C.my_fts5_inst_count(c.api, c.fts, &n); rv != C.SQLITE_OK {
+		return 0, Errno(rv)
+	}
+	return int(n), nil
+}
+
+// Inst returns the phrase number, the column and the token offset of the i-th phrase instance in the current row.
+func (c *FTS5Context) Inst(i int) (phrase, col, offset int, err error) {
+	var p, cl, o C.int
+	if rv := C.my_fts5_inst(c.api, c.fts, C.int(i), &p, &cl, &o); rv != C.SQLITE_OK {
+		return 0, 0, 0, Errno(rv)
+	}
+	return int(p), int(cl), int(o), nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func skipIfNoFTS5(t *testing.T) {
+	if !GetFeatures().HasFTS5 {
+		t.Skip("FTS5 not enabled")
+	}
+}
+
+func TestFTS5Table(t *testing.T) {
+	skipIfNoFTS5(t)
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.CreateFTS5Table("", "docs", []string{"title", "body", "tag"}, &FTS5Options{
+		Tokenizer: "porter unicode61",
+		Prefix:    []int{2, 3},
+		Detail:    FTS5DetailColumn,
+		Unindexed: []string{"tag"},
+	})
+	checkNoError(t, err, "couldn't create FTS5 table: %s")
+	err = db.FastExec(`INSERT INTO docs VALUES ('SQLite', 'full-text search engine', 'db');
+		INSERT INTO docs VALUES ('Go', 'programming language with search in title', 'lang');
+		INSERT INTO docs VALUES ('Misc', 'nothing "relevant"', 'search');`)
+	checkNoError(t, err, "couldn't insert: %s")
+
+	s, err := db.FTS5Search(FTS5Query{Table: "docs", Match: "search", Columns: []string{"title"}, Weights: []float64{10, 1}})
+	checkNoError(t, err, "couldn't search: %s")
+	var titles []string
+	err = s.Select(func(s *Stmt) error {
+		var rowid int64
+		var rank float64
+		var title string
+		if err := s.Scan(&rowid, &rank, &title); err != nil {
+			return err
+		}
+		assert.T(t, rank < 0, "bm25 rank expected to be negative")
+		titles = append(titles, title)
+		return nil
+	})
+	checkFinalize(s, t)
+	checkNoError(t, err, "couldn't search: %s")
+	assert.Equal(t, []string{"SQLite", "Go"}, titles) // 'tag' is not indexed
+
+	var count int
+	err = db.OneValue("SELECT count(*) FROM docs WHERE docs MATCH ?", &count, FTS5Phrase(`"relevant"`))
+	checkNoError(t, err, "couldn't search phrase: %s")
+	assert.Equal(t, 1, count)
+
+	checkNoError(t, db.FTS5SetConfig("", "docs", "automerge", 8), "couldn't set config: %s")
+	checkNoError(t, db.FTS5Merge("", "docs", 16), "couldn't merge: %s")
+	checkNoError(t, db.FTS5Optimize("", "docs"), "couldn't optimize: %s")
+	checkNoError(t, db.FTS5Rebuild("", "docs"), "couldn't rebuild: %s")
+
+	err = db.CreateFTS5Table("", "invalid", []string{"x"}, &FTS5Options{Contentless: true, Content: "docs"})
+	assert.T(t, err != nil, "error expected")
+}
+
+func TestFTS5ExternalContent(t *testing.T) {
+	skipIfNoFTS5(t)
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.FastExec(`CREATE TABLE posts (id INTEGER PRIMARY KEY, content TEXT);
+		INSERT INTO posts (content) VALUES ('hello world'), ('goodbye world');`)
+	checkNoError(t, err, "couldn't create content table: %s")
+	err = db.CreateFTS5Table("", "posts_fts", []string{"content"}, &FTS5Options{Content: "posts", ContentRowID: "id", NoColumnSize: true})
+	checkNoError(t, err, "couldn't create FTS5 table: %s")
+	checkNoError(t, db.FTS5Rebuild("", "posts_fts"), "couldn't rebuild: %s")
+
+	s, err := db.FTS5Search(FTS5Query{Table: "posts_fts", Match: "world", Limit: 1, Offset: 1})
+	checkNoError(t, err, "couldn't search: %s")
+	defer checkFinalize(s, t)
+	var rowid int64
+	var rank float64
+	var content string
+	found, err := s.SelectOneRow(&rowid, &rank, &content)
+	checkNoError(t, err, "couldn't search: %s")
+	assert.T(t, found)
+	assert.T(t, rowid == 1 || rowid == 2, "unexpected rowid")
+}
+
+func TestFTS5Function(t *testing.T) {
+	skipIfNoFTS5(t)
+	skipIfCgoCheckActive(t)
+	db := open(t)
+
+	err := db.CreateFTS5Table("", "docs", []string{"title", "body"}, nil)
+	checkNoError(t, err, "couldn't create FTS5 table: %s")
+	err = db.FastExec(`INSERT INTO docs VALUES ('one', 'go go go');
+		INSERT INTO docs VALUES ('two', 'go sqlite');`)
+	checkNoError(t, err, "couldn't insert: %s")
+
+	destroyed := false
+	err = db.CreateFTS5Function("hits", "udp", func(ctx *FTS5Context, nArg int) {
+		assert.Equal(t, "udp", ctx.UserData())
+		assert.Equal(t, 2, ctx.ColumnCount())
+		assert.Equal(t, 1, ctx.PhraseCount())
+		assert.Equal(t, 1, ctx.PhraseSize(0))
+		rows, err := ctx.RowCount()
+		checkNoError(t, err, "couldn't get row count: %s")
+		assert.Equal(t, int64(2), rows)
+		n, err := ctx.InstCount()
+		if err != nil {
+			ctx.ResultError(err.Error())
+			return
+		}
+		for i := 0; i < n; i++ {
+			phrase, col, _, err := ctx.Inst(i)
+			checkNoError(t, err, "couldn't get phrase instance: %s")
+			assert.Equal(t, 0, phrase)
+			assert.Equal(t, 1, col)
+		}
+		title, err := ctx.ColumnText(0)
+		checkNoError(t, err, "couldn't get column text: %s")
+		size, err := ctx.ColumnSize(1)
+		checkNoError(t, err, "couldn't get column size: %s")
+		ctx.ResultText(fmt.Sprintf("%s:%d/%d*%d", title, n, size, ctx.Int(0)))
+	}, func(pApp interface{}) {
+		destroyed = true
+	})
+	checkNoError(t, err, "couldn't create FTS5 function: %s")
+
+	var hits []string
+	s, err := db.Prepare("SELECT hits(docs, 10) FROM docs WHERE docs MATCH 'go' ORDER BY rowid")
+	checkNoError(t, err, "couldn't prepare: %s")
+	err = s.Select(func(s *Stmt) error {
+		var h string
+		if err := s.Scan(&h); err != nil {
+			return err
+		}
+		hits = append(hits, h)
+		return nil
+	})
+	checkFinalize(s, t)
+	checkNoError(t, err, "couldn't select: %s")
+	assert.Equal(t, []string{"one:3/3*10", "two:1/2*10"}, hits)
+
+	checkNoError(t, db.Close(), "couldn't close: %s")
+	assert.T(t, destroyed, "destroy function expected to be called")
+}
+
+func TestFTS5SyncTriggers(t *testing.T) {
+	skipIfNoFTS5(t)
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.FastExec(`CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT, body TEXT, author TEXT);
+		INSERT INTO posts (title, body, author) VALUES ('first', 'hello world', 'me');`)
+	checkNoError(t, err, "couldn't create content table: %s")
+	err = db.CreateFTS5Table("", "posts_fts", []string{"title", "body"}, &FTS5Options{Content: "posts", ContentRowID: "id"})
+	checkNoError(t, err, "couldn't create FTS5 table: %s")
+
+	triggers, err := db.FTS5SyncTriggers("", "posts_fts", "posts", "id")
+	checkNoError(t, err, "couldn't generate triggers: %s")
+	assert.Equal(t, 3, len(triggers))
+	assert.Equal(t, `CREATE TRIGGER "posts_fts_ai" AFTER INSERT ON "posts" BEGIN
+  INSERT INTO "posts_fts"(rowid, "title", "body") VALUES (new."id", new."title", new."body");
+END`, triggers[0])
+
+	checkNoError(t, db.CreateFTS5SyncTriggers("", "posts_fts", "posts", "id"), "couldn't create triggers: %s")
+	checkNoError(t, db.FTS5Rebuild("", "posts_fts"), "couldn't rebuild: %s")
+
+	count := func(match string) int {
+		var n int
+		err := db.OneValue("SELECT count(*) FROM posts_fts WHERE posts_fts MATCH ?", &n, match)
+		checkNoError(t, err, "couldn't search: %s")
+		return n
+	}
+	assert.Equal(t, 1, count("hello"))
+	checkNoError(t, db.Exec("INSERT INTO posts (title, body) VALUES (?, ?)", "second", "goodbye world"), "couldn't insert: %s")
+	assert.Equal(t, 2, count("world"))
+	checkNoError(t, db.Exec("UPDATE posts SET body = 'hello again' WHERE title = 'second'"), "couldn't update: %s")
+	assert.Equal(t, 0, count("goodbye"))
+	assert.Equal(t, 2, count("hello"))
+	checkNoError(t, db.Exec("DELETE FROM posts WHERE title = 'first'"), "couldn't delete: %s")
+	assert.Equal(t, 1, count("hello"))
+	checkNoError(t, db.FTS5IntegrityCheck("", "posts_fts"), "integrity check failed: %s")
+
+	_, err = db.FTS5SyncTriggers("", "unknown", "posts", "")
+	assert.T(t, err != nil, "error expected")
+
+	if !VersionAtLeast(3044000) {
+		return // integrity-check does not compare the index with the content table
+	}
+	// out-of-band change
+	checkNoError(t, db.FastExec("DROP TRIGGER posts_fts_ad; DELETE FROM posts;"), "couldn't delete: %s")
+	rebuilt, err := db.FTS5Repair("", "posts_fts")
+	checkNoError(t, err, "couldn't repair: %s")
+	assert.T(t, rebuilt, "index expected to be rebuilt")
+	assert.Equal(t, 0, count("hello"))
+	rebuilt, err = db.FTS5Repair("", "posts_fts")
+	checkNoError(t, err, "couldn't repair: %s")
+	assert.T(t, !rebuilt, "index expected to be valid")
+
+}
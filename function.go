@@ -10,11 +10,11 @@ package sqlite
 // These wrappers are necessary because SQLITE_TRANSIENT
 // is a pointer constant, and cgo doesn't translate them correctly.
 
-static inline void my_result_text(sqlite3_context *ctx, char *p, int np) {
-	sqlite3_result_text(ctx, p, np, SQLITE_TRANSIENT);
+static inline void my_result_text(sqlite3_context *ctx, char *p, sqlite3_uint64 np) {
+	sqlite3_result_text64(ctx, p, np, SQLITE_TRANSIENT, SQLITE_UTF8);
 }
-static inline void my_result_blob(sqlite3_context *ctx, void *p, int np) {
-	sqlite3_result_blob(ctx, p, np, SQLITE_TRANSIENT);
+static inline void my_result_blob(sqlite3_context *ctx, void *p, sqlite3_uint64 np) {
+	sqlite3_result_blob64(ctx, p, np, SQLITE_TRANSIENT);
 }
 
 static inline void my_result_value(sqlite3_context *ctx, sqlite3_value **argv, int i) {
@@ -113,6 +113,8 @@ func (c *FunctionContext) Result(r interface{}) {
 		c.ResultBlob(r)
 	case ZeroBlobLength:
 		c.ResultZeroblob(r)
+	case ZeroBlobLength64:
+		c.ResultZeroblob64(r)
 	case error:
 		c.ResultError(r.Error())
 	case Errno:
@@ -138,16 +140,13 @@ func (c *FunctionContext) ResultBool(b bool) {
 
 // ResultBlob sets the result of an SQL function.
 // (See sqlite3_result_blob, http://sqlite.org/c3ref/result_blob.html)
+// Blobs exceeding LimitLength are reported as too big.
 func (c *Context) ResultBlob(b []byte) {
-	if i64 && len(b) > math.MaxInt32 {
-		C.sqlite3_result_error_toobig((*C.sqlite3_context)(c))
-		return
-	}
 	var p *byte
 	if len(b) > 0 {
 		p = &b[0]
 	}
-	C.my_result_blob((*C.sqlite3_context)(c), unsafe.Pointer(p), C.int(len(b)))
+	C.my_result_blob((*C.sqlite3_context)(c), unsafe.Pointer(p), C.sqlite3_uint64(len(b)))
 }
 
 // ResultBlob sets the result of an SQL function.
@@ -230,9 +229,10 @@ func (c *FunctionContext) ResultNull() {
 
 // ResultText sets the result of an SQL function.
 // (See sqlite3_result_text, http://sqlite.org/c3ref/result_blob.html)
+// Strings exceeding LimitLength are reported as too big.
 func (c *Context) ResultText(s string) {
-	cs, l := cstring(s)
-	C.my_result_text((*C.sqlite3_context)(c), cs, l)
+	cs, _ := cstring(s)
+	C.my_result_text((*C.sqlite3_context)(c), cs, C.sqlite3_uint64(len(s)))
 }
 
 // ResultText sets the result of an SQL function.
@@ -258,6 +258,19 @@ func (c *FunctionContext) ResultZeroblob(n ZeroBlobLength) {
 	c.sc.ResultZeroblob(n)
 }
 
+// ResultZeroblob64 sets the result of an SQL function.
+// (See sqlite3_result_zeroblob64, http://sqlite.org/c3ref/result_blob.html)
+func (c *Context) ResultZeroblob64(n ZeroBlobLength64) {
+	if rv := C.sqlite3_result_zeroblob64((*C.sqlite3_context)(c), C.sqlite3_uint64(n)); rv != C.SQLITE_OK {
+		C.sqlite3_result_error_code((*C.sqlite3_context)(c), rv)
+	}
+}
+
+// ResultZeroblob64 sets the result of an SQL function.
+func (c *FunctionContext) ResultZeroblob64(n ZeroBlobLength64) {
+	c.sc.ResultZeroblob64(n)
+}
+
 // UserData returns the user data for functions.
 // (See http://sqlite.org/c3ref/user_data.html)
 func (c *FunctionContext) UserData() interface{} {
@@ -0,0 +1,259 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gen generates Go structs from the schema of an SQLite database.
+//
+// For each table, it emits a struct with `db` tags, constants for the table and column names,
+// SQL statements (select, insert) and Scan/Bind methods to be used with the native API:
+//
+//	s, err := db.Prepare(model.TestSelect)
+//	// TODO error handling
+//	defer s.Finalize()
+//	err = s.Select(func(s *sqlite.Stmt) error {
+//		var t model.Test
+//		return t.Scan(s)
+//	})
+//
+// See the sqlitegen command for go:generate usage.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gwenn/gosqlite"
+)
+
+// Options customizes the generated code.
+type Options struct {
+	Package string   // package name (default is "model")
+	DbName  string   // database name (optional, default is 'main')
+	Tables  []string // tables to generate (default is all tables, views are not included)
+}
+
+// Generate writes the Go source code (gofmt-ed) matching the tables of the database to w.
+func Generate(w io.Writer, c *sqlite.Conn, opts Options) error {
+	tables := opts.Tables
+	if len(tables) == 0 {
+		var err error
+		if tables, err = c.Tables(opts.DbName); err != nil {
+			return err
+		}
+		sort.Strings(tables)
+	}
+	pkg := opts.Package
+	if len(pkg) == 0 {
+		pkg = "model"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by sqlitegen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	var body bytes.Buffer
+	imports := make(map[string]bool)
+	imports["github.com/gwenn/gosqlite"] = true
+	declared := make(map[string]string) // package level identifier => table
+	for i, name := range goNames(tables) {
+		table := tables[i]
+		columns, err := c.Columns(opts.DbName, table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			return fmt.Errorf("no such table: %q", table)
+		}
+		for _, id := range writeTable(&body, table, name, columns, imports) {
+			if other, ok := declared[id]; ok {
+				return fmt.Errorf("identifier %s generated for table %q conflicts with table %q", id, table, other)
+			}
+			declared[id] = table
+		}
+	}
+	b.WriteString("import (\n")
+	var std, others []string
+	for path := range imports {
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			others = append(others, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+	for _, path := range std {
+		fmt.Fprintf(&b, "\t%q\n", path)
+	}
+	if len(std) > 0 {
+		b.WriteString("\n")
+	}
+	for _, path := range others {
+		fmt.Fprintf(&b, "\t%q\n", path)
+	}
+	b.WriteString(")\n")
+	b.Write(body.Bytes())
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// writeTable writes the code matching the table and returns the package level identifiers declared.
+func writeTable(b *bytes.Buffer, table, name string, columns []sqlite.Column, imports map[string]bool) []string {
+	names := make([]string, len(columns))
+	identifiers := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = sqlite.QuoteIdentifier(col.Name)
+		identifiers[i] = col.Name
+	}
+	fields := goNames(identifiers, "Scan", "Bind") // methods
+	declared := []string{name, name + "Table", name + "Select", name + "Insert"}
+	fmt.Fprintf(b, "\n// %s maps the table %q.\ntype %s struct {\n", name, table, name)
+	for i, col := range columns {
+		typ := GoType(col.DataType)
+		if typ == "time.Time" {
+			imports["time"] = true
+		}
+		fmt.Fprintf(b, "\t%s %s `db:%q` // %s\n", fields[i], typ, col.Name, columnComment(col))
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(b, "// %s table and column names\nconst (\n", name)
+	fmt.Fprintf(b, "\t%sTable = %q\n", name, table)
+	for i, col := range columns {
+		fmt.Fprintf(b, "\t%sColumn%s = %q\n", name, fields[i], col.Name)
+		declared = append(declared, name+"Column"+fields[i])
+	}
+	b.WriteString(")\n\n")
+
+	quotedTable := sqlite.QuoteIdentifier(table)
+	fmt.Fprintf(b, "// %s statements (columns in struct order)\nconst (\n", name)
+	fmt.Fprintf(b, "\t%sSelect = %q\n", name, fmt.Sprintf("SELECT %s FROM %s", strings.Join(names, ", "), quotedTable))
+	fmt.Fprintf(b, "\t%sInsert = %q\n", name, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quotedTable, strings.Join(names, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")))
+	b.WriteString(")\n\n")
+
+	fmt.Fprintf(b, "// Scan scans the current row (selected with %sSelect) into r.\n", name)
+	fmt.Fprintf(b, "func (r *%s) Scan(s *sqlite.Stmt) error {\n\treturn s.Scan(", name)
+	for i := range fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "&r.%s", fields[i])
+	}
+	b.WriteString(")\n}\n\n")
+
+	fmt.Fprintf(b, "// Bind binds r fields to the parameters of s (prepared with %sInsert).\n", name)
+	fmt.Fprintf(b, "func (r *%s) Bind(s *sqlite.Stmt) error {\n\treturn s.Bind(", name)
+	for i := range fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "r.%s", fields[i])
+	}
+	b.WriteString(")\n}\n")
+	return declared
+}
+
+func columnComment(col sqlite.Column) string {
+	comment := col.DataType
+	if len(comment) == 0 {
+		comment = "(no type)"
+	}
+	if col.Pk > 0 {
+		comment += " PRIMARY KEY"
+	}
+	if col.NotNull {
+		comment += " NOT NULL"
+	}
+	return comment
+}
+
+// GoType returns the Go type matching a declared column type
+// (following the SQLite affinity rules: http://sqlite.org/datatype3.html#affname).
+func GoType(declType string) string {
+	t := strings.ToUpper(declType)
+	switch {
+	case strings.Contains(t, "INT"):
+		return "int64"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return "string"
+	case strings.Contains(t, "BLOB"), len(t) == 0:
+		return "[]byte"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "float64"
+	case strings.Contains(t, "BOOL"):
+		return "bool"
+	case strings.Contains(t, "DATE"), strings.Contains(t, "TIME"):
+		return "time.Time"
+	}
+	return "float64" // NUMERIC affinity
+}
+
+// commonInitialisms are upper-cased like golint does.
+var commonInitialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true, "GUID": true, "HTML": true,
+	"HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true, "QPS": true, "RAM": true,
+	"RHS": true, "RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true, "URL": true, "UTF8": true,
+	"VM": true, "XML": true,
+}
+
+// GoName converts an SQL identifier (like "user_id") to an exported Go identifier (like "UserID").
+func GoName(identifier string) string {
+	words := strings.FieldsFunc(identifier, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b bytes.Buffer
+	for _, word := range words {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	name := b.String()
+	if len(name) == 0 || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// goNames converts SQL identifiers to distinct Go identifiers (see GoName):
+// a numeric suffix is appended when two identifiers map to the same Go name (like "user_id" and "userId")
+// or when the Go name is reserved.
+func goNames(identifiers []string, reserved ...string) []string {
+	used := make(map[string]bool, len(identifiers)+len(reserved))
+	for _, name := range reserved {
+		used[name] = true
+	}
+	names := make([]string, len(identifiers))
+	for i, identifier := range identifiers {
+		names[i] = GoName(identifier)
+	}
+	taken := make(map[string]bool, len(names)) // names not renamed can't be used as suffixed ones
+	for _, name := range names {
+		taken[name] = true
+	}
+	for i, name := range names {
+		if used[name] {
+			for n := 2; ; n++ {
+				if candidate := name + strconv.Itoa(n); !used[candidate] && !taken[candidate] {
+					name = candidate
+					break
+				}
+			}
+			names[i] = name
+		}
+		used[name] = true
+	}
+	return names
+}
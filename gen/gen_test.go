@@ -0,0 +1,95 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gen_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/gwenn/gosqlite"
+	. "github.com/gwenn/gosqlite/gen"
+)
+
+func TestGoName(t *testing.T) {
+	assert.Equal(t, "UserID", GoName("user_id"))
+	assert.Equal(t, "OrderItems", GoName("order items"))
+	assert.Equal(t, "X2fa", GoName("2fa"))
+	assert.Equal(t, "URL", GoName("url"))
+}
+
+func TestGoType(t *testing.T) {
+	assert.Equal(t, "int64", GoType("BIGINT"))
+	assert.Equal(t, "string", GoType("varchar(10)"))
+	assert.Equal(t, "[]byte", GoType(""))
+	assert.Equal(t, "float64", GoType("DOUBLE PRECISION"))
+	assert.Equal(t, "bool", GoType("BOOLEAN"))
+	assert.Equal(t, "time.Time", GoType("DATETIME"))
+	assert.Equal(t, "float64", GoType("DECIMAL(10,5)"))
+}
+
+func TestGenerate(t *testing.T) {
+	db, err := sqlite.Open(":memory:")
+	assert.Tf(t, err == nil, "%v", err)
+	defer db.Close()
+	err = db.FastExec("CREATE TABLE user (id INTEGER PRIMARY KEY NOT NULL, name TEXT, created_at TIMESTAMP, avatar BLOB);" +
+		"CREATE TABLE other (x);")
+	assert.Tf(t, err == nil, "%v", err)
+
+	var b bytes.Buffer
+	err = Generate(&b, db, Options{Package: "test", Tables: []string{"user"}})
+	assert.Tf(t, err == nil, "%v", err)
+	src := b.String()
+	for _, expected := range []string{
+		"package test\n",
+		"\t\"time\"\n",
+		"type User struct {\n",
+		"\tID        int64     `db:\"id\"`         // INTEGER PRIMARY KEY NOT NULL\n",
+		"\tCreatedAt time.Time `db:\"created_at\"` // TIMESTAMP\n",
+		"\tUserColumnCreatedAt = \"created_at\"\n",
+		"UserSelect = \"SELECT \\\"id\\\", \\\"name\\\", \\\"created_at\\\", \\\"avatar\\\" FROM \\\"user\\\"\"",
+		"return s.Scan(&r.ID, &r.Name, &r.CreatedAt, &r.Avatar)",
+		"return s.Bind(r.ID, r.Name, r.CreatedAt, r.Avatar)",
+	} {
+		assert.Tf(t, strings.Contains(src, expected), "%q not found in:\n%s", expected, src)
+	}
+	assert.T(t, !strings.Contains(src, "Other"), "only specified tables expected")
+
+	b.Reset()
+	err = Generate(&b, db, Options{})
+	assert.Tf(t, err == nil, "%v", err)
+	assert.T(t, strings.Contains(b.String(), "package model\n"), "default package expected")
+	assert.T(t, strings.Contains(b.String(), "type Other struct"), "all tables expected")
+
+	err = Generate(&b, db, Options{Tables: []string{"unknown"}})
+	assert.T(t, err != nil, "error expected")
+}
+
+func TestGenerateNameCollisions(t *testing.T) {
+	db, err := sqlite.Open(":memory:")
+	assert.Tf(t, err == nil, "%v", err)
+	defer db.Close()
+	err = db.FastExec("CREATE TABLE user (user_id INT, \"user-id\" INT, UserID2 INT, scan INT);" +
+		"CREATE TABLE user_select (x);")
+	assert.Tf(t, err == nil, "%v", err)
+
+	var b bytes.Buffer
+	err = Generate(&b, db, Options{Tables: []string{"user"}})
+	assert.Tf(t, err == nil, "%v", err)
+	src := b.String()
+	for _, expected := range []string{
+		"\tUserID  int64 `db:\"user_id\"`",
+		"\tUserID3 int64 `db:\"user-id\"`",
+		"\tUserID2 int64 `db:\"UserID2\"`",
+		"\tScan2   int64 `db:\"scan\"`",
+		"return s.Scan(&r.UserID, &r.UserID3, &r.UserID2, &r.Scan2)",
+	} {
+		assert.Tf(t, strings.Contains(src, expected), "%q not found in:\n%s", expected, src)
+	}
+
+	err = Generate(&b, db, Options{Tables: []string{"user", "user_select"}})
+	assert.T(t, err != nil, "error expected")
+}
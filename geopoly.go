@@ -0,0 +1,189 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Polygon is a simple polygon defined by its vertices ([x, y]), without the closing vertex.
+// (See http://sqlite.org/geopoly.html)
+type Polygon [][2]float64
+
+// MarshalJSON returns the JSON representation expected by the geopoly functions: [[x0,y0],[x1,y1],...].
+func (p Polygon) MarshalJSON() ([]byte, error) {
+	return json.Marshal([][2]float64(p))
+}
+
+// String returns the JSON representation of the polygon.
+func (p Polygon) String() string {
+	b, err := json.Marshal([][2]float64(p))
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+// ParsePolygon decodes a polygon from its JSON representation.
+// The closing vertex is removed when present.
+func ParsePolygon(text string) (Polygon, error) {
+	var vertices [][2]float64
+	if err := json.Unmarshal([]byte(text), &vertices); err != nil {
+		return nil, err
+	}
+	return openRing(vertices), nil
+}
+
+func openRing(vertices [][2]float64) Polygon {
+	if n := len(vertices); n > 1 && vertices[0] == vertices[n-1] {
+		vertices = vertices[:n-1]
+	}
+	return Polygon(vertices)
+}
+
+// GeoJSONPolygon is a GeoJSON Polygon geometry (RFC 7946).
+type GeoJSONPolygon struct {
+	Type        string         `json:"type"` // "Polygon"
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+// GeoJSON converts the polygon to a GeoJSON geometry with a single (closed) ring.
+func (p Polygon) GeoJSON() GeoJSONPolygon {
+	ring := make([][2]float64, len(p), len(p)+1)
+	copy(ring, p)
+	if len(p) > 0 {
+		ring = append(ring, p[0])
+	}
+	return GeoJSONPolygon{Type: "Polygon", Coordinates: [][][2]float64{ring}}
+}
+
+// PolygonFromGeoJSON converts the exterior ring of a GeoJSON Polygon geometry.
+// Holes are not supported by geopoly.
+func PolygonFromGeoJSON(g GeoJSONPolygon) (Polygon, error) {
+	if g.Type != "Polygon" {
+		return nil, fmt.Errorf("unsupported GeoJSON geometry type: %q", g.Type)
+	}
+	if len(g.Coordinates) != 1 {
+		return nil, fmt.Errorf("exactly one ring expected in GeoJSON polygon (got %d)", len(g.Coordinates))
+	}
+	vertices := make([][2]float64, len(g.Coordinates[0]))
+	copy(vertices, g.Coordinates[0])
+	return openRing(vertices), nil
+}
+
+// CreateGeopolyTable creates a geopoly table (with a _shape column) and optional auxiliary columns.
+func (c *Conn) CreateGeopolyTable(dbName, table string, auxColumns ...string) error {
+	columns := make([]string, len(auxColumns))
+	for i, column := range auxColumns {
+		columns[i] = QuoteIdentifier(column)
+	}
+	return c.FastExec(fmt.Sprintf("CREATE VIRTUAL TABLE %s USING geopoly(%s)", QualifiedName(dbName, table), strings.Join(columns, ", ")))
+}
+
+// geopolyValue evaluates a geopoly function returning a number.
+func (c *Conn) geopolyValue(expr string, value interface{}, args ...interface{}) error {
+	s, err := c.prepare("SELECT "+expr, args...)
+	if err != nil {
+		return err
+	}
+	defer s.finalize()
+	found, err := s.SelectOneRow(value)
+	if err != nil {
+		return err
+	} else if !found {
+		return errors.New("no result")
+	}
+	return nil
+}
+
+// geopolyPolygon evaluates a geopoly function returning a polygon.
+func (c *Conn) geopolyPolygon(expr string, args ...interface{}) (Polygon, error) {
+	var text string
+	if err := c.geopolyValue("geopoly_json("+expr+")", &text, args...); err != nil {
+		return nil, err
+	}
+	if len(text) == 0 {
+		return nil, c.specificError("invalid polygon")
+	}
+	return ParsePolygon(text)
+}
+
+// GeopolyArea returns the area of the polygon (negative when vertices are clockwise).
+func (c *Conn) GeopolyArea(p Polygon) (area float64, err error) {
+	err = c.geopolyValue("geopoly_area(?)", &area, p.String())
+	return
+}
+
+// GeopolyContainsPoint returns true when the point is inside or on the boundary of the polygon.
+func (c *Conn) GeopolyContainsPoint(p Polygon, x, y float64) (contains bool, err error) {
+	var rv int
+	err = c.geopolyValue("geopoly_contains_point(?, ?, ?)", &rv, p.String(), x, y)
+	return rv != 0, err
+}
+
+// GeopolyOverlap returns true when the two polygons overlap.
+func (c *Conn) GeopolyOverlap(p1, p2 Polygon) (overlap bool, err error) {
+	var rv int
+	err = c.geopolyValue("geopoly_overlap(?, ?)", &rv, p1.String(), p2.String())
+	return rv != 0, err
+}
+
+// GeopolyWithin returns true when p1 is entirely contained by p2.
+func (c *Conn) GeopolyWithin(p1, p2 Polygon) (within bool, err error) {
+	var rv int
+	err = c.geopolyValue("geopoly_within(?, ?)", &rv, p1.String(), p2.String())
+	return rv != 0, err
+}
+
+// GeopolyBBox returns the bounding box of the polygon.
+func (c *Conn) GeopolyBBox(p Polygon) (Polygon, error) {
+	return c.geopolyPolygon("geopoly_bbox(?)", p.String())
+}
+
+// GeopolyCCW returns the polygon with its vertices in counter-clockwise order.
+func (c *Conn) GeopolyCCW(p Polygon) (Polygon, error) {
+	return c.geopolyPolygon("geopoly_ccw(?)", p.String())
+}
+
+// GeopolyRegular returns a regular polygon with n sides (3 to 1000) centered on (x, y)
+// and inscribed in a circle of radius r.
+func (c *Conn) GeopolyRegular(x, y, r float64, n int) (Polygon, error) {
+	return c.geopolyPolygon("geopoly_regular(?, ?, ?, ?)", x, y, r, n)
+}
+
+// GeopolyXForm applies the affine transformation (x, y) => (a*x + b*y + e, c*x + d*y + f) to the polygon.
+func (c *Conn) GeopolyXForm(p Polygon, a, b, cc, d, e, f float64) (Polygon, error) {
+	return c.geopolyPolygon("geopoly_xform(?, ?, ?, ?, ?, ?, ?)", p.String(), a, b, cc, d, e, f)
+}
+
+// GeopolySearch calls f for each shape of the geopoly table overlapping
+// (or within, when within is true) the window.
+func (c *Conn) GeopolySearch(dbName, table string, window Polygon, within bool, f func(rowid int64, shape Polygon) error) error {
+	function := "geopoly_overlap"
+	if within {
+		function = "geopoly_within"
+	}
+	s, err := c.prepare(fmt.Sprintf("SELECT rowid, geopoly_json(_shape) FROM %s WHERE %s(_shape, ?) ORDER BY rowid",
+		QualifiedName(dbName, table), function), window.String())
+	if err != nil {
+		return err
+	}
+	defer s.finalize()
+	return s.Select(func(s *Stmt) error {
+		var rowid int64
+		var text string
+		if err := s.Scan(&rowid, &text); err != nil {
+			return err
+		}
+		shape, err := ParsePolygon(text)
+		if err != nil {
+			return err
+		}
+		return f(rowid, shape)
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestPolygonConversions(t *testing.T) {
+	p, err := ParsePolygon("[[0,0],[1,0],[1,1],[0,0]]")
+	checkNoError(t, err, "couldn't parse polygon: %s")
+	assert.Equal(t, Polygon{{0, 0}, {1, 0}, {1, 1}}, p)
+	assert.Equal(t, "[[0,0],[1,0],[1,1]]", p.String())
+
+	g := p.GeoJSON()
+	b, err := json.Marshal(g)
+	checkNoError(t, err, "couldn't marshal GeoJSON: %s")
+	assert.Equal(t, `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`, string(b))
+	var decoded GeoJSONPolygon
+	checkNoError(t, json.Unmarshal(b, &decoded), "couldn't unmarshal GeoJSON: %s")
+	p2, err := PolygonFromGeoJSON(decoded)
+	checkNoError(t, err, "couldn't convert GeoJSON: %s")
+	assert.Equal(t, p, p2)
+
+	_, err = PolygonFromGeoJSON(GeoJSONPolygon{Type: "Point"})
+	assert.T(t, err != nil, "error expected")
+	_, err = ParsePolygon("{}")
+	assert.T(t, err != nil, "error expected")
+}
+
+func TestGeopoly(t *testing.T) {
+	if !GetFeatures().HasGeopoly {
+		t.Skip("Geopoly not enabled")
+	}
+	db := open(t)
+	defer checkClose(db, t)
+
+	square := Polygon{{0, 0}, {2, 0}, {2, 2}, {0, 2}}
+	area, err := db.GeopolyArea(square)
+	checkNoError(t, err, "couldn't compute area: %s")
+	assert.Equal(t, 4.0, area)
+
+	contains, err := db.GeopolyContainsPoint(square, 1, 1)
+	checkNoError(t, err, "couldn't check point: %s")
+	assert.T(t, contains)
+	contains, err = db.GeopolyContainsPoint(square, 3, 1)
+	checkNoError(t, err, "couldn't check point: %s")
+	assert.T(t, !contains)
+
+	small := Polygon{{0.5, 0.5}, {1, 0.5}, {1, 1}}
+	within, err := db.GeopolyWithin(small, square)
+	checkNoError(t, err, "couldn't check within: %s")
+	assert.T(t, within)
+	overlap, err := db.GeopolyOverlap(square, Polygon{{5, 5}, {6, 5}, {6, 6}})
+	checkNoError(t, err, "couldn't check overlap: %s")
+	assert.T(t, !overlap)
+
+	bbox, err := db.GeopolyBBox(small)
+	checkNoError(t, err, "couldn't compute bbox: %s")
+	assert.Equal(t, Polygon{{0.5, 0.5}, {1, 0.5}, {1, 1}, {0.5, 1}}, bbox)
+
+	ccw, err := db.GeopolyCCW(Polygon{{0, 0}, {0, 2}, {2, 2}, {2, 0}})
+	checkNoError(t, err, "couldn't reorder: %s")
+	area, err = db.GeopolyArea(ccw)
+	checkNoError(t, err, "couldn't compute area: %s")
+	assert.Equal(t, 4.0, area)
+
+	hexagon, err := db.GeopolyRegular(0, 0, 1, 6)
+	checkNoError(t, err, "couldn't create regular polygon: %s")
+	assert.Equal(t, 6, len(hexagon))
+
+	moved, err := db.GeopolyXForm(square, 1, 0, 0, 1, 10, 0)
+	checkNoError(t, err, "couldn't transform: %s")
+	assert.Equal(t, Polygon{{10, 0}, {12, 0}, {12, 2}, {10, 2}}, moved)
+
+	checkNoError(t, db.CreateGeopolyTable("", "shapes", "name"), "couldn't create table: %s")
+	for _, shape := range []Polygon{square, moved} {
+		checkNoError(t, db.Exec("INSERT INTO shapes (_shape, name) VALUES (?, 'x')", shape.String()), "couldn't insert: %s")
+	}
+	var found []int64
+	err = db.GeopolySearch("", "shapes", Polygon{{-1, -1}, {3, -1}, {3, 3}, {-1, 3}}, true, func(rowid int64, shape Polygon) error {
+		found = append(found, rowid)
+		assert.T(t, math.Abs(shape[1][0]-2) < 1e-6, "unexpected shape")
+		return nil
+	})
+	checkNoError(t, err, "couldn't search: %s")
+	assert.Equal(t, []int64{1}, found)
+}
@@ -0,0 +1,75 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestHarden(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	err := db.Exec("CREATE TABLE test (id INTEGER PRIMARY KEY, name TEXT)")
+	checkNoError(t, err, "couldn't create table: %s")
+
+	err = db.Harden(nil)
+	checkNoError(t, err, "couldn't harden connection: %s")
+	assert.Equal(t, int32(0), db.Limit(LimitAttached))
+	assert.Equal(t, int32(DefaultHardenSQLLength), db.Limit(LimitSQLLength))
+
+	err = db.Exec("INSERT INTO test (name) VALUES ('a')")
+	checkNoError(t, err, "couldn't insert: %s")
+	var n int
+	err = db.OneValue("SELECT count(*) FROM test WHERE name = lower('A')", &n)
+	checkNoError(t, err, "couldn't select: %s")
+	assert.Equal(t, 1, n)
+
+	denied := []string{
+		"CREATE TABLE other (x)",
+		"DROP TABLE test",
+		"ATTACH ':memory:' AS other",
+		"PRAGMA writable_schema = ON",
+		"SELECT load_extension('nowhere')",
+		"SELECT '" + strings.Repeat("x", DefaultHardenSQLLength) + "'",
+	}
+	if VersionNumber() >= 3029000 {
+		denied = append(denied, `SELECT count(*) FROM test WHERE name = "a"`) // no double-quoted string literal
+	}
+	for _, sql := range denied {
+		err = db.Exec(sql)
+		assert.Tf(t, err != nil, "error expected for %.50q", sql)
+	}
+}
+
+func TestHardenOptions(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	err := db.Exec("CREATE TABLE test (id INTEGER PRIMARY KEY, name TEXT)")
+	checkNoError(t, err, "couldn't create table: %s")
+
+	err = db.Harden(&HardenOptions{MaxSQLLength: 1000, AllowActions: []Action{Pragma}, Authorizer: DenyActions(Delete)})
+	checkNoError(t, err, "couldn't harden connection: %s")
+	assert.Equal(t, int32(1000), db.Limit(LimitSQLLength))
+	check, err := db.CellSizeCheck()
+	checkNoError(t, err, "couldn't query cell_size_check: %s")
+	assert.T(t, check, "cell_size_check expected")
+
+	err = db.Exec("INSERT INTO test (name) VALUES ('a')")
+	checkNoError(t, err, "couldn't insert: %s")
+	err = db.Exec("DELETE FROM test")
+	assert.T(t, err != nil, "delete denied")
+	err = db.Exec("PRAGMA writable_schema = ON")
+	checkNoError(t, err, "couldn't set writable_schema: %s")
+	err = db.Exec("UPDATE sqlite_master SET sql = 'CREATE TABLE test (x)' WHERE name = 'test'")
+	assert.T(t, err != nil, "schema update prevented by defensive mode")
+}
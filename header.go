@@ -0,0 +1,128 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// FileHeaderSize is the size of the database file header.
+const FileHeaderSize = 100
+
+const fileHeaderMagic = "SQLite format 3\x00"
+
+// FileHeader is the content of the database file header.
+// (See http://sqlite.org/fileformat2.html#the_database_header)
+type FileHeader struct {
+	PageSize          int    // in bytes (from 512 to 65536)
+	WriteVersion      int    // 1 for legacy (rollback journal), 2 for WAL
+	ReadVersion       int    // 1 for legacy (rollback journal), 2 for WAL
+	ReservedSpace     int    // unused space at the end of each page (used by encryption extensions for example)
+	FileChangeCounter uint32 //
+	PageCount         uint32 // in-header database size (valid only if VersionValidFor == FileChangeCounter)
+	FreelistTrunk     uint32 // page number of the first freelist trunk page
+	FreelistCount     uint32 // total number of freelist pages
+	SchemaCookie      uint32 // see PRAGMA schema_version
+	SchemaFormat      uint32 // 1 to 4
+	DefaultCacheSize  int32  // see PRAGMA default_cache_size
+	AutoVacuum        bool   // see PRAGMA auto_vacuum
+	IncrementalVacuum bool   // see PRAGMA auto_vacuum
+	Encoding          string // "UTF-8", "UTF-16le" or "UTF-16be" (empty when the database is empty)
+	UserVersion       int32  // see PRAGMA user_version
+	ApplicationID     int32  // see PRAGMA application_id
+	VersionValidFor   uint32 //
+	SQLiteVersion     int32  // SQLITE_VERSION_NUMBER of the library which last modified the database
+
+	// Only set by ReadFileHeader
+	WALFileExists     bool // a "-wal" file exists next to the database
+	JournalFileExists bool // a (possibly hot) "-journal" file exists next to the database
+}
+
+// WAL tells if the database is in WAL mode.
+func (h *FileHeader) WAL() bool {
+	return h.WriteVersion == 2 || h.ReadVersion == 2
+}
+
+// ReadFileHeader reads and parses the header of the specified database file
+// without opening a connection.
+// In WAL mode, the header may be stale until the WAL file is checkpointed.
+func ReadFileHeader(filename string) (*FileHeader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b := make([]byte, FileHeaderSize)
+	if _, err = io.ReadFull(f, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("%s: not a database (file too short)", filename)
+		}
+		return nil, err
+	}
+	h, err := ParseFileHeader(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	if _, err = os.Stat(filename + "-wal"); err == nil {
+		h.WALFileExists = true
+	}
+	if _, err = os.Stat(filename + "-journal"); err == nil {
+		h.JournalFileExists = true
+	}
+	return h, nil
+}
+
+// ParseFileHeader parses the first 100 bytes of a database file.
+func ParseFileHeader(b []byte) (*FileHeader, error) {
+	if len(b) < FileHeaderSize {
+		return nil, errors.New("not a database (header too short)")
+	}
+	if !bytes.Equal(b[:16], []byte(fileHeaderMagic)) {
+		return nil, errors.New("not a database (invalid magic string)")
+	}
+	be := binary.BigEndian
+	h := &FileHeader{
+		PageSize:          int(be.Uint16(b[16:18])),
+		WriteVersion:      int(b[18]),
+		ReadVersion:       int(b[19]),
+		ReservedSpace:     int(b[20]),
+		FileChangeCounter: be.Uint32(b[24:28]),
+		PageCount:         be.Uint32(b[28:32]),
+		FreelistTrunk:     be.Uint32(b[32:36]),
+		FreelistCount:     be.Uint32(b[36:40]),
+		SchemaCookie:      be.Uint32(b[40:44]),
+		SchemaFormat:      be.Uint32(b[44:48]),
+		DefaultCacheSize:  int32(be.Uint32(b[48:52])),
+		AutoVacuum:        be.Uint32(b[52:56]) != 0,
+		IncrementalVacuum: be.Uint32(b[64:68]) != 0,
+		UserVersion:       int32(be.Uint32(b[60:64])),
+		ApplicationID:     int32(be.Uint32(b[68:72])),
+		VersionValidFor:   be.Uint32(b[92:96]),
+		SQLiteVersion:     int32(be.Uint32(b[96:100])),
+	}
+	if h.PageSize == 1 {
+		h.PageSize = 65536
+	}
+	if h.PageSize < 512 || h.PageSize&(h.PageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid page size: %d", h.PageSize)
+	}
+	switch be.Uint32(b[56:60]) {
+	case 0:
+	case 1:
+		h.Encoding = "UTF-8"
+	case 2:
+		h.Encoding = "UTF-16le"
+	case 3:
+		h.Encoding = "UTF-16be"
+	default:
+		return nil, fmt.Errorf("invalid text encoding: %d", be.Uint32(b[56:60]))
+	}
+	return h, nil
+}
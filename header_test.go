@@ -0,0 +1,44 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestReadFileHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosqlite-test")
+	checkNoError(t, err, "couldn't create temp dir: %s")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "header.db")
+	db, err := Open(filename)
+	checkNoError(t, err, "couldn't open database: %s")
+	checkNoError(t, db.FastExec(`PRAGMA page_size = 8192; PRAGMA user_version = 42; PRAGMA application_id = 1234;
+		PRAGMA journal_mode = WAL; CREATE TABLE test (x); PRAGMA wal_checkpoint(TRUNCATE);`), "couldn't configure database: %s")
+
+	h, err := ReadFileHeader(filename)
+	checkNoError(t, err, "couldn't read header: %s")
+	assert.Equal(t, 8192, h.PageSize)
+	assert.Equal(t, int32(42), h.UserVersion)
+	assert.Equal(t, int32(1234), h.ApplicationID)
+	assert.Equal(t, "UTF-8", h.Encoding)
+	assert.T(t, h.WAL(), "WAL expected")
+	assert.T(t, h.WALFileExists, "WAL file expected")
+	assert.Equal(t, VersionNumber(), h.SQLiteVersion)
+	checkClose(db, t)
+
+	notDb := filepath.Join(dir, "text.db")
+	checkNoError(t, ioutil.WriteFile(notDb, []byte("hello"), 0600), "couldn't write file: %s")
+	_, err = ReadFileHeader(notDb)
+	assert.T(t, err != nil, "error expected")
+	_, err = ParseFileHeader(make([]byte, FileHeaderSize))
+	assert.T(t, err != nil, "error expected")
+}
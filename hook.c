@@ -3,6 +3,7 @@
 // license that can be found in the LICENSE file.
 
 #include <sqlite3.h>
+#include <string.h>
 // warning: incompatible pointer types passing
 //#include "_cgo_export.h"
 
@@ -24,6 +25,96 @@ void* goSqlite3UpdateHook(sqlite3 *db, void *udp) {
 	return sqlite3_update_hook(db, goXUpdateHook, udp);
 }
 
+// Tables/databases whose changes are reported to Go (all when empty),
+// filtered in C to avoid crossing into Go for every row changed.
+// The dispatcher of the callbacks added by Conn.AddUpdateHook (all) receives every change.
+typedef struct {
+	void *udp;
+	void *all;
+	int nDbNames;
+	char **dbNames;
+	int nTableNames;
+	char **tableNames;
+} goSqlite3UpdateFilter;
+
+void* goSqlite3NewUpdateFilter(void *udp) {
+	goSqlite3UpdateFilter *f = sqlite3_malloc(sizeof(goSqlite3UpdateFilter));
+	if (f == 0) {
+		return 0;
+	}
+	memset(f, 0, sizeof(goSqlite3UpdateFilter));
+	f->udp = udp;
+	return f;
+}
+
+static int appendName(char ***names, int *n, const char *name) {
+	char **tmp = sqlite3_realloc(*names, (*n + 1) * sizeof(char *));
+	if (tmp == 0) {
+		return SQLITE_NOMEM;
+	}
+	*names = tmp;
+	tmp[*n] = sqlite3_mprintf("%s", name);
+	if (tmp[*n] == 0) {
+		return SQLITE_NOMEM;
+	}
+	(*n)++;
+	return SQLITE_OK;
+}
+
+int goSqlite3UpdateFilterAdd(void *p, int isDbName, const char *name) {
+	goSqlite3UpdateFilter *f = p;
+	if (isDbName) {
+		return appendName(&f->dbNames, &f->nDbNames, name);
+	}
+	return appendName(&f->tableNames, &f->nTableNames, name);
+}
+
+void goSqlite3UpdateFilterAll(void *p, void *all) {
+	goSqlite3UpdateFilter *f = p;
+	f->all = all;
+}
+
+void goSqlite3FreeUpdateFilter(void *p) {
+	goSqlite3UpdateFilter *f = p;
+	int i;
+	for (i = 0; i < f->nDbNames; i++) {
+		sqlite3_free(f->dbNames[i]);
+	}
+	sqlite3_free(f->dbNames);
+	for (i = 0; i < f->nTableNames; i++) {
+		sqlite3_free(f->tableNames[i]);
+	}
+	sqlite3_free(f->tableNames);
+	sqlite3_free(f);
+}
+
+static int matchName(char **names, int n, const char *name) {
+	int i;
+	if (n == 0) {
+		return 1;
+	}
+	for (i = 0; i < n; i++) {
+		if (sqlite3_stricmp(names[i], name) == 0) {
+			return 1;
+		}
+	}
+	return 0;
+}
+
+static void filteredUpdateHook(void *p, int action, char const *dbName, char const *tableName, sqlite3_int64 rowID) {
+	goSqlite3UpdateFilter *f = p;
+	if (f->all != 0) {
+		goXUpdateHook(f->all, action, dbName, tableName, rowID);
+	}
+	if (matchName(f->dbNames, f->nDbNames, dbName) && matchName(f->tableNames, f->nTableNames, tableName)) {
+		goXUpdateHook(f->udp, action, dbName, tableName, rowID);
+	}
+}
+
+void* goSqlite3FilteredUpdateHook(sqlite3 *db, void *filter) {
+	return sqlite3_update_hook(db, filteredUpdateHook, filter);
+}
+
 /*
 extern int goXWalHook(void *udp, sqlite3* db, const char *dbName, int nEntry);
 
@@ -6,10 +6,16 @@ package sqlite
 
 /*
 #include <sqlite3.h>
+#include <stdlib.h>
 
 void* goSqlite3CommitHook(sqlite3 *db, void *udp);
 void* goSqlite3RollbackHook(sqlite3 *db, void *udp);
 void* goSqlite3UpdateHook(sqlite3 *db, void *udp);
+void* goSqlite3NewUpdateFilter(void *udp);
+int goSqlite3UpdateFilterAdd(void *filter, int isDbName, const char *name);
+void goSqlite3UpdateFilterAll(void *filter, void *udp);
+void goSqlite3FreeUpdateFilter(void *filter);
+void* goSqlite3FilteredUpdateHook(sqlite3 *db, void *filter);
 //void* goSqlite3WalHook(sqlite3 *db, void *udp);
 */
 import "C"
@@ -39,14 +45,56 @@ func goXCommitHook(udp unsafe.Pointer) C.int {
 func (c *Conn) CommitHook(f CommitHook, udp interface{}) {
 	if f == nil {
 		c.commitHook = nil
+		c.commitErr = nil
 		C.sqlite3_commit_hook(c.db, nil, nil)
 		return
 	}
 	// To make sure it is not gced, keep a reference in the connection.
 	c.commitHook = &sqliteCommitHook{f, udp}
+	c.commitErr = nil
 	C.goSqlite3CommitHook(c.db, unsafe.Pointer(c.commitHook))
 }
 
+// CommitValidator is the signature of a commit hook able to veto the commit.
+// If the callback returns an error, the commit is converted into a rollback
+// and the error is returned to the caller of Commit (or Exec...) instead of the SQLite constraint error.
+type CommitValidator func(udp interface{}) error
+
+type sqliteCommitValidator struct {
+	f   CommitValidator
+	udp interface{}
+}
+
+// CommitValidator registers a callback function to be invoked whenever a transaction is committed
+// (replacing the previous validator).
+// The validator is multiplexed with the callbacks added by Conn.AddCommitHook (and so with Conn.ChangesHook)
+// and is invoked before them: they are not invoked when the commit is vetoed.
+// Returning a non-nil error aborts the commit. For example, to enforce application-level invariants.
+// The callback must not use the connection.
+// Conn.CommitHook must not be used at the same time (it replaces the validator).
+// If f is nil, the current validator is removed.
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+// (See http://sqlite.org/c3ref/commit_hook.html)
+func (c *Conn) CommitValidator(f CommitValidator, udp interface{}) {
+	if f == nil {
+		c.commitValidator = nil
+	} else {
+		c.commitValidator = &sqliteCommitValidator{f, udp}
+	}
+	c.updateCommitDispatch()
+}
+
+// vetoedCommit returns (and forgets) the error returned by the CommitValidator
+// when rv is the constraint error reported by SQLite for the rolled back commit.
+func (c *Conn) vetoedCommit(rv C.int) error {
+	if c.commitErr == nil || rv&0xff != C.SQLITE_CONSTRAINT {
+		return nil
+	}
+	err := c.commitErr
+	c.commitErr = nil
+	return err
+}
+
 // RollbackHook is the callback function signature.
 type RollbackHook func(udp interface{})
 
@@ -91,6 +139,7 @@ func goXUpdateHook(udp unsafe.Pointer, action int, dbName, tableName *C.char, ro
 
 // UpdateHook registers a callback to be invoked each time a row is updated,
 // inserted or deleted using this database connection.
+// It replaces the callbacks added by Conn.AddUpdateHook and the hook set by Conn.ChangesHook.
 // Cannot be used with Go >= 1.6 and cgocheck enabled.
 // (See http://sqlite.org/c3ref/update_hook.html)
 func (c *Conn) UpdateHook(f UpdateHook, udp interface{}) {
@@ -104,6 +153,304 @@ func (c *Conn) UpdateHook(f UpdateHook, udp interface{}) {
 	C.goSqlite3UpdateHook(c.db, unsafe.Pointer(c.updateHook))
 }
 
+// Change is a row change reported by Conn.ChangesHook.
+type Change struct {
+	Action    Action // Insert, Update or Delete
+	DbName    string
+	TableName string
+	RowID     int64
+}
+
+// ChangesHook is the callback function signature of Conn.ChangesHook.
+type ChangesHook func(udp interface{}, changes []Change)
+
+// ChangesHookOptions restricts and groups the changes reported by Conn.ChangesHook.
+type ChangesHookOptions struct {
+	DbNames    []string // databases whose changes are reported (all when empty)
+	TableNames []string // tables whose changes are reported (all when empty)
+	// Batch coalesces the changes made by each transaction:
+	// they are delivered once, when the transaction is committed, and discarded when it is rolled back.
+	Batch bool
+}
+
+type sqliteChangesHook struct {
+	f          ChangesHook
+	udp        interface{}
+	hook       *sqliteUpdateHook // invoked by the filter
+	pending    []Change
+	commitID   HookID
+	rollbackID HookID
+}
+
+func (h *sqliteChangesHook) update(_ interface{}, a Action, dbName, tableName string, rowID int64) {
+	change := Change{a, dbName, tableName, rowID}
+	if h.commitID == 0 {
+		h.f(h.udp, []Change{change})
+		return
+	}
+	h.pending = append(h.pending, change)
+}
+
+func (h *sqliteChangesHook) commit(_ interface{}) bool {
+	if len(h.pending) > 0 {
+		changes := h.pending
+		h.pending = nil
+		h.f(h.udp, changes)
+	}
+	return false
+}
+
+func (h *sqliteChangesHook) rollback(_ interface{}) {
+	h.pending = nil
+}
+
+// ChangesHook registers a callback to be invoked when rows are updated, inserted or deleted
+// in the specified databases/tables (replacing the previous one).
+// Changes to other tables are filtered out before crossing into Go.
+// The hook is multiplexed with the callbacks added by Conn.AddUpdateHook
+// and Conn.UpdateHook must not be used at the same time (it replaces the hook).
+// With the Batch option, the changes are delivered from a commit hook (added with Conn.AddCommitHook)
+// so the callback must not use the connection and Conn.CommitHook/Conn.RollbackHook must not be used at the same time.
+// If f is nil, the current hook is removed.
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+// (See http://sqlite.org/c3ref/update_hook.html)
+func (c *Conn) ChangesHook(f ChangesHook, options *ChangesHookOptions, udp interface{}) error {
+	c.clearChangesHook()
+	if f == nil {
+		return nil
+	}
+	if options == nil {
+		options = &ChangesHookOptions{}
+	}
+	h := &sqliteChangesHook{f: f, udp: udp}
+	// To make sure it is not gced, keep a reference in the connection (through c.changesHook).
+	h.hook = &sqliteUpdateHook{h.update, nil}
+	filter := C.goSqlite3NewUpdateFilter(unsafe.Pointer(h.hook))
+	if filter == nil {
+		return ErrNoMem
+	}
+	if err := addFilterNames(filter, true, options.DbNames); err != nil {
+		C.goSqlite3FreeUpdateFilter(filter)
+		return err
+	}
+	if err := addFilterNames(filter, false, options.TableNames); err != nil {
+		C.goSqlite3FreeUpdateFilter(filter)
+		return err
+	}
+	if options.Batch {
+		h.commitID = c.AddCommitHook(h.commit, nil)
+		h.rollbackID = c.AddRollbackHook(h.rollback, nil)
+	}
+	c.changesHook = h
+	c.updateFilter = filter
+	c.updateUpdateDispatch()
+	return nil
+}
+
+func addFilterNames(filter unsafe.Pointer, dbNames bool, names []string) error {
+	for _, name := range names {
+		cname := C.CString(name)
+		rv := C.goSqlite3UpdateFilterAdd(filter, btocint(dbNames), cname)
+		C.free(unsafe.Pointer(cname))
+		if rv != C.SQLITE_OK {
+			return Errno(rv)
+		}
+	}
+	return nil
+}
+
+// clearChangesHook unregisters the hook set by Conn.ChangesHook (if any).
+func (c *Conn) clearChangesHook() {
+	h := c.changesHook
+	if h == nil {
+		return
+	}
+	c.changesHook = nil
+	if h.commitID != 0 {
+		c.RemoveCommitHook(h.commitID)
+		c.RemoveRollbackHook(h.rollbackID)
+	}
+	filter := c.updateFilter
+	c.updateFilter = nil
+	c.updateUpdateDispatch()
+	C.goSqlite3FreeUpdateFilter(filter)
+}
+
+// freeUpdateFilter releases the filter used by Conn.ChangesHook once it cannot be invoked anymore.
+func (c *Conn) freeUpdateFilter() {
+	if c.updateFilter != nil {
+		C.goSqlite3FreeUpdateFilter(c.updateFilter)
+		c.updateFilter = nil
+	}
+}
+
+// HookID identifies a callback registered with Conn.AddCommitHook, Conn.AddRollbackHook or Conn.AddUpdateHook.
+type HookID int
+
+type commitSubscriber struct {
+	id  HookID
+	f   CommitHook
+	udp interface{}
+}
+
+type rollbackSubscriber struct {
+	id  HookID
+	f   RollbackHook
+	udp interface{}
+}
+
+type updateSubscriber struct {
+	id  HookID
+	f   UpdateHook
+	udp interface{}
+}
+
+// hookSubscribers keeps track of the callbacks multiplexed on the single hook of each kind.
+type hookSubscribers struct {
+	lastID   HookID
+	commit   []commitSubscriber
+	rollback []rollbackSubscriber
+	update   []updateSubscriber
+}
+
+// AddCommitHook registers an additional callback to be invoked whenever a transaction is committed.
+// Contrary to Conn.CommitHook, previously added callbacks are kept.
+// The commit is converted into a rollback if any callback returns true (following ones are not invoked).
+// Conn.CommitHook must not be used at the same time (it replaces all the callbacks added).
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+func (c *Conn) AddCommitHook(f CommitHook, udp interface{}) HookID {
+	c.hooks.lastID++
+	c.hooks.commit = append(c.hooks.commit, commitSubscriber{c.hooks.lastID, f, udp})
+	if len(c.hooks.commit) == 1 {
+		c.updateCommitDispatch()
+	}
+	return c.hooks.lastID
+}
+
+// RemoveCommitHook unregisters a callback added by Conn.AddCommitHook.
+func (c *Conn) RemoveCommitHook(id HookID) {
+	for i, sub := range c.hooks.commit {
+		if sub.id == id {
+			c.hooks.commit = append(c.hooks.commit[:i:i], c.hooks.commit[i+1:]...)
+			if len(c.hooks.commit) == 0 {
+				c.updateCommitDispatch()
+			}
+			return
+		}
+	}
+}
+
+// updateCommitDispatch installs the commit hook multiplexing the validator and the added callbacks
+// (or removes it when there is none).
+func (c *Conn) updateCommitDispatch() {
+	if c.commitValidator != nil || len(c.hooks.commit) > 0 {
+		c.CommitHook(c.dispatchCommit, nil)
+	} else {
+		c.CommitHook(nil, nil)
+	}
+}
+
+func (c *Conn) dispatchCommit(_ interface{}) bool {
+	// a stale veto must not be returned for an unrelated failed commit
+	c.commitErr = nil
+	if v := c.commitValidator; v != nil {
+		if c.commitErr = v.f(v.udp); c.commitErr != nil {
+			return true
+		}
+	}
+	for _, sub := range c.hooks.commit {
+		if sub.f(sub.udp) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddRollbackHook registers an additional callback to be invoked each time a transaction is rolled back.
+// Contrary to Conn.RollbackHook, previously added callbacks are kept.
+// Conn.RollbackHook must not be used at the same time (it replaces all the callbacks added).
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+func (c *Conn) AddRollbackHook(f RollbackHook, udp interface{}) HookID {
+	c.hooks.lastID++
+	c.hooks.rollback = append(c.hooks.rollback, rollbackSubscriber{c.hooks.lastID, f, udp})
+	if len(c.hooks.rollback) == 1 {
+		c.RollbackHook(c.dispatchRollback, nil)
+	}
+	return c.hooks.lastID
+}
+
+// RemoveRollbackHook unregisters a callback added by Conn.AddRollbackHook.
+func (c *Conn) RemoveRollbackHook(id HookID) {
+	for i, sub := range c.hooks.rollback {
+		if sub.id == id {
+			c.hooks.rollback = append(c.hooks.rollback[:i:i], c.hooks.rollback[i+1:]...)
+			if len(c.hooks.rollback) == 0 {
+				c.RollbackHook(nil, nil)
+			}
+			return
+		}
+	}
+}
+
+func (c *Conn) dispatchRollback(_ interface{}) {
+	for _, sub := range c.hooks.rollback {
+		sub.f(sub.udp)
+	}
+}
+
+// AddUpdateHook registers an additional callback to be invoked each time a row is updated, inserted or deleted.
+// Contrary to Conn.UpdateHook, previously added callbacks (and the hook set by Conn.ChangesHook) are kept.
+// Conn.UpdateHook must not be used at the same time (it replaces all the callbacks added).
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+func (c *Conn) AddUpdateHook(f UpdateHook, udp interface{}) HookID {
+	c.hooks.lastID++
+	c.hooks.update = append(c.hooks.update, updateSubscriber{c.hooks.lastID, f, udp})
+	if len(c.hooks.update) == 1 {
+		c.updateUpdateDispatch()
+	}
+	return c.hooks.lastID
+}
+
+// RemoveUpdateHook unregisters a callback added by Conn.AddUpdateHook.
+func (c *Conn) RemoveUpdateHook(id HookID) {
+	for i, sub := range c.hooks.update {
+		if sub.id == id {
+			c.hooks.update = append(c.hooks.update[:i:i], c.hooks.update[i+1:]...)
+			if len(c.hooks.update) == 0 {
+				c.updateUpdateDispatch()
+			}
+			return
+		}
+	}
+}
+
+// updateUpdateDispatch installs the update hook multiplexing the added callbacks and the Conn.ChangesHook filter
+// (or removes it when there is none).
+func (c *Conn) updateUpdateDispatch() {
+	var all unsafe.Pointer
+	if len(c.hooks.update) > 0 {
+		// To make sure it is not gced, keep a reference in the connection.
+		c.updateHook = &sqliteUpdateHook{c.dispatchUpdate, nil}
+		all = unsafe.Pointer(c.updateHook)
+	} else {
+		c.updateHook = nil
+	}
+	if c.updateFilter != nil {
+		C.goSqlite3UpdateFilterAll(c.updateFilter, all)
+		C.goSqlite3FilteredUpdateHook(c.db, c.updateFilter)
+	} else if all != nil {
+		C.goSqlite3UpdateHook(c.db, all)
+	} else {
+		C.sqlite3_update_hook(c.db, nil, nil)
+	}
+}
+
+func (c *Conn) dispatchUpdate(_ interface{}, a Action, dbName, tableName string, rowID int64) {
+	for _, sub := range c.hooks.update {
+		sub.f(sub.udp, a, dbName, tableName, rowID)
+	}
+}
+
 /*
 type WalHook func(udp interface{}, c *Conn, dbName string, nEntry int) int
 
@@ -5,9 +5,11 @@
 package sqlite_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/bmizerany/assert"
 	. "github.com/gwenn/gosqlite"
 )
 
@@ -56,6 +58,179 @@ func TestCommitHook(t *testing.T) {
 	checkNoError(t, db.Commit(), "%s")
 }
 
+func TestCommitValidator(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+
+	errInvalid := errors.New("invalid")
+	valid := false
+	db.CommitValidator(func(udp interface{}) error {
+		if !valid {
+			return errInvalid
+		}
+		return nil
+	}, nil)
+	defer db.CommitValidator(nil, nil)
+
+	checkNoError(t, db.Begin(), "%s")
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('a')"), "%s")
+	assert.Equal(t, errInvalid, db.Commit())
+	assert.T(t, !db.InTransaction(), "commit should have been rolled back")
+	assert.Equal(t, errInvalid, db.Exec("INSERT INTO test (a_string) VALUES ('b')"))
+
+	var n int
+	checkNoError(t, db.OneValue("SELECT count(*) FROM test", &n), "%s")
+	assert.Equal(t, 0, n)
+
+	valid = true
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('c')"), "%s")
+	checkNoError(t, db.OneValue("SELECT count(*) FROM test", &n), "%s")
+	assert.Equal(t, 1, n)
+}
+
+func TestCommitValidatorWithChangesHook(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+
+	var calls [][]Change
+	checkNoError(t, db.ChangesHook(func(udp interface{}, changes []Change) {
+		calls = append(calls, changes)
+	}, &ChangesHookOptions{Batch: true}, nil), "%s")
+	errInvalid := errors.New("invalid")
+	valid := false
+	db.CommitValidator(func(udp interface{}) error {
+		if !valid {
+			return errInvalid
+		}
+		return nil
+	}, nil)
+
+	assert.Equal(t, errInvalid, db.Exec("INSERT INTO test (a_string) VALUES ('a')"))
+	assert.Equal(t, 0, len(calls))
+
+	valid = true
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('b')"), "%s")
+	assert.Equal(t, [][]Change{{{Insert, "main", "test", 1}}}, calls)
+
+	db.CommitValidator(nil, nil)
+	calls = nil
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('c')"), "%s")
+	assert.Equal(t, [][]Change{{{Insert, "main", "test", 2}}}, calls)
+}
+
+func TestChangesHook(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+	checkNoError(t, db.FastExec("CREATE TABLE other (x)"), "%s")
+
+	var calls [][]Change
+	hook := func(udp interface{}, changes []Change) {
+		calls = append(calls, changes)
+	}
+	checkNoError(t, db.ChangesHook(hook, &ChangesHookOptions{TableNames: []string{"TEST"}}, nil), "%s")
+	checkNoError(t, db.FastExec("INSERT INTO test (a_string) VALUES ('a'); INSERT INTO other VALUES (1)"), "%s")
+	assert.Equal(t, [][]Change{{{Insert, "main", "test", 1}}}, calls)
+
+	calls = nil
+	checkNoError(t, db.ChangesHook(hook, &ChangesHookOptions{DbNames: []string{"main"}, Batch: true}, nil), "%s")
+	checkNoError(t, db.Begin(), "%s")
+	checkNoError(t, db.FastExec("INSERT INTO other VALUES (2); UPDATE test SET a_string = 'b'"), "%s")
+	assert.Equal(t, 0, len(calls))
+	checkNoError(t, db.Commit(), "%s")
+	assert.Equal(t, [][]Change{{{Insert, "main", "other", 2}, {Update, "main", "test", 1}}}, calls)
+
+	calls = nil
+	checkNoError(t, db.Begin(), "%s")
+	checkNoError(t, db.FastExec("DELETE FROM other WHERE x = 2"), "%s")
+	checkNoError(t, db.Rollback(), "%s")
+	checkNoError(t, db.FastExec("DELETE FROM test WHERE id = 1"), "%s")
+	assert.Equal(t, [][]Change{{{Delete, "main", "test", 1}}}, calls)
+
+	checkNoError(t, db.ChangesHook(nil, nil, nil), "%s")
+	calls = nil
+	checkNoError(t, db.FastExec("INSERT INTO other VALUES (3)"), "%s")
+	assert.Equal(t, 0, len(calls))
+}
+
+func TestChangesHookWithUpdateHooks(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+	checkNoError(t, db.FastExec("CREATE TABLE other (x)"), "%s")
+
+	var updates []string
+	var calls [][]Change
+	u1 := db.AddUpdateHook(func(udp interface{}, a Action, dbName, tableName string, rowID int64) {
+		updates = append(updates, "u1."+tableName)
+	}, nil)
+	checkNoError(t, db.ChangesHook(func(udp interface{}, changes []Change) {
+		calls = append(calls, changes)
+	}, &ChangesHookOptions{TableNames: []string{"test"}}, nil), "%s")
+	u2 := db.AddUpdateHook(func(udp interface{}, a Action, dbName, tableName string, rowID int64) {
+		updates = append(updates, "u2."+tableName)
+	}, nil)
+
+	checkNoError(t, db.FastExec("INSERT INTO test (a_string) VALUES ('a'); INSERT INTO other VALUES (1)"), "%s")
+	assert.Equal(t, []string{"u1.test", "u2.test", "u1.other", "u2.other"}, updates)
+	assert.Equal(t, [][]Change{{{Insert, "main", "test", 1}}}, calls)
+
+	db.RemoveUpdateHook(u1)
+	db.RemoveUpdateHook(u2)
+	updates, calls = nil, nil
+	checkNoError(t, db.FastExec("INSERT INTO test (a_string) VALUES ('b')"), "%s")
+	assert.Equal(t, 0, len(updates))
+	assert.Equal(t, [][]Change{{{Insert, "main", "test", 2}}}, calls)
+
+	db.AddUpdateHook(func(udp interface{}, a Action, dbName, tableName string, rowID int64) {
+		updates = append(updates, "u3."+tableName)
+	}, nil)
+	checkNoError(t, db.ChangesHook(nil, nil, nil), "%s")
+	calls = nil
+	checkNoError(t, db.FastExec("INSERT INTO test (a_string) VALUES ('c')"), "%s")
+	assert.Equal(t, []string{"u3.test"}, updates)
+	assert.Equal(t, 0, len(calls))
+}
+
+func TestAddUpdateHookKeepsChangesHook(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+
+	var calls [][]Change
+	checkNoError(t, db.ChangesHook(func(udp interface{}, changes []Change) {
+		calls = append(calls, changes)
+	}, &ChangesHookOptions{Batch: true}, nil), "%s")
+	var updates int
+	id := db.AddUpdateHook(func(udp interface{}, a Action, dbName, tableName string, rowID int64) {
+		updates++
+	}, nil)
+
+	checkNoError(t, db.Begin(), "%s")
+	checkNoError(t, db.FastExec("INSERT INTO test (a_string) VALUES ('a'); INSERT INTO test (a_string) VALUES ('b')"), "%s")
+	checkNoError(t, db.Commit(), "%s")
+	assert.Equal(t, 2, updates)
+	assert.Equal(t, [][]Change{{{Insert, "main", "test", 1}, {Insert, "main", "test", 2}}}, calls)
+
+	db.RemoveUpdateHook(id)
+	calls = nil
+	checkNoError(t, db.Exec("DELETE FROM test WHERE id = 1"), "%s")
+	assert.Equal(t, 2, updates)
+	assert.Equal(t, [][]Change{{{Delete, "main", "test", 1}}}, calls)
+}
+
 func TestRollbackHook(t *testing.T) {
 	skipIfCgoCheckActive(t)
 
@@ -78,3 +253,48 @@ func TestUpdateHook(t *testing.T) {
 	db.UpdateHook(updateHook, t)
 	checkNoError(t, db.Exec("INSERT INTO test VALUES (1, 273.1, 0, 'data')"), "%s")
 }
+
+func TestHookMultiplexer(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+
+	var commits, rollbacks, updates []string
+	c1 := db.AddCommitHook(func(udp interface{}) bool {
+		commits = append(commits, "c1")
+		return false
+	}, nil)
+	db.AddCommitHook(func(udp interface{}) bool {
+		commits = append(commits, "c2")
+		return false
+	}, nil)
+	db.AddRollbackHook(func(udp interface{}) {
+		rollbacks = append(rollbacks, udp.(string))
+	}, "r1")
+	u1 := db.AddUpdateHook(func(udp interface{}, a Action, dbName, tableName string, rowID int64) {
+		updates = append(updates, "u1")
+	}, nil)
+	u2 := db.AddUpdateHook(func(udp interface{}, a Action, dbName, tableName string, rowID int64) {
+		updates = append(updates, "u2")
+	}, nil)
+
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('a')"), "%s")
+	assert.Equal(t, []string{"c1", "c2"}, commits)
+	assert.Equal(t, []string{"u1", "u2"}, updates)
+
+	db.RemoveCommitHook(c1)
+	db.RemoveUpdateHook(u1)
+	checkNoError(t, db.Begin(), "%s")
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('b')"), "%s")
+	checkNoError(t, db.Rollback(), "%s")
+	assert.Equal(t, []string{"r1"}, rollbacks)
+	assert.Equal(t, []string{"u1", "u2", "u2"}, updates)
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('c')"), "%s")
+	assert.Equal(t, []string{"c1", "c2", "c2"}, commits)
+
+	db.RemoveUpdateHook(u2)
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('d')"), "%s")
+	assert.Equal(t, 4, len(updates))
+}
@@ -0,0 +1,229 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HTTPVFS is a read-only VFS reading database files lazily over HTTP with range requests
+// (the server must support them), which allows querying large static databases
+// (hosted on object storage for example) without downloading them:
+//
+//	err := RegisterVFS("httpvfs", NewHTTPVFS(), false)
+//	db, err := OpenVfs("https://example.com/data.db", "httpvfs", OpenReadOnly)
+//
+// Files are expected to be immutable: they are read by blocks which are cached (per connection).
+// The database must not be in WAL mode (use PRAGMA journal_mode=DELETE before publishing it).
+type HTTPVFS struct {
+	Client    *http.Client // http.DefaultClient when nil
+	BlockSize int          // size of the blocks requested (64 KiB by default)
+	CacheSize int          // number of blocks cached per file (256 by default)
+}
+
+// NewHTTPVFS creates an HTTP VFS (to be registered with RegisterVFS) with default settings.
+func NewHTTPVFS() *HTTPVFS {
+	return &HTTPVFS{}
+}
+
+type httpFile struct {
+	vfs       *HTTPVFS
+	url       string
+	size      int64
+	blockSize int64
+	cacheSize int
+
+	mu     sync.Mutex
+	blocks map[int64]*list.Element
+	lru    *list.List
+}
+
+type httpBlock struct {
+	index int64
+	data  []byte
+}
+
+// Open implements the VFS interface.
+// Only main databases can be opened (read-only).
+func (v *HTTPVFS) Open(name string, flags OpenFlag) (VFSFile, OpenFlag, error) {
+	if flags&OpenMainDB == 0 {
+		return nil, 0, ErrCantOpen
+	}
+	f := &httpFile{vfs: v, url: name, blockSize: 64 * 1024, cacheSize: 256, blocks: make(map[int64]*list.Element), lru: list.New()}
+	if v.BlockSize > 0 {
+		f.blockSize = int64(v.BlockSize)
+	}
+	if v.CacheSize > 0 {
+		f.cacheSize = v.CacheSize
+	}
+	size, err := f.fetchSize()
+	if err != nil {
+		return nil, 0, err
+	}
+	f.size = size
+	return f, flags&^(OpenReadWrite|OpenCreate) | OpenReadOnly, nil
+}
+
+// Delete implements the VFS interface.
+func (v *HTTPVFS) Delete(name string, syncDir bool) error {
+	return ErrReadOnly
+}
+
+// Access implements the VFS interface.
+// Journal and WAL files never exist.
+func (v *HTTPVFS) Access(name string, flag AccessFlag) (bool, error) {
+	return false, nil
+}
+
+// FullPathname implements the VFS interface.
+func (v *HTTPVFS) FullPathname(name string) (string, error) {
+	return name, nil
+}
+
+func (v *HTTPVFS) client() *http.Client {
+	if v.Client == nil {
+		return http.DefaultClient
+	}
+	return v.Client
+}
+
+// fetchSize retrieves the file size with a HEAD request (or a one byte range request).
+func (f *httpFile) fetchSize() (int64, error) {
+	resp, err := f.vfs.client().Head(f.url)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && resp.ContentLength >= 0 {
+		return resp.ContentLength, nil
+	}
+	req, err := http.NewRequest("GET", f.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err = f.vfs.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("%s: unexpected status: %s", f.url, resp.Status)
+	}
+	// Content-Range: bytes 0-0/1234
+	cr := resp.Header.Get("Content-Range")
+	i := strings.LastIndexByte(cr, '/')
+	if i < 0 {
+		return 0, fmt.Errorf("%s: invalid Content-Range: %q", f.url, cr)
+	}
+	return strconv.ParseInt(cr[i+1:], 10, 64)
+}
+
+func (f *httpFile) block(index int64) ([]byte, error) {
+	f.mu.Lock()
+	if e, ok := f.blocks[index]; ok {
+		f.lru.MoveToFront(e)
+		f.mu.Unlock()
+		return e.Value.(*httpBlock).data, nil
+	}
+	f.mu.Unlock()
+
+	start := index * f.blockSize
+	end := start + f.blockSize
+	if end > f.size {
+		end = f.size
+	}
+	req, err := http.NewRequest("GET", f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end-1))
+	resp, err := f.vfs.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("%s: range requests not supported (status: %s)", f.url, resp.Status)
+	}
+	data := make([]byte, end-start)
+	if _, err = io.ReadFull(resp.Body, data); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.blocks[index]; !ok {
+		f.blocks[index] = f.lru.PushFront(&httpBlock{index, data})
+		for f.lru.Len() > f.cacheSize {
+			e := f.lru.Back()
+			f.lru.Remove(e)
+			delete(f.blocks, e.Value.(*httpBlock).index)
+		}
+	}
+	return data, nil
+}
+
+func (f *httpFile) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= f.size {
+			return n, io.EOF
+		}
+		data, err := f.block(pos / f.blockSize)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], data[pos%f.blockSize:])
+	}
+	return n, nil
+}
+
+func (f *httpFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, ErrReadOnly
+}
+
+func (f *httpFile) Close() error {
+	return nil
+}
+
+func (f *httpFile) Truncate(size int64) error {
+	return ErrReadOnly
+}
+
+func (f *httpFile) Sync(flags SyncFlag) error {
+	return nil
+}
+
+func (f *httpFile) FileSize() (int64, error) {
+	return f.size, nil
+}
+
+func (f *httpFile) Lock(level LockLevel) error {
+	return nil
+}
+
+func (f *httpFile) Unlock(level LockLevel) error {
+	return nil
+}
+
+func (f *httpFile) CheckReservedLock() (bool, error) {
+	return false, nil
+}
+
+func (f *httpFile) SectorSize() int {
+	return 0
+}
+
+func (f *httpFile) DeviceCharacteristics() DeviceCharacteristic {
+	return IocapImmutable
+}
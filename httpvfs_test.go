@@ -0,0 +1,61 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestHTTPVFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosqlite-test")
+	checkNoError(t, err, "couldn't create temp dir: %s")
+	defer os.RemoveAll(dir)
+	db, err := Open(filepath.Join(dir, "remote.db"))
+	checkNoError(t, err, "couldn't open database: %s")
+	checkNoError(t, db.FastExec(`CREATE TABLE test (id INTEGER PRIMARY KEY, data BLOB);
+		INSERT INTO test SELECT value, randomblob(512) FROM (WITH RECURSIVE c(value) AS (SELECT 1 UNION ALL SELECT value+1 FROM c LIMIT 1000) SELECT value FROM c);`),
+		"couldn't create table: %s")
+	checkClose(db, t)
+
+	var requests int32
+	fs := http.FileServer(http.Dir(dir))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fs.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	vfs := NewHTTPVFS()
+	vfs.BlockSize = 4096
+	checkNoError(t, RegisterVFS("httpvfs_test", vfs, false), "couldn't register VFS: %s")
+	defer UnregisterVFS("httpvfs_test")
+
+	db, err = OpenVfs(server.URL+"/remote.db", "httpvfs_test", OpenReadOnly)
+	checkNoError(t, err, "couldn't open remote database: %s")
+	defer checkClose(db, t)
+	var n int
+	checkNoError(t, db.OneValue("SELECT length(data) FROM test WHERE id = 500", &n), "couldn't select: %s")
+	assert.Equal(t, 512, n)
+	before := atomic.LoadInt32(&requests)
+	checkNoError(t, db.OneValue("SELECT length(data) FROM test WHERE id = 500", &n), "couldn't select: %s")
+	assert.Equal(t, before, atomic.LoadInt32(&requests)) // cached
+	checkNoError(t, db.OneValue("SELECT count(*) FROM test", &n), "couldn't count: %s")
+	assert.Equal(t, 1000, n)
+
+	err = db.FastExec("DELETE FROM test")
+	assert.T(t, err != nil, "read-only expected")
+
+	_, err = OpenVfs(server.URL+"/missing.db", "httpvfs_test", OpenReadOnly)
+	assert.T(t, err != nil, "open failure expected")
+}
@@ -36,6 +36,7 @@ struct sqlite3_intarray {
   int n;                    /* Number of elements in the array */
   sqlite3_int64 *a;         /* Contents of the array */
   void (*xFree)(void*);     /* Function used to free a[] */
+  int nCursor;              /* Number of open cursors (a[] cannot be rebound while > 0) */
 };
 
 /* Objects used internally by the virtual table implementation */
@@ -52,6 +53,8 @@ struct intarray_vtab {
 struct intarray_cursor {
   sqlite3_vtab_cursor base;    /* Base class */
   int i;                       /* Current cursor position */
+  int n;                       /* Number of elements when the scan started */
+  sqlite3_int64 *a;            /* Contents of the array when the scan started */
 };
 
 /*
@@ -106,6 +109,7 @@ static int intarrayOpen(sqlite3_vtab *pVTab, sqlite3_vtab_cursor **ppCursor){
   if( pCur ){
     memset(pCur, 0, sizeof(intarray_cursor));
     *ppCursor = (sqlite3_vtab_cursor *)pCur;
+    ((intarray_vtab*)pVTab)->pContent->nCursor++;
     rc = SQLITE_OK;
   }
   return rc;
@@ -116,6 +120,7 @@ static int intarrayOpen(sqlite3_vtab *pVTab, sqlite3_vtab_cursor **ppCursor){
 */
 static int intarrayClose(sqlite3_vtab_cursor *cur){
   intarray_cursor *pCur = (intarray_cursor *)cur;
+  ((intarray_vtab*)cur->pVtab)->pContent->nCursor--;
   sqlite3_free(pCur);
   return SQLITE_OK;
 }
@@ -125,9 +130,8 @@ static int intarrayClose(sqlite3_vtab_cursor *cur){
 */
 static int intarrayColumn(sqlite3_vtab_cursor *cur, sqlite3_context *ctx, int i){
   intarray_cursor *pCur = (intarray_cursor*)cur;
-  intarray_vtab *pVtab = (intarray_vtab*)cur->pVtab;
-  if( pCur->i>=0 && pCur->i<pVtab->pContent->n ){
-    sqlite3_result_int64(ctx, pVtab->pContent->a[pCur->i]);
+  if( pCur->i>=0 && pCur->i<pCur->n ){
+    sqlite3_result_int64(ctx, pCur->a[pCur->i]);
   }
   return SQLITE_OK;
 }
@@ -143,8 +147,7 @@ static int intarrayRowid(sqlite3_vtab_cursor *cur, sqlite_int64 *pRowid){
 
 static int intarrayEof(sqlite3_vtab_cursor *cur){
   intarray_cursor *pCur = (intarray_cursor *)cur;
-  intarray_vtab *pVtab = (intarray_vtab *)cur->pVtab;
-  return pCur->i>=pVtab->pContent->n;
+  return pCur->i>=pCur->n;
 }
 
 /*
@@ -165,7 +168,10 @@ static int intarrayFilter(
   int argc, sqlite3_value **argv
 ){
   intarray_cursor *pCur = (intarray_cursor *)pVtabCursor;
+  sqlite3_intarray *pContent = ((intarray_vtab*)pVtabCursor->pVtab)->pContent;
   pCur->i = 0;
+  pCur->n = pContent->n;
+  pCur->a = pContent->a;
   return SQLITE_OK;
 }
 
@@ -242,9 +248,8 @@ int sqlite3_intarray_create(
 /*
 ** Bind a new array array of integers to a specific intarray object.
 **
-** The array of integers bound must be unchanged for the duration of
-** any query against the corresponding virtual table.  If the integer
-** array does change or is deallocated undefined behavior will result.
+** SQLITE_LOCKED is returned (and aElements is left untouched) when
+** cursors are open on the corresponding virtual table.
 */
 int sqlite3_intarray_bind(
   sqlite3_intarray *pIntArray,   /* The intarray object to bind to */
@@ -252,6 +257,9 @@ int sqlite3_intarray_bind(
   sqlite3_int64 *aElements,      /* Content of the intarray */
   void (*xFree)(void*)           /* How to dispose of the intarray when done */
 ){
+  if( pIntArray->nCursor>0 ){
+    return SQLITE_LOCKED;
+  }
   if( pIntArray->xFree ){
     pIntArray->xFree(pIntArray->a);
   }
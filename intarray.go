@@ -7,17 +7,37 @@ package sqlite
 /*
 #include <sqlite3.h>
 #include <stdlib.h>
+#include <string.h>
 
 // An sqlite3_intarray is an abstract type to stores an instance of an integer array.
 typedef struct sqlite3_intarray sqlite3_intarray;
 int sqlite3_intarray_bind(sqlite3_intarray *pIntArray, int nElements, sqlite3_int64 *aElements, void (*xFree)(void*));
 int sqlite3_intarray_create(sqlite3 *db, const char *zName, sqlite3_intarray **ppReturn);
+
+// the elements are copied so that SQLite does not keep a pointer to Go memory
+static int goSqlite3IntarrayBind(sqlite3_intarray *pIntArray, int nElements, sqlite3_int64 *aElements) {
+	sqlite3_int64 *a = 0;
+	int rc;
+	if (nElements > 0) {
+		a = sqlite3_malloc(nElements * sizeof(sqlite3_int64));
+		if (a == 0) {
+			return SQLITE_NOMEM;
+		}
+		memcpy(a, aElements, nElements * sizeof(sqlite3_int64));
+	}
+	rc = sqlite3_intarray_bind(pIntArray, nElements, a, sqlite3_free);
+	if (rc != SQLITE_OK) {
+		sqlite3_free(a);
+	}
+	return rc;
+}
 */
 import "C"
 
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"unsafe"
 )
 
@@ -68,12 +88,12 @@ import (
 //	// Fill in content of a3
 //	p3.Bind(a3)
 //
-// A single intarray object can be rebound multiple times.  But do not
-// attempt to change the bindings of an intarray while it is in the middle
-// of a query.
+// A single intarray object can be rebound multiple times.  But the bindings
+// of an intarray cannot be changed while it is in the middle of a query
+// (Bind fails until the statements using it are reset or finalized).
 //
-// The application must not change the intarray values while an intarray is in
-// the middle of a query.
+// The values are copied by Bind so the application may change the slice
+// afterwards without impacting the queries.
 //
 // The intarray object is automatically destroyed when its corresponding
 // virtual table is dropped.  Since the virtual tables are created in the
@@ -81,15 +101,15 @@ import (
 // closes so the application does not normally need to take any special
 // action to free the intarray objects (except if connections are pooled...).
 type IntArray interface {
-	Bind(elements []int64)
+	Bind(elements []int64) error
 	Drop() error
 }
 
 type intArray struct {
-	c       *Conn
-	ia      *C.sqlite3_intarray
-	name    string
-	content []int64
+	mu   sync.Mutex // guards ia
+	c    *Conn
+	ia   *C.sqlite3_intarray
+	name string
 }
 
 // CreateIntArray create a specific instance of an intarray object.
@@ -117,19 +137,25 @@ func (c *Conn) CreateIntArray(name string) (IntArray, error) {
 
 // Bind a new array of integers to a specific intarray object.
 //
-// The array of integers bound must be unchanged for the duration of
-// any query against the corresponding virtual table.  If the integer
-// array does change or is deallocated undefined behavior will result.
-func (m *intArray) Bind(elements []int64) {
+// The integers are copied. An error is returned when the intarray is used by a query in progress
+// (each scan sees the array bound when it started) or when the intarray has been dropped.
+func (m *intArray) Bind(elements []int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.ia == nil {
-		return
+		return errors.New("sqlite intarray dropped")
 	}
-	m.content = elements
 	var p *int64
 	if len(elements) > 0 {
 		p = &elements[0]
 	}
-	C.sqlite3_intarray_bind(m.ia, C.int(len(elements)), (*C.sqlite3_int64)(unsafe.Pointer(p)), nil)
+	rv := C.goSqlite3IntarrayBind(m.ia, C.int(len(elements)), (*C.sqlite3_int64)(unsafe.Pointer(p)))
+	if rv == C.SQLITE_LOCKED {
+		return m.c.specificError("intarray %q is used by a query in progress", m.name)
+	} else if rv != C.SQLITE_OK {
+		return Errno(rv)
+	}
+	return nil
 }
 
 // Drop underlying virtual table.
@@ -137,6 +163,8 @@ func (m *intArray) Drop() error {
 	if m == nil {
 		return errors.New("nil sqlite intarray")
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.c == nil {
 		return nil
 	}
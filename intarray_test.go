@@ -78,6 +78,43 @@ func TestIntArrayModule(t *testing.T) {
 	checkNoError(t, p3.Drop(), "%s")
 }
 
+func TestIntArrayRebind(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	p, err := db.CreateIntArray("ex")
+	checkNoError(t, err, "%s")
+	values := []int64{1, 2, 3}
+	checkNoError(t, p.Bind(values), "%s")
+	values[0] = 10 // copied by Bind
+
+	s, err := db.Prepare("SELECT value FROM ex")
+	checkNoError(t, err, "%s")
+	defer checkFinalize(s, t)
+	assert.T(t, checkStep(t, s))
+	var i int
+	checkNoError(t, s.Scan(&i), "%s")
+	assert.Equal(t, 1, i)
+
+	err = p.Bind([]int64{4})
+	assert.T(t, err != nil, "error expected while the intarray is used by a query")
+	n := 1
+	for checkStep(t, s) {
+		n++
+	}
+	assert.Equal(t, 3, n)
+
+	checkNoError(t, s.Reset(), "%s")
+	checkNoError(t, p.Bind([]int64{4}), "%s")
+	assert.T(t, checkStep(t, s))
+	checkNoError(t, s.Scan(&i), "%s")
+	assert.Equal(t, 4, i)
+	checkNoError(t, s.Reset(), "%s")
+
+	checkNoError(t, p.Drop(), "%s")
+	assert.T(t, p.Bind(nil) != nil, "error expected once dropped")
+}
+
 const IntArraySize = 100
 
 func BenchmarkNoIntArray(b *testing.B) {
@@ -0,0 +1,165 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// IntegrityChecker periodically checks the integrity of a database in background
+// on a dedicated connection (read-only preferably) so that corruption is detected early:
+//
+//	ic := NewIntegrityChecker(func() (*Conn, error) {
+//		return Open(filename, OpenReadOnly)
+//	}, time.Hour, func(violations []string, err error) {
+//		if err != nil || len(violations) > 0 {
+//			log.Printf("integrity check: %v %v", violations, err)
+//		}
+//	})
+//	ic.Start()
+//	defer ic.Stop()
+type IntegrityChecker struct {
+	DbName    string        // optional (default is 'main')
+	Full      bool          // integrity_check instead of quick_check
+	MaxErrors int           // maximum number of violations reported (100 by default)
+	Jitter    time.Duration // random delay added to the interval
+	// Skip is called before each check (optional): the check is skipped when it returns true
+	// (during heavy load for example).
+	Skip func() bool
+
+	open     func() (*Conn, error)
+	interval time.Duration
+	report   func(violations []string, err error)
+
+	checkMu sync.Mutex // serializes checks
+	mu      sync.Mutex
+	c       *Conn
+	paused  bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewIntegrityChecker creates an integrity checker which opens its dedicated connection with open
+// and reports the result of each check (violations is empty when the database is ok).
+func NewIntegrityChecker(open func() (*Conn, error), interval time.Duration, report func(violations []string, err error)) *IntegrityChecker {
+	return &IntegrityChecker{open: open, interval: interval, report: report}
+}
+
+// Start starts checking in background (the first check happens after one interval).
+func (ic *IntegrityChecker) Start() {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	if ic.stop != nil {
+		return
+	}
+	ic.stop = make(chan struct{})
+	ic.done = make(chan struct{})
+	go ic.run(ic.stop, ic.done)
+}
+
+// Stop interrupts the check in progress (if any), stops checking and closes the dedicated connection.
+func (ic *IntegrityChecker) Stop() {
+	ic.mu.Lock()
+	stop, done := ic.stop, ic.done
+	ic.stop, ic.done = nil, nil
+	if ic.c != nil {
+		ic.c.Interrupt()
+	}
+	ic.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	// wait for a check invoked by the user (already interrupted)
+	ic.checkMu.Lock()
+	defer ic.checkMu.Unlock()
+	ic.mu.Lock()
+	if ic.c != nil {
+		ic.c.Close()
+		ic.c = nil
+	}
+	ic.mu.Unlock()
+}
+
+// Pause suspends the checks (until Resume is called).
+func (ic *IntegrityChecker) Pause() {
+	ic.mu.Lock()
+	ic.paused = true
+	ic.mu.Unlock()
+}
+
+// Resume resumes the checks suspended by Pause.
+func (ic *IntegrityChecker) Resume() {
+	ic.mu.Lock()
+	ic.paused = false
+	ic.mu.Unlock()
+}
+
+// Check runs a check immediately (even if paused) and returns the violations found.
+func (ic *IntegrityChecker) Check() ([]string, error) {
+	ic.checkMu.Lock()
+	defer ic.checkMu.Unlock()
+	ic.mu.Lock()
+	c := ic.c
+	ic.mu.Unlock()
+	if c == nil {
+		var err error
+		if c, err = ic.open(); err != nil {
+			return nil, err
+		}
+		ic.mu.Lock()
+		ic.c = c
+		ic.mu.Unlock()
+	}
+	max := ic.MaxErrors
+	if max <= 0 {
+		max = 100
+	}
+	violations, err := c.IntegrityViolations(ic.DbName, max, !ic.Full)
+	if err != nil {
+		// the connection is reopened for the next check
+		ic.mu.Lock()
+		if ic.c == c {
+			ic.c = nil
+		}
+		ic.mu.Unlock()
+		c.Close()
+	}
+	return violations, err
+}
+
+func (ic *IntegrityChecker) run(stop, done chan struct{}) {
+	defer close(done)
+	for {
+		delay := ic.interval
+		if ic.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(ic.Jitter)))
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		ic.mu.Lock()
+		paused := ic.paused
+		ic.mu.Unlock()
+		if paused || (ic.Skip != nil && ic.Skip()) {
+			continue
+		}
+		violations, err := ic.Check()
+		select {
+		case <-stop: // interrupted
+			return
+		default:
+		}
+		if ic.report != nil {
+			ic.report(violations, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestIntegrityViolations(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	violations, err := db.IntegrityViolations("", 10, true)
+	checkNoError(t, err, "couldn't check integrity: %s")
+	assert.Equal(t, 0, len(violations))
+}
+
+func TestIntegrityChecker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosqlite-test")
+	checkNoError(t, err, "couldn't create temp dir: %s")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "check.db")
+	db, err := Open(filename)
+	checkNoError(t, err, "couldn't open database: %s")
+	checkNoError(t, db.FastExec("CREATE TABLE test (x); INSERT INTO test VALUES (1)"), "couldn't create table: %s")
+	checkClose(db, t)
+
+	results := make(chan error, 10)
+	ic := NewIntegrityChecker(func() (*Conn, error) {
+		return Open(filename, OpenReadOnly)
+	}, 10*time.Millisecond, func(violations []string, err error) {
+		if err == nil && len(violations) > 0 {
+			err = ErrCorrupt
+		}
+		select {
+		case results <- err:
+		default:
+		}
+	})
+	ic.Jitter = time.Millisecond
+	ic.Full = true
+	ic.Start()
+	select {
+	case err = <-results:
+		checkNoError(t, err, "integrity check failed: %s")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no integrity check")
+	}
+	ic.Pause()
+	ic.Stop()
+
+	violations, err := ic.Check()
+	checkNoError(t, err, "couldn't check: %s")
+	assert.Equal(t, 0, len(violations))
+	ic.Stop()
+}
@@ -0,0 +1,144 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JSONNode is one element of a JSON value as returned by the json_each and json_tree table-valued functions.
+// (See http://sqlite.org/json1.html#jeach)
+type JSONNode struct {
+	Key     interface{} // member name (string) or array index (int64), nil for the top-level element
+	Value   interface{} // SQL value (JSON text for arrays and objects)
+	Type    string      // 'null', 'true', 'false', 'integer', 'real', 'text', 'array' or 'object'
+	Atom    interface{} // SQL value for primitive types, nil for arrays and objects
+	ID      int64
+	Parent  int64 // -1 for the top-level element
+	FullKey string
+	Path    string
+}
+
+// JSONEach calls f for each direct child of the JSON element at path ("$" when empty).
+func (c *Conn) JSONEach(jsonText, path string, f func(n JSONNode) error) error {
+	return c.jsonWalk("json_each", jsonText, path, f)
+}
+
+// JSONTree calls f for each element of the JSON element at path ("$" when empty), recursively.
+func (c *Conn) JSONTree(jsonText, path string, f func(n JSONNode) error) error {
+	return c.jsonWalk("json_tree", jsonText, path, f)
+}
+
+func (c *Conn) jsonWalk(function, jsonText, path string, f func(n JSONNode) error) error {
+	if len(path) == 0 {
+		path = "$"
+	}
+	s, err := c.prepare(fmt.Sprintf("SELECT key, value, type, atom, id, coalesce(parent, -1), fullkey, path FROM %s(?, ?)", function),
+		jsonText, path)
+	if err != nil {
+		return err
+	}
+	defer s.finalize()
+	return s.Select(func(s *Stmt) (err error) {
+		var n JSONNode
+		n.Key, _ = s.ScanValue(0, false)
+		n.Value, _ = s.ScanValue(1, false)
+		n.Type, _ = s.ScanText(2)
+		n.Atom, _ = s.ScanValue(3, false)
+		if n.ID, _, err = s.ScanInt64(4); err != nil {
+			return
+		}
+		if n.Parent, _, err = s.ScanInt64(5); err != nil {
+			return
+		}
+		n.FullKey, _ = s.ScanText(6)
+		n.Path, _ = s.ScanText(7)
+		return f(n)
+	})
+}
+
+// QueryJSON executes the query and returns one map (column name => value) per row.
+// Text values of columns declared as JSON are decoded.
+func (c *Conn) QueryJSON(query string, args ...interface{}) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	err := c.queryJSON(query, args, func(columns []string, values []interface{}) error {
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if raw, ok := values[i].(json.RawMessage); ok {
+				var v interface{}
+				if err := json.Unmarshal(raw, &v); err != nil {
+					return err
+				}
+				values[i] = v
+			}
+			row[column] = values[i]
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	return rows, err
+}
+
+// QueryJSONRaw executes the query and returns one JSON object per row (with keys in column order).
+// Text values of columns declared as JSON are embedded as is. Blobs are base64 encoded.
+func (c *Conn) QueryJSONRaw(query string, args ...interface{}) ([]json.RawMessage, error) {
+	var rows []json.RawMessage
+	err := c.queryJSON(query, args, func(columns []string, values []interface{}) error {
+		var b bytes.Buffer
+		b.WriteByte('{')
+		for i, column := range columns {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			k, err := json.Marshal(column)
+			if err != nil {
+				return err
+			}
+			v, err := json.Marshal(values[i])
+			if err != nil {
+				return err
+			}
+			b.Write(k)
+			b.WriteByte(':')
+			b.Write(v)
+		}
+		b.WriteByte('}')
+		rows = append(rows, json.RawMessage(b.Bytes()))
+		return nil
+	})
+	return rows, err
+}
+
+func (c *Conn) queryJSON(query string, args []interface{}, f func(columns []string, values []interface{}) error) error {
+	s, err := c.prepare(query, args...)
+	if err != nil {
+		return err
+	}
+	defer s.finalize()
+	columns := s.ColumnNames()
+	isJSON := make([]bool, len(columns))
+	for i := range columns {
+		isJSON[i] = strings.EqualFold(s.ColumnDeclaredType(i), "JSON")
+	}
+	values := make([]interface{}, len(columns))
+	return s.Select(func(s *Stmt) error {
+		s.ScanValues(values)
+		for i, v := range values {
+			switch v := v.(type) {
+			case string:
+				if isJSON[i] && json.Valid([]byte(v)) {
+					values[i] = json.RawMessage(v)
+				}
+			case time.Time:
+				values[i] = v.Format(time.RFC3339Nano)
+			}
+		}
+		return f(columns, values)
+	})
+}
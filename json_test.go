@@ -0,0 +1,80 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestJSONEachAndTree(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	const doc = `{"name": "gosqlite", "tags": ["go", "sqlite"], "stars": 42}`
+	var keys []interface{}
+	err := db.JSONEach(doc, "", func(n JSONNode) error {
+		keys = append(keys, n.Key)
+		if n.Key == "stars" {
+			assert.Equal(t, "integer", n.Type)
+			assert.Equal(t, int64(42), n.Atom)
+			assert.Equal(t, "$.stars", n.FullKey)
+		}
+		return nil
+	})
+	checkNoError(t, err, "couldn't iterate json_each: %s")
+	assert.Equal(t, []interface{}{"name", "tags", "stars"}, keys)
+
+	var tags []interface{}
+	err = db.JSONEach(doc, "$.tags", func(n JSONNode) error {
+		assert.Equal(t, "$.tags", n.Path)
+		tags = append(tags, n.Key, n.Value)
+		return nil
+	})
+	checkNoError(t, err, "couldn't iterate json_each: %s")
+	assert.Equal(t, []interface{}{int64(0), "go", int64(1), "sqlite"}, tags)
+
+	var paths []string
+	err = db.JSONTree(doc, "", func(n JSONNode) error {
+		if n.Parent == -1 {
+			assert.Equal(t, "object", n.Type)
+			assert.Equal(t, nil, n.Atom)
+		}
+		paths = append(paths, n.FullKey)
+		return nil
+	})
+	checkNoError(t, err, "couldn't iterate json_tree: %s")
+	assert.Equal(t, []string{"$", "$.name", "$.tags", "$.tags[0]", "$.tags[1]", "$.stars"}, paths)
+
+	err = db.JSONEach("not json", "", func(n JSONNode) error { return nil })
+	assert.T(t, err != nil, "error expected")
+}
+
+func TestQueryJSON(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.FastExec(`CREATE TABLE docs (id INTEGER PRIMARY KEY, title TEXT, meta JSON, data BLOB);
+		INSERT INTO docs VALUES (1, 'first', '{"tags":["a","b"]}', x'0102');
+		INSERT INTO docs VALUES (2, NULL, '[1,2]', NULL);`)
+	checkNoError(t, err, "couldn't create table: %s")
+
+	rows, err := db.QueryJSON("SELECT id, title, meta FROM docs WHERE id > ? ORDER BY id", 0)
+	checkNoError(t, err, "couldn't query: %s")
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, int64(1), rows[0]["id"])
+	assert.Equal(t, "first", rows[0]["title"])
+	assert.Equal(t, map[string]interface{}{"tags": []interface{}{"a", "b"}}, rows[0]["meta"])
+	assert.Equal(t, nil, rows[1]["title"])
+	assert.Equal(t, []interface{}{float64(1), float64(2)}, rows[1]["meta"])
+
+	raws, err := db.QueryJSONRaw("SELECT id, title, meta, data FROM docs ORDER BY id")
+	checkNoError(t, err, "couldn't query: %s")
+	assert.Equal(t, 2, len(raws))
+	assert.Equal(t, `{"id":1,"title":"first","meta":{"tags":["a","b"]},"data":"AQI="}`, string(raws[0]))
+	assert.Equal(t, `{"id":2,"title":null,"meta":[1,2],"data":null}`, string(raws[1]))
+}
@@ -0,0 +1,81 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Encryption is not supported by the public domain SQLite library:
+// Key and Rekey work only with a library built with SQLITE_HAS_CODEC (SQLCipher, SEE, ...).
+// With the "codec" build tag, sqlite3_key_v2/sqlite3_rekey_v2 are called directly
+// (the library must then export them), otherwise the "key" and "rekey" pragmas are used.
+
+// Key sets the encryption key of the specified database ("main" when empty).
+// It must be called right after the connection is opened, before any other statement
+// (see OpenWithKey).
+// (See https://www.zetetic.net/sqlcipher/sqlcipher-api/#key)
+func (c *Conn) Key(dbName string, key []byte) error {
+	if len(dbName) == 0 {
+		dbName = "main"
+	}
+	return c.key(dbName, key)
+}
+
+// Rekey changes the encryption key of the specified database ("main" when empty).
+// An empty key decrypts the database (if supported by the library).
+// (See https://www.zetetic.net/sqlcipher/sqlcipher-api/#rekey)
+func (c *Conn) Rekey(dbName string, key []byte) error {
+	if len(dbName) == 0 {
+		dbName = "main"
+	}
+	return c.rekey(dbName, key)
+}
+
+// OpenWithKey opens a new database connection and sets its encryption key
+// before any other statement is executed.
+func OpenWithKey(filename string, key []byte, flags ...OpenFlag) (*Conn, error) {
+	c, err := openVfs(filename, "", flags...)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.Key("main", key); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c.autoRegistered()
+}
+
+// keyFromDSN extracts (and removes) the "_key" parameter from an URI filename:
+//
+//	file:test.db?_key=secret&cache=shared
+func keyFromDSN(name string) (string, []byte) {
+	i := strings.IndexByte(name, '?')
+	if i < 0 || !strings.HasPrefix(name, "file:") {
+		return name, nil
+	}
+	params := strings.Split(name[i+1:], "&")
+	var key []byte
+	kept := params[:0]
+	for _, param := range params {
+		if strings.HasPrefix(param, "_key=") {
+			value, err := url.QueryUnescape(param[5:])
+			if err != nil {
+				value = param[5:]
+			}
+			key = []byte(value)
+			continue
+		}
+		kept = append(kept, param)
+	}
+	if key == nil {
+		return name, nil
+	}
+	if len(kept) == 0 {
+		return name[:i], key
+	}
+	return name[:i+1] + strings.Join(kept, "&"), key
+}
@@ -0,0 +1,38 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build codec
+
+package sqlite
+
+/*
+#cgo CFLAGS: -DSQLITE_HAS_CODEC
+#include <sqlite3.h>
+#include <stdlib.h>
+
+// Declared only when SQLITE_HAS_CODEC is defined in the library header.
+int sqlite3_key_v2(sqlite3 *db, const char *zDbName, const void *pKey, int nKey);
+int sqlite3_rekey_v2(sqlite3 *db, const char *zDbName, const void *pKey, int nKey);
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+func (c *Conn) key(dbName string, key []byte) error {
+	zDbName := C.CString(dbName)
+	defer C.free(unsafe.Pointer(zDbName))
+	pKey := C.CBytes(key)
+	defer C.free(pKey)
+	return c.error(C.sqlite3_key_v2(c.db, zDbName, pKey, C.int(len(key))), "Conn.Key")
+}
+
+func (c *Conn) rekey(dbName string, key []byte) error {
+	zDbName := C.CString(dbName)
+	defer C.free(unsafe.Pointer(zDbName))
+	pKey := C.CBytes(key)
+	defer C.free(pKey)
+	return c.error(C.sqlite3_rekey_v2(c.db, zDbName, pKey, C.int(len(key))), "Conn.Rekey")
+}
@@ -0,0 +1,27 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !codec
+
+package sqlite
+
+import (
+	"fmt"
+)
+
+func (c *Conn) key(dbName string, key []byte) error {
+	return c.keyPragma(dbName, "key", key)
+}
+
+func (c *Conn) rekey(dbName string, key []byte) error {
+	return c.keyPragma(dbName, "rekey", key)
+}
+
+func (c *Conn) keyPragma(dbName, pragma string, key []byte) error {
+	// unknown pragmas are silently ignored by SQLite
+	if !CompileOptionUsed("HAS_CODEC") {
+		return c.specificError("%s: SQLite library built without encryption support (SQLITE_HAS_CODEC)", pragma)
+	}
+	return c.FastExec(fmt.Sprintf("PRAGMA %s.%s = %s", doubleQuote(dbName), pragma, QuoteLiteral(string(key))))
+}
@@ -0,0 +1,39 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestKey(t *testing.T) {
+	if CompileOptionUsed("HAS_CODEC") {
+		db, err := OpenWithKey(":memory:", []byte("secret"))
+		checkNoError(t, err, "couldn't open with key: %s")
+		defer checkClose(db, t)
+		checkNoError(t, db.FastExec("CREATE TABLE test (x)"), "couldn't create table: %s")
+		checkNoError(t, db.Rekey("", []byte("other")), "couldn't rekey: %s")
+		return
+	}
+	registered := false
+	AutoRegister(func(c *Conn) error {
+		registered = true
+		return nil
+	})
+	defer ResetAutoRegister()
+	_, err := OpenWithKey(":memory:", []byte("secret"))
+	assert.T(t, err != nil, "error expected without encryption support")
+	assert.T(t, !registered, "key expected to be set before auto registration")
+
+	db, err := sql.Open("sqlite3", "file:dummy.db?mode=memory&_key=secret")
+	checkNoError(t, err, "couldn't open database: %s")
+	defer db.Close()
+	err = db.Ping()
+	assert.T(t, err != nil, "error expected without encryption support")
+}
@@ -0,0 +1,120 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var leakDetection struct {
+	sync.Mutex
+	enabled bool
+	report  func(msg string)
+}
+
+// EnableLeakDetection turns on a debug mode where the call-site of Open and Prepare is recorded
+// and a finalizer is registered on each connection and statement:
+// when one is garbage-collected without having been closed/finalized, the leak is reported
+// with the call-site stack (with sqlite3_log, see ConfigLog, when report is nil).
+// The "Dangling statement" warnings logged by Conn.Close also include the stack.
+// Only objects created after the mode is turned on are tracked.
+func EnableLeakDetection(report func(msg string)) {
+	leakDetection.Lock()
+	leakDetection.enabled = true
+	leakDetection.report = report
+	leakDetection.Unlock()
+}
+
+// DisableLeakDetection turns off the leak detection mode (for objects created afterwards).
+func DisableLeakDetection() {
+	leakDetection.Lock()
+	leakDetection.enabled = false
+	leakDetection.report = nil
+	leakDetection.Unlock()
+}
+
+func leakDetectionEnabled() bool {
+	leakDetection.Lock()
+	defer leakDetection.Unlock()
+	return leakDetection.enabled
+}
+
+func reportLeak(msg string) {
+	leakDetection.Lock()
+	report := leakDetection.report
+	leakDetection.Unlock()
+	if report == nil {
+		Log(C.SQLITE_MISUSE, msg)
+		return
+	}
+	report(msg)
+}
+
+// callers returns the stack of the caller (skipping gosqlite internal frames).
+func callers() string {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/gwenn/gosqlite.") || b.Len() > 0 {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+func (c *Conn) trackLeak() {
+	if !leakDetectionEnabled() {
+		return
+	}
+	stack := callers()
+	c.stmtStacks = make(map[*C.sqlite3_stmt]string)
+	runtime.SetFinalizer(c, func(c *Conn) {
+		if c.db != nil {
+			reportLeak("Conn garbage-collected without having been closed, opened at:\n" + stack)
+		}
+	})
+}
+
+func (s *Stmt) trackLeak() {
+	if s.c.stmtStacks == nil || s.stmt == nil {
+		return
+	}
+	stack := callers()
+	s.c.stmtStacks[s.stmt] = stack
+	sql := s.SQL()
+	runtime.SetFinalizer(s, func(s *Stmt) {
+		if s.stmt != nil {
+			reportLeak(fmt.Sprintf("Stmt %q garbage-collected without having been finalized, prepared at:\n%s", sql, stack))
+		}
+	})
+}
+
+func (s *Stmt) untrackLeak() {
+	if s.c.stmtStacks != nil {
+		delete(s.c.stmtStacks, s.stmt)
+	}
+}
+
+// preparedAt returns the call-site of the preparation of the statement (if tracked).
+func (c *Conn) preparedAt(stmt *C.sqlite3_stmt) string {
+	if stack, ok := c.stmtStacks[stmt]; ok {
+		return ", prepared at:\n" + stack
+	}
+	return ""
+}
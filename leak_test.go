@@ -0,0 +1,54 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestLeakDetection(t *testing.T) {
+	var mu sync.Mutex
+	var leaks []string
+	EnableLeakDetection(func(msg string) {
+		mu.Lock()
+		leaks = append(leaks, msg)
+		mu.Unlock()
+	})
+	defer DisableLeakDetection()
+
+	db := open(t)
+	defer checkClose(db, t)
+	func() {
+		s, err := db.Prepare("SELECT 1")
+		checkNoError(t, err, "couldn't prepare: %s")
+		s.Cacheable = false
+		_ = s // leaked
+	}()
+	s, err := db.Prepare("SELECT 2")
+	checkNoError(t, err, "couldn't prepare: %s")
+	checkFinalize(s, t)
+
+	var found bool
+	for i := 0; i < 20 && !found; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		found = len(leaks) > 0
+		mu.Unlock()
+	}
+	assert.T(t, found, "leak expected")
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, len(leaks))
+	assert.T(t, strings.Contains(leaks[0], `"SELECT 1"`), leaks[0])
+	assert.T(t, strings.Contains(leaks[0], "TestLeakDetection"), leaks[0])
+}
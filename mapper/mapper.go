@@ -0,0 +1,416 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mapper maps Go structs to SQLite tables to avoid routine INSERT/SELECT/UPDATE boilerplate.
+// It is not an ORM: there is no relation nor schema management.
+//
+// Fields are mapped to columns with the `db` tag (`db:"-"` to skip a field, `db:"id,pk"` to mark the primary key).
+// Untagged exported fields are mapped to their lower-cased name.
+// When no field is tagged "pk", the field mapped to the column "id" is the primary key.
+// The table name is returned by the TableName method when implemented, otherwise it is the lower-cased type name.
+//
+//	type User struct {
+//		ID   int64  `db:"id,pk"`
+//		Name string `db:"name"`
+//	}
+//	u := User{Name: "alice"}
+//	err := mapper.Insert(db, &u) // u.ID is set to the generated rowid
+//	var users []User
+//	err = mapper.Where("name LIKE ?", "a%").OrderBy("name").Limit(10).Find(db, &users)
+package mapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gwenn/gosqlite"
+)
+
+// Tabler can be implemented to specify the table mapped by a struct.
+type Tabler interface {
+	TableName() string
+}
+
+type field struct {
+	column string
+	index  []int
+}
+
+type mapping struct {
+	table  string
+	fields []field
+	pk     int // index in fields (-1 when there is no primary key)
+}
+
+var (
+	mappingsMu sync.Mutex
+	mappings   = make(map[reflect.Type]*mapping)
+)
+
+// structValue returns the struct pointed by row.
+func structValue(row interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(row)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("non-nil pointer to struct expected (got %T)", row)
+	}
+	return v.Elem(), nil
+}
+
+func mappingOf(t reflect.Type) *mapping {
+	mappingsMu.Lock()
+	defer mappingsMu.Unlock()
+	if m, ok := mappings[t]; ok {
+		return m
+	}
+	m := &mapping{table: strings.ToLower(t.Name()), pk: -1}
+	if tabler, ok := reflect.New(t).Interface().(Tabler); ok {
+		m.table = tabler.TableName()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		tag := sf.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		column := parts[0]
+		if len(column) == 0 {
+			column = strings.ToLower(sf.Name)
+		}
+		for _, option := range parts[1:] {
+			if option == "pk" {
+				m.pk = len(m.fields)
+			}
+		}
+		m.fields = append(m.fields, field{column: column, index: sf.Index})
+	}
+	if m.pk < 0 {
+		for i, f := range m.fields {
+			if strings.EqualFold(f.column, "id") {
+				m.pk = i
+				break
+			}
+		}
+	}
+	mappings[t] = m
+	return m
+}
+
+func (m *mapping) columns(skip int) []string {
+	columns := make([]string, 0, len(m.fields))
+	for i, f := range m.fields {
+		if i != skip {
+			columns = append(columns, sqlite.QuoteIdentifier(f.column))
+		}
+	}
+	return columns
+}
+
+func (m *mapping) values(v reflect.Value, skip int) []interface{} {
+	values := make([]interface{}, 0, len(m.fields))
+	for i, f := range m.fields {
+		if i != skip {
+			values = append(values, v.FieldByIndex(f.index).Interface())
+		}
+	}
+	return values
+}
+
+func (m *mapping) pointers(v reflect.Value) []interface{} {
+	ptrs := make([]interface{}, len(m.fields))
+	for i, f := range m.fields {
+		ptrs[i] = v.FieldByIndex(f.index).Addr().Interface()
+	}
+	return ptrs
+}
+
+func (m *mapping) selectSQL() string {
+	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(m.columns(-1), ", "), sqlite.QuoteIdentifier(m.table))
+}
+
+// fieldIndex returns the index in fields of the specified column (compared case insensitively) or -1.
+func (m *mapping) fieldIndex(column string) int {
+	for i, f := range m.fields {
+		if strings.EqualFold(f.column, column) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *mapping) checkPk() error {
+	if m.pk < 0 {
+		return fmt.Errorf("no primary key for table %q", m.table)
+	}
+	return nil
+}
+
+func isZeroInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	}
+	return false
+}
+
+// Insert inserts row (a pointer to struct) into its table.
+// When the primary key is an integer field with a zero value, it is omitted
+// and the field is set to the rowid generated by SQLite.
+func Insert(c *sqlite.Conn, row interface{}) error {
+	v, err := structValue(row)
+	if err != nil {
+		return err
+	}
+	m := mappingOf(v.Type())
+	skip := -1
+	if m.pk >= 0 && isZeroInt(v.FieldByIndex(m.fields[m.pk].index)) {
+		skip = m.pk
+	}
+	columns := m.columns(skip)
+	var sql string
+	if len(columns) == 0 {
+		sql = fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", sqlite.QuoteIdentifier(m.table))
+	} else {
+		sql = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", sqlite.QuoteIdentifier(m.table), strings.Join(columns, ", "),
+			strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+	}
+	_, rowid, err := c.ExecResult(sql, m.values(v, skip)...)
+	if err != nil {
+		return err
+	}
+	if skip >= 0 {
+		v.FieldByIndex(m.fields[m.pk].index).SetInt(rowid)
+	}
+	return nil
+}
+
+// Get loads the row matching the primary key pk into row (a pointer to struct).
+// Returns false when there is no such row.
+func Get(c *sqlite.Conn, row interface{}, pk interface{}) (found bool, err error) {
+	v, err := structValue(row)
+	if err != nil {
+		return false, err
+	}
+	m := mappingOf(v.Type())
+	if err = m.checkPk(); err != nil {
+		return false, err
+	}
+	s, err := c.Prepare(fmt.Sprintf("%s WHERE %s = ?", m.selectSQL(), sqlite.QuoteIdentifier(m.fields[m.pk].column)), pk)
+	if err != nil {
+		return false, err
+	}
+	defer s.Finalize()
+	return s.SelectOneRow(m.pointers(v)...)
+}
+
+// UpdateColumns updates the specified columns (all except the primary key when none is specified)
+// of the row identified by its primary key.
+// Returns the number of rows changed (zero when there is no such row).
+func UpdateColumns(c *sqlite.Conn, row interface{}, columns ...string) (changes int, err error) {
+	v, err := structValue(row)
+	if err != nil {
+		return 0, err
+	}
+	m := mappingOf(v.Type())
+	if err = m.checkPk(); err != nil {
+		return 0, err
+	}
+	var updated []int // indexes in fields
+	if len(columns) == 0 {
+		for i := range m.fields {
+			if i != m.pk {
+				updated = append(updated, i)
+			}
+		}
+	} else {
+		seen := make(map[int]bool, len(columns))
+		for _, column := range columns {
+			i := m.fieldIndex(column)
+			switch {
+			case i < 0:
+				return 0, fmt.Errorf("unknown column %q for table %q", column, m.table)
+			case i == m.pk:
+				return 0, fmt.Errorf("primary key column %q of table %q cannot be updated", column, m.table)
+			case seen[i]:
+				return 0, fmt.Errorf("duplicate column %q for table %q", column, m.table)
+			}
+			seen[i] = true
+			updated = append(updated, i)
+		}
+	}
+	sets := make([]string, len(updated))
+	args := make([]interface{}, len(updated), len(updated)+1)
+	for j, i := range updated {
+		f := m.fields[i]
+		sets[j] = sqlite.QuoteIdentifier(f.column) + " = ?"
+		args[j] = v.FieldByIndex(f.index).Interface()
+	}
+	pk := m.fields[m.pk]
+	args = append(args, v.FieldByIndex(pk.index).Interface())
+	return c.ExecDml(fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", sqlite.QuoteIdentifier(m.table), strings.Join(sets, ", "),
+		sqlite.QuoteIdentifier(pk.column)), args...)
+}
+
+// Delete deletes the row identified by its primary key.
+// Returns the number of rows deleted.
+func Delete(c *sqlite.Conn, row interface{}) (changes int, err error) {
+	v, err := structValue(row)
+	if err != nil {
+		return 0, err
+	}
+	m := mappingOf(v.Type())
+	if err = m.checkPk(); err != nil {
+		return 0, err
+	}
+	pk := m.fields[m.pk]
+	return c.ExecDml(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", sqlite.QuoteIdentifier(m.table), sqlite.QuoteIdentifier(pk.column)),
+		v.FieldByIndex(pk.index).Interface())
+}
+
+// Query builds the WHERE/ORDER BY/LIMIT clauses of a SELECT statement with its parameters.
+// The zero value selects all rows.
+type Query struct {
+	where   []string
+	args    []interface{}
+	orderBy []string
+	limit   int
+	offset  int
+}
+
+// Where starts a query with the condition cond (with ? placeholders bound to args).
+func Where(cond string, args ...interface{}) *Query {
+	return new(Query).And(cond, args...)
+}
+
+// And adds a condition (combined with the previous ones using AND).
+func (q *Query) And(cond string, args ...interface{}) *Query {
+	q.where = append(q.where, "("+cond+")")
+	q.args = append(q.args, args...)
+	return q
+}
+
+// Or combines the previous conditions with cond using OR.
+func (q *Query) Or(cond string, args ...interface{}) *Query {
+	if len(q.where) == 0 {
+		return q.And(cond, args...)
+	}
+	q.where = []string{"(" + strings.Join(q.where, " AND ") + " OR (" + cond + "))"}
+	q.args = append(q.args, args...)
+	return q
+}
+
+// In adds the condition "column IN (values...)".
+// With no value, the condition is always false.
+func (q *Query) In(column string, values ...interface{}) *Query {
+	if len(values) == 0 {
+		return q.And("0")
+	}
+	return q.And(fmt.Sprintf("%s IN (%s)", sqlite.QuoteIdentifier(column),
+		strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")), values...)
+}
+
+// OrderBy adds ordering terms (like "name" or "age DESC").
+func (q *Query) OrderBy(terms ...string) *Query {
+	q.orderBy = append(q.orderBy, terms...)
+	return q
+}
+
+// Limit sets the maximum number of rows returned.
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Offset sets the number of rows skipped.
+func (q *Query) Offset(n int) *Query {
+	q.offset = n
+	return q
+}
+
+// SQL returns the clauses (starting with a space when not empty) and their parameters.
+func (q *Query) SQL() (string, []interface{}) {
+	var b strings.Builder
+	if len(q.where) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(q.where, " AND "))
+	}
+	if len(q.orderBy) > 0 {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(strings.Join(q.orderBy, ", "))
+	}
+	if q.limit > 0 || q.offset > 0 {
+		limit := q.limit
+		if limit <= 0 {
+			limit = -1
+		}
+		fmt.Fprintf(&b, " LIMIT %d", limit)
+		if q.offset > 0 {
+			fmt.Fprintf(&b, " OFFSET %d", q.offset)
+		}
+	}
+	return b.String(), q.args
+}
+
+// Find appends the matching rows to dest (a pointer to a slice of structs).
+func (q *Query) Find(c *sqlite.Conn, dest interface{}) error {
+	sv := reflect.ValueOf(dest)
+	if sv.Kind() != reflect.Ptr || sv.IsNil() || sv.Elem().Kind() != reflect.Slice || sv.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("non-nil pointer to slice of structs expected (got %T)", dest)
+	}
+	slice := sv.Elem()
+	t := slice.Type().Elem()
+	m := mappingOf(t)
+	clauses, args := q.SQL()
+	s, err := c.Prepare(m.selectSQL()+clauses, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Finalize()
+	return s.Select(func(s *sqlite.Stmt) error {
+		v := reflect.New(t).Elem()
+		if err := s.Scan(m.pointers(v)...); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, v))
+		return nil
+	})
+}
+
+// First loads the first matching row into row (a pointer to struct).
+// Returns false when there is no matching row.
+func (q *Query) First(c *sqlite.Conn, row interface{}) (found bool, err error) {
+	v, err := structValue(row)
+	if err != nil {
+		return false, err
+	}
+	m := mappingOf(v.Type())
+	first := *q
+	first.limit = 1
+	clauses, args := first.SQL()
+	s, err := c.Prepare(m.selectSQL()+clauses, args...)
+	if err != nil {
+		return false, err
+	}
+	defer s.Finalize()
+	return s.SelectOneRow(m.pointers(v)...)
+}
+
+// Count returns the number of rows matching the query in the table mapped by model (a pointer to struct).
+// Order, limit and offset are ignored.
+func (q *Query) Count(c *sqlite.Conn, model interface{}) (count int64, err error) {
+	v, err := structValue(model)
+	if err != nil {
+		return 0, err
+	}
+	m := mappingOf(v.Type())
+	where := Query{where: q.where, args: q.args}
+	clauses, args := where.SQL()
+	err = c.OneValue(fmt.Sprintf("SELECT count(*) FROM %s%s", sqlite.QuoteIdentifier(m.table), clauses), &count, args...)
+	return
+}
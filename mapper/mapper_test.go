@@ -0,0 +1,153 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mapper_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/gwenn/gosqlite"
+	. "github.com/gwenn/gosqlite/mapper"
+)
+
+type user struct {
+	ID      int64  `db:"id,pk"`
+	Name    string `db:"name"`
+	Age     int    `db:"age"`
+	Ignored string `db:"-"`
+}
+
+func (user) TableName() string {
+	return "users"
+}
+
+func open(t *testing.T) *sqlite.Conn {
+	db, err := sqlite.Open(":memory:")
+	assert.Tf(t, err == nil, "%v", err)
+	err = db.FastExec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, age INTEGER)")
+	assert.Tf(t, err == nil, "%v", err)
+	return db
+}
+
+func TestInsertGetUpdateDelete(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+
+	u := user{Name: "alice", Age: 30, Ignored: "x"}
+	err := Insert(db, &u)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, int64(1), u.ID)
+
+	var got user
+	found, err := Get(db, &got, u.ID)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.T(t, found)
+	assert.Equal(t, user{ID: 1, Name: "alice", Age: 30}, got)
+
+	found, err = Get(db, &got, 2)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.T(t, !found)
+
+	u.Name = "bob"
+	u.Age = 31
+	changes, err := UpdateColumns(db, &u, "age")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 1, changes)
+	_, err = Get(db, &got, u.ID)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, "alice", got.Name)
+	assert.Equal(t, 31, got.Age)
+
+	changes, err = UpdateColumns(db, &u)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 1, changes)
+	_, err = Get(db, &got, u.ID)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, "bob", got.Name)
+
+	_, err = UpdateColumns(db, &u, "unknown")
+	assert.T(t, err != nil, "error expected")
+	assert.Equal(t, `unknown column "unknown" for table "users"`, err.Error())
+	_, err = UpdateColumns(db, &u, "age", "ID")
+	assert.T(t, err != nil, "error expected")
+	assert.Equal(t, `primary key column "ID" of table "users" cannot be updated`, err.Error())
+	_, err = UpdateColumns(db, &u, "age", "Age")
+	assert.T(t, err != nil, "error expected")
+	assert.Equal(t, `duplicate column "Age" for table "users"`, err.Error())
+
+	changes, err = Delete(db, &u)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 1, changes)
+
+	err = Insert(db, u)
+	assert.T(t, err != nil, "error expected")
+}
+
+type ticket struct {
+	ID int64 `db:"id,pk"`
+}
+
+func (ticket) TableName() string {
+	return "tickets"
+}
+
+func TestInsertDefaultValues(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+	err := db.FastExec("CREATE TABLE tickets (id INTEGER PRIMARY KEY)")
+	assert.Tf(t, err == nil, "%v", err)
+
+	var tk ticket
+	err = Insert(db, &tk)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, int64(1), tk.ID)
+	tk = ticket{}
+	err = Insert(db, &tk)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, int64(2), tk.ID)
+}
+
+func TestQuery(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		err := Insert(db, &user{Name: name, Age: len(name) * 10})
+		assert.Tf(t, err == nil, "%v", err)
+	}
+
+	var users []user
+	err := Where("age > ?", 30).OrderBy("name DESC").Find(db, &users)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 3, len(users))
+	assert.Equal(t, "dave", users[0].Name)
+
+	users = nil
+	err = Where("age = ?", 40).Or("name = ?", "bob").OrderBy("id").Limit(1).Offset(1).Find(db, &users)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 1, len(users))
+	assert.Equal(t, "dave", users[0].Name)
+
+	users = nil
+	err = new(Query).In("name", "alice", "carol").Find(db, &users)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 2, len(users))
+
+	var u user
+	found, err := Where("name LIKE ?", "c%").First(db, &u)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.T(t, found)
+	assert.Equal(t, "carol", u.Name)
+
+	count, err := Where("age >= ?", 40).Limit(1).Count(db, &u)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, int64(3), count)
+
+	sql, args := Where("a = ?", 1).And("b = ?", 2).OrderBy("c").SQL()
+	assert.Equal(t, " WHERE (a = ?) AND (b = ?) ORDER BY c", sql)
+	assert.Equal(t, []interface{}{1, 2}, args)
+
+	err = Where("1").Find(db, users)
+	assert.T(t, err != nil, "error expected")
+}
@@ -0,0 +1,319 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"io"
+	"sort"
+	"sync"
+)
+
+// MemVFS is an in-memory VFS: named files are shared by all connections
+// opened with the same VFS (until they are deleted) and can be extracted or injected.
+//
+//	vfs := NewMemVFS()
+//	err := RegisterVFS("memvfs", vfs, false)
+//	c1, err := OpenVfs("test.db", "memvfs")
+//	c2, err := OpenVfs("test.db", "memvfs") // same database
+//	content, ok := vfs.Bytes("test.db")
+//
+// WAL journal mode is not supported (except with exclusive locking mode).
+type MemVFS struct {
+	// DefaultQuota is the maximum size of new files (no limit when zero or negative).
+	// Writes beyond the quota fail with ErrFull.
+	DefaultQuota int64
+
+	mu    sync.Mutex
+	files map[string]*memData
+}
+
+type memData struct {
+	mu    sync.Mutex
+	data  []byte
+	quota int64
+	// locks
+	shared    int
+	reserved  bool
+	pending   bool
+	exclusive bool
+}
+
+type memFile struct {
+	vfs           *MemVFS
+	name          string
+	d             *memData
+	level         LockLevel
+	reserved      bool
+	pending       bool
+	deleteOnClose bool
+}
+
+// NewMemVFS creates an in-memory VFS (to be registered with RegisterVFS).
+func NewMemVFS() *MemVFS {
+	return &MemVFS{files: make(map[string]*memData)}
+}
+
+// Bytes returns a copy of the content of the named file.
+// The file should not be modified concurrently (no transaction should be in progress).
+func (m *MemVFS) Bytes(name string) ([]byte, bool) {
+	m.mu.Lock()
+	d, ok := m.files[name]
+	m.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]byte(nil), d.data...), true
+}
+
+// SetBytes creates or replaces the content of the named file with a copy of data
+// (a serialized database for example).
+// The file should not be opened by any connection.
+func (m *MemVFS) SetBytes(name string, data []byte) {
+	d := m.file(name)
+	d.mu.Lock()
+	d.data = append([]byte(nil), data...)
+	d.mu.Unlock()
+}
+
+// SetQuota sets the maximum size of the named file (created if needed).
+// No limit when max is zero or negative.
+func (m *MemVFS) SetQuota(name string, max int64) {
+	d := m.file(name)
+	d.mu.Lock()
+	d.quota = max
+	d.mu.Unlock()
+}
+
+// Size returns the size of the named file.
+func (m *MemVFS) Size(name string) (int64, bool) {
+	m.mu.Lock()
+	d, ok := m.files[name]
+	m.mu.Unlock()
+	if !ok {
+		return 0, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return int64(len(d.data)), true
+}
+
+// Names returns the (sorted) names of the files.
+func (m *MemVFS) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Remove deletes the named file.
+// Connections using it keep their content until they are closed.
+func (m *MemVFS) Remove(name string) {
+	m.mu.Lock()
+	delete(m.files, name)
+	m.mu.Unlock()
+}
+
+func (m *MemVFS) file(name string) *memData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = make(map[string]*memData)
+	}
+	d, ok := m.files[name]
+	if !ok {
+		d = &memData{quota: m.DefaultQuota}
+		m.files[name] = d
+	}
+	return d
+}
+
+// Open implements the VFS interface.
+func (m *MemVFS) Open(name string, flags OpenFlag) (VFSFile, OpenFlag, error) {
+	if len(name) == 0 { // temporary file
+		return &memFile{vfs: m, d: &memData{quota: m.DefaultQuota}}, flags, nil
+	}
+	m.mu.Lock()
+	_, exists := m.files[name]
+	m.mu.Unlock()
+	if !exists && flags&OpenCreate == 0 {
+		return nil, 0, ErrCantOpen
+	} else if exists && flags&OpenExclusive != 0 {
+		return nil, 0, ErrCantOpen
+	}
+	return &memFile{vfs: m, name: name, d: m.file(name), deleteOnClose: flags&OpenDeleteOnClose != 0}, flags, nil
+}
+
+// Delete implements the VFS interface.
+func (m *MemVFS) Delete(name string, syncDir bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, name)
+	return nil
+}
+
+// Access implements the VFS interface.
+func (m *MemVFS) Access(name string, flag AccessFlag) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.files[name]
+	return ok, nil
+}
+
+// FullPathname implements the VFS interface.
+func (m *MemVFS) FullPathname(name string) (string, error) {
+	return name, nil
+}
+
+func (f *memFile) Close() error {
+	f.Unlock(LockNone)
+	if f.deleteOnClose && len(f.name) > 0 {
+		f.vfs.mu.Lock()
+		if f.vfs.files[f.name] == f.d {
+			delete(f.vfs.files, f.name)
+		}
+		f.vfs.mu.Unlock()
+	}
+	return nil
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.d.mu.Lock()
+	defer f.d.mu.Unlock()
+	if off >= int64(len(f.d.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.d.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	f.d.mu.Lock()
+	defer f.d.mu.Unlock()
+	end := off + int64(len(p))
+	if end > int64(len(f.d.data)) {
+		if f.d.quota > 0 && end > f.d.quota {
+			return 0, ErrFull
+		}
+		if end > int64(cap(f.d.data)) {
+			data := make([]byte, end, 2*end)
+			copy(data, f.d.data)
+			f.d.data = data
+		} else {
+			f.d.data = f.d.data[:end]
+		}
+	}
+	return copy(f.d.data[off:], p), nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.d.mu.Lock()
+	defer f.d.mu.Unlock()
+	if size < int64(len(f.d.data)) {
+		f.d.data = f.d.data[:size]
+	} else if size > int64(len(f.d.data)) {
+		if f.d.quota > 0 && size > f.d.quota {
+			return ErrFull
+		}
+		f.d.data = append(f.d.data, make([]byte, size-int64(len(f.d.data)))...)
+	}
+	return nil
+}
+
+func (f *memFile) Sync(flags SyncFlag) error {
+	return nil
+}
+
+func (f *memFile) FileSize() (int64, error) {
+	f.d.mu.Lock()
+	defer f.d.mu.Unlock()
+	return int64(len(f.d.data)), nil
+}
+
+func (f *memFile) Lock(level LockLevel) error {
+	d := f.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if f.level >= level {
+		return nil
+	}
+	switch level {
+	case LockShared:
+		if d.pending || d.exclusive {
+			return ErrBusy
+		}
+		d.shared++
+	case LockReserved:
+		if d.reserved {
+			return ErrBusy
+		}
+		d.reserved = true
+		f.reserved = true
+	case LockExclusive:
+		if !f.pending {
+			if d.pending {
+				return ErrBusy
+			}
+			d.pending = true
+			f.pending = true
+		}
+		if d.shared > 1 {
+			return ErrBusy // keep the pending lock
+		}
+		d.exclusive = true
+	}
+	f.level = level
+	return nil
+}
+
+func (f *memFile) Unlock(level LockLevel) error {
+	d := f.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if f.level <= level && !f.pending {
+		return nil
+	}
+	if f.reserved {
+		d.reserved = false
+		f.reserved = false
+	}
+	if f.pending {
+		d.pending = false
+		f.pending = false
+	}
+	if f.level == LockExclusive {
+		d.exclusive = false
+	}
+	if level == LockNone && f.level >= LockShared {
+		d.shared--
+	}
+	if level < f.level {
+		f.level = level
+	}
+	return nil
+}
+
+func (f *memFile) CheckReservedLock() (bool, error) {
+	d := f.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.reserved || d.pending || d.exclusive, nil
+}
+
+func (f *memFile) SectorSize() int {
+	return 0
+}
+
+func (f *memFile) DeviceCharacteristics() DeviceCharacteristic {
+	return IocapAtomic | IocapPowersafeOverwrite | IocapSafeAppend | IocapSequential
+}
@@ -0,0 +1,73 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestMemVFS(t *testing.T) {
+	vfs := NewMemVFS()
+	checkNoError(t, RegisterVFS("memvfs_test", vfs, false), "couldn't register VFS: %s")
+	defer UnregisterVFS("memvfs_test")
+
+	c1, err := OpenVfs("test.db", "memvfs_test")
+	checkNoError(t, err, "couldn't open database: %s")
+	defer checkClose(c1, t)
+	checkNoError(t, c1.FastExec("CREATE TABLE test (x); INSERT INTO test VALUES (1), (2)"), "couldn't create table: %s")
+
+	c2, err := OpenVfs("file:test.db?vfs=memvfs_test", "", OpenURI, OpenReadWrite)
+	checkNoError(t, err, "couldn't open database: %s")
+	defer checkClose(c2, t)
+	var n int
+	checkNoError(t, c2.OneValue("SELECT count(*) FROM test", &n), "couldn't count: %s")
+	assert.Equal(t, 2, n)
+
+	// locking
+	checkNoError(t, c1.Begin(), "couldn't begin: %s")
+	checkNoError(t, c1.FastExec("INSERT INTO test VALUES (3)"), "couldn't insert: %s")
+	err = c2.FastExec("INSERT INTO test VALUES (4)")
+	assert.T(t, err != nil, "busy expected")
+	checkNoError(t, c1.Commit(), "couldn't commit: %s")
+	checkNoError(t, c2.OneValue("SELECT count(*) FROM test", &n), "couldn't count: %s")
+	assert.Equal(t, 3, n)
+
+	// extract / inject
+	content, ok := vfs.Bytes("test.db")
+	assert.T(t, ok, "file expected")
+	assert.Equal(t, "SQLite format 3\x00", string(content[:16]))
+	vfs.SetBytes("copy.db", content)
+	assert.Equal(t, []string{"copy.db", "test.db"}, vfs.Names())
+	c3, err := OpenVfs("copy.db", "memvfs_test", OpenReadOnly)
+	checkNoError(t, err, "couldn't open copy: %s")
+	defer checkClose(c3, t)
+	checkNoError(t, c3.OneValue("SELECT count(*) FROM test", &n), "couldn't count: %s")
+	assert.Equal(t, 3, n)
+
+	_, err = OpenVfs("missing.db", "memvfs_test", OpenReadOnly)
+	assert.T(t, err != nil, "open failure expected")
+}
+
+func TestMemVFSQuota(t *testing.T) {
+	vfs := NewMemVFS()
+	checkNoError(t, RegisterVFS("memvfs_quota", vfs, false), "couldn't register VFS: %s")
+	defer UnregisterVFS("memvfs_quota")
+	vfs.SetQuota("quota.db", 16*1024)
+
+	db, err := OpenVfs("quota.db", "memvfs_quota")
+	checkNoError(t, err, "couldn't open database: %s")
+	defer checkClose(db, t)
+	checkNoError(t, db.FastExec("CREATE TABLE test (x)"), "couldn't create table: %s")
+	err = db.FastExec("INSERT INTO test SELECT randomblob(1024) FROM (WITH RECURSIVE c(i) AS (SELECT 1 UNION ALL SELECT i+1 FROM c LIMIT 100) SELECT i FROM c)")
+	assert.T(t, err != nil, "quota error expected")
+	if cerr, ok := err.(ConnError); ok {
+		assert.Equal(t, ErrFull, cerr.Code())
+	}
+	size, _ := vfs.Size("quota.db")
+	assert.T(t, size <= 16*1024, "quota exceeded")
+}
@@ -66,6 +66,35 @@ func (c *Conn) Tables(dbName string) ([]string, error) {
 	return tables, nil
 }
 
+// TableExists returns true if the specified table exists (case insensitive).
+// The database name can be empty, "main", "temp" or the name of an attached database.
+func (c *Conn) TableExists(dbName, table string) (bool, error) {
+	return c.objectExists(dbName, "table", table)
+}
+
+// ViewExists returns true if the specified view exists (case insensitive).
+// The database name can be empty, "main", "temp" or the name of an attached database.
+func (c *Conn) ViewExists(dbName, view string) (bool, error) {
+	return c.objectExists(dbName, "view", view)
+}
+
+func (c *Conn) objectExists(dbName, typ, name string) (bool, error) {
+	var sql string
+	if len(dbName) == 0 {
+		sql = "SELECT 1 FROM sqlite_master WHERE type = ? AND name = ? COLLATE NOCASE"
+	} else if strings.EqualFold("temp", dbName) {
+		sql = "SELECT 1 FROM sqlite_temp_master WHERE type = ? AND name = ? COLLATE NOCASE"
+	} else {
+		sql = fmt.Sprintf("SELECT 1 FROM %s.sqlite_master WHERE type = ? AND name = ? COLLATE NOCASE", doubleQuote(dbName))
+	}
+	s, err := c.prepare(sql, typ, name)
+	if err != nil {
+		return false, err
+	}
+	defer s.finalize()
+	return s.Next()
+}
+
 // Views returns views from 'sqlite_master'/'sqlite_temp_master'.
 // The database name can be empty, "main", "temp" or the name of an attached database.
 func (c *Conn) Views(dbName string) ([]string, error) {
@@ -124,6 +153,62 @@ func (c *Conn) Indexes(dbName string) (map[string]string, error) {
 	return indexes, nil
 }
 
+// Trigger is the description of one trigger
+// See Conn.Triggers/TableTriggers
+type Trigger struct {
+	Name  string
+	Table string // table or view the trigger is attached to
+	SQL   string // CREATE TRIGGER statement
+}
+
+// Triggers returns triggers from 'sqlite_master'/'sqlite_temp_master'.
+// The database name can be empty, "main", "temp" or the name of an attached database.
+func (c *Conn) Triggers(dbName string) ([]Trigger, error) {
+	return c.triggers(dbName, "")
+}
+
+// TableTriggers returns the triggers attached to the given table (or view).
+// No error is returned if the table does not exist.
+// The database name can be empty, "main", "temp" or the name of an attached database.
+func (c *Conn) TableTriggers(dbName, table string) ([]Trigger, error) {
+	return c.triggers(dbName, table)
+}
+
+func (c *Conn) triggers(dbName, table string) ([]Trigger, error) {
+	var sql string
+	if len(dbName) == 0 {
+		sql = "SELECT name, tbl_name, sql FROM sqlite_master WHERE type = 'trigger'"
+	} else if strings.EqualFold("temp", dbName) {
+		sql = "SELECT name, tbl_name, sql FROM sqlite_temp_master WHERE type = 'trigger'"
+	} else {
+		sql = fmt.Sprintf("SELECT name, tbl_name, sql FROM %s.sqlite_master WHERE type = 'trigger'", doubleQuote(dbName))
+	}
+	var args []interface{}
+	if len(table) > 0 {
+		sql += " AND tbl_name = ? COLLATE NOCASE"
+		args = append(args, table)
+	}
+	sql += " ORDER BY 1"
+	s, err := c.prepare(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer s.finalize()
+	var triggers = make([]Trigger, 0, 5)
+	err = s.Select(func(s *Stmt) (err error) {
+		t := Trigger{}
+		if err = s.Scan(&t.Name, &t.Table, &t.SQL); err != nil {
+			return
+		}
+		triggers = append(triggers, t)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return triggers, nil
+}
+
 // Column is the description of one table's column
 // See Conn.Columns/IndexColumns
 type Column struct {
@@ -5,6 +5,8 @@
 package sqlite_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/bmizerany/assert"
@@ -75,6 +77,109 @@ func TestViews(t *testing.T) {
 	assert.T(t, err != nil)
 }
 
+func TestTableExists(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+	checkNoError(t, db.FastExec("CREATE VIEW myview AS SELECT 1"), "error creating view: %s")
+
+	exists, err := db.TableExists("", "TEST")
+	checkNoError(t, err, "error looking for table: %s")
+	assert.T(t, exists, "table expected")
+	exists, err = db.TableExists("main", "myview")
+	checkNoError(t, err, "error looking for table: %s")
+	assert.T(t, !exists, "no table expected")
+	exists, err = db.TableExists("temp", "test")
+	checkNoError(t, err, "error looking for table: %s")
+	assert.T(t, !exists, "no temp table expected")
+
+	exists, err = db.ViewExists("main", "myview")
+	checkNoError(t, err, "error looking for view: %s")
+	assert.T(t, exists, "view expected")
+
+	_, err = db.TableExists("bim", "test")
+	assert.T(t, err != nil)
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `"test"`, QuoteIdentifier("test"))
+	assert.Equal(t, `"a""b"`, QuoteIdentifier(`a"b`))
+	assert.Equal(t, `''`, QuoteLiteral(""))
+	assert.Equal(t, `'it''s'`, QuoteLiteral("it's"))
+	assert.Equal(t, `"'"""`, QuoteIdentifier(`'"`))
+	assert.Equal(t, `it''s ""`, EscapeString(`it's ""`))
+	assert.Equal(t, `('a'||char(0)||'''b'||char(0)||'')`, QuoteLiteral("a\x00'b\x00"))
+	assert.Equal(t, `X''`, BlobLiteral(nil))
+	assert.Equal(t, `X'00FF0A'`, BlobLiteral([]byte{0, 255, 10}))
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	for _, s := range []string{"", "it's", "''", "a\x00b", "\x00'\x00", `"`} {
+		var h string
+		err := db.OneValue("SELECT hex("+QuoteLiteral(s)+")", &h)
+		checkNoError(t, err, "error selecting literal: %s")
+		assert.Equal(t, fmt.Sprintf("%X", s), h, s)
+		if !strings.ContainsRune(s, 0) {
+			var text string
+			err = db.OneValue("SELECT '"+EscapeString(s)+"'", &text)
+			checkNoError(t, err, "error selecting literal: %s")
+			assert.Equal(t, s, text)
+		}
+	}
+	b := []byte{0, 'a', '\'', 0}
+	var blob []byte
+	err := db.OneValue("SELECT "+BlobLiteral(b), &blob)
+	checkNoError(t, err, "error selecting blob literal: %s")
+	assert.Equal(t, b, blob)
+
+	name := `a "weird" 'name'`
+	err = db.FastExec("CREATE TABLE " + QuoteIdentifier(name) + " (" + QuoteIdentifier(`"`) + " TEXT)")
+	checkNoError(t, err, "error creating table: %s")
+	columns, err := db.Columns("", name)
+	checkNoError(t, err, "error listing columns: %s")
+	assert.Equal(t, 1, len(columns))
+	assert.Equal(t, `"`, columns[0].Name)
+}
+
+func TestKeywords(t *testing.T) {
+	if VersionNumber() < 3024000 {
+		t.Skipf("SQLite version too old (%d < %d)", VersionNumber(), 3024000)
+	}
+	n := KeywordCount()
+	assert.T(t, n > 100, "too few keywords")
+	for i := 0; i < n; i++ {
+		keyword := KeywordName(i)
+		assert.T(t, IsKeyword(keyword), keyword)
+	}
+	assert.Equal(t, "", KeywordName(n))
+	assert.T(t, IsKeyword("select"), "select")
+	assert.T(t, !IsKeyword("test"), "test")
+	assert.T(t, !IsKeyword(""), "empty")
+}
+
+func TestQualifiedName(t *testing.T) {
+	assert.Equal(t, `"test"`, QualifiedName("", "test"))
+	assert.Equal(t, `"my db"."a""b"`, QualifiedName("my db", `a"b`))
+
+	for _, name := range []string{"main.test", `"main"."test"`, "[main].`test`"} {
+		dbName, table, err := ParseQualifiedName(name)
+		checkNoError(t, err, "error parsing qualified name: %s")
+		assert.Equal(t, "main", dbName, name)
+		assert.Equal(t, "test", table, name)
+	}
+	dbName, table, err := ParseQualifiedName(`"a.""b"`)
+	checkNoError(t, err, "error parsing qualified name: %s")
+	assert.Equal(t, "", dbName)
+	assert.Equal(t, `a."b`, table)
+
+	for _, name := range []string{"", "main.", ".test", `"main`, "a.b.c", `"a"b`} {
+		_, _, err = ParseQualifiedName(name)
+		assert.Tf(t, err != nil, "error expected with %q", name)
+	}
+}
+
 func TestIndexes(t *testing.T) {
 	db := open(t)
 	defer checkClose(db, t)
@@ -98,6 +203,37 @@ func TestIndexes(t *testing.T) {
 	assert.T(t, err != nil)
 }
 
+func TestTriggers(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+
+	triggers, err := db.Triggers("")
+	checkNoError(t, err, "error looking for triggers: %s")
+	assert.Equal(t, 0, len(triggers), "trigger count")
+	checkNoError(t, db.Exec("CREATE TRIGGER trg AFTER INSERT ON test BEGIN SELECT 1; END"), "%s")
+
+	triggers, err = db.Triggers("main")
+	checkNoError(t, err, "error looking for triggers: %s")
+	assert.Equal(t, 1, len(triggers), "trigger count")
+	assert.Equal(t, "trg", triggers[0].Name, "trigger name")
+	assert.Equal(t, "test", triggers[0].Table, "trigger table")
+	assert.Equal(t, "CREATE TRIGGER trg AFTER INSERT ON test BEGIN SELECT 1; END", triggers[0].SQL, "trigger sql")
+
+	triggers, err = db.TableTriggers("", "test")
+	checkNoError(t, err, "error looking for triggers: %s")
+	assert.Equal(t, 1, len(triggers), "trigger count")
+	triggers, err = db.TableTriggers("", "other")
+	checkNoError(t, err, "error looking for triggers: %s")
+	assert.Equal(t, 0, len(triggers), "trigger count")
+
+	_, err = db.Triggers("temp")
+	checkNoError(t, err, "error looking for triggers: %s")
+
+	_, err = db.Triggers("bim")
+	assert.T(t, err != nil)
+}
+
 func TestColumns(t *testing.T) {
 	db := open(t)
 	defer checkClose(db, t)
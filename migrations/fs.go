@@ -0,0 +1,71 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build go1.16
+
+package migrations
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// FromFS loads migrations from the SQL files found in the directory dir of fsys (an embed.FS for example).
+// Files must be named VERSION_NAME.up.sql or VERSION_NAME.down.sql (like 0001_init.up.sql),
+// other files are ignored.
+func FromFS(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int32]*Migration)
+	var versions []int32
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		base := strings.TrimSuffix(name, ".sql")
+		var up bool
+		if strings.HasSuffix(base, ".up") {
+			up = true
+			base = strings.TrimSuffix(base, ".up")
+		} else if strings.HasSuffix(base, ".down") {
+			base = strings.TrimSuffix(base, ".down")
+		} else {
+			continue
+		}
+		var label string
+		if i := strings.IndexByte(base, '_'); i >= 0 {
+			base, label = base[:i], base[i+1:]
+		}
+		version, err := strconv.ParseInt(base, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name: %q", name)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[int32(version)]
+		if !ok {
+			m = &Migration{Version: int32(version), Name: label}
+			byVersion[m.Version] = m
+			versions = append(versions, m.Version)
+		}
+		if up {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(versions))
+	for _, version := range versions {
+		migrations = append(migrations, *byVersion[version])
+	}
+	return migrations, nil
+}
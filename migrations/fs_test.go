@@ -0,0 +1,42 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build go1.16
+
+package migrations_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite/migrations"
+)
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_index.up.sql":    {Data: []byte("CREATE INDEX test_idx ON test(data);")},
+		"sql/0002_index.down.sql":  {Data: []byte("DROP INDEX test_idx;")},
+		"sql/0001_init.up.sql":     {Data: []byte("CREATE TABLE test (data TEXT);")},
+		"sql/0001_init.down.sql":   {Data: []byte("DROP TABLE test;")},
+		"sql/README.md":            {Data: []byte("ignored")},
+		"sql/0003_ignored.sql.bak": {Data: []byte("ignored")},
+	}
+	migrations, err := FromFS(fsys, "sql")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 2, len(migrations))
+	m, err := New(migrations...)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, "init", m.Migrations[0].Name)
+	assert.Equal(t, "DROP INDEX test_idx;", m.Migrations[1].Down)
+
+	db := open(t)
+	defer db.Close()
+	applied, err := m.Up(db)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 2, len(applied))
+
+	_, err = FromFS(fstest.MapFS{"sql/x_init.up.sql": {Data: []byte("")}}, "sql")
+	assert.T(t, err != nil, "invalid version expected")
+}
@@ -0,0 +1,325 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package migrations applies ordered schema migrations to an SQLite database.
+//
+// Each migration is executed in its own (immediate) transaction and the current version
+// is tracked either in the database header (PRAGMA user_version) or in a dedicated table.
+//
+//	m, err := migrations.New(
+//		migrations.Migration{Version: 1, Name: "init", Up: "CREATE TABLE ...", Down: "DROP TABLE ..."},
+//		migrations.Migration{Version: 2, Name: "index", Up: "CREATE INDEX ...", Down: "DROP INDEX ..."},
+//	)
+//	// TODO error handling
+//	applied, err := m.Up(db)
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gwenn/gosqlite"
+)
+
+// Migration is one step of the schema evolution.
+type Migration struct {
+	Version int32 // strictly positive and unique
+	Name    string
+	// Up and Down are SQL scripts (statements separated by semicolons).
+	// They are ignored when the corresponding function is specified.
+	Up, Down         string
+	UpFunc, DownFunc func(c *sqlite.Conn) error
+	// DisableForeignKeys turns foreign key enforcement off while the migration is applied
+	// (needed by the generalized ALTER TABLE procedure: http://sqlite.org/lang_altertable.html#otheralter).
+	// Foreign keys are checked (PRAGMA foreign_key_check) before the transaction is committed
+	// and enforcement is restored afterwards.
+	DisableForeignKeys bool
+	// DeferForeignKeys postpones foreign key checks until the migration transaction is committed
+	// (See http://sqlite.org/pragma.html#pragma_defer_foreign_keys).
+	DeferForeignKeys bool
+}
+
+func (m Migration) String() string {
+	if len(m.Name) == 0 {
+		return fmt.Sprintf("%d", m.Version)
+	}
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// Store enumerates the ways the current version is persisted.
+type Store int
+
+// Version stores
+const (
+	UserVersion Store = iota // PRAGMA user_version
+	Table                    // one row per applied migration in Migrator.TableName
+)
+
+// DefaultTableName is the name of the table used by the Table store when Migrator.TableName is empty.
+const DefaultTableName = "schema_migrations"
+
+// Migrator applies a set of migrations.
+type Migrator struct {
+	Migrations []Migration // sorted by version
+	Store      Store
+	TableName  string // see DefaultTableName
+	// DryRun makes Up/To apply the migrations in a single transaction which is always rolled back.
+	DryRun bool
+}
+
+// New checks and sorts the specified migrations.
+// The current version is stored in user_version by default.
+func New(migrations ...Migration) (*Migrator, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Sort(byVersion(sorted))
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("invalid migration version: %d", m.Version)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version: %d", m.Version)
+		}
+		if len(m.Up) == 0 && m.UpFunc == nil {
+			return nil, fmt.Errorf("no up script for migration %s", m)
+		}
+	}
+	return &Migrator{Migrations: sorted}, nil
+}
+
+func (m *Migrator) tableName() string {
+	if len(m.TableName) == 0 {
+		return DefaultTableName
+	}
+	return m.TableName
+}
+
+// Version returns the version of the last migration applied (zero when there is none).
+func (m *Migrator) Version(c *sqlite.Conn) (int32, error) {
+	if m.Store == UserVersion {
+		return c.UserVersion("")
+	}
+	exists, err := c.TableExists("", m.tableName())
+	if err != nil || !exists {
+		return 0, err
+	}
+	var version int32
+	err = c.OneValue(fmt.Sprintf("SELECT coalesce(max(version), 0) FROM %s", sqlite.QuoteIdentifier(m.tableName())), &version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Pending returns the migrations not yet applied.
+func (m *Migrator) Pending(c *sqlite.Conn) ([]Migration, error) {
+	current, err := m.Version(c)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, mig := range m.Migrations {
+		if mig.Version > current {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies all pending migrations.
+// The migrations successfully applied are returned (even when an error occurs).
+func (m *Migrator) Up(c *sqlite.Conn) ([]Migration, error) {
+	if len(m.Migrations) == 0 {
+		return nil, nil
+	}
+	return m.To(c, m.Migrations[len(m.Migrations)-1].Version)
+}
+
+// To migrates up or down to the specified version (zero reverts all the migrations).
+// The migrations successfully applied (or reverted) are returned (even when an error occurs).
+func (m *Migrator) To(c *sqlite.Conn, version int32) ([]Migration, error) {
+	if version != 0 && m.index(version) < 0 {
+		return nil, fmt.Errorf("unknown migration version: %d", version)
+	}
+	current, err := m.Version(c)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := m.plan(current, version)
+	if err != nil || len(steps) == 0 {
+		return nil, err
+	}
+	if m.DryRun {
+		return m.dryRun(c, steps)
+	}
+	var done []Migration
+	for _, s := range steps {
+		if err = m.run(c, s); err != nil {
+			return done, fmt.Errorf("migration %s failed: %s", s.Migration, err)
+		}
+		done = append(done, s.Migration)
+	}
+	return done, nil
+}
+
+// step is a migration to be applied (up) or reverted (down).
+type step struct {
+	Migration
+	up       bool
+	previous int32 // version after a down step
+}
+
+func (m *Migrator) index(version int32) int {
+	for i, mig := range m.Migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Migrator) plan(current, target int32) ([]step, error) {
+	var steps []step
+	if target >= current {
+		for _, mig := range m.Migrations {
+			if mig.Version > current && mig.Version <= target {
+				steps = append(steps, step{Migration: mig, up: true})
+			}
+		}
+		return steps, nil
+	}
+	for i := len(m.Migrations) - 1; i >= 0; i-- {
+		mig := m.Migrations[i]
+		if mig.Version > current || mig.Version <= target {
+			continue
+		}
+		if len(mig.Down) == 0 && mig.DownFunc == nil {
+			return nil, fmt.Errorf("migration %s cannot be reverted", mig)
+		}
+		var previous int32
+		if i > 0 {
+			previous = m.Migrations[i-1].Version
+		}
+		steps = append(steps, step{Migration: mig, previous: previous})
+	}
+	return steps, nil
+}
+
+// run applies one step in its own transaction.
+func (m *Migrator) run(c *sqlite.Conn, s step) error {
+	if s.DisableForeignKeys {
+		restore, err := disableForeignKeys(c)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+	return c.Transaction(sqlite.Immediate, func(c *sqlite.Conn) error {
+		return m.apply(c, s)
+	})
+}
+
+var errDryRun = errors.New("dry run")
+
+// dryRun applies all steps in one transaction which is rolled back.
+func (m *Migrator) dryRun(c *sqlite.Conn, steps []step) ([]Migration, error) {
+	for _, s := range steps {
+		if s.DisableForeignKeys {
+			restore, err := disableForeignKeys(c)
+			if err != nil {
+				return nil, err
+			}
+			defer restore()
+			break
+		}
+	}
+	var done []Migration
+	err := c.Transaction(sqlite.Immediate, func(c *sqlite.Conn) error {
+		for _, s := range steps {
+			if err := m.apply(c, s); err != nil {
+				return fmt.Errorf("migration %s failed: %s", s.Migration, err)
+			}
+			done = append(done, s.Migration)
+		}
+		return errDryRun
+	})
+	if err == errDryRun {
+		err = nil
+	}
+	return done, err
+}
+
+func (m *Migrator) apply(c *sqlite.Conn, s step) error {
+	if s.DeferForeignKeys {
+		if err := c.FastExec("PRAGMA defer_foreign_keys=ON"); err != nil {
+			return err
+		}
+	}
+	var err error
+	if s.up && s.UpFunc != nil {
+		err = s.UpFunc(c)
+	} else if s.up {
+		err = c.FastExec(s.Up)
+	} else if s.DownFunc != nil {
+		err = s.DownFunc(c)
+	} else {
+		err = c.FastExec(s.Down)
+	}
+	if err != nil {
+		return err
+	}
+	if s.DisableForeignKeys {
+		violations, err := c.ForeignKeyCheck("", "")
+		if err != nil {
+			return err
+		} else if len(violations) > 0 {
+			return fmt.Errorf("%d foreign key violation(s) (first in table %q, rowid %d)", len(violations), violations[0].Table, violations[0].RowID)
+		}
+	}
+	return m.setVersion(c, s)
+}
+
+func (m *Migrator) setVersion(c *sqlite.Conn, s step) error {
+	if m.Store == UserVersion {
+		if s.up {
+			return c.SetUserVersion("", s.Version)
+		}
+		return c.SetUserVersion("", s.previous)
+	}
+	table := sqlite.QuoteIdentifier(m.tableName())
+	if !s.up {
+		return c.Exec(fmt.Sprintf("DELETE FROM %s WHERE version = ?", table), s.Version)
+	}
+	err := c.FastExec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version INTEGER PRIMARY KEY NOT NULL, name TEXT NOT NULL,"+
+		" applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP)", table))
+	if err != nil {
+		return err
+	}
+	return c.Exec(fmt.Sprintf("INSERT INTO %s (version, name) VALUES (?, ?)", table), s.Version, s.Name)
+}
+
+// disableForeignKeys turns foreign key enforcement off (outside of any transaction)
+// and returns a function which restores the previous setting.
+func disableForeignKeys(c *sqlite.Conn) (restore func(), err error) {
+	if c.InTransaction() {
+		return nil, errors.New("foreign keys cannot be disabled inside a transaction")
+	}
+	enabled, err := c.IsFKeyEnabled()
+	if err != nil || !enabled {
+		return func() {}, err
+	}
+	if _, err = c.EnableFKey(false); err != nil {
+		return nil, err
+	}
+	return func() {
+		c.EnableFKey(true)
+	}, nil
+}
+
+type byVersion []Migration
+
+func (s byVersion) Len() int           { return len(s) }
+func (s byVersion) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byVersion) Less(i, j int) bool { return s[i].Version < s[j].Version }
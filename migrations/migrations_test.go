@@ -0,0 +1,144 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package migrations_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/gwenn/gosqlite"
+	. "github.com/gwenn/gosqlite/migrations"
+)
+
+func open(t *testing.T) *sqlite.Conn {
+	db, err := sqlite.Open(":memory:")
+	assert.Tf(t, err == nil, "%v", err)
+	return db
+}
+
+var testMigrations = []Migration{
+	{Version: 2, Name: "child", Up: "CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent(id));",
+		Down: "DROP TABLE child;"},
+	{Version: 1, Name: "parent", Up: "CREATE TABLE parent (id INTEGER PRIMARY KEY, name TEXT);",
+		Down: "DROP TABLE parent;"},
+	{Version: 3, Name: "rename", DisableForeignKeys: true, UpFunc: func(c *sqlite.Conn) error {
+		return c.FastExec("CREATE TABLE new_parent (id INTEGER PRIMARY KEY, label TEXT);" +
+			"INSERT INTO new_parent SELECT id, name FROM parent;" +
+			"DROP TABLE parent;" +
+			"ALTER TABLE new_parent RENAME TO parent;")
+	}},
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Migration{Version: 0, Up: "SELECT 1"})
+	assert.T(t, err != nil, "invalid version expected")
+	_, err = New(Migration{Version: 1, Up: "SELECT 1"}, Migration{Version: 1, Up: "SELECT 1"})
+	assert.T(t, err != nil, "duplicate version expected")
+	_, err = New(Migration{Version: 1})
+	assert.T(t, err != nil, "missing script expected")
+	m, err := New(testMigrations...)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, int32(1), m.Migrations[0].Version)
+	assert.Equal(t, "1_parent", m.Migrations[0].String())
+}
+
+func TestUpAndDown(t *testing.T) {
+	for _, store := range []Store{UserVersion, Table} {
+		db := open(t)
+		m, err := New(testMigrations[:2]...)
+		assert.Tf(t, err == nil, "%v", err)
+		m.Store = store
+
+		applied, err := m.Up(db)
+		assert.Tf(t, err == nil, "%v", err)
+		assert.Equal(t, 2, len(applied))
+		version, err := m.Version(db)
+		assert.Tf(t, err == nil, "%v", err)
+		assert.Equal(t, int32(2), version)
+		pending, err := m.Pending(db)
+		assert.Tf(t, err == nil, "%v", err)
+		assert.Equal(t, 0, len(pending))
+
+		reverted, err := m.To(db, 1)
+		assert.Tf(t, err == nil, "%v", err)
+		assert.Equal(t, 1, len(reverted))
+		assert.Equal(t, int32(2), reverted[0].Version)
+		exists, err := db.TableExists("", "child")
+		assert.Tf(t, err == nil, "%v", err)
+		assert.T(t, !exists, "child table dropped")
+		version, err = m.Version(db)
+		assert.Tf(t, err == nil, "%v", err)
+		assert.Equal(t, int32(1), version)
+
+		_, err = m.To(db, 0)
+		assert.Tf(t, err == nil, "%v", err)
+		version, err = m.Version(db)
+		assert.Tf(t, err == nil, "%v", err)
+		assert.Equal(t, int32(0), version)
+		db.Close()
+	}
+}
+
+func TestFailure(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+	m, err := New(testMigrations[1], Migration{Version: 2, Up: "CREATE TABLE test (data TEXT); INSERT INTO unknown VALUES (1);"})
+	assert.Tf(t, err == nil, "%v", err)
+	applied, err := m.Up(db)
+	assert.T(t, err != nil, "error expected")
+	assert.Equal(t, 1, len(applied))
+	exists, err := db.TableExists("", "test")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.T(t, !exists, "migration rolled back")
+	_, err = m.To(db, 0)
+	assert.Tf(t, err == nil, "%v", err)
+
+	m, err = New(Migration{Version: 1, Up: "SELECT 1"})
+	assert.Tf(t, err == nil, "%v", err)
+	_, err = m.Up(db)
+	assert.Tf(t, err == nil, "%v", err)
+	_, err = m.To(db, 0)
+	assert.T(t, err != nil, "irreversible migration")
+}
+
+func TestDryRun(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+	m, err := New(testMigrations...)
+	assert.Tf(t, err == nil, "%v", err)
+	m.DryRun = true
+	applied, err := m.Up(db)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 3, len(applied))
+	version, err := m.Version(db)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, int32(0), version)
+	exists, err := db.TableExists("", "parent")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.T(t, !exists, "dry run rolled back")
+}
+
+func TestDisableForeignKeys(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+	_, err := db.EnableFKey(true)
+	assert.Tf(t, err == nil, "%v", err)
+	m, err := New(testMigrations...)
+	assert.Tf(t, err == nil, "%v", err)
+	_, err = m.To(db, 2)
+	assert.Tf(t, err == nil, "%v", err)
+	err = db.FastExec("INSERT INTO parent VALUES (1, 'p'); INSERT INTO child VALUES (1, 1);")
+	assert.Tf(t, err == nil, "%v", err)
+
+	_, err = m.Up(db)
+	assert.Tf(t, err == nil, "%v", err)
+	enabled, err := db.IsFKeyEnabled()
+	assert.Tf(t, err == nil, "%v", err)
+	assert.T(t, enabled, "foreign keys enforcement restored")
+	var label string
+	err = db.OneValue("SELECT label FROM parent WHERE id = 1", &label)
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, "p", label)
+}
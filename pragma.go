@@ -12,6 +12,7 @@ import "C"
 import (
 	"fmt"
 	"io"
+	"time"
 )
 
 // IntegrityCheck checks database integrity.
@@ -37,6 +38,37 @@ func (c *Conn) IntegrityCheck(dbName string, max int, quick bool) error {
 	return nil
 }
 
+// IntegrityViolations checks database integrity and returns all the problems found
+// (at most max, nil when the database is ok).
+// Database name is optional (default is 'main').
+// (See http://www.sqlite.org/pragma.html#pragma_integrity_check
+// and http://www.sqlite.org/pragma.html#pragma_quick_check)
+func (c *Conn) IntegrityViolations(dbName string, max int, quick bool) ([]string, error) {
+	var prefix string
+	if quick {
+		prefix = "quick"
+	} else {
+		prefix = "integrity"
+	}
+	s, err := c.prepare(pragma(dbName, fmt.Sprintf("%s_check(%d)", prefix, max)))
+	if err != nil {
+		return nil, err
+	}
+	defer s.finalize()
+	var violations []string
+	err = s.Select(func(s *Stmt) error {
+		msg, _ := s.ScanText(0)
+		if msg != "ok" {
+			violations = append(violations, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return violations, nil
+}
+
 // Encoding returns the text encoding used by the specified database.
 // Database name is optional (default is 'main').
 // (See http://sqlite.org/pragma.html#pragma_encoding)
@@ -135,6 +167,62 @@ func (c *Conn) SetSynchronous(dbName string, mode int) error {
 	return c.FastExec(pragma(dbName, fmt.Sprintf("synchronous=%d", mode)))
 }
 
+// AutoVacuumMode enumerates auto-vacuum modes.
+type AutoVacuumMode int32
+
+// Auto-vacuum modes
+const (
+	AutoVacuumNone        AutoVacuumMode = 0
+	AutoVacuumFull        AutoVacuumMode = 1
+	AutoVacuumIncremental AutoVacuumMode = 2
+)
+
+// AutoVacuum queries the auto-vacuum status in the database.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html#pragma_auto_vacuum)
+func (c *Conn) AutoVacuum(dbName string) (AutoVacuumMode, error) {
+	var mode int32
+	err := c.oneValue(pragma(dbName, "auto_vacuum"), &mode)
+	if err != nil {
+		return -1, err
+	}
+	return AutoVacuumMode(mode), nil
+}
+
+// SetAutoVacuum changes the auto-vacuum status in the database.
+// Changing from or to AutoVacuumNone is effective only before the first table is created or after a Vacuum.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html#pragma_auto_vacuum)
+func (c *Conn) SetAutoVacuum(dbName string, mode AutoVacuumMode) error {
+	return c.FastExec(pragma(dbName, fmt.Sprintf("auto_vacuum=%d", mode)))
+}
+
+// IncrementalVacuum removes up to 'pages' pages from the freelist (all pages if 'pages' is zero or negative).
+// Only effective when auto-vacuum mode is AutoVacuumIncremental.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html#pragma_incremental_vacuum)
+func (c *Conn) IncrementalVacuum(dbName string, pages int) error {
+	return c.FastExec(pragma(dbName, fmt.Sprintf("incremental_vacuum(%d)", pages)))
+}
+
+// VacuumInto creates a vacuumed copy of the database into the specified file (which must not exist or be empty).
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/lang_vacuum.html#vacuuminto)
+func (c *Conn) VacuumInto(dbName, filename string) error {
+	var sql string
+	if len(dbName) == 0 {
+		sql = "VACUUM INTO ?"
+	} else {
+		sql = fmt.Sprintf("VACUUM %s INTO ?", doubleQuote(dbName))
+	}
+	s, err := c.prepare(sql, filename)
+	if err != nil {
+		return err
+	}
+	defer s.finalize()
+	return s.exec()
+}
+
 // FkViolation is the description of one foreign key constraint violation.
 type FkViolation struct {
 	Table  string
@@ -222,6 +310,26 @@ func (c *Conn) SetApplicationID(dbName string, id int) error {
 	return c.FastExec(pragma(dbName, fmt.Sprintf("application_id=%d", id)))
 }
 
+// UserVersion queries the "user version" integer located into the database header.
+// It is not used internally by SQLite and is usually used by applications to track the schema version.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html#pragma_user_version)
+func (c *Conn) UserVersion(dbName string) (int32, error) {
+	var version int32
+	err := c.oneValue(pragma(dbName, "user_version"), &version)
+	if err != nil {
+		return -1, err
+	}
+	return version, nil
+}
+
+// SetUserVersion changes the "user version".
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html#pragma_user_version)
+func (c *Conn) SetUserVersion(dbName string, version int32) error {
+	return c.FastExec(pragma(dbName, fmt.Sprintf("user_version=%d", version)))
+}
+
 // MMapSize queries the maximum number of bytes that are set aside for memory-mapped I/O.
 // Database name is optional (default is 'main').
 // (See http://www.sqlite.org/pragma.html#pragma_mmap_size and http://sqlite.org/mmap.html)
@@ -249,6 +357,338 @@ func (c *Conn) SetMMapSize(dbName string, size int64) (int64, error) {
 	return newSize, nil
 }
 
+// DatabaseStats reports the storage used by one database.
+// See Conn.DatabaseStats
+type DatabaseStats struct {
+	PageCount     int64
+	PageSize      int64
+	FreelistCount int64         // number of unused pages
+	Objects       []ObjectStats // nil when the DBSTAT virtual table is not available
+}
+
+// Size returns the size of the database in bytes.
+func (s DatabaseStats) Size() int64 {
+	return s.PageCount * s.PageSize
+}
+
+// ObjectStats reports the storage used by one table or index (including overflow pages).
+type ObjectStats struct {
+	Name    string // table or index name
+	Pages   int64  // number of pages used
+	Payload int64  // bytes of payload stored
+	Unused  int64  // unused bytes in pages
+}
+
+// DatabaseStats gathers page_count, page_size, freelist_count
+// and, when SQLite is compiled with SQLITE_ENABLE_DBSTAT_VTAB, per-table/per-index statistics.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html#pragma_page_count and http://sqlite.org/dbstat.html)
+func (c *Conn) DatabaseStats(dbName string) (DatabaseStats, error) {
+	var stats DatabaseStats
+	if err := c.oneValue(pragma(dbName, "page_count"), &stats.PageCount); err != nil {
+		return stats, err
+	}
+	if err := c.oneValue(pragma(dbName, "page_size"), &stats.PageSize); err != nil {
+		return stats, err
+	}
+	if err := c.oneValue(pragma(dbName, "freelist_count"), &stats.FreelistCount); err != nil {
+		return stats, err
+	}
+	if !CompileOptionUsed("ENABLE_DBSTAT_VTAB") {
+		return stats, nil
+	}
+	if len(dbName) == 0 {
+		dbName = "main"
+	}
+	s, err := c.prepare("SELECT name, count(*), sum(payload), sum(unused) FROM dbstat WHERE schema = ? GROUP BY name ORDER BY name", dbName)
+	if err != nil {
+		return stats, err
+	}
+	defer s.finalize()
+	stats.Objects = make([]ObjectStats, 0, 20)
+	err = s.execQuery(func(s *Stmt) (err error) {
+		o := ObjectStats{}
+		if err = s.Scan(&o.Name, &o.Pages, &o.Payload, &o.Unused); err != nil {
+			return
+		}
+		stats.Objects = append(stats.Objects, o)
+		return
+	})
+	if err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// PragmaInt queries the integer value of the specified pragma.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html)
+func (c *Conn) PragmaInt(dbName, name string) (int64, error) {
+	if err := c.checkPragmaName(name); err != nil {
+		return -1, err
+	}
+	var value int64
+	err := c.oneValue(pragma(dbName, name), &value)
+	if err != nil {
+		return -1, err
+	}
+	return value, nil
+}
+
+// SetPragmaInt changes the integer value of the specified pragma.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html)
+func (c *Conn) SetPragmaInt(dbName, name string, value int64) error {
+	if err := c.checkPragmaName(name); err != nil {
+		return err
+	}
+	return c.FastExec(pragma(dbName, fmt.Sprintf("%s=%d", name, value)))
+}
+
+// PragmaText queries the text value of the specified pragma.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html)
+func (c *Conn) PragmaText(dbName, name string) (string, error) {
+	if err := c.checkPragmaName(name); err != nil {
+		return "", err
+	}
+	var value string
+	err := c.oneValue(pragma(dbName, name), &value)
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// SetPragmaText changes the text value of the specified pragma.
+// The value is quoted as an SQL literal.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html)
+func (c *Conn) SetPragmaText(dbName, name, value string) error {
+	if err := c.checkPragmaName(name); err != nil {
+		return err
+	}
+	return c.FastExec(pragma(dbName, name+Mprintf("=%Q", value)))
+}
+
+// PragmaBool queries the boolean value of the specified pragma.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html)
+func (c *Conn) PragmaBool(dbName, name string) (bool, error) {
+	if err := c.checkPragmaName(name); err != nil {
+		return false, err
+	}
+	var value bool
+	err := c.oneValue(pragma(dbName, name), &value)
+	if err != nil {
+		return false, err
+	}
+	return value, nil
+}
+
+// SetPragmaBool changes the boolean value of the specified pragma.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html)
+func (c *Conn) SetPragmaBool(dbName, name string, value bool) error {
+	if err := c.checkPragmaName(name); err != nil {
+		return err
+	}
+	return c.FastExec(pragma(dbName, fmt.Sprintf("%s=%t", name, value)))
+}
+
+// checkPragmaName ensures that name is a plain identifier (no injection is possible).
+func (c *Conn) checkPragmaName(name string) error {
+	if len(name) == 0 {
+		return c.specificError("invalid pragma name: %q", name)
+	}
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		if !(ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || (i > 0 && '0' <= ch && ch <= '9')) {
+			return c.specificError("invalid pragma name: %q", name)
+		}
+	}
+	return nil
+}
+
+// PageCacheSize queries the suggested maximum number of database disk pages held in memory
+// (or the amount of memory in kibibytes when negative).
+// Not to be confused with the prepared statements cache (see Conn.CacheSize).
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html#pragma_cache_size)
+func (c *Conn) PageCacheSize(dbName string) (int, error) {
+	var size int
+	err := c.oneValue(pragma(dbName, "cache_size"), &size)
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// SetPageCacheSize changes the suggested maximum number of database disk pages held in memory.
+// If the size is negative, the number of pages is adjusted to use approximately abs(size*1024) bytes of memory.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html#pragma_cache_size)
+func (c *Conn) SetPageCacheSize(dbName string, size int) error {
+	return c.FastExec(pragma(dbName, fmt.Sprintf("cache_size=%d", size)))
+}
+
+// TempStore enumerates the locations of temporary tables and indices.
+type TempStore int32
+
+// Temporary storage locations
+const (
+	TempStoreDefault TempStore = 0 // compile-time default (SQLITE_TEMP_STORE)
+	TempStoreFile    TempStore = 1
+	TempStoreMemory  TempStore = 2
+)
+
+// TempStore queries where temporary tables and indices are stored (for the connection).
+// (See http://sqlite.org/pragma.html#pragma_temp_store)
+func (c *Conn) TempStore() (TempStore, error) {
+	var store int32
+	err := c.oneValue("PRAGMA temp_store", &store)
+	if err != nil {
+		return -1, err
+	}
+	return TempStore(store), nil
+}
+
+// SetTempStore changes where temporary tables and indices are stored (for the connection).
+// (See http://sqlite.org/pragma.html#pragma_temp_store)
+func (c *Conn) SetTempStore(store TempStore) error {
+	return c.FastExec(fmt.Sprintf("PRAGMA temp_store=%d", store))
+}
+
+// WalAutoCheckpoint queries the write-ahead log auto-checkpoint interval (in pages) for the connection.
+// Zero or a negative value means that auto-checkpointing is disabled.
+// (See http://sqlite.org/pragma.html#pragma_wal_autocheckpoint)
+func (c *Conn) WalAutoCheckpoint() (int, error) {
+	var n int
+	err := c.oneValue("PRAGMA wal_autocheckpoint", &n)
+	if err != nil {
+		return -1, err
+	}
+	return n, nil
+}
+
+// SetWalAutoCheckpoint configures the write-ahead log auto-checkpoint interval (in pages) for the connection.
+// If n is zero or negative, auto-checkpointing is disabled.
+// (See http://sqlite.org/c3ref/wal_autocheckpoint.html)
+func (c *Conn) SetWalAutoCheckpoint(n int) error {
+	return c.error(C.sqlite3_wal_autocheckpoint(c.db, C.int(n)), "Conn.SetWalAutoCheckpoint")
+}
+
+// JournalSizeLimit queries the maximum size (in bytes) of rollback journals and WAL files left in the file-system after transactions or checkpoints.
+// A negative value means no limit.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html#pragma_journal_size_limit)
+func (c *Conn) JournalSizeLimit(dbName string) (int64, error) {
+	var limit int64
+	err := c.oneValue(pragma(dbName, "journal_size_limit"), &limit)
+	if err != nil {
+		return -1, err
+	}
+	return limit, nil
+}
+
+// SetJournalSizeLimit changes the maximum size (in bytes) of rollback journals and WAL files left in the file-system.
+// A negative value means no limit.
+// Database name is optional (default is 'main').
+// Returns the new limit.
+// (See http://sqlite.org/pragma.html#pragma_journal_size_limit)
+func (c *Conn) SetJournalSizeLimit(dbName string, limit int64) (int64, error) {
+	var newLimit int64
+	err := c.oneValue(pragma(dbName, fmt.Sprintf("journal_size_limit=%d", limit)), &newLimit)
+	if err != nil {
+		return -1, err
+	}
+	return newLimit, nil
+}
+
+// GetBusyTimeout returns the busy timeout currently in force (zero when there is none).
+// The value is queried with PRAGMA busy_timeout when available (SQLite >= 3.7.15)
+// otherwise the duration set by Conn.BusyTimeout is returned.
+// PRAGMA busy_timeout reports none when the counting handler installed by Conn.BusyTimeout is in force:
+// its timeout is returned instead.
+// (See http://sqlite.org/pragma.html#pragma_busy_timeout)
+func (c *Conn) GetBusyTimeout() (time.Duration, error) {
+	if VersionNumber() < 3007015 {
+		return c.busyTimeout, nil
+	}
+	var ms int64
+	err := c.oneValue("PRAGMA busy_timeout", &ms)
+	if err != nil {
+		return c.busyTimeout, err
+	} else if ms == 0 && c.busyHandler == nil {
+		return c.busyTimerTimeout(), nil
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// SecureDeleteMode enumerates the ways deleted content is overwritten.
+type SecureDeleteMode int32
+
+// Secure delete modes
+const (
+	SecureDeleteOff  SecureDeleteMode = 0
+	SecureDeleteOn   SecureDeleteMode = 1 // deleted content is overwritten with zeros
+	SecureDeleteFast SecureDeleteMode = 2 // deleted content is overwritten only if it does not increase I/O (SQLite >= 3.20)
+)
+
+// SecureDelete queries whether deleted content is overwritten with zeros.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/pragma.html#pragma_secure_delete)
+func (c *Conn) SecureDelete(dbName string) (SecureDeleteMode, error) {
+	var mode int32
+	err := c.oneValue(pragma(dbName, "secure_delete"), &mode)
+	if err != nil {
+		return -1, err
+	}
+	return SecureDeleteMode(mode), nil
+}
+
+// SetSecureDelete changes whether deleted content is overwritten with zeros.
+// Database name is optional (default is 'main' and all attached databases).
+// Returns the new mode.
+// (See http://sqlite.org/pragma.html#pragma_secure_delete)
+func (c *Conn) SetSecureDelete(dbName string, mode SecureDeleteMode) (SecureDeleteMode, error) {
+	var v string
+	switch mode {
+	case SecureDeleteOff:
+		v = "0"
+	case SecureDeleteOn:
+		v = "1"
+	case SecureDeleteFast:
+		v = "FAST"
+	default:
+		return -1, c.specificError("invalid secure delete mode: %d", mode)
+	}
+	var newMode int32
+	err := c.oneValue(pragma(dbName, "secure_delete="+v), &newMode)
+	if err != nil {
+		return -1, err
+	}
+	return SecureDeleteMode(newMode), nil
+}
+
+// CellSizeCheck queries whether database b-tree pages are checked for sanity as they are read (for the connection).
+// (See http://sqlite.org/pragma.html#pragma_cell_size_check)
+func (c *Conn) CellSizeCheck() (bool, error) {
+	var check bool
+	err := c.oneValue("PRAGMA cell_size_check", &check)
+	if err != nil {
+		return false, err
+	}
+	return check, nil
+}
+
+// SetCellSizeCheck enables or disables additional sanity checking on database b-tree pages (for the connection).
+// Corruption is detected earlier, at the cost of a little CPU.
+// (See http://sqlite.org/pragma.html#pragma_cell_size_check)
+func (c *Conn) SetCellSizeCheck(on bool) error {
+	return c.FastExec(fmt.Sprintf("PRAGMA cell_size_check=%t", on))
+}
+
 func pragma(dbName, pragmaName string) string {
 	if len(dbName) == 0 {
 		return "PRAGMA " + pragmaName
@@ -266,6 +706,7 @@ func (c *Conn) oneValue(query string, value interface{}) error { // no cache
 	}
 	defer s.finalize()
 	rv := C.sqlite3_step(s.stmt)
+	c.countBusy(rv)
 	err = Errno(rv)
 	if err == Row {
 		return s.Scan(value)
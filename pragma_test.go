@@ -153,6 +153,25 @@ func TestApplicationID(t *testing.T) {
 	assert.T(t, err != nil)
 }
 
+func TestUserVersion(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	version, err := db.UserVersion("")
+	checkNoError(t, err, "error getting user version: %s")
+	assert.Equalf(t, int32(0), version, "got: %d; want: %d", version, 0)
+
+	err = db.SetUserVersion("main", -42)
+	checkNoError(t, err, "error setting user version: %s")
+
+	version, err = db.UserVersion("main")
+	checkNoError(t, err, "error getting user version: %s")
+	assert.Equalf(t, int32(-42), version, "got: %d; want: %d", version, -42)
+
+	_, err = db.UserVersion("bim")
+	assert.T(t, err != nil)
+}
+
 func TestForeignKeyCheck(t *testing.T) {
 	if VersionNumber() < 3007016 {
 		t.Skipf("SQLite version too old (%d < %d)", VersionNumber(), 3007016)
@@ -218,3 +237,180 @@ func TestMMapSize(t *testing.T) {
 	checkNoError(t, err, "error while setting mmap size: %s")
 	assert.Equal(t, int64(1048576), newSize)
 }
+
+func TestDatabaseStats(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+
+	stats, err := db.DatabaseStats("")
+	checkNoError(t, err, "error while reading database stats: %s")
+	assert.T(t, stats.PageCount > 0, "page count")
+	assert.T(t, stats.PageSize > 0, "page size")
+	assert.Equal(t, stats.PageCount*stats.PageSize, stats.Size(), "database size")
+	if CompileOptionUsed("ENABLE_DBSTAT_VTAB") {
+		assert.T(t, len(stats.Objects) > 0, "expected per-table statistics")
+	} else {
+		assert.T(t, stats.Objects == nil, "unexpected per-table statistics")
+	}
+
+	_, err = db.DatabaseStats("bim")
+	assert.T(t, err != nil, "expected error with unknown database")
+}
+
+func TestGenericPragma(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.SetPragmaInt("", "cache_size", -4000)
+	checkNoError(t, err, "error setting pragma: %s")
+	size, err := db.PragmaInt("main", "cache_size")
+	checkNoError(t, err, "error reading pragma: %s")
+	assert.Equal(t, int64(-4000), size, "cache_size")
+
+	err = db.SetPragmaBool("", "foreign_keys", true)
+	checkNoError(t, err, "error setting pragma: %s")
+	fk, err := db.PragmaBool("", "foreign_keys")
+	checkNoError(t, err, "error reading pragma: %s")
+	assert.T(t, fk, "foreign_keys")
+
+	err = db.SetPragmaText("", "journal_mode", "off")
+	checkNoError(t, err, "error setting pragma: %s")
+	mode, err := db.PragmaText("", "journal_mode")
+	checkNoError(t, err, "error reading pragma: %s")
+	assert.Equal(t, "off", mode, "journal_mode")
+
+	_, err = db.PragmaInt("", "cache_size; DROP TABLE test")
+	assert.T(t, err != nil, "expected invalid pragma name error")
+	err = db.SetPragmaText("", "", "x")
+	assert.T(t, err != nil, "expected invalid pragma name error")
+}
+
+func TestAutoVacuum(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	mode, err := db.AutoVacuum("")
+	checkNoError(t, err, "error while getting auto vacuum mode: %s")
+	assert.Equal(t, AutoVacuumNone, mode)
+	err = db.SetAutoVacuum("main", AutoVacuumIncremental)
+	checkNoError(t, err, "error while setting auto vacuum mode: %s")
+	mode, err = db.AutoVacuum("main")
+	checkNoError(t, err, "error while getting auto vacuum mode: %s")
+	assert.Equal(t, AutoVacuumIncremental, mode)
+
+	createTable(db, t)
+	checkNoError(t, db.FastExec("INSERT INTO test (a_string) SELECT hex(randomblob(1000)) FROM test"), "%s")
+	err = db.IncrementalVacuum("", 10)
+	checkNoError(t, err, "error while vacuuming: %s")
+	err = db.IncrementalVacuum("main", 0)
+	checkNoError(t, err, "error while vacuuming: %s")
+}
+
+func TestVacuumInto(t *testing.T) {
+	if VersionNumber() < 3027000 {
+		t.Skipf("SQLite version too old (%d < %d)", VersionNumber(), 3027000)
+	}
+	f, err := ioutil.TempFile("", "gosqlite.db.")
+	checkNoError(t, err, "couldn't create temp file: %s")
+	checkNoError(t, f.Close(), "couldn't close temp file: %s")
+	defer os.Remove(f.Name())
+
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+	err = db.VacuumInto("", f.Name())
+	checkNoError(t, err, "error while vacuuming into: %s")
+
+	copy, err := Open(f.Name())
+	checkNoError(t, err, "couldn't open database file: %s")
+	defer checkClose(copy, t)
+	exists, err := copy.TableExists("", "test")
+	checkNoError(t, err, "error looking for table: %s")
+	assert.T(t, exists, "table expected in copy")
+}
+
+func TestPageCacheSize(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.SetPageCacheSize("", 100)
+	checkNoError(t, err, "error while setting cache size: %s")
+	size, err := db.PageCacheSize("main")
+	checkNoError(t, err, "error while getting cache size: %s")
+	assert.Equal(t, 100, size)
+	err = db.SetPageCacheSize("main", -2000)
+	checkNoError(t, err, "error while setting cache size: %s")
+	size, err = db.PageCacheSize("")
+	checkNoError(t, err, "error while getting cache size: %s")
+	assert.Equal(t, -2000, size)
+}
+
+func TestTempStore(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.SetTempStore(TempStoreMemory)
+	checkNoError(t, err, "error while setting temp store: %s")
+	store, err := db.TempStore()
+	checkNoError(t, err, "error while getting temp store: %s")
+	assert.Equal(t, TempStoreMemory, store)
+}
+
+func TestSecureDelete(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	mode, err := db.SetSecureDelete("", SecureDeleteOn)
+	checkNoError(t, err, "error while setting secure delete: %s")
+	assert.Equal(t, SecureDeleteOn, mode)
+	mode, err = db.SecureDelete("main")
+	checkNoError(t, err, "error while getting secure delete: %s")
+	assert.Equal(t, SecureDeleteOn, mode)
+	if VersionNumber() >= 3020000 {
+		mode, err = db.SetSecureDelete("main", SecureDeleteFast)
+		checkNoError(t, err, "error while setting secure delete: %s")
+		assert.Equal(t, SecureDeleteFast, mode)
+	}
+	_, err = db.SetSecureDelete("", SecureDeleteMode(3))
+	assert.T(t, err != nil, "error expected")
+}
+
+func TestCellSizeCheck(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.SetCellSizeCheck(true)
+	checkNoError(t, err, "error while enabling cell size check: %s")
+	check, err := db.CellSizeCheck()
+	checkNoError(t, err, "error while getting cell size check: %s")
+	assert.T(t, check, "expected cell size check enabled")
+}
+
+func TestWalAutoCheckpoint(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.SetWalAutoCheckpoint(500)
+	checkNoError(t, err, "error while setting wal auto-checkpoint: %s")
+	n, err := db.WalAutoCheckpoint()
+	checkNoError(t, err, "error while getting wal auto-checkpoint: %s")
+	assert.Equal(t, 500, n)
+	err = db.SetWalAutoCheckpoint(0)
+	checkNoError(t, err, "error while disabling wal auto-checkpoint: %s")
+	n, err = db.WalAutoCheckpoint()
+	checkNoError(t, err, "error while getting wal auto-checkpoint: %s")
+	assert.Equal(t, 0, n)
+}
+
+func TestJournalSizeLimit(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	limit, err := db.SetJournalSizeLimit("", 1<<20)
+	checkNoError(t, err, "error while setting journal size limit: %s")
+	assert.Equal(t, int64(1<<20), limit)
+	limit, err = db.JournalSizeLimit("main")
+	checkNoError(t, err, "error while getting journal size limit: %s")
+	assert.Equal(t, int64(1<<20), limit)
+}
@@ -0,0 +1,218 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build rbu
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+#include <sqlite3rbu.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// The RBU extension is not part of the default SQLite build:
+// this file is compiled only with the "rbu" build tag and requires an SQLite library
+// built with SQLITE_ENABLE_RBU (and the sqlite3rbu.h header from ext/rbu).
+
+// RBUState enumerates the stages of an RBU update.
+// (See http://sqlite.org/rbu.html)
+type RBUState int32
+
+// RBU update stages
+const (
+	RBUStateOAL        RBUState = 1 // building the *-oal file
+	RBUStateMove       RBUState = 2 // moving the *-oal file to the *-wal file
+	RBUStateCheckpoint RBUState = 3 // checkpointing the *-wal file into the target database
+	RBUStateDone       RBUState = 4
+	RBUStateError      RBUState = 5
+)
+
+func (s RBUState) String() string {
+	switch s {
+	case RBUStateOAL:
+		return "oal"
+	case RBUStateMove:
+		return "move"
+	case RBUStateCheckpoint:
+		return "checkpoint"
+	case RBUStateDone:
+		return "done"
+	case RBUStateError:
+		return "error"
+	}
+	return fmt.Sprintf("RBUState(%d)", int32(s))
+}
+
+// RBUError is returned when an RBU operation fails.
+type RBUError struct {
+	code Errno
+	msg  string
+}
+
+// Code returns the SQLite error code.
+func (e RBUError) Code() Errno {
+	return e.code
+}
+
+func (e RBUError) Error() string {
+	if len(e.msg) > 0 {
+		return fmt.Sprintf("%s (%s)", e.msg, e.code.Error())
+	}
+	return e.code.Error()
+}
+
+// RBU is a resumable bulk update (or vacuum) of a target database.
+// (See http://sqlite.org/rbu.html)
+type RBU struct {
+	rbu *C.sqlite3rbu
+}
+
+// OpenRBU prepares the update of the target database with the content of the rbu database.
+// The state is saved in the rbu database unless a state database is specified.
+// An interrupted update is resumed when the same databases are opened again.
+// (See http://sqlite.org/rbu.html#sqlite3rbu_open)
+func OpenRBU(target, rbu, state string) (*RBU, error) {
+	zTarget := C.CString(target)
+	defer C.free(unsafe.Pointer(zTarget))
+	zRbu := C.CString(rbu)
+	defer C.free(unsafe.Pointer(zRbu))
+	var zState *C.char
+	if len(state) > 0 {
+		zState = C.CString(state)
+		defer C.free(unsafe.Pointer(zState))
+	}
+	return newRBU(C.sqlite3rbu_open(zTarget, zRbu, zState))
+}
+
+// OpenRBUVacuum prepares a resumable vacuum of the target database.
+// The state is saved in the specified state database (or in <target>-vacuum when empty).
+// (See http://sqlite.org/rbu.html#rbu_vacuum)
+func OpenRBUVacuum(target, state string) (*RBU, error) {
+	zTarget := C.CString(target)
+	defer C.free(unsafe.Pointer(zTarget))
+	var zState *C.char
+	if len(state) > 0 {
+		zState = C.CString(state)
+		defer C.free(unsafe.Pointer(zState))
+	}
+	return newRBU(C.sqlite3rbu_vacuum(zTarget, zState))
+}
+
+func newRBU(p *C.sqlite3rbu) (*RBU, error) {
+	if p == nil {
+		return nil, ErrNoMem
+	}
+	r := &RBU{p}
+	if r.State() == RBUStateError {
+		return nil, r.Close()
+	}
+	return r, nil
+}
+
+// Step performs one step of the update.
+// Returns true when the update is complete.
+// (See sqlite3rbu_step)
+func (r *RBU) Step() (done bool, err error) {
+	if r == nil || r.rbu == nil {
+		return false, errors.New("nil or closed sqlite RBU")
+	}
+	rv := C.sqlite3rbu_step(r.rbu)
+	if rv == C.SQLITE_DONE {
+		return true, nil
+	} else if rv != C.SQLITE_OK {
+		return false, RBUError{code: Errno(rv), msg: "RBU step failed (see Close for details)"}
+	}
+	return false, nil
+}
+
+// SaveState saves the progress of the update so that it can be resumed after a crash.
+// (See sqlite3rbu_savestate)
+func (r *RBU) SaveState() error {
+	if r == nil || r.rbu == nil {
+		return errors.New("nil or closed sqlite RBU")
+	}
+	if rv := C.sqlite3rbu_savestate(r.rbu); rv != C.SQLITE_OK {
+		return RBUError{code: Errno(rv)}
+	}
+	return nil
+}
+
+// Run performs up to maxSteps steps (all steps when maxSteps is zero or negative)
+// and closes the update, saving its state.
+// Returns true when the update is complete.
+func (r *RBU) Run(maxSteps int) (done bool, err error) {
+	for i := 0; maxSteps <= 0 || i < maxSteps; i++ {
+		if done, err = r.Step(); done || err != nil {
+			break
+		}
+	}
+	if cerr := r.Close(); err == nil || cerr != nil {
+		err = cerr
+	}
+	return done && err == nil, err
+}
+
+// State returns the current stage of the update.
+// (See sqlite3rbu_state)
+func (r *RBU) State() RBUState {
+	return RBUState(C.sqlite3rbu_state(r.rbu))
+}
+
+// Progress returns the number of b-tree operations (or pages checkpointed) performed so far.
+// (See sqlite3rbu_progress)
+func (r *RBU) Progress() int64 {
+	return int64(C.sqlite3rbu_progress(r.rbu))
+}
+
+// StageProgress returns the progress of the OAL and checkpoint stages in permyriads (0 to 10000).
+// (See sqlite3rbu_bp_progress)
+func (r *RBU) StageProgress() (oal, checkpoint int) {
+	var one, two C.int
+	C.sqlite3rbu_bp_progress(r.rbu, &one, &two)
+	return int(one), int(two)
+}
+
+// SetTempSizeLimit limits the temporary disk space used by the update (zero means no limit).
+// A negative value only queries the current limit.
+// (See sqlite3rbu_temp_size_limit)
+func (r *RBU) SetTempSizeLimit(n int64) int64 {
+	return int64(C.sqlite3rbu_temp_size_limit(r.rbu, C.sqlite3_int64(n)))
+}
+
+// TempSize returns the temporary disk space currently used by the update.
+// (See sqlite3rbu_temp_size)
+func (r *RBU) TempSize() int64 {
+	return int64(C.sqlite3rbu_temp_size(r.rbu))
+}
+
+// Close saves the state of the update (if not complete) and releases all resources.
+// (See sqlite3rbu_close)
+func (r *RBU) Close() error {
+	if r == nil {
+		return errors.New("nil sqlite RBU")
+	}
+	if r.rbu == nil {
+		return nil
+	}
+	var zErrmsg *C.char
+	rv := C.sqlite3rbu_close(r.rbu, &zErrmsg)
+	r.rbu = nil
+	if rv == C.SQLITE_OK || rv == C.SQLITE_DONE {
+		return nil
+	}
+	err := RBUError{code: Errno(rv)}
+	if zErrmsg != nil {
+		err.msg = C.GoString(zErrmsg)
+		C.sqlite3_free(unsafe.Pointer(zErrmsg))
+	}
+	return err
+}
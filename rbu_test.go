@@ -0,0 +1,55 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build rbu
+
+package sqlite_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestRBU(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosqlite-rbu-")
+	checkNoError(t, err, "couldn't create temp dir: %s")
+	defer os.RemoveAll(dir)
+	target, rbu := filepath.Join(dir, "target.db"), filepath.Join(dir, "rbu.db")
+
+	db, err := Open(target)
+	checkNoError(t, err, "couldn't open target: %s")
+	checkNoError(t, db.FastExec("CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)"), "couldn't create table: %s")
+	checkClose(db, t)
+	db, err = Open(rbu)
+	checkNoError(t, err, "couldn't open rbu: %s")
+	err = db.FastExec(`CREATE TABLE data_t (id, name, rbu_control);
+		INSERT INTO data_t VALUES (1, 'one', 0), (2, 'two', 0);`)
+	checkNoError(t, err, "couldn't create data table: %s")
+	checkClose(db, t)
+
+	r, err := OpenRBU(target, rbu, "")
+	checkNoError(t, err, "couldn't open RBU: %s")
+	done, err := r.Run(1) // interrupted
+	checkNoError(t, err, "couldn't run RBU: %s")
+	assert.T(t, !done, "RBU expected to be interrupted")
+
+	r, err = OpenRBU(target, rbu, "") // resumed
+	checkNoError(t, err, "couldn't open RBU: %s")
+	assert.T(t, r.Progress() >= 0)
+	done, err = r.Run(0)
+	checkNoError(t, err, "couldn't run RBU: %s")
+	assert.T(t, done, "RBU expected to be complete")
+
+	db, err = Open(target)
+	checkNoError(t, err, "couldn't open target: %s")
+	defer checkClose(db, t)
+	var count int
+	checkNoError(t, db.OneValue("SELECT count(*) FROM t", &count), "couldn't count: %s")
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,16 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build recover
+
+#include <sqlite3.h>
+#include <sqlite3recover.h>
+// warning: incompatible pointer types passing
+//#include "_cgo_export.h"
+
+extern int goXRecoverSQL(void *udp, const char *zSql);
+
+sqlite3_recover *goSqlite3RecoverInitSQL(sqlite3 *db, const char *zDb, void *udp) {
+	return sqlite3_recover_init_sql(db, zDb, goXRecoverSQL, udp);
+}
@@ -0,0 +1,105 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build recover
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+#include <sqlite3recover.h>
+#include <stdlib.h>
+
+sqlite3_recover *goSqlite3RecoverInitSQL(sqlite3 *db, const char *zDb, void *udp);
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// The recovery extension is not part of the SQLite library:
+// this file is compiled only with the "recover" build tag and requires
+// ext/recover (sqlite3recover.c, dbdata.c and sqlite3recover.h) from SQLite >= 3.40
+// to be compiled into the library (with SQLITE_ENABLE_DBPAGE_VTAB).
+
+// RecoverOptions customizes the recovery.
+// (See http://sqlite.org/recovery.html)
+type RecoverOptions struct {
+	// LostAndFound is the name of the table where orphaned rows are stored (orphaned rows are discarded when empty).
+	LostAndFound string
+	// FreelistCorrupt tells that the freelist should be ignored (its pages may then be recovered as orphaned rows).
+	FreelistCorrupt bool
+	// NoRowids tells that rowid values are not preserved (except for INTEGER PRIMARY KEY columns).
+	NoRowids bool
+	// SlowIndexes creates indexes before the data is inserted.
+	SlowIndexes bool
+}
+
+type sqliteRecover struct {
+	f   func(sql string) error
+	err error
+}
+
+//export goXRecoverSQL
+func goXRecoverSQL(udp unsafe.Pointer, zSql *C.char) C.int {
+	arg := (*sqliteRecover)(udp)
+	if arg.err = arg.f(C.GoString(zSql)); arg.err != nil {
+		return C.SQLITE_ABORT
+	}
+	return C.SQLITE_OK
+}
+
+// RecoverTo recovers as much data as possible from the (corrupt) database dbName
+// and writes it to a new database (which must not exist).
+// (See http://sqlite.org/recovery.html)
+func (c *Conn) RecoverTo(dbName, dest string, opts *RecoverOptions) error {
+	zDb := C.CString(dbName)
+	defer C.free(unsafe.Pointer(zDb))
+	zURI := C.CString(dest)
+	defer C.free(unsafe.Pointer(zURI))
+	return c.recover(C.sqlite3_recover_init(c.db, zDb, zURI), opts, nil)
+}
+
+// RecoverSQL recovers as much data as possible from the (corrupt) database dbName
+// and calls f with each SQL statement which would recreate it.
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+// (See http://sqlite.org/recovery.html)
+func (c *Conn) RecoverSQL(dbName string, opts *RecoverOptions, f func(sql string) error) error {
+	zDb := C.CString(dbName)
+	defer C.free(unsafe.Pointer(zDb))
+	arg := &sqliteRecover{f: f}
+	return c.recover(C.goSqlite3RecoverInitSQL(c.db, zDb, unsafe.Pointer(arg)), opts, arg)
+}
+
+func (c *Conn) recover(p *C.sqlite3_recover, opts *RecoverOptions, arg *sqliteRecover) error {
+	if p == nil {
+		return ErrNoMem
+	}
+	if opts != nil {
+		var zLostAndFound *C.char
+		if len(opts.LostAndFound) > 0 {
+			zLostAndFound = C.CString(opts.LostAndFound)
+			defer C.free(unsafe.Pointer(zLostAndFound))
+		}
+		C.sqlite3_recover_config(p, C.SQLITE_RECOVER_LOST_AND_FOUND, unsafe.Pointer(zLostAndFound))
+		freelistCorrupt, rowids, slowIndexes := btocint(opts.FreelistCorrupt), btocint(!opts.NoRowids), btocint(opts.SlowIndexes)
+		C.sqlite3_recover_config(p, C.SQLITE_RECOVER_FREELIST_CORRUPT, unsafe.Pointer(&freelistCorrupt))
+		C.sqlite3_recover_config(p, C.SQLITE_RECOVER_ROWIDS, unsafe.Pointer(&rowids))
+		C.sqlite3_recover_config(p, C.SQLITE_RECOVER_SLOWINDEXES, unsafe.Pointer(&slowIndexes))
+	}
+	C.sqlite3_recover_run(p)
+	var err error
+	if rv := C.sqlite3_recover_errcode(p); rv != C.SQLITE_OK {
+		if arg != nil && arg.err != nil {
+			err = arg.err
+		} else {
+			err = c.specificError("recovery failed: %s (%s)", C.GoString(C.sqlite3_recover_errmsg(p)), Errno(rv))
+		}
+	}
+	if rv := C.sqlite3_recover_finish(p); rv != C.SQLITE_OK && err == nil {
+		err = c.error(rv, "Conn.Recover")
+	}
+	return err
+}
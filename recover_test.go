@@ -0,0 +1,49 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build recover
+
+package sqlite_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestRecover(t *testing.T) {
+	skipIfCgoCheckActive(t)
+	dir, err := ioutil.TempDir("", "gosqlite-recover-")
+	checkNoError(t, err, "couldn't create temp dir: %s")
+	defer os.RemoveAll(dir)
+
+	db, err := Open(filepath.Join(dir, "src.db"))
+	checkNoError(t, err, "couldn't open source: %s")
+	defer checkClose(db, t)
+	err = db.FastExec(`CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT);
+		INSERT INTO t (name) VALUES ('one'), ('two');`)
+	checkNoError(t, err, "couldn't create table: %s")
+
+	dest := filepath.Join(dir, "dest.db")
+	checkNoError(t, db.RecoverTo("main", dest, &RecoverOptions{LostAndFound: "lost_and_found"}), "couldn't recover: %s")
+	recovered, err := Open(dest)
+	checkNoError(t, err, "couldn't open recovered database: %s")
+	defer checkClose(recovered, t)
+	var count int
+	checkNoError(t, recovered.OneValue("SELECT count(*) FROM t", &count), "couldn't count: %s")
+	assert.Equal(t, 2, count)
+
+	var script []string
+	err = db.RecoverSQL("main", nil, func(sql string) error {
+		script = append(script, sql)
+		return nil
+	})
+	checkNoError(t, err, "couldn't recover: %s")
+	assert.T(t, strings.Contains(strings.Join(script, "\n"), "'two'"), "recovered data expected in SQL output")
+}
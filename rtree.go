@@ -0,0 +1,227 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RTreeBox is a bounding box (Min[i] <= Max[i] for each dimension).
+type RTreeBox struct {
+	ID       int64
+	Min, Max []float64
+}
+
+// RTreeMatch specifies how boxes are compared to the window of a query.
+type RTreeMatch int
+
+// R-Tree window queries
+const (
+	RTreeOverlaps RTreeMatch = iota // boxes overlapping the window
+	RTreeWithin                     // boxes entirely inside the window
+	RTreeContains                   // boxes containing the window
+)
+
+// RTreeVisibility tells how a node or an entry of an R-Tree relates to the region of a custom query.
+type RTreeVisibility int
+
+// R-Tree custom query visibilities
+const (
+	RTreeNotWithin    RTreeVisibility = iota // completely outside the query region
+	RTreePartlyWithin                        // partially overlapping the query region
+	RTreeFullyWithin                         // fully contained within the query region
+)
+
+// RTreeQueryInfo describes the node or the entry checked by an RTreeQueryFunc.
+// (See http://sqlite.org/rtree.html#custom_r_tree_queries)
+type RTreeQueryInfo struct {
+	Params       []float64 // parameters of the query function
+	Coords       []float64 // bounding box of the node or entry (min0, max0, min1, max1...)
+	Level        int       // 0 for entries, > 0 for nodes
+	MaxLevel     int       // level of the root node
+	Rowid        int64     // rowid of the entry (only meaningful for entries)
+	ParentScore  float64
+	ParentWithin RTreeVisibility
+	// Within must be set by the callback (initialized to ParentWithin).
+	// Nodes and entries which are RTreeNotWithin are discarded.
+	Within RTreeVisibility
+	// Score may be set by the callback (initialized to ParentScore):
+	// nodes and entries with lower scores are returned first.
+	Score float64
+}
+
+// RTreeQueryFunc is the signature of R-Tree custom query callbacks (see Conn.CreateRTreeQuery).
+// The returned error aborts the query (its code is used when it is an Errno).
+type RTreeQueryFunc func(info *RTreeQueryInfo) error
+
+type sqliteRTreeQuery struct {
+	c *Conn
+	f RTreeQueryFunc
+}
+
+// RTree gives typed access to an R-Tree virtual table.
+// (See http://sqlite.org/rtree.html)
+type RTree struct {
+	c       *Conn
+	dbName  string
+	table   string
+	columns []string // id, min0, max0, min1, max1...
+}
+
+// CreateRTree creates an R-Tree table with the specified number of dimensions (1 to 5)
+// and columns named id, min0, max0, min1, max1...
+// When integer is true, coordinates are stored as 32-bit signed integers (rtree_i32).
+func (c *Conn) CreateRTree(dbName, table string, dims int, integer bool) (*RTree, error) {
+	if dims < 1 || dims > 5 {
+		return nil, c.specificError("invalid number of R-Tree dimensions: %d", dims)
+	}
+	columns := []string{"id"}
+	for i := 0; i < dims; i++ {
+		columns = append(columns, fmt.Sprintf("min%d", i), fmt.Sprintf("max%d", i))
+	}
+	module := "rtree"
+	if integer {
+		module = "rtree_i32"
+	}
+	if err := c.FastExec(fmt.Sprintf("CREATE VIRTUAL TABLE %s USING %s(%s)", QualifiedName(dbName, table), module, strings.Join(columns, ", "))); err != nil {
+		return nil, err
+	}
+	return &RTree{c: c, dbName: dbName, table: table, columns: columns}, nil
+}
+
+// OpenRTree gives access to an existing R-Tree table with the specified number of dimensions.
+// Column names are retrieved from the table (auxiliary columns are ignored).
+func (c *Conn) OpenRTree(dbName, table string, dims int) (*RTree, error) {
+	columns, err := c.Columns(dbName, table)
+	if err != nil {
+		return nil, err
+	}
+	if dims < 1 || len(columns) < 1+2*dims {
+		return nil, c.specificError("%q is not an R-Tree table with %d dimension(s)", table, dims)
+	}
+	t := &RTree{c: c, dbName: dbName, table: table}
+	for _, column := range columns[:1+2*dims] {
+		t.columns = append(t.columns, column.Name)
+	}
+	return t, nil
+}
+
+// Dims returns the number of dimensions.
+func (t *RTree) Dims() int {
+	return (len(t.columns) - 1) / 2
+}
+
+func (t *RTree) checkBox(box RTreeBox) error {
+	if len(box.Min) != t.Dims() || len(box.Max) != t.Dims() {
+		return t.c.specificError("invalid box for R-Tree %q: %d dimension(s) expected", t.table, t.Dims())
+	}
+	return nil
+}
+
+// Insert inserts (or replaces) the box.
+// When box.ID is zero, a new id is generated and returned.
+func (t *RTree) Insert(box RTreeBox) (id int64, err error) {
+	if err = t.checkBox(box); err != nil {
+		return 0, err
+	}
+	args := make([]interface{}, 0, len(t.columns))
+	if box.ID == 0 {
+		args = append(args, nil)
+	} else {
+		args = append(args, box.ID)
+	}
+	for i := range box.Min {
+		args = append(args, box.Min[i], box.Max[i])
+	}
+	quoted := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		quoted[i] = QuoteIdentifier(column)
+	}
+	_, id, err = t.c.ExecResult(fmt.Sprintf("INSERT OR REPLACE INTO %s (%s) VALUES (%s)", QualifiedName(t.dbName, t.table),
+		strings.Join(quoted, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(t.columns)), ", ")), args...)
+	return id, err
+}
+
+// Delete deletes the box with the specified id.
+func (t *RTree) Delete(id int64) error {
+	return t.c.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", QualifiedName(t.dbName, t.table), QuoteIdentifier(t.columns[0])), id)
+}
+
+// Get returns the box with the specified id.
+func (t *RTree) Get(id int64) (box RTreeBox, found bool, err error) {
+	err = t.query(fmt.Sprintf("%s = ?", QuoteIdentifier(t.columns[0])), []interface{}{id}, func(b RTreeBox) error {
+		box, found = b, true
+		return nil
+	})
+	return
+}
+
+// Search calls f for each box matching the window (ordered by id).
+func (t *RTree) Search(window RTreeBox, match RTreeMatch, f func(box RTreeBox) error) error {
+	if err := t.checkBox(window); err != nil {
+		return err
+	}
+	var where []string
+	var args []interface{}
+	for i := range window.Min {
+		min, max := QuoteIdentifier(t.columns[1+2*i]), QuoteIdentifier(t.columns[2+2*i])
+		switch match {
+		case RTreeOverlaps:
+			where = append(where, max+" >= ?", min+" <= ?")
+			args = append(args, window.Min[i], window.Max[i])
+		case RTreeWithin:
+			where = append(where, min+" >= ?", max+" <= ?")
+			args = append(args, window.Min[i], window.Max[i])
+		case RTreeContains:
+			where = append(where, min+" <= ?", max+" >= ?")
+			args = append(args, window.Min[i], window.Max[i])
+		default:
+			return t.c.specificError("invalid R-Tree match: %d", match)
+		}
+	}
+	return t.query(strings.Join(where, " AND "), args, f)
+}
+
+// Match calls f for each box matching the R-Tree custom query function (ordered by id).
+//
+//	rt.Match("circle", []float64{x, y, radius}, f)
+//	// SELECT ... WHERE id MATCH circle(x, y, radius)
+func (t *RTree) Match(queryFunc string, params []float64, f func(box RTreeBox) error) error {
+	args := make([]interface{}, len(params))
+	for i, param := range params {
+		args[i] = param
+	}
+	return t.query(fmt.Sprintf("%s MATCH %s(%s)", QuoteIdentifier(t.columns[0]), QuoteIdentifier(queryFunc),
+		strings.TrimSuffix(strings.Repeat("?, ", len(params)), ", ")), args, f)
+}
+
+func (t *RTree) query(where string, args []interface{}, f func(box RTreeBox) error) error {
+	quoted := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		quoted[i] = QuoteIdentifier(column)
+	}
+	s, err := t.c.prepare(fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY 1", strings.Join(quoted, ", "), QualifiedName(t.dbName, t.table), where), args...)
+	if err != nil {
+		return err
+	}
+	defer s.finalize()
+	dims := t.Dims()
+	return s.Select(func(s *Stmt) (err error) {
+		box := RTreeBox{Min: make([]float64, dims), Max: make([]float64, dims)}
+		if box.ID, _, err = s.ScanInt64(0); err != nil {
+			return
+		}
+		for i := 0; i < dims; i++ {
+			if box.Min[i], _, err = s.ScanDouble(1 + 2*i); err != nil {
+				return
+			}
+			if box.Max[i], _, err = s.ScanDouble(2 + 2*i); err != nil {
+				return
+			}
+		}
+		return f(box)
+	})
+}
@@ -0,0 +1,16 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build rtree
+
+#include <sqlite3.h>
+#include "_cgo_export.h"
+
+static int cXRTreeQuery(sqlite3_rtree_query_info *info) {
+	return goXRTreeQuery(info);
+}
+
+int goSqlite3CreateRTreeQuery(sqlite3 *db, const char *zQueryFunc, void *pContext) {
+	return sqlite3_rtree_query_callback(db, zQueryFunc, cXRTreeQuery, pContext, goXRTreeQueryDestroy);
+}
@@ -0,0 +1,89 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build rtree
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+#include <stdlib.h>
+
+int goSqlite3CreateRTreeQuery(sqlite3 *db, const char *zQueryFunc, void *pContext);
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// The R-Tree extension is not part of the default SQLite build:
+// this file is compiled only with the "rtree" build tag and requires an SQLite library
+// built with SQLITE_ENABLE_RTREE (but not SQLITE_RTREE_INT_ONLY).
+
+func rtreeDoubles(p *C.sqlite3_rtree_dbl, n C.int) []float64 {
+	if n <= 0 {
+		return nil
+	}
+	values := make([]float64, n)
+	for i, v := range (*[1 << 16]C.sqlite3_rtree_dbl)(unsafe.Pointer(p))[:n:n] {
+		values[i] = float64(v)
+	}
+	return values
+}
+
+//export goXRTreeQuery
+func goXRTreeQuery(pInfo unsafe.Pointer) C.int {
+	info := (*C.sqlite3_rtree_query_info)(pInfo)
+	q := (*sqliteRTreeQuery)(info.pContext)
+	qi := &RTreeQueryInfo{
+		Params:       rtreeDoubles(info.aParam, info.nParam),
+		Coords:       rtreeDoubles(info.aCoord, info.nCoord),
+		Level:        int(info.iLevel),
+		MaxLevel:     int(info.mxLevel),
+		Rowid:        int64(info.iRowid),
+		ParentScore:  float64(info.rParentScore),
+		ParentWithin: RTreeVisibility(info.eParentWithin),
+	}
+	qi.Within, qi.Score = qi.ParentWithin, qi.ParentScore
+	if err := q.f(qi); err != nil {
+		if errno, ok := err.(Errno); ok {
+			return C.int(errno)
+		}
+		return C.SQLITE_ERROR
+	}
+	info.eWithin = C.int(qi.Within)
+	info.rScore = C.sqlite3_rtree_dbl(qi.Score)
+	return C.SQLITE_OK
+}
+
+//export goXRTreeQueryDestroy
+func goXRTreeQueryDestroy(pContext unsafe.Pointer) {
+	q := (*sqliteRTreeQuery)(pContext)
+	delete(q.c.rtreeQueries, q)
+}
+
+// CreateRTreeQuery registers an R-Tree custom query function (like a circular range query)
+// usable in MATCH expressions (see RTree.Match):
+//
+//	SELECT id FROM demo WHERE id MATCH circle(45.3, 22.9, 5.0)
+//
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+// (See http://sqlite.org/rtree.html#custom_r_tree_queries)
+func (c *Conn) CreateRTreeQuery(name string, f RTreeQueryFunc) error {
+	if f == nil {
+		return c.specificError("nil R-Tree query function: %q", name)
+	}
+	zName := C.CString(name)
+	defer C.free(unsafe.Pointer(zName))
+	// To make sure it is not gced, keep a reference in the connection
+	// (until SQLite releases the query by calling goXRTreeQueryDestroy).
+	q := &sqliteRTreeQuery{c, f}
+	if len(c.rtreeQueries) == 0 {
+		c.rtreeQueries = make(map[*sqliteRTreeQuery]struct{})
+	}
+	c.rtreeQueries[q] = struct{}{}
+	return c.error(C.goSqlite3CreateRTreeQuery(c.db, zName, unsafe.Pointer(q)), fmt.Sprintf("Conn.CreateRTreeQuery(%q)", name))
+}
@@ -0,0 +1,75 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build rtree
+
+package sqlite_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+// circle(x, y, radius) matches the points (boxes) whose center is inside the circle.
+func circle(info *RTreeQueryInfo) error {
+	if len(info.Params) != 3 {
+		return ErrError
+	}
+	x, y, r := info.Params[0], info.Params[1], info.Params[2]
+	if info.Level > 0 { // node: does its box intersect the circle?
+		dx := math.Max(0, math.Max(info.Coords[0]-x, x-info.Coords[1]))
+		dy := math.Max(0, math.Max(info.Coords[2]-y, y-info.Coords[3]))
+		if dx*dx+dy*dy > r*r {
+			info.Within = RTreeNotWithin
+		} else {
+			info.Within = RTreePartlyWithin
+		}
+		return nil
+	}
+	dx, dy := (info.Coords[0]+info.Coords[1])/2-x, (info.Coords[2]+info.Coords[3])/2-y
+	if d := math.Sqrt(dx*dx + dy*dy); d <= r {
+		info.Within = RTreeFullyWithin
+		info.Score = d
+	} else {
+		info.Within = RTreeNotWithin
+	}
+	return nil
+}
+
+func TestRTreeQuery(t *testing.T) {
+	skipIfCgoCheckActive(t)
+	db := open(t)
+	defer checkClose(db, t)
+
+	rt, err := db.CreateRTree("", "points", 2, false)
+	checkNoError(t, err, "couldn't create R-Tree: %s")
+	for i := 0; i < 100; i++ {
+		x, y := float64(i%10), float64(i/10)
+		_, err = rt.Insert(RTreeBox{ID: int64(i + 1), Min: []float64{x, y}, Max: []float64{x, y}})
+		checkNoError(t, err, "couldn't insert: %s")
+	}
+	err = db.CreateRTreeQuery("circle", circle)
+	checkNoError(t, err, "couldn't create R-Tree query: %s")
+
+	var ids []int64
+	err = rt.Match("circle", []float64{5, 5, 1}, func(box RTreeBox) error {
+		ids = append(ids, box.ID)
+		return nil
+	})
+	checkNoError(t, err, "couldn't match: %s")
+	assert.Equal(t, []int64{46, 55, 56, 57, 66}, ids)
+
+	var id int64
+	err = db.OneValue("SELECT id FROM points WHERE id MATCH circle(0.1, 0.1, 1)", &id)
+	checkNoError(t, err, "couldn't match: %s")
+	assert.Equal(t, int64(1), id)
+
+	err = rt.Match("circle", []float64{5, 5}, func(box RTreeBox) error {
+		return nil
+	})
+	assert.T(t, err != nil, "error expected")
+}
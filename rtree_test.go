@@ -0,0 +1,68 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestRTree(t *testing.T) {
+	if !GetFeatures().HasRTree {
+		t.Skip("R-Tree not enabled")
+	}
+	db := open(t)
+	defer checkClose(db, t)
+
+	rt, err := db.CreateRTree("", "boxes", 2, false)
+	checkNoError(t, err, "couldn't create R-Tree: %s")
+	assert.Equal(t, 2, rt.Dims())
+
+	id, err := rt.Insert(RTreeBox{Min: []float64{0, 0}, Max: []float64{10, 10}})
+	checkNoError(t, err, "couldn't insert: %s")
+	assert.Equal(t, int64(1), id)
+	_, err = rt.Insert(RTreeBox{ID: 5, Min: []float64{20, 20}, Max: []float64{30, 30}})
+	checkNoError(t, err, "couldn't insert: %s")
+	_, err = rt.Insert(RTreeBox{ID: 7, Min: []float64{2, 2}, Max: []float64{3, 3}})
+	checkNoError(t, err, "couldn't insert: %s")
+	_, err = rt.Insert(RTreeBox{Min: []float64{0}, Max: []float64{1}})
+	assert.T(t, err != nil, "error expected")
+
+	search := func(window RTreeBox, match RTreeMatch) []int64 {
+		var ids []int64
+		err := rt.Search(window, match, func(box RTreeBox) error {
+			ids = append(ids, box.ID)
+			return nil
+		})
+		checkNoError(t, err, "couldn't search: %s")
+		return ids
+	}
+	assert.Equal(t, []int64{1, 7}, search(RTreeBox{Min: []float64{1, 1}, Max: []float64{5, 5}}, RTreeOverlaps))
+	assert.Equal(t, []int64{7}, search(RTreeBox{Min: []float64{1, 1}, Max: []float64{5, 5}}, RTreeWithin))
+	assert.Equal(t, []int64{1}, search(RTreeBox{Min: []float64{1, 1}, Max: []float64{5, 5}}, RTreeContains))
+	assert.Equal(t, []int64(nil), search(RTreeBox{Min: []float64{40, 40}, Max: []float64{50, 50}}, RTreeOverlaps))
+
+	box, found, err := rt.Get(5)
+	checkNoError(t, err, "couldn't get: %s")
+	assert.T(t, found)
+	assert.Equal(t, RTreeBox{ID: 5, Min: []float64{20, 20}, Max: []float64{30, 30}}, box)
+
+	checkNoError(t, rt.Delete(5), "couldn't delete: %s")
+	_, found, err = rt.Get(5)
+	checkNoError(t, err, "couldn't get: %s")
+	assert.T(t, !found)
+
+	err = db.FastExec("CREATE VIRTUAL TABLE ints USING rtree_i32(id, x0, x1, +label)")
+	checkNoError(t, err, "couldn't create R-Tree: %s")
+	rt, err = db.OpenRTree("", "ints", 1)
+	checkNoError(t, err, "couldn't open R-Tree: %s")
+	_, err = rt.Insert(RTreeBox{ID: 1, Min: []float64{-5}, Max: []float64{5}})
+	checkNoError(t, err, "couldn't insert: %s")
+	assert.Equal(t, []int64{1}, search(RTreeBox{Min: []float64{0}, Max: []float64{1}}, RTreeOverlaps))
+	_, err = db.OpenRTree("", "ints", 2)
+	assert.T(t, err != nil, "error expected")
+}
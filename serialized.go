@@ -0,0 +1,129 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"sync"
+)
+
+// SerializedConn wraps a connection so that it can be shared by many goroutines:
+// each operation holds a mutex for its whole duration, so that results like changes count
+// or last insert rowid cannot be mixed up between goroutines.
+// Statements must not escape the callbacks (Do, Select, Transaction).
+// Only the most common operations are wrapped: use Do for anything else
+// (prepared statements, pragmas, hooks, functions, backups...).
+//
+//	sc := NewSerializedConn(db)
+//	rowid, err := sc.Insert("INSERT INTO test (name) VALUES (?)", "Bart")
+//	err = sc.Do(func(c *Conn) error {
+//		// c is used exclusively here
+//	})
+type SerializedConn struct {
+	mu sync.Mutex
+	c  *Conn
+}
+
+// NewSerializedConn wraps c which must not be used directly anymore.
+func NewSerializedConn(c *Conn) *SerializedConn {
+	return &SerializedConn{c: c}
+}
+
+// Do executes f with exclusive access to the connection.
+func (sc *SerializedConn) Do(f func(c *Conn) error) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return f(sc.c)
+}
+
+// Exec is like Conn.Exec with exclusive access to the connection.
+func (sc *SerializedConn) Exec(cmd string, args ...interface{}) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.Exec(cmd, args...)
+}
+
+// FastExec is like Conn.FastExec with exclusive access to the connection.
+func (sc *SerializedConn) FastExec(sql string) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.FastExec(sql)
+}
+
+// ExecDml is like Conn.ExecDml with exclusive access to the connection
+// (the number of changes returned is the one of cmd).
+func (sc *SerializedConn) ExecDml(cmd string, args ...interface{}) (int, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.ExecDml(cmd, args...)
+}
+
+// Insert is like Conn.Insert with exclusive access to the connection
+// (the rowid returned is the one inserted by cmd).
+func (sc *SerializedConn) Insert(cmd string, args ...interface{}) (int64, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.Insert(cmd, args...)
+}
+
+// ExecResult is like Conn.ExecResult with exclusive access to the connection.
+func (sc *SerializedConn) ExecResult(cmd string, args ...interface{}) (changes int64, lastRowid int64, err error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.ExecResult(cmd, args...)
+}
+
+// ExecNamed is like Conn.ExecNamed with exclusive access to the connection.
+func (sc *SerializedConn) ExecNamed(cmd string, args map[string]interface{}) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.ExecNamed(cmd, args)
+}
+
+// Select is like Conn.Select with exclusive access to the connection (during the whole iteration).
+func (sc *SerializedConn) Select(query string, rowCallbackHandler func(s *Stmt) error, args ...interface{}) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.Select(query, rowCallbackHandler, args...)
+}
+
+// SelectNamed is like Conn.SelectNamed with exclusive access to the connection (during the whole iteration).
+func (sc *SerializedConn) SelectNamed(query string, rowCallbackHandler func(s *Stmt) error, args map[string]interface{}) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.SelectNamed(query, rowCallbackHandler, args)
+}
+
+// Exists is like Conn.Exists with exclusive access to the connection.
+func (sc *SerializedConn) Exists(query string, args ...interface{}) (bool, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.Exists(query, args...)
+}
+
+// OneValue is like Conn.OneValue with exclusive access to the connection.
+func (sc *SerializedConn) OneValue(query string, value interface{}, args ...interface{}) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.OneValue(query, value, args...)
+}
+
+// Transaction is like Conn.Transaction with exclusive access to the connection (until the transaction ends).
+func (sc *SerializedConn) Transaction(t TransactionType, f func(c *Conn) error) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.Transaction(t, f)
+}
+
+// Interrupt interrupts the operation in progress (without waiting for exclusive access).
+func (sc *SerializedConn) Interrupt() {
+	sc.c.Interrupt()
+}
+
+// Close waits for the operation in progress and closes the connection.
+func (sc *SerializedConn) Close() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c.Close()
+}
@@ -0,0 +1,61 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestSerializedConn(t *testing.T) {
+	db := open(t)
+	sc := NewSerializedConn(db)
+	defer func() {
+		checkNoError(t, sc.Close(), "error while closing connection: %s")
+	}()
+	checkNoError(t, sc.FastExec("CREATE TABLE test (id INTEGER PRIMARY KEY, g INT)"), "create error: %s")
+
+	var wg sync.WaitGroup
+	rowids := make([][]int64, 4)
+	for g := range rowids {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				rowid, err := sc.Insert("INSERT INTO test (g) VALUES (?)", g)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				rowids[g] = append(rowids[g], rowid)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for g, ids := range rowids {
+		for _, rowid := range ids {
+			var owner int
+			checkNoError(t, sc.OneValue("SELECT g FROM test WHERE id = ?", &owner, rowid), "select error: %s")
+			assert.Equalf(t, g, owner, "rowid %d", rowid)
+		}
+	}
+	changes, err := sc.ExecDml("UPDATE test SET g = 0 WHERE g = 1")
+	checkNoError(t, err, "update error: %s")
+	assert.Equal(t, 50, changes)
+
+	n, rowid, err := sc.ExecResult("INSERT INTO test (g) VALUES (?)", 4)
+	checkNoError(t, err, "insert error: %s")
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, int64(201), rowid)
+	err = sc.Do(func(c *Conn) error {
+		assert.Equal(t, int64(201), c.LastInsertRowid())
+		return nil
+	})
+	checkNoError(t, err, "do error: %s")
+}
@@ -15,12 +15,15 @@ type pendingAction struct {
 	dbName  string
 	tblName string
 	typ     string
+	name    string // index or trigger name
 }
 
 type CompletionCache struct {
 	memDb          *sqlite.Conn    // SQLite FTS extension is used to do auto-completion
 	insert         *sqlite.Stmt    // statement used to update col_names table
 	pendingActions []pendingAction // actions trapped by the authorizer for deferred cache update
+	watched        *sqlite.Conn    // connection whose schema changes are automatically reflected (see Watch)
+	schemaVersions map[string]int  // schema version by database name of the watched connection
 }
 
 func CreateCache() (*CompletionCache, error) {
@@ -47,6 +50,8 @@ func (cc *CompletionCache) init() error {
 	CREATE VIRTUAL TABLE module_names USING fts4(name, args, tokenize=porter, matchinfo=fts3, notindexed=args);
 	CREATE VIRTUAL TABLE cmd_names USING fts4(name, args, tokenize=porter, matchinfo=fts3, notindexed=args);
 	CREATE VIRTUAL TABLE col_names USING fts4(db_name, tbl_name, type, col_name, tokenize=porter, matchinfo=fts3, notindexed=type);
+	CREATE VIRTUAL TABLE obj_names USING fts4(db_name, tbl_name, type, name, tokenize=porter, matchinfo=fts3, notindexed=type);
+	CREATE VIRTUAL TABLE coll_names USING fts4(name, tokenize=porter, matchinfo=fts3);
 	`
 	var err error
 	if err = cc.memDb.FastExec(cmd); err != nil {
@@ -318,7 +323,10 @@ func (cc *CompletionCache) Cache(db *sqlite.Conn) error {
 			cc.pendingActions = append(cc.pendingActions, pendingAction{action: action, dbName: dbName, tblName: arg1, typ: "view"})
 		case sqlite.AlterTable:
 			cc.pendingActions = append(cc.pendingActions, pendingAction{action: action, dbName: arg1, tblName: arg2, typ: "table"})
-			// TODO trigger, index
+		case sqlite.CreateIndex, sqlite.CreateTempIndex, sqlite.DropIndex, sqlite.DropTempIndex:
+			cc.pendingActions = append(cc.pendingActions, pendingAction{action: action, dbName: dbName, tblName: arg2, typ: "index", name: arg1})
+		case sqlite.CreateTrigger, sqlite.CreateTempTrigger, sqlite.DropTrigger, sqlite.DropTempTrigger:
+			cc.pendingActions = append(cc.pendingActions, pendingAction{action: action, dbName: dbName, tblName: arg2, typ: "trigger", name: arg1})
 		}
 		return sqlite.AuthOk
 	}, nil)
@@ -333,6 +341,28 @@ func (cc *CompletionCache) Cache(db *sqlite.Conn) error {
 			return err
 		}
 	}
+	return cc.cacheCollations(db)
+}
+
+// cacheCollations caches the collating sequences registered for the connection.
+func (cc *CompletionCache) cacheCollations(db *sqlite.Conn) error {
+	if err := cc.memDb.FastExec("DELETE FROM coll_names"); err != nil {
+		return err
+	}
+	var names []string
+	err := db.Select("PRAGMA collation_list", func(s *sqlite.Stmt) error {
+		name, _ := s.ScanText(1)
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err = cc.memDb.Exec("INSERT INTO coll_names (name) VALUES (?)", name); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -363,6 +393,37 @@ func (cc *CompletionCache) cache(db *sqlite.Conn, dbName string) error {
 		}
 	}
 
+	return cc.cacheObjects(db, dbName)
+}
+
+// cacheObjects caches indexes and triggers names.
+func (cc *CompletionCache) cacheObjects(db *sqlite.Conn, dbName string) error {
+	var master string
+	if dbName == "temp" {
+		master = "sqlite_temp_master"
+	} else {
+		master = sqlite.QuoteIdentifier(dbName) + ".sqlite_master"
+	}
+	type object struct {
+		typ, name, tblName string
+	}
+	var objects []object
+	err := db.Select("SELECT type, name, tbl_name FROM "+master+" WHERE type IN ('index', 'trigger')", func(s *sqlite.Stmt) error {
+		var o object
+		if err := s.Scan(&o.typ, &o.name, &o.tblName); err != nil {
+			return err
+		}
+		objects = append(objects, o)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, o := range objects {
+		if err = cc.memDb.Exec("INSERT INTO obj_names (db_name, tbl_name, type, name) VALUES (?, ?, ?, ?)", dbName, o.tblName, o.typ, o.name); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -398,6 +459,9 @@ func (cc *CompletionCache) Update(db *sqlite.Conn) error {
 			if err := cc.memDb.Exec("DELETE FROM col_names WHERE db_name = ?", pa.dbName); err != nil {
 				return err
 			}
+			if err := cc.memDb.Exec("DELETE FROM obj_names WHERE db_name = ?", pa.dbName); err != nil {
+				return err
+			}
 		case sqlite.AlterTable:
 			if err := cc.memDb.Exec("DELETE FROM col_names WHERE db_name = ? AND tbl_name = ?", pa.dbName, pa.tblName); err != nil {
 				return err
@@ -411,15 +475,90 @@ func (cc *CompletionCache) Update(db *sqlite.Conn) error {
 			if err := cc.memDb.Exec("DELETE FROM col_names WHERE db_name = ? AND tbl_name = ?", pa.dbName, pa.tblName); err != nil {
 				return err
 			}
+			// indexes and triggers are dropped with their table
+			if err := cc.memDb.Exec("DELETE FROM obj_names WHERE db_name = ? AND tbl_name = ?", pa.dbName, pa.tblName); err != nil {
+				return err
+			}
+		case sqlite.CreateIndex, sqlite.CreateTempIndex, sqlite.CreateTrigger, sqlite.CreateTempTrigger:
+			if err := cc.memDb.Exec("INSERT INTO obj_names (db_name, tbl_name, type, name) VALUES (?, ?, ?, ?)", pa.dbName, pa.tblName, pa.typ, pa.name); err != nil {
+				return err
+			}
+		case sqlite.DropIndex, sqlite.DropTempIndex, sqlite.DropTrigger, sqlite.DropTempTrigger:
+			if err := cc.memDb.Exec("DELETE FROM obj_names WHERE db_name = ? AND type = ? AND name = ?", pa.dbName, pa.typ, pa.name); err != nil {
+				return err
+			}
 		}
 	}
 	cc.pendingActions = cc.pendingActions[:0]
 	return nil
 }
 
+// Watch caches the schema of db and makes completions automatically reflect schema changes
+// (DDL, ATTACH or DETACH) without calling Update:
+// the schema version of each database is checked before completion
+// and the databases whose schema has changed are cached again.
+func (cc *CompletionCache) Watch(db *sqlite.Conn) error {
+	if err := cc.Flush(db); err != nil {
+		return err
+	}
+	cc.watched = db
+	cc.schemaVersions = make(map[string]int)
+	if err := cc.refresh(); err != nil {
+		return err
+	}
+	return cc.cacheCollations(db)
+}
+
+// refresh updates the cache when the schema of the watched connection has changed.
+func (cc *CompletionCache) refresh() error {
+	db := cc.watched
+	if db == nil {
+		return nil
+	}
+	dbNames, err := db.Databases()
+	if err != nil {
+		return err
+	}
+	for dbName := range cc.schemaVersions {
+		if _, ok := dbNames[dbName]; !ok { // detached
+			if err = cc.uncache(dbName); err != nil {
+				return err
+			}
+			delete(cc.schemaVersions, dbName)
+		}
+	}
+	for dbName := range dbNames {
+		var version int
+		if err = db.OneValue("PRAGMA "+sqlite.QuoteIdentifier(dbName)+".schema_version", &version); err != nil {
+			return err
+		}
+		if v, ok := cc.schemaVersions[dbName]; ok && v == version {
+			continue
+		}
+		if err = cc.uncache(dbName); err != nil {
+			return err
+		}
+		if err = cc.cache(db, dbName); err != nil {
+			return err
+		}
+		cc.schemaVersions[dbName] = version
+	}
+	return nil
+}
+
+// uncache removes the specified database from the cache.
+func (cc *CompletionCache) uncache(dbName string) error {
+	if err := cc.memDb.Exec("DELETE FROM col_names WHERE db_name = ?", dbName); err != nil {
+		return err
+	}
+	return cc.memDb.Exec("DELETE FROM obj_names WHERE db_name = ?", dbName)
+}
+
 func (cc *CompletionCache) Flush(db *sqlite.Conn) error {
+	cc.watched = nil
+	cc.schemaVersions = nil
 	cc.pendingActions = cc.pendingActions[:0]
-	return cc.memDb.FastExec("DELETE FROM col_names")
+	return cc.memDb.FastExec("DELETE FROM col_names; DELETE FROM obj_names; DELETE FROM coll_names")
 }
 
 func (cc *CompletionCache) CompletePragma(prefix string) ([]string, error) {
@@ -450,6 +589,9 @@ func (cc *CompletionCache) complete(tbl, prefix string) ([]string, error) {
 }
 
 func (cc *CompletionCache) CompleteDbName(prefix string) ([]string, error) {
+	if err := cc.refresh(); err != nil {
+		return nil, err
+	}
 	s, err := cc.memDb.Prepare("SELECT DISTINCT db_name FROM col_names WHERE db_name MATCH ?||'*' ORDER BY 1", prefix)
 	if err != nil {
 		return nil, err
@@ -467,6 +609,9 @@ func (cc *CompletionCache) CompleteDbName(prefix string) ([]string, error) {
 }
 
 func (cc *CompletionCache) CompleteTableName(dbName, prefix, typ string) ([]string, error) {
+	if err := cc.refresh(); err != nil {
+		return nil, err
+	}
 	args := make([]interface{}, 0, 3)
 	if dbName != "" {
 		args = append(args, dbName)
@@ -505,8 +650,73 @@ func (cc *CompletionCache) CompleteTableName(dbName, prefix, typ string) ([]stri
 	return names, nil
 }
 
+// CompleteIndex returns the names of indexes matching prefix (for DROP INDEX or REINDEX).
+// dbName may be empty to search all databases.
+func (cc *CompletionCache) CompleteIndex(dbName, prefix string) ([]string, error) {
+	return cc.completeObject(dbName, "index", prefix)
+}
+
+// CompleteTrigger returns the names of triggers matching prefix (for DROP TRIGGER).
+// dbName may be empty to search all databases.
+func (cc *CompletionCache) CompleteTrigger(dbName, prefix string) ([]string, error) {
+	return cc.completeObject(dbName, "trigger", prefix)
+}
+
+// CompleteCollation returns the names of collating sequences matching prefix (for COLLATE or REINDEX).
+func (cc *CompletionCache) CompleteCollation(prefix string) ([]string, error) {
+	return cc.complete("coll_names", prefix)
+}
+
+func (cc *CompletionCache) completeObject(dbName, typ, prefix string) ([]string, error) {
+	if err := cc.refresh(); err != nil {
+		return nil, err
+	}
+	var sql string
+	args := make([]interface{}, 0, 3)
+	if dbName == "" {
+		sql = "SELECT DISTINCT name FROM obj_names WHERE type = ? AND name MATCH ?||'*' ORDER BY 1"
+	} else {
+		sql = "SELECT DISTINCT name FROM obj_names WHERE db_name = ? AND type = ? AND name MATCH ?||'*' ORDER BY 1"
+		args = append(args, dbName)
+	}
+	args = append(args, typ, prefix)
+	s, err := cc.memDb.Prepare(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Finalize()
+	var names []string
+	if err = s.Select(func(s *sqlite.Stmt) error {
+		name, _ := s.ScanText(0)
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// CompleteTable returns the names of tables and views matching prefix.
+// dbPrefix may be empty to search all databases.
+func (cc *CompletionCache) CompleteTable(dbPrefix, prefix string) ([]string, error) {
+	return cc.CompleteTableName(dbPrefix, prefix, "")
+}
+
+// CompleteColumn returns the names of the columns of table matching prefix.
+// table may be qualified by a database name ("db.table") but must not be an alias (see SQLContext).
+func (cc *CompletionCache) CompleteColumn(table, prefix string) ([]string, error) {
+	var dbName string
+	if i := strings.IndexByte(table, '.'); i >= 0 {
+		dbName, table = table[:i], table[i+1:]
+	}
+	return cc.CompleteColName(dbName, []string{table}, prefix)
+}
+
 // tbl_names is mandatory
 func (cc *CompletionCache) CompleteColName(dbName string, tbl_names []string, prefix string) ([]string, error) {
+	if err := cc.refresh(); err != nil {
+		return nil, err
+	}
 	args := make([]interface{}, 0, 10)
 	if dbName != "" {
 		args = append(args, dbName)
@@ -538,3 +748,195 @@ func (cc *CompletionCache) CompleteColName(dbName string, tbl_names []string, pr
 	}
 	return names, nil
 }
+
+// CompletionContext tells which kind of identifier is expected at the cursor position.
+type CompletionContext int
+
+const (
+	UnknownContext   CompletionContext = iota
+	TableContext                       // after FROM, JOIN, INTO, UPDATE, TABLE
+	ColumnContext                      // after SELECT, WHERE, ON, SET, BY, HAVING or an operator
+	IndexContext                       // after DROP INDEX or REINDEX
+	TriggerContext                     // after DROP TRIGGER
+	CollationContext                   // after COLLATE
+)
+
+// SQLContext is a simple SQL-context detector.
+// It returns the kind of identifier expected at the cursor position (pos) in line,
+// the qualifier typed just before the current word ("db." or "table.", an alias being replaced by its table) if any
+// and the tables referenced by the current statement (to complete column names).
+func SQLContext(line string, pos int) (ctx CompletionContext, qualifier string, tables []string) {
+	items := scan(line)
+	// restrict items to the statement under the cursor
+	start, end := 0, len(items)
+	for i, it := range items {
+		if it.typ == itemSemi {
+			if it.pos < pos {
+				start = i + 1
+			} else {
+				end = i
+				break
+			}
+		}
+	}
+	items = items[start:end]
+
+	var before []item // significant items before the cursor
+	for _, it := range items {
+		if it.pos >= pos || it.typ == itemEOF || it.typ == itemError {
+			break
+		}
+		if it.typ != itemSpace {
+			before = append(before, it)
+		}
+	}
+	n := len(before)
+	// the word under the cursor is the prefix to complete
+	if n > 0 && (before[n-1].typ == itemID || before[n-1].typ > itemKeyword) && before[n-1].pos+len(before[n-1].val) == pos {
+		n--
+	}
+	if n > 1 && before[n-1].typ == itemDot && before[n-2].typ == itemID {
+		qualifier = unquote(before[n-2].val)
+		n -= 2
+	}
+	if n > 0 {
+		ctx = expectedContext(before[:n])
+	}
+	tables, aliases := referencedTables(items)
+	if table, ok := aliases[strings.ToLower(qualifier)]; ok {
+		qualifier = table
+	}
+	return ctx, qualifier, tables
+}
+
+// expectedContext returns the kind of identifier expected after items.
+func expectedContext(items []item) CompletionContext {
+	var clause itemType
+	for _, it := range items {
+		switch it.typ {
+		case itemSelect, itemFrom, itemWhere, itemBy, itemHaving, itemSet, itemOn, itemUsing, itemValues, itemLimit:
+			clause = it.typ
+		}
+	}
+	n := len(items)
+	if n > 2 && items[n-1].typ == itemExists && items[n-2].typ == itemIf { // DROP INDEX IF EXISTS
+		n -= 2
+	}
+	if n > 1 && items[n-2].typ == itemDrop {
+		switch items[n-1].typ {
+		case itemIndex:
+			return IndexContext
+		case itemTrigger:
+			return TriggerContext
+		case itemTable, itemView:
+			return TableContext
+		}
+	}
+	switch last := items[len(items)-1].typ; last {
+	case itemReindex:
+		return IndexContext
+	case itemCollate:
+		return CollationContext
+	case itemFrom, itemJoin, itemInto, itemUpdate, itemTable:
+		return TableContext
+	case itemComma:
+		if clause == itemFrom {
+			return TableContext
+		} else if clause == itemValues || clause == itemLimit {
+			return UnknownContext
+		}
+		return ColumnContext
+	case itemSelect, itemWhere, itemBy, itemHaving, itemSet, itemOn, itemAnd, itemOr, itemNot, itemDistinct, itemLP,
+		itemEq, itemNE, itemLT, itemLE, itemGT, itemGE, itemPlus, itemMinus, itemStar, itemSlash, itemRem, itemConcat,
+		itemLikeKw, itemIs, itemIn, itemBetween, itemWhen, itemThen, itemElse, itemCase:
+		if clause == itemValues || clause == itemLimit {
+			return UnknownContext
+		}
+		return ColumnContext
+	}
+	return UnknownContext
+}
+
+// referencedTables returns the names of the tables following FROM, JOIN, INTO, UPDATE or TABLE
+// and their aliases (mapped to the possibly qualified table name).
+func referencedTables(items []item) (tables []string, aliases map[string]string) {
+	var clause itemType
+	expectTable := false
+	for i := 0; i < len(items); i++ {
+		it := items[i]
+		switch it.typ {
+		case itemSpace:
+			continue
+		case itemFrom, itemJoin, itemInto, itemUpdate, itemTable:
+			expectTable = true
+		case itemComma:
+			expectTable = clause == itemFrom
+		case itemID:
+			if expectTable {
+				name := unquote(it.val)
+				qualified := name
+				// skip database name
+				if i+2 < len(items) && items[i+1].typ == itemDot && items[i+2].typ == itemID {
+					i += 2
+					name = unquote(items[i].val)
+					qualified += "." + name
+				}
+				tables = append(tables, name)
+				if j := nextAlias(items, i+1); j > 0 {
+					if aliases == nil {
+						aliases = make(map[string]string)
+					}
+					aliases[strings.ToLower(unquote(items[j].val))] = qualified
+					i = j
+				}
+			}
+			expectTable = false
+		default:
+			expectTable = false
+		}
+		switch it.typ {
+		case itemSelect, itemFrom, itemWhere, itemGroup, itemOrder, itemHaving, itemSet, itemValues, itemLimit:
+			clause = it.typ
+		}
+	}
+	return tables, aliases
+}
+
+// nextAlias returns the index of the alias ("[AS] alias") following a table name
+// (items[i:] being the items after the name) or -1.
+func nextAlias(items []item, i int) int {
+	as := false
+	for ; i < len(items); i++ {
+		switch items[i].typ {
+		case itemSpace:
+			continue
+		case itemAs:
+			if as {
+				return -1
+			}
+			as = true
+			continue
+		case itemID:
+			return i
+		}
+		return -1
+	}
+	return -1
+}
+
+// unquote removes the quotes around an identifier.
+func unquote(id string) string {
+	if len(id) >= 2 {
+		switch id[0] {
+		case '"', '`':
+			if id[len(id)-1] == id[0] {
+				return strings.Replace(id[1:len(id)-1], id[:1]+id[:1], id[:1], -1)
+			}
+		case '[':
+			if id[len(id)-1] == ']' {
+				return id[1 : len(id)-1]
+			}
+		}
+	}
+	return id
+}
@@ -5,6 +5,7 @@
 package shell_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/bmizerany/assert"
@@ -89,3 +90,134 @@ func TestCacheUpdate(t *testing.T) {
 	assert.Equal(t, 1, len(col_names), "got %d column names; expected %d", len(col_names), 1)
 	assert.Equal(t, []string{"name"}, col_names, "unexpected column names")
 }
+
+func TestSQLContext(t *testing.T) {
+	tests := []struct {
+		line      string
+		ctx       CompletionContext
+		qualifier string
+		tables    []string
+	}{
+		{"SELECT * FROM te", TableContext, "", []string{"te"}},
+		{"SELECT * FROM main.te", TableContext, "main", []string{"te"}},
+		{"SELECT na", ColumnContext, "", nil},
+		{"SELECT id, na| FROM test", ColumnContext, "", []string{"test"}},
+		{"SELECT * FROM test WHERE na", ColumnContext, "", []string{"test"}},
+		{"SELECT * FROM test t JOIN other o ON t.na", ColumnContext, "test", []string{"test", "other"}},
+		{"SELECT e.| FROM emp e", ColumnContext, "emp", []string{"emp"}},
+		{"SELECT E.na| FROM main.emp AS e, dept WHERE 1", ColumnContext, "main.emp", []string{"emp", "dept"}},
+		{"SELECT 1; UPDATE test SET na", ColumnContext, "", []string{"test"}},
+		{"INSERT INTO te", TableContext, "", []string{"te"}},
+		{"SELECT * FROM a, b", TableContext, "", []string{"a", "b"}},
+		{"VACUUM", UnknownContext, "", nil},
+		{"DROP INDEX IF EXISTS main.id", IndexContext, "main", nil},
+		{"REINDEX id", IndexContext, "", nil},
+		{"DROP TRIGGER tr", TriggerContext, "", nil},
+		{"SELECT * FROM test ORDER BY name COLLATE no", CollationContext, "", []string{"test"}},
+	}
+	for _, test := range tests {
+		// '|' marks the cursor position (default is the end of line)
+		line, pos := test.line, len(test.line)
+		if i := strings.IndexByte(line, '|'); i >= 0 {
+			line, pos = line[:i]+line[i+1:], i
+		}
+		ctx, qualifier, tables := SQLContext(line, pos)
+		assert.Equalf(t, test.ctx, ctx, "%q: unexpected context", test.line)
+		assert.Equalf(t, test.qualifier, qualifier, "%q: unexpected qualifier", test.line)
+		assert.Equalf(t, test.tables, tables, "%q: unexpected tables", test.line)
+	}
+}
+
+func TestCompleteTableAndColumn(t *testing.T) {
+	db, err := sqlite.Open(":memory:")
+	assert.Tf(t, err == nil, "%v", err)
+	defer db.Close()
+	cc := createCache(t)
+	defer cc.Close()
+	err = db.FastExec("CREATE TABLE test (id INTEGER PRIMARY KEY NOT NULL, name TEXT UNIQUE NOT NULL)")
+	assert.Tf(t, err == nil, "%v", err)
+	err = cc.Cache(db)
+	assert.Tf(t, err == nil, "%v", err)
+
+	tbl_names, err := cc.CompleteTable("main", "te")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, []string{"test"}, tbl_names, "unexpected table names")
+
+	col_names, err := cc.CompleteColumn("main.test", "na")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, []string{"name"}, col_names, "unexpected column names")
+}
+
+func TestCompleteIndexAndTrigger(t *testing.T) {
+	db, err := sqlite.Open(":memory:")
+	assert.Tf(t, err == nil, "%v", err)
+	defer db.Close()
+	cc := createCache(t)
+	defer cc.Close()
+	err = db.FastExec("CREATE TABLE test (id INTEGER PRIMARY KEY NOT NULL, name TEXT NOT NULL); CREATE INDEX test_name ON test (name)")
+	assert.Tf(t, err == nil, "%v", err)
+	err = cc.Cache(db)
+	assert.Tf(t, err == nil, "%v", err)
+	err = db.FastExec("CREATE TRIGGER test_trigger AFTER INSERT ON test BEGIN SELECT 1; END")
+	assert.Tf(t, err == nil, "%v", err)
+	err = cc.Update(db)
+	assert.Tf(t, err == nil, "%v", err)
+
+	idx_names, err := cc.CompleteIndex("main", "test")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, []string{"test_name"}, idx_names, "unexpected index names")
+
+	trg_names, err := cc.CompleteTrigger("", "test")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, []string{"test_trigger"}, trg_names, "unexpected trigger names")
+
+	coll_names, err := cc.CompleteCollation("NO")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, []string{"NOCASE"}, coll_names, "unexpected collation names")
+
+	err = db.FastExec("DROP INDEX test_name")
+	assert.Tf(t, err == nil, "%v", err)
+	err = cc.Update(db)
+	assert.Tf(t, err == nil, "%v", err)
+	idx_names, err = cc.CompleteIndex("", "test")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 0, len(idx_names), "expected no index")
+}
+
+func TestWatch(t *testing.T) {
+	db, err := sqlite.Open(":memory:")
+	assert.Tf(t, err == nil, "%v", err)
+	defer db.Close()
+	cc := createCache(t)
+	defer cc.Close()
+	err = cc.Watch(db)
+	assert.Tf(t, err == nil, "%v", err)
+
+	err = db.FastExec("CREATE TABLE test (id INTEGER PRIMARY KEY NOT NULL, name TEXT NOT NULL)")
+	assert.Tf(t, err == nil, "%v", err)
+	tbl_names, err := cc.CompleteTable("", "te")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, []string{"test"}, tbl_names, "unexpected table names")
+
+	err = db.Begin()
+	assert.Tf(t, err == nil, "%v", err)
+	err = db.FastExec("DROP TABLE test")
+	assert.Tf(t, err == nil, "%v", err)
+	err = db.Rollback()
+	assert.Tf(t, err == nil, "%v", err)
+	col_names, err := cc.CompleteColumn("test", "na")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, []string{"name"}, col_names, "unexpected column names")
+
+	err = db.FastExec("ATTACH ':memory:' AS aux; CREATE TABLE aux.other (x)")
+	assert.Tf(t, err == nil, "%v", err)
+	tbl_names, err = cc.CompleteTable("aux", "ot")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, []string{"other"}, tbl_names, "unexpected table names")
+
+	err = db.FastExec("DETACH aux")
+	assert.Tf(t, err == nil, "%v", err)
+	db_names, err := cc.CompleteDbName("a")
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, 0, len(db_names), "expected no database")
+}
@@ -0,0 +1,301 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shell
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// FormatOptions controls the output of Format.
+type FormatOptions struct {
+	LowerKeywords bool   // keywords in lower case (upper case by default)
+	Indent        string // used to indent continuation lines (two spaces by default)
+}
+
+// Format pretty-prints SQL statements:
+//   - keywords are upper-cased (or lower-cased),
+//   - SELECT clauses (FROM, WHERE, GROUP BY, ...), joins and compound operators start a new line,
+//   - items of the select list, VALUES rows and SET assignments are put one per line (and aligned),
+//   - AND/OR conditions of WHERE/HAVING/ON clauses start a new (indented) line,
+//   - subqueries are indented.
+//
+// Comments are kept. Each statement ends with a new line.
+func Format(sql string, options *FormatOptions) (string, error) {
+	if options == nil {
+		options = &FormatOptions{}
+	}
+	f := &formatter{indent: options.Indent, lower: options.LowerKeywords}
+	if f.indent == "" {
+		f.indent = "  "
+	}
+	f.reset()
+	for _, it := range scan(sql) {
+		switch it.typ {
+		case itemError:
+			return "", errors.New(it.val)
+		case itemEOF:
+			return strings.TrimRight(f.b.String(), " \n") + "\n", nil
+		}
+		f.item(it)
+	}
+	return f.b.String(), nil
+}
+
+// formatLevel is the state saved when entering parentheses.
+type formatLevel struct {
+	base     int    // indentation level of the clauses
+	listCol  int    // column at which the items of a list are aligned (-1 when commas don't break lines)
+	subquery bool   // parentheses enclosing a SELECT
+	margin   string // indentation of the line where the subquery starts
+	cond     bool   // AND/OR break lines
+}
+
+type formatter struct {
+	b      bytes.Buffer
+	indent string
+	lower  bool
+
+	formatLevel
+	levels   []formatLevel // saved states (one per open parenthesis)
+	prev     item          // last significant item written
+	first    itemType      // first keyword of the statement
+	between  bool          // BETWEEN seen, waiting for its AND
+	listNext bool          // the next item is the first of an aligned list
+	newLine  bool          // the next item starts a new line
+	unary    bool          // the last item written is a unary operator
+}
+
+func (f *formatter) reset() {
+	f.formatLevel = formatLevel{listCol: -1}
+	f.levels = f.levels[:0]
+	f.prev = item{typ: itemSemi}
+	f.first = itemEOF
+	f.between = false
+	f.listNext = false
+	f.unary = false
+}
+
+// column returns the current column on the current line.
+func (f *formatter) column() int {
+	b := f.b.Bytes()
+	return len(b) - (bytes.LastIndexByte(b, '\n') + 1)
+}
+
+// breakLine starts a new line indented at the specified level.
+func (f *formatter) breakLine(level int) {
+	f.trimSpaces()
+	if f.b.Len() > 0 {
+		f.b.WriteByte('\n')
+	}
+	f.b.WriteString(strings.Repeat(f.indent, level))
+	f.newLine = false
+}
+
+// startSubquery indents the subquery relative to the line where it starts.
+func (f *formatter) startSubquery() {
+	b := f.b.Bytes()
+	line := b[bytes.LastIndexByte(b, '\n')+1:]
+	f.margin = string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+	f.subquery = true
+	f.base = strings.Count(f.margin, f.indent) + 1
+	f.breakLine(f.base)
+}
+
+func (f *formatter) trimSpaces() {
+	b := bytes.TrimRight(f.b.Bytes(), " ")
+	f.b.Truncate(len(b))
+}
+
+func (f *formatter) atLineStart() bool {
+	b := f.b.Bytes()
+	return len(bytes.TrimLeft(b[bytes.LastIndexByte(b, '\n')+1:], " ")) == 0
+}
+
+// queryLevel tells if clause keywords are expected (top-level or subquery).
+func (f *formatter) queryLevel() bool {
+	return len(f.levels) == 0 || f.subquery
+}
+
+func (f *formatter) item(it item) {
+	if it.typ == itemSpace {
+		f.comment(it.val)
+		return
+	}
+	if f.newLine {
+		f.breakLine(f.base)
+	}
+	val := it.val
+	if it.typ > itemKeyword {
+		if f.lower {
+			val = strings.ToLower(val)
+		} else {
+			val = strings.ToUpper(val)
+		}
+		if f.first == itemEOF && it.typ != itemExplain && it.typ != itemQuery && it.typ != itemPlan {
+			f.first = it.typ
+		}
+	}
+	switch it.typ {
+	case itemSemi:
+		f.trimSpaces()
+		f.b.WriteString(";\n")
+		f.reset()
+		return
+	case itemLP:
+		f.space(it)
+		f.alignList(it)
+		f.b.WriteString(val)
+		f.levels = append(f.levels, f.formatLevel)
+		f.formatLevel = formatLevel{base: f.base, listCol: -1}
+		f.prev = it
+		return
+	case itemRP:
+		f.trimSpaces()
+		if n := len(f.levels); n > 0 {
+			if f.subquery {
+				f.breakLine(0)
+				f.b.WriteString(f.margin)
+			}
+			f.formatLevel = f.levels[n-1]
+			f.levels = f.levels[:n-1]
+		}
+		f.b.WriteString(val)
+		f.prev = it
+		return
+	case itemComma:
+		f.trimSpaces()
+		f.b.WriteString(val)
+		if f.listCol >= 0 {
+			f.trimSpaces()
+			f.b.WriteByte('\n')
+			f.b.WriteString(strings.Repeat(" ", f.listCol))
+		}
+		f.prev = it
+		return
+	}
+	if f.queryLevel() || f.prev.typ == itemLP && (it.typ == itemSelect || it.typ == itemWith) {
+		f.clause(it)
+	}
+	f.space(it)
+	f.alignList(it)
+	f.b.WriteString(val)
+	switch it.typ {
+	case itemMinus, itemPlus, itemBitNot:
+		f.unary = !isOperand(f.prev.typ)
+	case itemBetween:
+		f.between = true
+	case itemSelect, itemValues, itemSet:
+		f.listNext = true
+	}
+	f.prev = it
+}
+
+// alignList records the column of the first item of a list.
+func (f *formatter) alignList(it item) {
+	if f.listNext && it.typ != itemDistinct && it.typ != itemAll {
+		f.listCol = f.column()
+		f.listNext = false
+	}
+}
+
+// clause starts a new line before the clause keywords.
+func (f *formatter) clause(it item) {
+	switch it.typ {
+	case itemSelect:
+		if f.prev.typ == itemLP {
+			f.startSubquery()
+		} else if !f.atLineStart() {
+			f.breakLine(f.base)
+		}
+		f.listCol, f.cond = -1, false
+	case itemWith:
+		if f.prev.typ == itemLP {
+			f.startSubquery()
+		}
+	case itemFrom:
+		if f.first != itemDelete {
+			f.breakLine(f.base)
+		}
+		f.listCol, f.cond = -1, false
+	case itemWhere, itemHaving:
+		f.breakLine(f.base)
+		f.listCol, f.cond = -1, true
+	case itemGroup, itemOrder, itemLimit, itemUnion, itemIntersect, itemExcept:
+		f.breakLine(f.base)
+		f.listCol, f.cond = -1, false
+	case itemValues, itemSet:
+		if f.first == itemInsert || f.first == itemReplace || f.first == itemUpdate || f.first == itemWith {
+			f.breakLine(f.base)
+		}
+		f.listCol, f.cond = -1, false
+	case itemJoinKw, itemJoin:
+		if f.prev.typ != itemJoinKw {
+			f.breakLine(f.base)
+		}
+		f.listCol, f.cond = -1, false
+	case itemOn:
+		if f.first == itemSelect || f.first == itemWith {
+			f.cond = true
+		}
+	case itemAnd, itemOr:
+		if f.between && it.typ == itemAnd {
+			f.between = false
+		} else if f.cond {
+			f.breakLine(f.base + 1)
+		}
+	}
+}
+
+// space writes a space before it when needed.
+func (f *formatter) space(it item) {
+	if f.atLineStart() {
+		return
+	}
+	switch it.typ {
+	case itemDot:
+		return
+	case itemLP:
+		if f.prev.typ == itemID {
+			return // function call
+		}
+	case itemString, itemID: // escaped quotes ('it''s' is scanned as two strings)
+		if f.prev.typ == it.typ && len(it.val) > 0 && len(f.prev.val) > 0 && f.prev.val[0] == it.val[0] && (it.val[0] == '\'' || it.val[0] == '"' || it.val[0] == '`') {
+			return
+		}
+	}
+	switch f.prev.typ {
+	case itemLP, itemDot:
+		return
+	case itemMinus, itemPlus, itemBitNot:
+		if f.unary {
+			return
+		}
+	}
+	f.b.WriteByte(' ')
+}
+
+// comment writes comments (spaces are ignored).
+func (f *formatter) comment(val string) {
+	val = strings.TrimSpace(val)
+	if len(val) == 0 {
+		return
+	}
+	f.space(item{typ: itemSpace})
+	f.b.WriteString(val)
+	if strings.HasPrefix(val, "--") {
+		f.newLine = true
+	}
+}
+
+// isOperand tells if an item of type t may end an operand (so that a following minus is binary).
+func isOperand(t itemType) bool {
+	switch t {
+	case itemRP, itemString, itemID, itemVariable, itemBlob, itemInteger, itemFloat, itemNull, itemEnd:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shell_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite/shell"
+)
+
+func TestFormat(t *testing.T) {
+	var tests = []struct {
+		sql      string
+		expected string
+	}{
+		{"select a, b as x from t1 join t2 on t1.id = t2.id and t2.x > -1 where a between 1 and 2 and b = 'it''s' order by b desc limit 10;",
+			"SELECT a,\n       b AS x\nFROM t1\nJOIN t2 ON t1.id = t2.id\n  AND t2.x > -1\nWHERE a BETWEEN 1 AND 2\n  AND b = 'it''s'\nORDER BY b DESC\nLIMIT 10;\n"},
+		{"insert into t (a, b) values (1, 'x'), (2, 'y')",
+			"INSERT INTO t(a, b)\nVALUES (1, 'x'),\n       (2, 'y')\n"},
+		{"update t set a = 1, b = b - 1 where id in (select id from u where x = 1) -- done",
+			"UPDATE t\nSET a = 1,\n    b = b - 1\nWHERE id IN (\n  SELECT id\n  FROM u\n  WHERE x = 1\n) -- done\n"},
+		{"SELECT count(*) FROM t; DELETE FROM t WHERE id = -1;",
+			"SELECT count(*)\nFROM t;\nDELETE FROM t\nWHERE id = -1;\n"},
+	}
+	for _, test := range tests {
+		formatted, err := Format(test.sql, nil)
+		assert.Tf(t, err == nil, "%v", err)
+		assert.Equal(t, test.expected, formatted)
+	}
+}
+
+func TestFormatOptions(t *testing.T) {
+	formatted, err := Format("SELECT 1 FROM (SELECT 2)", &FormatOptions{LowerKeywords: true, Indent: "\t"})
+	assert.Tf(t, err == nil, "%v", err)
+	assert.Equal(t, "select 1\nfrom (\n\tselect 2\n)\n", formatted)
+}
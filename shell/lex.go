@@ -384,6 +384,20 @@ func lex(input string) *lexer {
 	return l
 }
 
+// scan returns all the items of the input (until EOF or the first error).
+func scan(input string) []item {
+	l := lex(input)
+	var items []item
+	for {
+		item := l.nextItem()
+		items = append(items, item)
+		if item.typ == itemEOF || item.typ == itemError {
+			break
+		}
+	}
+	return items
+}
+
 // run runs the state machine for the lexer.
 func (l *lexer) run() {
 	for state := lexStart; state != nil; {
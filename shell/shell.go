@@ -9,20 +9,27 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"os/user"
 	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"text/tabwriter"
+	"time"
 	"unicode"
 
 	"github.com/gwenn/gosqlite"
 	"github.com/gwenn/gosqlite/shell"
 	"github.com/gwenn/liner"
+	"github.com/gwenn/yacr"
 )
 
 func check(err error) {
@@ -32,11 +39,28 @@ func check(err error) {
 }
 func trace(err error) bool {
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
+		if colored {
+			fmt.Fprintln(os.Stderr, colorError+err.Error()+colorReset)
+		} else {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
 	}
 	return err != nil
 }
 
+// colored is true when ANSI colors are used (interactive session without --no-color flag).
+var colored bool
+
+// ANSI escape sequences.
+// Headers and NULLs sequences have the same length as colorPlain to keep tabwriter alignment.
+const (
+	colorReset  = "\x1b[0m"
+	colorPlain  = colorReset
+	colorHeader = "\x1b[1m"  // bold
+	colorNull   = "\x1b[2m"  // faint
+	colorError  = "\x1b[31m" // red
+)
+
 const (
 	mainPrompt      = "sqlite> "
 	continuePrompt  = "   ...> "
@@ -137,19 +161,798 @@ func completion(cc *shell.CompletionCache, line string, pos int) (string, []stri
 	} else {
 		fields := strings.Fields(prefix)
 		if strings.EqualFold("PRAGMA", fields[0]) { // TODO check pos
-			matches, err = cc.CompletePragma(fields[1])
-			check(err)
+			if len(fields) > 1 {
+				matches, err = cc.CompletePragma(fields[1])
+				check(err)
+			}
+			return prefix, matches, line[pos:]
+		}
+		// current word
+		start := strings.LastIndexFunc(prefix, func(r rune) bool {
+			return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+		}) + 1
+		word := prefix[start:]
+		if len(word) == 0 {
+			return prefix, nil, line[pos:]
+		}
+		ctx, qualifier, tables := shell.SQLContext(line, pos)
+		switch ctx {
+		case shell.TableContext:
+			matches, err = cc.CompleteTable(qualifier, word)
+		case shell.IndexContext:
+			matches, err = cc.CompleteIndex(qualifier, word)
+		case shell.TriggerContext:
+			matches, err = cc.CompleteTrigger(qualifier, word)
+		case shell.CollationContext:
+			matches, err = cc.CompleteCollation(word)
+		case shell.ColumnContext:
+			if len(qualifier) > 0 {
+				matches, err = cc.CompleteColumn(qualifier, word)
+			} else {
+				if len(tables) > 0 {
+					matches, err = cc.CompleteColName("", tables, word)
+				}
+				if err == nil {
+					var funcs []string
+					funcs, err = cc.CompleteFunc(word)
+					matches = append(matches, funcs...)
+				}
+			}
+		}
+		if trace(err) {
+			return prefix, nil, line[pos:]
+		}
+		if len(matches) > 0 {
+			prefix = prefix[:start]
 		}
 	}
 	return prefix, matches, line[pos:]
 }
 
+// shellState gathers the settings altered by dot commands.
+type shellState struct {
+	db        *sqlite.Conn
+	cc        *shell.CompletionCache
+	headers   bool   // .headers ON|OFF
+	separator string // .separator STRING (used by .import and .export)
+	nullvalue string // .nullvalue STRING
+	mode      string // .mode column|markdown|html
+	out       io.Writer
+	outFile   *os.File               // not nil when output is redirected by .output or .once
+	once      bool                   // output is restored to stdout after the next statement
+	timer     bool                   // .timer ON|OFF
+	stats     bool                   // .stats ON|OFF
+	params    map[string]interface{} // .parameter set NAME VALUE
+	bail      bool                   // .bail ON|OFF
+	echo      bool                   // .echo ON|OFF
+	expert    bool                   // .expert (the next SQL statements are analysed instead of executed)
+	format    bool                   // .format (the next SQL statements are pretty-printed instead of executed)
+}
+
+func newShellState(db *sqlite.Conn, cc *shell.CompletionCache) *shellState {
+	return &shellState{db: db, cc: cc, headers: true, separator: ",", mode: "column", out: os.Stdout,
+		params: make(map[string]interface{})}
+}
+
+var (
+	errExit = errors.New("exit")
+	// errBail is returned when an error happens and .bail is on (the error has already been reported).
+	errBail = errors.New("bail")
+)
+
+// splitArgs splits a dot command line into words.
+// Words may be quoted with single or double quotes (only when the quote starts the word).
+func splitArgs(line string) []string {
+	var args []string
+	var b bytes.Buffer
+	var quote rune
+	inWord := false
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				b.WriteRune(r)
+			}
+		case !inWord && (r == '\'' || r == '"'):
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				args = append(args, b.String())
+				b.Reset()
+				inWord = false
+			}
+		default:
+			b.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, b.String())
+	}
+	return args
+}
+
+func booleanValue(arg string) (bool, error) {
+	switch strings.ToLower(arg) {
+	case "on", "yes", "true", "1":
+		return true, nil
+	case "off", "no", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("ERROR: Not a boolean value: %q. Assuming \"no\".", arg)
+}
+
+// unescape interprets backslash escapes (\t, \n, \r, \\) as done by the reference shell.
+func unescape(arg string) string {
+	if strings.IndexByte(arg, '\\') < 0 {
+		return arg
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(arg); i++ {
+		c := arg[i]
+		if c == '\\' && i+1 < len(arg) {
+			i++
+			switch arg[i] {
+			case 't':
+				c = '\t'
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			default:
+				c = arg[i]
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// splitQualifiedName splits "db.table" into its database and table parts.
+func splitQualifiedName(name string) (string, string) {
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+func (st *shellState) doMetaCommand(line string) error {
+	args := splitArgs(line[1:])
+	if len(args) == 0 {
+		return nil
+	}
+	cmd := args[0]
+	args = args[1:]
+	switch cmd {
+	case "attach":
+		if len(args) != 2 && !(len(args) == 3 && strings.EqualFold(args[1], "as")) {
+			return errors.New("Usage: .attach FILE ?AS? NAME")
+		}
+		return st.attach(args[0], args[len(args)-1])
+	case "backup", "save":
+		if len(args) < 1 || len(args) > 2 {
+			return errors.New("Usage: .backup ?DB? FILE")
+		}
+		dbName, filename := "main", args[0]
+		if len(args) == 2 {
+			dbName, filename = args[0], args[1]
+		}
+		return st.backup(dbName, filename)
+	case "bail":
+		if len(args) != 1 {
+			return errors.New("Usage: .bail ON|OFF")
+		}
+		b, err := booleanValue(args[0])
+		st.bail = b
+		return err
+	case "clone":
+		if len(args) != 1 {
+			return errors.New("Usage: .clone NEWDB")
+		}
+		return st.clone(args[0])
+	case "databases":
+		if len(args) != 0 {
+			return errors.New("Usage: .databases")
+		}
+		return st.databases()
+	case "detach":
+		if len(args) != 1 {
+			return errors.New("Usage: .detach NAME")
+		}
+		return st.db.Exec(sqlite.Mprintf("DETACH DATABASE %Q", args[0]))
+	case "echo":
+		if len(args) != 1 {
+			return errors.New("Usage: .echo ON|OFF")
+		}
+		b, err := booleanValue(args[0])
+		st.echo = b
+		return err
+	case "exit", "quit":
+		return errExit
+	case "expert":
+		if len(args) != 0 {
+			return errors.New("Usage: .expert")
+		}
+		st.expert = true
+		return nil
+	case "format":
+		if len(args) != 0 {
+			return errors.New("Usage: .format")
+		}
+		st.format = true
+		return nil
+	case "export":
+		if len(args) != 2 {
+			return errors.New("Usage: .export FILE TABLE")
+		}
+		return st.export(args[0], args[1])
+	case "headers", "header":
+		if len(args) != 1 {
+			return errors.New("Usage: .headers ON|OFF")
+		}
+		b, err := booleanValue(args[0])
+		st.headers = b
+		return err
+	case "import":
+		if len(args) != 2 {
+			return errors.New("Usage: .import FILE TABLE")
+		}
+		return st.importCSV(args[0], args[1])
+	case "mode":
+		if len(args) != 1 {
+			return errors.New("Usage: .mode column|markdown|html")
+		}
+		switch mode := strings.ToLower(args[0]); mode {
+		case "column", "markdown", "html":
+			st.mode = mode
+			return nil
+		}
+		return fmt.Errorf("Error: mode should be one of: column markdown html")
+	case "nullvalue":
+		if len(args) != 1 {
+			return errors.New("Usage: .nullvalue STRING")
+		}
+		st.nullvalue = unescape(args[0])
+		return nil
+	case "once":
+		if len(args) != 1 {
+			return errors.New("Usage: .once FILE")
+		}
+		if err := st.redirect(args[0]); err != nil {
+			return err
+		}
+		st.once = true
+		return nil
+	case "open":
+		if len(args) > 1 {
+			return errors.New("Usage: .open ?FILENAME?")
+		}
+		filename := ":memory:"
+		if len(args) == 1 {
+			filename = args[0]
+		}
+		return st.open(filename)
+	case "output":
+		if len(args) > 1 {
+			return errors.New("Usage: .output ?FILE?")
+		}
+		if len(args) == 0 || args[0] == "stdout" {
+			return st.resetOutput()
+		}
+		return st.redirect(args[0])
+	case "parameter", "param":
+		return st.parameter(args)
+	case "read":
+		if len(args) != 1 {
+			return errors.New("Usage: .read FILE")
+		}
+		return st.read(args[0])
+	case "restore":
+		if len(args) < 1 || len(args) > 2 {
+			return errors.New("Usage: .restore ?DB? FILE")
+		}
+		dbName, filename := "main", args[0]
+		if len(args) == 2 {
+			dbName, filename = args[0], args[1]
+		}
+		return st.restore(dbName, filename)
+	case "separator":
+		if len(args) != 1 {
+			return errors.New("Usage: .separator STRING (used by .import and .export only)")
+		}
+		st.separator = unescape(args[0])
+		return nil
+	case "stats":
+		if len(args) != 1 {
+			return errors.New("Usage: .stats ON|OFF")
+		}
+		b, err := booleanValue(args[0])
+		st.stats = b
+		return err
+	case "timer":
+		if len(args) != 1 {
+			return errors.New("Usage: .timer ON|OFF")
+		}
+		b, err := booleanValue(args[0])
+		st.timer = b
+		return err
+	}
+	return fmt.Errorf("Error: unknown command or invalid arguments:  %q. Enter \".help\" for help", cmd)
+}
+
+// open closes the current database and opens the specified one.
+// The completion cache now watches the new database schema.
+func (st *shellState) open(filename string) error {
+	db, err := sqlite.Open(filename)
+	if err != nil {
+		return err
+	}
+	if err = st.cc.Watch(db); err != nil {
+		db.Close()
+		trace(st.cc.Watch(st.db))
+		return err
+	}
+	old := st.db
+	st.db = db
+	return old.Close()
+}
+
+// databases lists names and files of attached databases.
+func (st *shellState) databases() error {
+	dbs, err := st.db.Databases()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(dbs))
+	for name := range dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(st.out, "%s: %s\n", name, dbs[name])
+	}
+	return nil
+}
+
+// attach attaches the database file filename as name.
+func (st *shellState) attach(filename, name string) error {
+	return st.db.Exec(sqlite.Mprintf("ATTACH DATABASE %Q AS %Q", filename, name))
+}
+
+const (
+	backupPagesPerStep = 100
+	// progress is displayed only when the database has more pages than this threshold.
+	backupProgressThreshold = 1000
+)
+
+// copyDatabase copies the content of src database into dst database
+// and displays the progress on stderr for large databases.
+func copyDatabase(dst *sqlite.Conn, dstName string, src *sqlite.Conn, srcName string) error {
+	bck, err := sqlite.NewBackup(dst, dstName, src, srcName)
+	if err != nil {
+		return err
+	}
+	c := make(chan sqlite.BackupStatus)
+	done := make(chan bool)
+	go func() {
+		progress := false
+		for status := range c {
+			if status.PageCount > backupProgressThreshold {
+				fmt.Fprintf(os.Stderr, "\r%d/%d pages", status.PageCount-status.Remaining, status.PageCount)
+				progress = true
+			}
+		}
+		if progress {
+			fmt.Fprintln(os.Stderr)
+		}
+		done <- true
+	}()
+	err = bck.Run(backupPagesPerStep, 0, c)
+	close(c)
+	<-done
+	return err
+}
+
+// backup copies the database dbName to the file filename.
+func (st *shellState) backup(dbName, filename string) error {
+	dst, err := sqlite.Open(filename)
+	if err != nil {
+		return err
+	}
+	err = copyDatabase(dst, "main", st.db, dbName)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// restore replaces the content of the database dbName by the content of the file filename.
+func (st *shellState) restore(dbName, filename string) error {
+	src, err := sqlite.Open(filename, sqlite.OpenReadOnly)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return copyDatabase(st.db, dbName, src, "main")
+}
+
+// clone copies the main database into the new file filename.
+func (st *shellState) clone(filename string) error {
+	if _, err := os.Stat(filename); err == nil {
+		return fmt.Errorf("File %q already exists.", filename)
+	}
+	return st.backup("main", filename)
+}
+
+// parameter manages the values bound to named parameters:
+//
+//	.parameter clear            Erase all bindings
+//	.parameter list             List the current parameter bindings
+//	.parameter set NAME VALUE   Set parameter NAME to VALUE
+//	.parameter unset NAME       Remove NAME from the binding table
+func (st *shellState) parameter(args []string) error {
+	if len(args) == 0 {
+		return errors.New("Usage: .parameter clear|list|set NAME VALUE|unset NAME")
+	}
+	switch {
+	case args[0] == "clear" && len(args) == 1:
+		st.params = make(map[string]interface{})
+		return nil
+	case args[0] == "list" && len(args) == 1:
+		names := make([]string, 0, len(st.params))
+		for name := range st.params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(st.out, "%-20s %v\n", name, st.params[name])
+		}
+		return nil
+	case args[0] == "set" && len(args) == 3:
+		st.params[args[1]] = st.evalParameter(args[2])
+		return nil
+	case args[0] == "unset" && len(args) == 2:
+		delete(st.params, args[1])
+		return nil
+	}
+	return errors.New("Usage: .parameter clear|list|set NAME VALUE|unset NAME")
+}
+
+// evalParameter evaluates value as an SQL expression (like 'abc', 123 or date('now')).
+// If the evaluation fails, value is used as a text.
+func (st *shellState) evalParameter(value string) interface{} {
+	var v interface{}
+	if err := st.db.OneValue("SELECT "+value, &v); err != nil {
+		return value
+	}
+	return v
+}
+
+// bindParameters binds the values set by .parameter to the matching named parameters.
+func (st *shellState) bindParameters(s *sqlite.Stmt) error {
+	for i := 1; i <= s.BindParameterCount(); i++ {
+		name, err := s.BindParameterName(i)
+		if err != nil {
+			return err
+		}
+		if v, ok := st.params[name]; ok {
+			if err = s.BindByIndex(i, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// printStats displays statement and connection status counters (and resets them).
+func (st *shellState) printStats(s *sqlite.Stmt) {
+	w := os.Stderr
+	fmt.Fprintf(w, "Fullscan Steps:                      %d\n", s.Status(sqlite.StmtStatusFullScanStep, true))
+	fmt.Fprintf(w, "Sort Operations:                     %d\n", s.Status(sqlite.StmtStatusSort, true))
+	fmt.Fprintf(w, "Autoindex Inserts:                   %d\n", s.Status(sqlite.StmtStatusAutoIndex, true))
+	fmt.Fprintf(w, "Virtual Machine Steps:               %d\n", s.Status(sqlite.StmtStatusVMStep, true))
+	if hit, _, err := st.db.Status(sqlite.DbStatusCacheHit, true); err == nil {
+		fmt.Fprintf(w, "Page cache hits:                     %d\n", hit)
+	}
+	if miss, _, err := st.db.Status(sqlite.DbStatusCacheMiss, true); err == nil {
+		fmt.Fprintf(w, "Page cache misses:                   %d\n", miss)
+	}
+	busy := st.db.BusyStats(true)
+	fmt.Fprintf(w, "Busy errors:                         %d\n", busy.Busy)
+	fmt.Fprintf(w, "Busy handler calls (wait):           %d (%s)\n", busy.Calls, busy.Wait)
+}
+
+// redirect sends output to the specified file.
+func (st *shellState) redirect(filename string) error {
+	if err := st.resetOutput(); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	st.out = f
+	st.outFile = f
+	return nil
+}
+
+// resetOutput sends output back to stdout.
+func (st *shellState) resetOutput() error {
+	st.out = os.Stdout
+	st.once = false
+	if st.outFile == nil {
+		return nil
+	}
+	err := st.outFile.Close()
+	st.outFile = nil
+	return err
+}
+
+func (st *shellState) importCSV(filename, table string) error {
+	if len(st.separator) != 1 {
+		return errors.New("Error: multi-character separators are not allowed for import")
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dbName, tblName := splitQualifiedName(table)
+	ic := sqlite.ImportConfig{
+		Name:      filename,
+		Separator: st.separator[0],
+		Quoted:    true,
+		Headers:   st.headers,
+		Log:       os.Stderr,
+	}
+	return st.db.ImportCSV(f, ic, dbName, tblName)
+}
+
+// export dumps the content of the specified table in CSV or JSON (depending on the file extension).
+func (st *shellState) export(filename, table string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	dbName, tblName := splitQualifiedName(table)
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		err = exportJSON(st.db, dbName, tblName, f)
+	} else if len(st.separator) != 1 {
+		err = errors.New("Error: multi-character separators are not allowed for export")
+	} else {
+		w := yacr.NewWriter(f, st.separator[0], true)
+		err = st.db.ExportTableToCSV(dbName, tblName, "", st.headers, w)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// exportJSON writes table content as an array of objects (one per row).
+func exportJSON(db *sqlite.Conn, dbName, table string, w io.Writer) error {
+	var sql string
+	if len(dbName) == 0 {
+		sql = sqlite.Mprintf("SELECT * FROM %Q", table)
+	} else {
+		sql = sqlite.Mprintf("SELECT * FROM %Q.%Q", dbName, table)
+	}
+	s, err := db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer s.Finalize()
+	columnNames := s.ColumnNames()
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	bw.WriteString("[\n")
+	sep := ""
+	err = s.Select(func(s *sqlite.Stmt) error {
+		row := make(map[string]interface{}, len(columnNames))
+		for i, name := range columnNames {
+			row[name], _ = s.ScanValue(i, false)
+		}
+		bw.WriteString(sep)
+		sep = ","
+		return enc.Encode(row)
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
+
+// execute runs one or many statements (separated by semi-colon) and prints results.
+// Execution stops at the first error (which is reported on stderr and returned).
+func (st *shellState) execute(cmd string) error {
+	defer func() {
+		if st.once {
+			trace(st.resetOutput())
+		}
+	}()
+	if st.expert {
+		st.expert = false
+		return st.adviseIndexes(cmd)
+	}
+	if st.format {
+		st.format = false
+		formatted, err := shell.Format(cmd, nil)
+		if trace(err) {
+			return err
+		}
+		_, err = io.WriteString(st.out, formatted)
+		return err
+	}
+	for len(cmd) > 0 {
+		start := time.Now()
+		s, err := st.db.Prepare(cmd)
+		if trace(err) {
+			return err
+		} else if s.Empty() {
+			cmd = s.Tail()
+			continue
+		}
+		if err = st.bindParameters(s); trace(err) {
+			s.Finalize()
+			return err
+		}
+		columnCount := s.ColumnCount()
+		if columnCount > 0 && st.mode == "markdown" {
+			err = s.ExportToMarkdown(st.out, &sqlite.TableOptions{Headers: st.headers, NullValue: st.nullvalue})
+		} else if columnCount > 0 && st.mode == "html" {
+			err = s.ExportToHTML(st.out, &sqlite.TableOptions{Headers: st.headers, NullValue: st.nullvalue})
+		} else if columnCount > 0 {
+			tw := tabwriter.NewWriter(st.out, 0, 8, 0, '\t', 0)
+			color := colored && st.out == os.Stdout
+			if st.headers {
+				// FIXME headers are displayed only if DataCount() > 0
+				headers := s.ColumnNames()
+				for _, header := range headers {
+					if color {
+						io.WriteString(tw, colorHeader+header+colorReset)
+					} else {
+						io.WriteString(tw, header)
+					}
+					io.WriteString(tw, "\t")
+				}
+				io.WriteString(tw, "\n")
+			}
+			err = s.Select(func(s *sqlite.Stmt) error {
+				for i := 0; i < columnCount; i++ {
+					blob, isNull := s.ScanRawBytes(i)
+					if color {
+						if isNull {
+							io.WriteString(tw, colorNull)
+						} else {
+							io.WriteString(tw, colorPlain)
+						}
+					}
+					if isNull {
+						io.WriteString(tw, st.nullvalue)
+					} else {
+						tw.Write(blob)
+					}
+					if color {
+						io.WriteString(tw, colorReset)
+					}
+					io.WriteString(tw, "\t") // https://github.com/kr/text
+				}
+				io.WriteString(tw, "\n")
+				return nil
+			})
+			tw.Flush()
+		} else {
+			err = s.Exec()
+		}
+		if trace(err) {
+			s.Finalize()
+			return err
+		}
+		if st.stats {
+			st.printStats(s)
+		}
+		if err = s.Finalize(); trace(err) {
+			return err
+		}
+		if st.timer {
+			fmt.Fprintf(os.Stderr, "Run Time: real %.3f\n", time.Since(start).Seconds())
+		}
+		cmd = s.Tail()
+	}
+	return nil
+}
+
+// adviseIndexes prints the indexes suggested for the statements (which are not executed).
+func (st *shellState) adviseIndexes(cmd string) error {
+	suggestions, err := st.db.AdviseIndexes(sqlite.SplitStatements(cmd)...)
+	if trace(err) {
+		return err
+	}
+	if len(suggestions) == 0 {
+		fmt.Fprintln(st.out, "(no new indexes)")
+	}
+	for _, suggestion := range suggestions {
+		fmt.Fprintf(st.out, "%s; -- benefit: %d rows\n", suggestion.SQL, suggestion.Benefit)
+	}
+	return nil
+}
+
+// read executes the SQL statements and dot commands contained in the specified file.
+func (st *shellState) read(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return st.process(bufio.NewReader(f))
+}
+
+// process executes the SQL statements and dot commands read from in.
+// Dot commands are recognized only at the beginning of a line, outside of any SQL statement.
+// The SQL text between them is split with sqlite.SplitStatements.
+// When .bail is on, processing stops at the first error and errBail is returned.
+func (st *shellState) process(in *bufio.Reader) error {
+	var b bytes.Buffer
+	for {
+		line, err := in.ReadString('\n')
+		if len(line) == 0 && err != nil {
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if pending := b.String(); isCommand(line) && (sqlite.IsComplete(pending) || len(sqlite.SplitStatements(pending)) == 0) {
+			if err = st.executeAll(pending); err != nil {
+				return err
+			}
+			b.Reset()
+			st.echoInput(line)
+			if err = st.doMetaCommand(line); err == errExit || err == errBail {
+				return err
+			} else if trace(err) && st.bail {
+				return errBail
+			}
+			continue
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return st.executeAll(b.String())
+}
+
+// executeAll executes the SQL statements contained in sql one by one.
+// When .bail is on, execution stops at the first error and errBail is returned.
+func (st *shellState) executeAll(sql string) error {
+	for _, cmd := range sqlite.SplitStatements(sql) {
+		st.echoInput(cmd)
+		if err := st.execute(cmd); err != nil && st.bail {
+			return errBail
+		}
+	}
+	return nil
+}
+
+// echoInput prints the command before its execution when .echo is on.
+func (st *shellState) echoInput(cmd string) {
+	if st.echo {
+		fmt.Fprintln(st.out, strings.TrimRight(cmd, "\n"))
+	}
+}
+
 func main() {
+	noColor := flag.Bool("no-color", false, "disable ANSI colors")
+	flag.Parse()
 	var err error
 	check(err)
 	if !liner.IsTerminal() {
 		return // TODO non-interactive mode
 	}
+	colored = !*noColor && os.Getenv("TERM") != "dumb"
 	state, err := liner.NewLiner()
 	check(err)
 	defer func() {
@@ -169,21 +972,22 @@ func main() {
 	check(err)
 
 	dbFilename := ":memory:"
-	if len(os.Args) > 1 {
-		dbFilename = os.Args[1]
+	if flag.NArg() > 0 {
+		dbFilename = flag.Arg(0)
 	}
 	db, err := sqlite.Open(dbFilename) // TODO command-line flag
 	check(err)
-	defer db.Close()
 
 	catchInterrupt()
 
-	err = completionCache.Cache(db)
+	err = completionCache.Watch(db)
 	check(err)
 
-	// TODO .mode MODE ?TABLE?     Set output mode where MODE is one of:
-	// TODO .separator STRING      Change separator used by output mode and .import
-	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 0, '\t', 0)
+	st := newShellState(db, completionCache)
+	defer func() {
+		st.resetOutput()
+		st.db.Close() // the database may have been changed by .open
+	}()
 	prompt := mainPrompt
 	var b bytes.Buffer
 	for {
@@ -198,64 +1002,30 @@ func main() {
 
 		if isBlank(line) {
 			continue
-		} else if isCommand(line) {
-			fmt.Println("TBD")
+		} else if b.Len() == 0 && isCommand(line) {
+			appendHistory(state, line)
+			st.echoInput(line)
+			err = st.doMetaCommand(line)
+			if err == errExit {
+				break
+			} else if err != errBail {
+				trace(err)
+			}
 			continue
 		}
 
 		b.WriteString(line)
 		cmd := b.String()
-		if !sqlite.Complete(cmd) {
+		if !sqlite.IsComplete(cmd) {
 			b.WriteByte(' ') // TODO Validate ' ' versus '\n'
 			prompt = continuePrompt
 			continue
 		}
 		prompt = mainPrompt
-		// TODO .echo ON|OFF           Turn command echo on or off
-		//fmt.Println(cmd)
 		appendHistory(state, cmd)
-		for len(cmd) > 0 {
-			s, err := db.Prepare(cmd)
-			if trace(err) {
-				break // TODO bail_on_error
-			} else if s.Empty() {
-				cmd = s.Tail()
-				continue
-			}
-			columnCount := s.ColumnCount()
-			if columnCount > 0 {
-				// FIXME headers are displayed only if DataCount() > 0
-				headers := s.ColumnNames() // TODO .header(s) ON|OFF      Turn display of headers on or off
-				for _, header := range headers {
-					io.WriteString(tw, header)
-					io.WriteString(tw, "\t")
-				}
-				io.WriteString(tw, "\n")
-				err = s.Select(func(s *sqlite.Stmt) error {
-					for i := 0; i < columnCount; i++ {
-						blob, _ := s.ScanRawBytes(i)
-						// TODO .nullvalue STRING      Use STRING in place of NULL values
-						tw.Write(blob)
-						io.WriteString(tw, "\t") // https://github.com/kr/text
-					}
-					io.WriteString(tw, "\n")
-					return nil
-				})
-				tw.Flush()
-			} else {
-				err = s.Exec()
-			}
-			if trace(err) {
-				s.Finalize()
-				break // TODO bail_on_error
-			}
-			if trace(s.Finalize()) {
-				break // TODO bail_on_error
-			}
-			cmd = s.Tail()
-		} // exec
+		st.echoInput(cmd)
+		st.execute(cmd)
 		b.Reset()
-		completionCache.Update(db)
 	}
 }
 
@@ -267,14 +1037,16 @@ func main() {
 .dump ?TABLE? ...      Dump the database in an SQL text format => ???
 .echo ON|OFF           Turn command echo on or off
 .exit                  Exit this program => *
+.expert                Suggest indexes for the next SQL statements (instead of executing them) => *
 .explain ?ON|OFF?      Turn output mode suitable for EXPLAIN on or off.
+.format                Pretty-print the next SQL statements (instead of executing them) => shell.Format
 .header(s) ON|OFF      Turn display of headers on or off => *
 .help                  Show this message => *
 .import FILE TABLE     Import data from FILE into TABLE => ImportCSV(FILE, ImportConfig, ???, TABLE) (TABLE may be qualified)
 .indices ?TABLE?       Show names of all indices => db.Indexes("main", both) + filter
 .load FILE ?ENTRY?     Load an extension library => db.LoadExtension(FILE, ?ENTRY?)
 .log FILE|off          Turn logging on or off.  FILE can be stderr/stdout
-.mode MODE ?TABLE?     Set output mode
+.mode MODE ?TABLE?     Set output mode => * (column, markdown or html)
 .nullvalue STRING      Use STRING in place of NULL values
 .open ?FILENAME?       Close existing database and reopen FILENAME
 .output FILENAME       Send output to FILENAME => *
@@ -286,7 +1058,7 @@ func main() {
 .restore ?DB? FILE     Restore content of DB (default "main") from FILE => NewBackup(db, ?DB?, src, "main")
 .save FILE             Write in-memory database into FILE => NewBackup(dst, "main", db, "main")
 .schema ?TABLE?        Show the CREATE statements => *
-.separator STRING      Change separator used by output mode and .import => *
+.separator STRING      Change separator used by .import and .export (ignored by the column, markdown and html output modes) => *
 .show                  Show the current values for various settings
 .stats ON|OFF          Turn stats on or off
 .tables ?TABLE?        List names of tables => *
@@ -0,0 +1,208 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"strings"
+)
+
+// SplitStatements splits sql into the statements it contains (separated by semicolons).
+// Semicolons inside string literals, quoted identifiers, comments and trigger bodies are ignored.
+// Leading spaces and comments are removed from each statement, and empty statements are skipped.
+// The last statement may not be terminated by a semicolon.
+// No connection is needed (the text is not parsed by SQLite, so invalid statements are not detected).
+func SplitStatements(sql string) []string {
+	var stmts []string
+	for {
+		n, _ := skipSpacesAndComments(sql)
+		sql = sql[n:]
+		if len(sql) == 0 {
+			return stmts
+		}
+		n, _ = scanStatement(sql)
+		if stmt := strings.TrimSpace(sql[:n]); stmt != ";" {
+			stmts = append(stmts, stmt)
+		}
+		sql = sql[n:]
+	}
+}
+
+// IsComplete tells if sql ends with a complete statement (terminated by a semicolon).
+// Contrary to Complete, it does not rely on SQLite:
+// semicolons inside trigger bodies (BEGIN ... END blocks, with CASE ... END expressions),
+// string/blob literals, quoted identifiers and comments are correctly ignored.
+// Like Complete, statements are not checked for syntax errors.
+func IsComplete(sql string) bool {
+	complete := false
+	for {
+		n, ok := skipSpacesAndComments(sql)
+		if !ok {
+			return false
+		}
+		sql = sql[n:]
+		if len(sql) == 0 {
+			return complete
+		}
+		n, complete = scanStatement(sql)
+		sql = sql[n:]
+	}
+}
+
+// skipSpacesAndComments returns the position of the first token of sql
+// and false when sql ends with an unterminated block comment.
+func skipSpacesAndComments(sql string) (int, bool) {
+	i := 0
+	for i < len(sql) {
+		switch c := sql[i]; {
+		case isSQLSpace(c):
+			i++
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			i += lineCommentLen(sql[i:])
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			n, ok := blockCommentLen(sql[i:])
+			if !ok {
+				return len(sql), false
+			}
+			i += n
+		default:
+			return i, true
+		}
+	}
+	return i, true
+}
+
+// scanStatement returns the length of the first statement of sql (including its terminating semicolon).
+// complete is false when there is no terminating semicolon
+// (or when the statement ends inside a literal, a comment or a trigger body).
+func scanStatement(sql string) (n int, complete bool) {
+	var t triggerState
+	i := 0
+	for i < len(sql) {
+		c := sql[i]
+		switch {
+		case isSQLSpace(c):
+			i++
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			i += lineCommentLen(sql[i:])
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			l, ok := blockCommentLen(sql[i:])
+			if !ok {
+				return len(sql), false
+			}
+			i += l
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			l, ok := quotedLen(sql[i:])
+			if !ok {
+				return len(sql), false
+			}
+			i += l
+			t.token("")
+		case c == ';':
+			i++
+			if t.terminated() {
+				return i, true
+			}
+		case isIDChar(c):
+			j := i + 1
+			for j < len(sql) && isIDChar(sql[j]) {
+				j++
+			}
+			t.token(sql[i:j])
+			i = j
+		default:
+			i++
+			t.token("")
+		}
+	}
+	return len(sql), false
+}
+
+// triggerState tracks CREATE TRIGGER statements whose body contains semicolons:
+// such a statement ends with the semicolon following the END of its body (BEGIN ... END),
+// CASE ... END expressions being taken into account.
+type triggerState struct {
+	nTokens int  // number of tokens seen (limited to the statement prefix)
+	create  bool // CREATE [TEMP|TEMPORARY] seen at the beginning of the statement
+	trigger bool
+	body    bool // BEGIN seen
+	depth   int  // BEGIN/CASE ... END nesting in the trigger body
+}
+
+func (t *triggerState) token(word string) {
+	if t.trigger {
+		if !t.body {
+			if strings.EqualFold(word, "BEGIN") {
+				t.body = true
+				t.depth = 1
+			}
+		} else if strings.EqualFold(word, "CASE") {
+			t.depth++
+		} else if strings.EqualFold(word, "END") {
+			t.depth--
+		}
+		return
+	}
+	t.nTokens++
+	switch {
+	case t.nTokens == 1 && strings.EqualFold(word, "EXPLAIN"):
+		t.nTokens = 0
+	case t.nTokens == 1 && (strings.EqualFold(word, "QUERY") || strings.EqualFold(word, "PLAN")): // EXPLAIN QUERY PLAN
+		t.nTokens = 0
+	case t.nTokens == 1 && strings.EqualFold(word, "CREATE"):
+		t.create = true
+	case t.create && t.nTokens == 2 && (strings.EqualFold(word, "TEMP") || strings.EqualFold(word, "TEMPORARY")):
+	case t.create && t.nTokens <= 3 && strings.EqualFold(word, "TRIGGER"):
+		t.trigger = true
+	default:
+		t.create = false
+	}
+}
+
+func (t *triggerState) terminated() bool {
+	return !t.trigger || (t.body && t.depth <= 0)
+}
+
+func isSQLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isIDChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
+
+// lineCommentLen returns the length of the comment (starting with --) up to the end of line (included).
+func lineCommentLen(sql string) int {
+	if i := strings.IndexByte(sql, '\n'); i >= 0 {
+		return i + 1
+	}
+	return len(sql)
+}
+
+// blockCommentLen returns the length of the comment (starting with /*) and whether it is terminated.
+func blockCommentLen(sql string) (int, bool) {
+	if i := strings.Index(sql[2:], "*/"); i >= 0 {
+		return i + 4, true
+	}
+	return len(sql), false
+}
+
+// quotedLen returns the length of the literal or quoted identifier starting at sql[0]
+// (where quotes are escaped by doubling them) and whether it is terminated.
+func quotedLen(sql string) (int, bool) {
+	delim := sql[0]
+	if delim == '[' {
+		delim = ']'
+	}
+	for i := 1; i < len(sql); i++ {
+		if sql[i] == delim {
+			if delim != ']' && i+1 < len(sql) && sql[i+1] == delim {
+				i++
+				continue
+			}
+			return i + 1, true
+		}
+	}
+	return len(sql), false
+}
@@ -0,0 +1,68 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestSplitStatements(t *testing.T) {
+	for sql, expected := range map[string][]string{
+		"":                      nil,
+		" -- comment\n /* */ ;": nil,
+		"SELECT 1":              {"SELECT 1"},
+		"SELECT 1; SELECT 2;":   {"SELECT 1;", "SELECT 2;"},
+		"SELECT ';', \"a;b\", [c;d], `e;f`; -- x;y\nSELECT 'it''s';": {"SELECT ';', \"a;b\", [c;d], `e;f`;", "SELECT 'it''s';"},
+		"SELECT 1 /* ; */; /* leading */ SELECT 2;;":                 {"SELECT 1 /* ; */;", "SELECT 2;"},
+		"CREATE TEMP TRIGGER t AFTER INSERT ON x BEGIN INSERT INTO y VALUES (CASE WHEN 1 THEN 2 END); DELETE FROM z; END; SELECT 1": {
+			"CREATE TEMP TRIGGER t AFTER INSERT ON x BEGIN INSERT INTO y VALUES (CASE WHEN 1 THEN 2 END); DELETE FROM z; END;", "SELECT 1"},
+		"SELECT 'unterminated; SELECT 2;": {"SELECT 'unterminated; SELECT 2;"},
+	} {
+		assert.Equalf(t, expected, SplitStatements(sql), "%q", sql)
+	}
+}
+
+func TestSplitStatementsPrepare(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	stmts := SplitStatements("CREATE TABLE x (a); CREATE TRIGGER t AFTER INSERT ON x BEGIN SELECT 1; SELECT 2; END; INSERT INTO x VALUES (';')")
+	assert.Equal(t, 3, len(stmts))
+	for _, sql := range stmts {
+		s, err := db.Prepare(sql)
+		checkNoError(t, err, "couldn't prepare stmt: %s")
+		assert.Equal(t, "", s.Tail())
+		checkNoError(t, s.Exec(), "couldn't execute stmt: %s")
+		checkFinalize(s, t)
+	}
+}
+
+func TestIsComplete(t *testing.T) {
+	for sql, expected := range map[string]bool{
+		"":                     false,
+		";":                    true,
+		"SELECT 1":             false,
+		"SELECT 1;":            true,
+		"SELECT 1; -- comment": true,
+		"SELECT 1; /* comment": false,
+		"SELECT 1; SELECT 2":   false,
+		"SELECT ';":            false,
+		"SELECT ';';":          true,
+		"SELECT x'00';":        true,
+		"SELECT [a;b":          false,
+		"SELECT 1 -- ;":        false,
+		"CREATE TRIGGER t AFTER INSERT ON x BEGIN SELECT 1;":                           false,
+		"CREATE TRIGGER t AFTER INSERT ON x BEGIN SELECT CASE WHEN 1 THEN 2 END;":      false,
+		"CREATE TRIGGER t AFTER INSERT ON x BEGIN SELECT CASE WHEN 1 THEN 2 END; END":  false,
+		"CREATE TRIGGER t AFTER INSERT ON x BEGIN SELECT CASE WHEN 1 THEN 2 END; END;": true,
+		"create temporary trigger t after insert on x begin select 1; end; -- done":    true,
+		"EXPLAIN CREATE TRIGGER t AFTER INSERT ON x BEGIN SELECT 1;":                   false,
+		"CREATE TABLE trigger (x); SELECT 1;":                                          true,
+	} {
+		assert.Equalf(t, expected, IsComplete(sql), "%q", sql)
+	}
+}
@@ -17,6 +17,15 @@ const char *sqlite3_errstr(int rc) {
 	return "";
 }
 #endif
+#if SQLITE_VERSION_NUMBER < 3031000
+static const char *goSqlite3UriKey(const char *zFilename, int n) {
+	return 0;
+}
+#else
+static const char *goSqlite3UriKey(const char *zFilename, int n) {
+	return sqlite3_uri_key(zFilename, n);
+}
+#endif
 */
 import "C"
 
@@ -26,6 +35,8 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 	"unsafe"
 )
@@ -64,6 +75,7 @@ type ConnError struct {
 	code    Errno  // thread safe error code
 	msg     string // it might be the case that a second error occurs on a separate thread in between the time of the first error and the call to retrieve this message.
 	details string // contextual informations, thread safe
+	cause   error  // original error returned by a virtual table method
 }
 
 // Code returns the original SQLite error code (or -1 for errors generated by the Go wrapper)
@@ -83,6 +95,12 @@ func (e ConnError) Filename() string {
 	return e.c.Filename("main")
 }
 
+// Unwrap returns the original Go error returned by the virtual table method which made the SQLite call fail (or nil).
+// So errors.As can be used to retrieve the typed errors of a Module.
+func (e ConnError) Unwrap() error {
+	return e.cause
+}
+
 func (e ConnError) Error() string { // FIXME code.Error() & e.msg are often redundant...
 	if len(e.details) > 0 {
 		return fmt.Sprintf("%s (%s) (%s)", e.msg, e.details, e.code.Error())
@@ -148,13 +166,18 @@ func (c *Conn) error(rv C.int, details ...string) error {
 	if c == nil {
 		return errors.New("nil sqlite database")
 	}
+	c.countBusy(rv)
 	if rv == C.SQLITE_OK {
 		return nil
 	}
+	if err := c.vetoedCommit(rv); err != nil {
+		return err
+	}
 	err := ConnError{c: c, code: Errno(rv), msg: C.GoString(C.sqlite3_errmsg(c.db))}
 	if len(details) > 0 {
 		err.details = details[0]
 	}
+	err.cause = c.vtabError(err.msg)
 	return err
 }
 
@@ -183,22 +206,59 @@ type Conn struct {
 	stmtCache       *cache
 	authorizer      *sqliteAuthorizer
 	busyHandler     *sqliteBusyHandler
+	busyTimeout     time.Duration
+	busyOverrides   []time.Duration // stack of the timeouts in force (see overrideBusyTimeout)
+	busyTimer       unsafe.Pointer  // C-allocated busy timeout handler state (see setBusyTimeout)
+	busyCounters    busyCounters
 	profile         *sqliteProfile
 	progressHandler *sqliteProgressHandler
 	trace           *sqliteTrace
 	commitHook      *sqliteCommitHook
+	commitValidator *sqliteCommitValidator
+	commitErr       error // error returned by the CommitValidator for the last vetoed commit
+	vtabErr         error // error returned by the last failed virtual table method
 	rollbackHook    *sqliteRollbackHook
 	updateHook      *sqliteUpdateHook
+	updateFilter    unsafe.Pointer // C-allocated filter used by ChangesHook
+	changesHook     *sqliteChangesHook
+	hooks           hookSubscribers
 	udfs            map[string]*sqliteFunction
-	modules         map[string]*sqliteModule
+	modules         map[*sqliteModule]struct{}     // registered modules not yet destroyed by SQLite
+	rtreeQueries    map[*sqliteRTreeQuery]struct{} // registered R-Tree queries not yet destroyed by SQLite
+	fts5Functions   map[string]*fts5Function
 	timeUsed        time.Time
 	nTransaction    uint8
+	savepoints      []savepoint // savepoints started with Conn.Savepoint (innermost last)
+	lastSavepoint   uint64
+	restoreBusy     func() // restores the busy timeout overridden by BeginTransactionTimeout
+	affinity        *affinityCheck
+	stmtStacks      map[*C.sqlite3_stmt]string // call-site of the preparation of each statement (leak detection)
+	unlockNotify    bool                       // wait/retry on shared-cache table lock errors
+	interruptMu     sync.Mutex                 // protects closing and closed (Interrupt versus Close)
+	closing         bool
+	closed          chan struct{} // closed when the connection is closed (see InterruptOnDone)
 	// DefaultTimeLayout specifies the layout used to persist time ("2006-01-02 15:04:05.000Z07:00" by default).
 	// When set to "", time is persisted as integer (unix time).
 	// Using type alias implementing the Scanner/Valuer interfaces is suggested...
 	DefaultTimeLayout string
-	// ScanNumericalAsTime tells the driver to try to parse column with NUMERIC affinity as time.Time (using the DefaultTimeLayout)
+	// ScanTimeLayouts specifies the layouts tried in sequence to parse text timestamps when scanning
+	// (only the DefaultTimeLayout when empty). See TimeLayouts.
+	ScanTimeLayouts []string
+	// ScanNumericalAsTime tells the driver to try to parse column with NUMERIC affinity as time.Time (using the ScanTimeLayouts)
 	ScanNumericalAsTime bool
+	// ScanDeclaredTypes tells the driver to convert values according to the column declared type:
+	// BOOLEAN to bool, DATE/DATETIME/TIMESTAMP to time.Time (using the ScanTimeLayouts) and DECIMAL to string
+	ScanDeclaredTypes bool
+	// NullIfEmptyString transforms empty string to null when bound (initialized with the NullIfEmptyString global)
+	NullIfEmptyString bool
+	// NullIfZeroTime transforms zero time (time.Time.IsZero) to null when bound (initialized with the NullIfZeroTime global)
+	NullIfZeroTime bool
+	// NullIfEmptyBlob transforms empty (or nil) []byte to null when bound (a zero-length blob is bound by default)
+	NullIfEmptyBlob bool
+	// ScanEmptyBlobAsNil tells ScanValue (and the driver) to return a nil []byte for zero-length blob (instead of []byte{})
+	ScanEmptyBlobAsNil bool
+	// AutoReset is the default value of Stmt.AutoReset for statements prepared afterwards
+	AutoReset bool
 }
 
 // Version returns the run-time library version number
@@ -214,6 +274,26 @@ func VersionNumber() int32 {
 	return int32(C.sqlite3_libversion_number())
 }
 
+// VersionAtLeast checks that the run-time library version number is greater than or equal to minVersionNumber (as 300X00Y).
+func VersionAtLeast(minVersionNumber int) bool {
+	return int(VersionNumber()) >= minVersionNumber
+}
+
+// FormatVersionNumber converts a version number like 3024000 to "3.24.0".
+func FormatVersionNumber(versionNumber int) string {
+	return fmt.Sprintf("%d.%d.%d", versionNumber/1000000, versionNumber/1000%1000, versionNumber%1000)
+}
+
+// RequireVersion returns an error when the run-time library is older than minVersionNumber (as 300X00Y).
+// Packages depending on recent features (upsert: 3024000, window functions: 3025000, ...)
+// may call it at startup to fail fast.
+func RequireVersion(minVersionNumber int) error {
+	if VersionAtLeast(minVersionNumber) {
+		return nil
+	}
+	return fmt.Errorf("SQLite version %s or later required (linked version is %s)", FormatVersionNumber(minVersionNumber), Version())
+}
+
 // OpenFlag enumerates flags for file open operations
 type OpenFlag int32
 
@@ -240,9 +320,22 @@ func Open(filename string, flags ...OpenFlag) (*Conn, error) {
 
 // OpenVfs opens a new database with a specified virtual file system.
 func OpenVfs(filename string, vfsname string, flags ...OpenFlag) (*Conn, error) {
+	c, err := openVfs(filename, vfsname, flags...)
+	if err != nil {
+		return nil, err
+	}
+	return c.autoRegistered()
+}
+
+// openVfs opens a new database without calling the functions registered with AutoRegister
+// (so that an encryption key can be set before any other statement is executed).
+func openVfs(filename string, vfsname string, flags ...OpenFlag) (*Conn, error) {
 	if C.sqlite3_threadsafe() == 0 {
 		return nil, errors.New("sqlite library was not compiled for thread-safe operation")
 	}
+	if strings.IndexByte(filename, 0) >= 0 {
+		return nil, OpenError{Code: ErrCantOpen, Filename: filename, Msg: "filename contains a NUL byte"}
+	}
 	var openFlags int
 	if len(flags) > 0 {
 		for _, flag := range flags {
@@ -277,13 +370,54 @@ func OpenVfs(filename string, vfsname string, flags ...OpenFlag) (*Conn, error)
 	if db == nil {
 		return nil, errors.New("sqlite succeeded without returning a database")
 	}
-	c := &Conn{db: db, stmtCache: newCache(), DefaultTimeLayout: "2006-01-02 15:04:05.000Z07:00"}
+	c := &Conn{db: db, stmtCache: newCache(), DefaultTimeLayout: "2006-01-02 15:04:05.000Z07:00",
+		NullIfEmptyString: NullIfEmptyString, NullIfZeroTime: NullIfZeroTime}
 	if os.Getenv("SQLITE_DEBUG") != "" {
 		//c.SetAuthorizer(authorizer, c.db)
 		c.Trace(trace, "TRACE")
 		//c.SetCacheSize(0)
 	}
+	c.trackLeak()
+	return c, nil
+}
+
+var autoRegister struct {
+	sync.Mutex
+	fs []func(c *Conn) error
+}
 
+// AutoRegister registers a function which is called for each new connection
+// (created by Open, OpenVfs, OpenWithKey, the database/sql driver or a Pool factory using Open)
+// to declare functions, collations, modules...
+// When an encryption key is specified, the functions are called after the key has been set.
+// If it fails, the connection is closed and Open returns the error.
+// (See http://sqlite.org/c3ref/auto_extension.html)
+func AutoRegister(f func(c *Conn) error) {
+	autoRegister.Lock()
+	autoRegister.fs = append(autoRegister.fs, f)
+	autoRegister.Unlock()
+}
+
+// ResetAutoRegister unregisters all functions registered with AutoRegister.
+// (See http://sqlite.org/c3ref/reset_auto_extension.html)
+func ResetAutoRegister() {
+	autoRegister.Lock()
+	autoRegister.fs = nil
+	autoRegister.Unlock()
+}
+
+// autoRegistered calls the functions registered with AutoRegister on c.
+// On failure, c is closed.
+func (c *Conn) autoRegistered() (*Conn, error) {
+	autoRegister.Lock()
+	fs := autoRegister.fs
+	autoRegister.Unlock()
+	for _, f := range fs {
+		if err := f(c); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+	}
 	return c, nil
 }
 
@@ -299,10 +433,16 @@ func trace(d interface{}, sql string) {
 
 // BusyTimeout sets a busy timeout and clears any previously set handler.
 // If duration is zero or negative, turns off busy handler.
+// Contrary to sqlite3_busy_timeout, the handler invocations are counted (see Conn.BusyStats).
 // (See http://sqlite.org/c3ref/busy_timeout.html)
 func (c *Conn) BusyTimeout(d time.Duration) error {
 	c.busyHandler = nil
-	return c.error(C.sqlite3_busy_timeout(c.db, C.int(d/time.Millisecond)), "Conn.BusyTimeout")
+	if d <= 0 {
+		c.busyTimeout = 0
+	} else {
+		c.busyTimeout = d / time.Millisecond * time.Millisecond
+	}
+	return c.error(c.setBusyTimeout(d), "Conn.BusyTimeout")
 }
 
 // Readonly determines if a database is read-only.
@@ -320,9 +460,88 @@ func (c *Conn) Readonly(dbName string) (bool, error) {
 // Filename returns the filename for a database connection.
 // (See http://sqlite.org/c3ref/db_filename.html)
 func (c *Conn) Filename(dbName string) string {
+	return C.GoString(c.dbFilename(dbName))
+}
+
+func (c *Conn) dbFilename(dbName string) *C.char {
 	cname := C.CString(dbName)
 	defer C.free(unsafe.Pointer(cname))
-	return C.GoString(C.sqlite3_db_filename(c.db, cname))
+	return C.sqlite3_db_filename(c.db, cname)
+}
+
+// URIParameter returns the value of the query parameter param in the URI used to open the specified database.
+// Database name is optional (default is 'main').
+// ok is false when the parameter is not present or when dbName is not the name of a database.
+// (See http://sqlite.org/c3ref/uri_boolean.html)
+func (c *Conn) URIParameter(dbName, param string) (value string, ok bool) {
+	if len(dbName) == 0 {
+		dbName = "main"
+	}
+	zFilename := c.dbFilename(dbName)
+	if zFilename == nil {
+		return "", false
+	}
+	cparam := C.CString(param)
+	defer C.free(unsafe.Pointer(cparam))
+	p := C.sqlite3_uri_parameter(zFilename, cparam)
+	if p == nil {
+		return "", false
+	}
+	return C.GoString(p), true
+}
+
+// URIBoolean returns the value of the boolean query parameter param in the URI used to open the specified database
+// ("1", "yes", "true" or "on" versus "0", "no", "false" or "off").
+// dflt is returned when the parameter is not present or not a boolean.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/c3ref/uri_boolean.html)
+func (c *Conn) URIBoolean(dbName, param string, dflt bool) bool {
+	if len(dbName) == 0 {
+		dbName = "main"
+	}
+	zFilename := c.dbFilename(dbName)
+	if zFilename == nil {
+		return dflt
+	}
+	cparam := C.CString(param)
+	defer C.free(unsafe.Pointer(cparam))
+	return C.sqlite3_uri_boolean(zFilename, cparam, btocint(dflt)) != 0
+}
+
+// URIInt64 returns the value of the integer query parameter param in the URI used to open the specified database.
+// dflt is returned when the parameter is not present or not an integer.
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/c3ref/uri_boolean.html)
+func (c *Conn) URIInt64(dbName, param string, dflt int64) int64 {
+	if len(dbName) == 0 {
+		dbName = "main"
+	}
+	zFilename := c.dbFilename(dbName)
+	if zFilename == nil {
+		return dflt
+	}
+	cparam := C.CString(param)
+	defer C.free(unsafe.Pointer(cparam))
+	return int64(C.sqlite3_uri_int64(zFilename, cparam, C.sqlite3_int64(dflt)))
+}
+
+// URIKey returns the name of the n-th query parameter (0-based) in the URI used to open the specified database.
+// ok is false when n is out of range (always with SQLite < 3.31).
+// Database name is optional (default is 'main').
+// (See http://sqlite.org/c3ref/uri_boolean.html)
+func (c *Conn) URIKey(dbName string, n int) (key string, ok bool) {
+	if len(dbName) == 0 {
+		dbName = "main"
+	}
+	zFilename := c.dbFilename(dbName)
+	if zFilename == nil || n < 0 {
+		return "", false
+	}
+	p := C.goSqlite3UriKey(zFilename, C.int(n))
+	if p == nil {
+		return "", false
+	}
+	return C.GoString(p), true
 }
 
 // Exec prepares and executes one or many parameterized statement(s) (separated by semi-colon).
@@ -358,6 +577,33 @@ func (c *Conn) Exec(cmd string, args ...interface{}) error {
 	return nil
 }
 
+// ExecNamed is like Exec but binds the parameters of each statement by their name (see Stmt.BindNamed):
+// the same args are used for all the statements.
+func (c *Conn) ExecNamed(cmd string, args map[string]interface{}) error {
+	for len(cmd) > 0 {
+		s, err := c.prepare(cmd)
+		if err != nil {
+			return err
+		} else if s.stmt == nil {
+			// this happens for a comment or white-space
+			cmd = s.tail
+			continue
+		}
+		if err = s.BindNamed(args); err == nil {
+			err = s.exec()
+		}
+		if err != nil {
+			s.finalize()
+			return err
+		}
+		if err = s.finalize(); err != nil {
+			return err
+		}
+		cmd = s.tail
+	}
+	return nil
+}
+
 // ExecDml helps executing DML statement:
 // (1) it binds the specified args,
 // (2) it executes the statement,
@@ -371,6 +617,18 @@ func (c *Conn) ExecDml(cmd string, args ...interface{}) (changes int, err error)
 	return s.ExecDml(args...)
 }
 
+// ExecResult is like ExecDml but returns both the number of rows changed and the last inserted rowid
+// (without the race of calling Changes and LastInsertRowid on a shared connection).
+// See Stmt.ExecResult
+func (c *Conn) ExecResult(cmd string, args ...interface{}) (changes int64, lastRowid int64, err error) {
+	s, err := c.Prepare(cmd)
+	if err != nil {
+		return -1, -1, err
+	}
+	defer s.Finalize()
+	return s.ExecResult(args...)
+}
+
 // Insert is like ExecDml but returns the autoincremented rowid.
 func (c *Conn) Insert(cmd string, args ...interface{}) (rowid int64, err error) {
 	n, err := c.ExecDml(cmd, args...)
@@ -397,6 +655,19 @@ func (c *Conn) Select(query string, rowCallbackHandler func(s *Stmt) error, args
 	return s.Select(rowCallbackHandler, args...)
 }
 
+// SelectNamed is like Select but binds parameters by their name (see Stmt.BindNamed).
+func (c *Conn) SelectNamed(query string, rowCallbackHandler func(s *Stmt) error, args map[string]interface{}) error {
+	s, err := c.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer s.Finalize()
+	if err = s.BindNamed(args); err != nil {
+		return err
+	}
+	return s.Select(rowCallbackHandler)
+}
+
 // SelectByID helps executing SELECT statement that is expected to return only one row.
 // Args are for scanning (not binding).
 // Returns false if there is no matching row.
@@ -469,9 +740,14 @@ func (c *Conn) LastInsertRowid() int64 {
 }
 
 // Interrupt interrupts a long-running query.
+// It is safe to call it concurrently with Close (it is a no-op once Close has begun).
 // (See http://sqlite.org/c3ref/interrupt.html)
 func (c *Conn) Interrupt() {
-	C.sqlite3_interrupt(c.db)
+	c.interruptMu.Lock()
+	defer c.interruptMu.Unlock()
+	if !c.closing && c.db != nil {
+		C.sqlite3_interrupt(c.db)
+	}
 }
 
 // GetAutocommit tests for auto-commit mode.
@@ -480,6 +756,21 @@ func (c *Conn) GetAutocommit() bool {
 	return C.sqlite3_get_autocommit(c.db) != 0
 }
 
+// InTransaction tests whether a transaction (started with BEGIN or SAVEPOINT) is active.
+// (See http://sqlite.org/c3ref/get_autocommit.html)
+func (c *Conn) InTransaction() bool {
+	return !c.GetAutocommit()
+}
+
+// SavepointDepth returns the number of active savepoints started with Conn.Savepoint (or Conn.Transaction).
+// Savepoints started by executing SQL directly are not counted.
+func (c *Conn) SavepointDepth() int {
+	if c.GetAutocommit() { // transaction committed or rolled back (maybe automatically)
+		c.savepoints = c.savepoints[:0]
+	}
+	return len(c.savepoints)
+}
+
 // TransactionType enumerates the different transaction behaviors
 // See Conn.BeginTransaction
 type TransactionType uint8
@@ -510,6 +801,28 @@ func (c *Conn) BeginTransaction(t TransactionType) error {
 	panic(fmt.Sprintf("Unsupported transaction type: '%#v'", t))
 }
 
+// BeginTransactionTimeout begins a transaction of the specified type
+// with a busy timeout overriding the connection one until the transaction ends (Commit or Rollback).
+// The timeout or handler previously set by BusyTimeout or BusyHandler is then restored.
+func (c *Conn) BeginTransactionTimeout(t TransactionType, d time.Duration) error {
+	restore := c.overrideBusyTimeout(d)
+	if err := c.BeginTransaction(t); err != nil {
+		restore()
+		return err
+	}
+	c.restoreBusy = restore
+	return nil
+}
+
+// transactionEnded resets the state attached to the current transaction.
+func (c *Conn) transactionEnded() {
+	c.savepoints = c.savepoints[:0]
+	if c.restoreBusy != nil {
+		c.restoreBusy()
+		c.restoreBusy = nil
+	}
+}
+
 // Commit commits transaction.
 // It is strongly discouraged to defer Commit without checking the error returned.
 func (c *Conn) Commit() error {
@@ -517,16 +830,29 @@ func (c *Conn) Commit() error {
 	// it is considered a best practice to always issue a ROLLBACK if an error is encountered.
 	// In situations when SQLite was already forced to roll back the transaction and has returned to autocommit mode,
 	// the ROLLBACK will do nothing but return an error that can be safely ignored.
+	if c.GetAutocommit() {
+		return c.specificError("cannot commit - no transaction is active")
+	}
 	err := c.FastExec("COMMIT")
 	if err != nil && !c.GetAutocommit() {
 		c.Rollback()
 	}
+	if c.GetAutocommit() {
+		c.transactionEnded()
+	}
 	return err
 }
 
 // Rollback rollbacks transaction
 func (c *Conn) Rollback() error {
-	return c.FastExec("ROLLBACK")
+	if c.GetAutocommit() {
+		return c.specificError("cannot rollback - no transaction is active")
+	}
+	err := c.FastExec("ROLLBACK")
+	if c.GetAutocommit() {
+		c.transactionEnded()
+	}
+	return err
 }
 
 // Transaction is used to execute a function inside an SQLite database transaction.
@@ -578,13 +904,37 @@ func (c *Conn) Transaction(t TransactionType, f func(c *Conn) error) error {
 // Savepoint starts a new transaction with a name.
 // (See http://sqlite.org/lang_savepoint.html)
 func (c *Conn) Savepoint(name string) error {
-	return c.FastExec(Mprintf("SAVEPOINT %Q", name))
+	if c.GetAutocommit() {
+		c.transactionEnded()
+	}
+	err := c.FastExec(Mprintf("SAVEPOINT %Q", name))
+	if err == nil {
+		c.lastSavepoint++
+		c.savepoints = append(c.savepoints, savepoint{name, c.lastSavepoint})
+	}
+	return err
+}
+
+// savepoint identifies an entry of the savepoints stack:
+// the same name may be reused by a later (or nested) savepoint.
+type savepoint struct {
+	name string
+	id   uint64
 }
 
 // ReleaseSavepoint causes all savepoints back to and including the most recent savepoint with a matching name to be removed from the transaction stack.
 // (See http://sqlite.org/lang_savepoint.html)
 func (c *Conn) ReleaseSavepoint(name string) error {
-	return c.FastExec(Mprintf("RELEASE %Q", name))
+	err := c.FastExec(Mprintf("RELEASE %Q", name))
+	if err == nil {
+		for i := len(c.savepoints) - 1; i >= 0; i-- {
+			if strings.EqualFold(c.savepoints[i].name, name) {
+				c.savepoints = c.savepoints[:i]
+				break
+			}
+		}
+	}
+	return err
 }
 
 // RollbackSavepoint reverts the state of the database back to what it was just before the corresponding SAVEPOINT.
@@ -593,6 +943,83 @@ func (c *Conn) RollbackSavepoint(name string) error {
 	return c.FastExec(Mprintf("ROLLBACK TO SAVEPOINT %Q", name))
 }
 
+// SavepointScope is a savepoint started by Conn.SavepointScope.
+// It must be ended by either Release or Rollback.
+type SavepointScope struct {
+	c     *Conn
+	name  string
+	id    uint64
+	depth int // position in the savepoints stack (1 for the outermost)
+	done  bool
+}
+
+// SavepointScope starts a new savepoint and returns a handle to release or rollback it.
+// Nested scopes must be ended before their parent (ending a parent ends all its children).
+// (See http://sqlite.org/lang_savepoint.html)
+func (c *Conn) SavepointScope(name string) (*SavepointScope, error) {
+	if err := c.Savepoint(name); err != nil {
+		return nil, err
+	}
+	return &SavepointScope{c: c, name: name, id: c.lastSavepoint, depth: len(c.savepoints)}, nil
+}
+
+// Name returns the savepoint name.
+func (sp *SavepointScope) Name() string {
+	return sp.name
+}
+
+// Active tests whether the savepoint has been neither released nor rolled back
+// (by this scope, by a parent scope or by the end of the transaction).
+func (sp *SavepointScope) Active() bool {
+	return !sp.done && sp.c.SavepointDepth() >= sp.depth && sp.c.savepoints[sp.depth-1].id == sp.id
+}
+
+// releaseShadowing releases the nested savepoints with the same name
+// (RELEASE and ROLLBACK TO target the most recent savepoint with a matching name).
+func (sp *SavepointScope) releaseShadowing() error {
+	for i := len(sp.c.savepoints) - 1; i >= sp.depth; i-- {
+		if strings.EqualFold(sp.c.savepoints[i].name, sp.name) {
+			if err := sp.c.ReleaseSavepoint(sp.name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Release commits the changes made since the savepoint was started (into the enclosing transaction if any).
+func (sp *SavepointScope) Release() error {
+	if !sp.Active() {
+		return sp.c.specificError("savepoint %q is not active", sp.name)
+	}
+	if err := sp.releaseShadowing(); err != nil {
+		return err
+	}
+	err := sp.c.ReleaseSavepoint(sp.name)
+	if err == nil {
+		sp.done = true
+	}
+	return err
+}
+
+// Rollback reverts the changes made since the savepoint was started and ends the savepoint.
+func (sp *SavepointScope) Rollback() error {
+	if !sp.Active() {
+		return sp.c.specificError("savepoint %q is not active", sp.name)
+	}
+	if err := sp.releaseShadowing(); err != nil {
+		return err
+	}
+	if err := sp.c.RollbackSavepoint(sp.name); err != nil {
+		return err
+	}
+	err := sp.c.ReleaseSavepoint(sp.name)
+	if err == nil {
+		sp.done = true
+	}
+	return err
+}
+
 /*
 func (c *Conn) exec(cmd string) error {
 	s, err := c.prepare(cmd)
@@ -601,6 +1028,7 @@ func (c *Conn) exec(cmd string) error {
 	}
 	defer s.finalize()
 	rv := C.sqlite3_step(s.stmt)
+	c.countBusy(rv)
 	if Errno(rv) != Done { // this check cannot be done with sqlite3_exec
 		return s.error(rv, "Conn.exec(%q)", cmd)
 	}
@@ -610,6 +1038,7 @@ func (c *Conn) exec(cmd string) error {
 
 // FastExec executes one or many non-parameterized statement(s) (separated by semi-colon) with no control and no stmt cache.
 func (c *Conn) FastExec(sql string) error {
+	c.checkAffinity("Conn.FastExec")
 	sqlstr := C.CString(sql)
 	err := c.error(C.sqlite3_exec(c.db, sqlstr, nil, nil, nil))
 	C.free(unsafe.Pointer(sqlstr))
@@ -626,6 +1055,10 @@ func (c *Conn) Close() error {
 		return nil
 	}
 
+	c.interruptMu.Lock()
+	c.closing = true
+	c.interruptMu.Unlock()
+
 	c.stmtCache.flush()
 
 	rv := C.sqlite3_close(c.db)
@@ -635,9 +1068,9 @@ func (c *Conn) Close() error {
 		stmt := C.sqlite3_next_stmt(c.db, nil)
 		for stmt != nil {
 			if C.sqlite3_stmt_busy(stmt) != 0 {
-				Log(C.SQLITE_MISUSE, "Dangling statement (not reset): \""+C.GoString(C.sqlite3_sql(stmt))+"\"")
+				Log(C.SQLITE_MISUSE, "Dangling statement (not reset): \""+C.GoString(C.sqlite3_sql(stmt))+"\""+c.preparedAt(stmt))
 			} else {
-				Log(C.SQLITE_MISUSE, "Dangling statement (not finalize): \""+C.GoString(C.sqlite3_sql(stmt))+"\"")
+				Log(C.SQLITE_MISUSE, "Dangling statement (not finalize): \""+C.GoString(C.sqlite3_sql(stmt))+"\""+c.preparedAt(stmt))
 			}
 			C.sqlite3_finalize(stmt)
 			stmt = C.sqlite3_next_stmt(c.db, nil)
@@ -645,14 +1078,56 @@ func (c *Conn) Close() error {
 		rv = C.sqlite3_close(c.db)
 	}
 
+	c.interruptMu.Lock()
+	defer c.interruptMu.Unlock()
+	c.closing = false
 	if rv != C.SQLITE_OK {
 		Log(int32(rv), "error while closing Conn")
 		return c.error(rv, "Conn.Close")
 	}
 	c.db = nil
+	c.freeUpdateFilter()
+	c.freeBusyTimer()
+	if c.closed != nil {
+		close(c.closed)
+	}
 	return nil
 }
 
+// CloseWithTimeout closes a database connection gracefully:
+// running statements (in other goroutines) are interrupted and busy statements are given
+// up to d to be reset before being force-finalized (like Close does).
+// The connection must have been opened in serialized mode (OpenFullMutex).
+func (c *Conn) CloseWithTimeout(d time.Duration) error {
+	if c == nil {
+		return errors.New("nil sqlite database")
+	}
+	if c.db == nil {
+		return nil
+	}
+	if c.hasBusyStmt() {
+		C.sqlite3_interrupt(c.db)
+		deadline := time.Now().Add(d)
+		for wait := time.Millisecond; c.hasBusyStmt() && time.Now().Before(deadline); {
+			time.Sleep(wait)
+			if wait < 50*time.Millisecond {
+				wait *= 2
+			}
+		}
+	}
+	return c.Close()
+}
+
+// hasBusyStmt tells if one of the connection statements is running or has not been reset.
+func (c *Conn) hasBusyStmt() bool {
+	for stmt := C.sqlite3_next_stmt(c.db, nil); stmt != nil; stmt = C.sqlite3_next_stmt(c.db, stmt) {
+		if C.sqlite3_stmt_busy(stmt) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // IsClosed tells if the database connection has been closed.
 func (c *Conn) IsClosed() bool {
 	return c == nil || c.db == nil
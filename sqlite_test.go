@@ -5,9 +5,12 @@
 package sqlite_test
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
@@ -71,6 +74,17 @@ func TestVersion(t *testing.T) {
 	}
 }
 
+func TestRequireVersion(t *testing.T) {
+	assert.Equal(t, "3.24.0", FormatVersionNumber(3024000))
+	assert.Equal(t, Version(), FormatVersionNumber(int(VersionNumber())))
+	assert.T(t, VersionAtLeast(int(VersionNumber())), "current version expected")
+	assert.T(t, !VersionAtLeast(int(VersionNumber())+1), "next version not expected")
+	checkNoError(t, RequireVersion(3007000), "version required: %s")
+	err := RequireVersion(4000000)
+	assert.T(t, err != nil, "error expected")
+	assert.T(t, strings.Contains(err.Error(), "4.0.0"), err.Error())
+}
+
 func TestOpen(t *testing.T) {
 	db := open(t)
 	checkNoError(t, db.Close(), "Error closing database: %s")
@@ -82,6 +96,95 @@ func TestOpenFailure(t *testing.T) {
 	//println(err.Error())
 }
 
+func TestAutoRegister(t *testing.T) {
+	defer ResetAutoRegister()
+	AutoRegister(func(c *Conn) error {
+		return c.FastExec("CREATE TEMP TABLE auto (x)")
+	})
+	db := open(t)
+	ok, err := db.Exists("SELECT 1 FROM temp.sqlite_master WHERE name = 'auto'")
+	checkNoError(t, err, "couldn't check table: %s")
+	assert.T(t, ok, "auto registration expected")
+	checkClose(db, t)
+
+	AutoRegister(func(c *Conn) error {
+		return errors.New("registration failure")
+	})
+	db, err = Open(":memory:")
+	assert.T(t, db == nil && err != nil, "open failure expected")
+
+	ResetAutoRegister()
+	db = open(t)
+	checkClose(db, t)
+}
+
+func TestURIParameters(t *testing.T) {
+	f, err := ioutil.TempFile("", "gosqlite-test")
+	checkNoError(t, err, "couldn't create temp file: %s")
+	checkNoError(t, f.Close(), "couldn't close temp file: %s")
+	defer os.Remove(f.Name())
+	db, err := Open("file:"+f.Name()+"?cache_bytes=1024&fast=yes&name=test", OpenURI, OpenReadWrite, OpenCreate)
+	checkNoError(t, err, "couldn't open database: %s")
+	defer checkClose(db, t)
+
+	value, ok := db.URIParameter("", "name")
+	assert.T(t, ok, "name parameter expected")
+	assert.Equal(t, "test", value)
+	_, ok = db.URIParameter("main", "missing")
+	assert.T(t, !ok, "no parameter expected")
+	_, ok = db.URIParameter("bim", "name")
+	assert.T(t, !ok, "no database expected")
+
+	assert.T(t, db.URIBoolean("", "fast", false), "fast parameter expected")
+	assert.T(t, db.URIBoolean("", "missing", true), "default value expected")
+	assert.Equal(t, int64(1024), db.URIInt64("", "cache_bytes", 0))
+	assert.Equal(t, int64(-1), db.URIInt64("", "missing", -1))
+
+	if VersionNumber() >= 3031000 {
+		key, ok := db.URIKey("", 0)
+		assert.T(t, ok, "first key expected")
+		assert.Equal(t, "cache_bytes", key)
+		_, ok = db.URIKey("", 3)
+		assert.T(t, !ok, "no key expected")
+	}
+}
+
+func TestFileURI(t *testing.T) {
+	assert.Equal(t, "file:test.db", FileURI("test.db", nil))
+	assert.Equal(t, "file:///tmp/a%20b%23%3F%25.db?mode=ro&x=a%26b%3Dc", FileURI("/tmp/a b#?%.db", map[string]string{"x": "a&b=c", "mode": "ro"}))
+	assert.Equal(t, "file:%C3%A9t%C3%A9.db", FileURI("été.db", nil))
+
+	dir, err := ioutil.TempDir("", "gosqlite-test")
+	checkNoError(t, err, "couldn't create temp dir: %s")
+	defer os.RemoveAll(dir)
+	names := []string{"with space.db", "hash#1.db", "percent%20.db", "été 日本.db"}
+	if runtime.GOOS != "windows" {
+		names = append(names, "question?.db")
+	}
+	for _, name := range names {
+		filename := filepath.Join(dir, name)
+		db, err := Open(filename)
+		checkNoError(t, err, "couldn't open database: %s")
+		checkNoError(t, db.FastExec("CREATE TABLE test (x); INSERT INTO test VALUES (1)"), "couldn't create table: %s")
+		checkClose(db, t)
+		_, err = os.Stat(filename)
+		checkNoError(t, err, "database file not found: %s")
+
+		db, err = Open(FileURI(filename, map[string]string{"mode": "ro"}), OpenURI, OpenReadWrite)
+		checkNoError(t, err, "couldn't open database by URI: %s")
+		var n int
+		checkNoError(t, db.OneValue("SELECT count(*) FROM test", &n), "couldn't count: %s")
+		assert.Equal(t, 1, n)
+		ro, err := db.Readonly("main")
+		checkNoError(t, err, "couldn't check read-only: %s")
+		assert.T(t, ro, "read-only expected")
+		checkClose(db, t)
+	}
+
+	_, err = Open("nul\x00.db")
+	assert.T(t, err != nil, "error expected with NUL byte")
+}
+
 func TestCreateTable(t *testing.T) {
 	db := open(t)
 	defer checkClose(db, t)
@@ -169,6 +272,25 @@ func TestInsert(t *testing.T) {
 	assert.T(t, !cs.Busy(), "expected statement to be reset")
 }
 
+func TestExecResult(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+
+	changes, rowid, err := db.ExecResult("INSERT INTO test (a_string) VALUES (?), (?)", "a", "b")
+	checkNoError(t, err, "insert error: %s")
+	assert.Equal(t, int64(2), changes)
+	assert.Equal(t, int64(2), rowid)
+
+	// the last inserted rowid is not meaningful after an UPDATE
+	changes, _, err = db.ExecResult("UPDATE test SET int_num = 1")
+	checkNoError(t, err, "update error: %s")
+	assert.Equal(t, int64(2), changes)
+
+	_, _, err = db.ExecResult("INSERT INTO unknown VALUES (1)")
+	assert.T(t, err != nil, "error expected")
+}
+
 /*
 func TestLoadExtension(t *testing.T) { // OMIT_LOAD_EXTENSION
 	db := open(t)
@@ -236,6 +358,26 @@ func TestComplete(t *testing.T) {
 	assert.T(t, c, "expected complete statement")
 }
 
+func TestMprintf(t *testing.T) {
+	assert.Equal(t, "SAVEPOINT 'it''s'", Mprintf("SAVEPOINT %Q", "it's"))
+	assert.Equal(t, "INSERT INTO \"a\"\"b\" VALUES ('x''y', NULL, 42, 3.50, 'z')",
+		Mprintf("INSERT INTO \"%w\" VALUES ('%q', %Q, %d, %.2f, %Q)", `a"b`, "x'y", nil, 42, 3.5, []byte("z")))
+	assert.Equal(t, "100% [   ab] ff (NULL) x", Mprintf("%d%% [%*s] %x %q %c", 100, 5, "ab", 255, nil, 'x'))
+	assert.Equal(t, "1 2 ", Mprintf("%lld %i %s", int64(1), 2))
+}
+
+func TestStrGlobAndLike(t *testing.T) {
+	if VersionNumber() < 3010000 {
+		t.Skipf("SQLite version too old (%d < %d)", VersionNumber(), 3010000)
+	}
+	assert.T(t, StrGlob("a*[0-9]", "abc1"), "glob match expected")
+	assert.T(t, !StrGlob("a*", "ABC"), "glob is case sensitive")
+	assert.T(t, StrLike("a%", "ABC", 0), "like is case insensitive")
+	assert.T(t, !StrLike("a_", "abc", 0), "no like match expected")
+	assert.T(t, StrLike(`100\%`, "100%", '\\'), "escaped like match expected")
+	assert.T(t, !StrLike(`100\%`, "1000", '\\'), "no escaped like match expected")
+}
+
 func TestExecMisuse(t *testing.T) {
 	db := open(t)
 	defer checkClose(db, t)
@@ -259,6 +401,56 @@ func TestExecTwice(t *testing.T) {
 	checkNoError(t, err, "Exec error: %s")
 }
 
+func TestSavepointScope(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+
+	outer, err := db.SavepointScope("outer")
+	checkNoError(t, err, "Error while creating savepoint: %s")
+	assert.Equal(t, "outer", outer.Name())
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('outer')"), "insert error: %s")
+	inner, err := db.SavepointScope("inner")
+	checkNoError(t, err, "Error while creating savepoint: %s")
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('inner')"), "insert error: %s")
+	assert.Equal(t, 2, db.SavepointDepth())
+	checkNoError(t, inner.Rollback(), "Error while rolling back savepoint: %s")
+	assert.T(t, !inner.Active(), "inner savepoint ended")
+	assert.T(t, inner.Release() != nil, "error expected")
+	assert.Equal(t, 1, db.SavepointDepth())
+
+	inner, err = db.SavepointScope("inner")
+	checkNoError(t, err, "Error while creating savepoint: %s")
+	checkNoError(t, outer.Release(), "Error while releasing savepoint: %s")
+	assert.T(t, !inner.Active(), "inner savepoint ended by its parent")
+	assert.T(t, !db.InTransaction(), "no transaction expected")
+
+	var n int
+	checkNoError(t, db.OneValue("SELECT count(*) FROM test", &n), "count error: %s")
+	assert.Equal(t, 1, n)
+
+	// stale handle with the same name at the same depth
+	stale, err := db.SavepointScope("sp")
+	checkNoError(t, err, "Error while creating savepoint: %s")
+	checkNoError(t, stale.Release(), "Error while releasing savepoint: %s")
+	current, err := db.SavepointScope("sp")
+	checkNoError(t, err, "Error while creating savepoint: %s")
+	assert.T(t, !stale.Active(), "stale savepoint")
+	assert.T(t, stale.Rollback() != nil, "error expected")
+	assert.T(t, current.Active(), "current savepoint")
+
+	// nested savepoint with the same name
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('current')"), "insert error: %s")
+	nested, err := db.SavepointScope("sp")
+	checkNoError(t, err, "Error while creating savepoint: %s")
+	checkNoError(t, db.Exec("INSERT INTO test (a_string) VALUES ('nested')"), "insert error: %s")
+	checkNoError(t, current.Rollback(), "Error while rolling back savepoint: %s")
+	assert.T(t, !nested.Active(), "nested savepoint ended by its parent")
+	assert.T(t, !db.InTransaction(), "no transaction expected")
+	checkNoError(t, db.OneValue("SELECT count(*) FROM test", &n), "count error: %s")
+	assert.Equal(t, 1, n)
+}
+
 func TestTransaction(t *testing.T) {
 	db := open(t)
 	defer checkClose(db, t)
@@ -280,12 +472,48 @@ func TestCommitMisuse(t *testing.T) {
 	err := db.Commit()
 	assert.T(t, err != nil, "error expected")
 	if cerr, ok := err.(ConnError); ok {
-		assert.Equal(t, ErrError, cerr.Code())
-		assert.Equal(t, 1, cerr.ExtendedCode())
+		assert.Equal(t, ErrSpecific, cerr.Code())
 	} else {
 		t.Errorf("got %s; want ConnError", reflect.TypeOf(err))
 	}
-	assert.Equal(t, err, db.LastError())
+	err = db.Rollback()
+	assert.T(t, err != nil, "error expected")
+}
+
+func TestInTransaction(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	assert.T(t, !db.InTransaction(), "no transaction expected")
+	checkNoError(t, db.Begin(), "Error while beginning transaction: %s")
+	assert.T(t, db.InTransaction(), "transaction expected")
+	assert.Equal(t, 0, db.SavepointDepth())
+	checkNoError(t, db.Savepoint("a"), "Error while creating savepoint: %s")
+	checkNoError(t, db.Savepoint("b"), "Error while creating savepoint: %s")
+	checkNoError(t, db.Savepoint("c"), "Error while creating savepoint: %s")
+	assert.Equal(t, 3, db.SavepointDepth())
+	checkNoError(t, db.RollbackSavepoint("c"), "Error while rolling back savepoint: %s")
+	assert.Equal(t, 3, db.SavepointDepth())
+	checkNoError(t, db.ReleaseSavepoint("B"), "Error while releasing savepoint: %s")
+	assert.Equal(t, 1, db.SavepointDepth())
+	checkNoError(t, db.Commit(), "Error while committing transaction: %s")
+	assert.T(t, !db.InTransaction(), "no transaction expected")
+	assert.Equal(t, 0, db.SavepointDepth())
+
+	checkNoError(t, db.Savepoint("a"), "Error while creating savepoint: %s")
+	assert.T(t, db.InTransaction(), "transaction expected")
+	assert.Equal(t, 1, db.SavepointDepth())
+	checkNoError(t, db.Rollback(), "Error while rolling back transaction: %s")
+	assert.Equal(t, 0, db.SavepointDepth())
+
+	err := db.Transaction(Deferred, func(c *Conn) error {
+		return c.Transaction(Deferred, func(c *Conn) error {
+			assert.T(t, c.InTransaction(), "transaction expected")
+			assert.Equal(t, 1, c.SavepointDepth())
+			return nil
+		})
+	})
+	checkNoError(t, err, "Error while executing transaction: %s")
 }
 
 func TestNilDb(t *testing.T) {
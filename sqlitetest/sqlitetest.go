@@ -0,0 +1,302 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build go1.15
+
+// Package sqlitetest provides helpers for tests using SQLite databases:
+// isolated databases closed automatically, fixtures loading,
+// table snapshots comparison and deterministic clock/random functions
+// (the builtin date and time functions are not affected, see RegisterDeterministicFunctions).
+//
+//	func TestSomething(t *testing.T) {
+//		db := sqlitetest.Open(t)
+//		sqlitetest.LoadSQL(t, db, "CREATE TABLE test (data TEXT);")
+//		before := sqlitetest.TakeSnapshot(t, db, "test")
+//		// ...
+//		sqlitetest.AssertUnchanged(t, db, before)
+//	}
+package sqlitetest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gwenn/gosqlite"
+)
+
+// Open opens a private in-memory database which is closed when the test completes.
+func Open(tb testing.TB) *sqlite.Conn {
+	tb.Helper()
+	db, err := sqlite.Open(":memory:")
+	if err != nil {
+		tb.Fatalf("couldn't open in-memory database: %s", err)
+	}
+	tb.Cleanup(func() { closeDb(tb, db) })
+	return db
+}
+
+// OpenFile opens a database in a temporary directory which is closed and removed when the test completes.
+// It should be used when several connections must share the same database.
+// The database file name is returned along with the connection.
+func OpenFile(tb testing.TB) (*sqlite.Conn, string) {
+	tb.Helper()
+	filename := filepath.Join(tb.TempDir(), "test.db")
+	db, err := sqlite.Open(filename, sqlite.OpenReadWrite, sqlite.OpenCreate, sqlite.OpenFullMutex)
+	if err != nil {
+		tb.Fatalf("couldn't open database file: %s", err)
+	}
+	tb.Cleanup(func() { closeDb(tb, db) })
+	return db, filename
+}
+
+func closeDb(tb testing.TB, db *sqlite.Conn) {
+	if err := db.Close(); err != nil {
+		tb.Errorf("couldn't close database: %s", err)
+	}
+}
+
+// LoadSQL executes the specified SQL script (statements separated by semicolons).
+func LoadSQL(tb testing.TB, db *sqlite.Conn, script string) {
+	tb.Helper()
+	if err := db.FastExec(script); err != nil {
+		tb.Fatalf("couldn't load SQL fixture: %s", err)
+	}
+}
+
+// LoadCSV inserts the records read from r into the existing table.
+// The first record contains the column names. Empty fields are inserted as NULL.
+func LoadCSV(tb testing.TB, db *sqlite.Conn, table string, r io.Reader) {
+	tb.Helper()
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		tb.Fatalf("couldn't read CSV fixture: %s", err)
+	}
+	if len(records) == 0 {
+		return
+	}
+	err = db.Transaction(sqlite.Immediate, func(c *sqlite.Conn) error {
+		s, err := c.Prepare(insertSQL(table, records[0]))
+		if err != nil {
+			return err
+		}
+		defer s.Finalize()
+		args := make([]interface{}, len(records[0]))
+		for _, record := range records[1:] {
+			for i := range args {
+				args[i] = nil
+				if i < len(record) && len(record[i]) > 0 {
+					args[i] = record[i]
+				}
+			}
+			if err = s.Exec(args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("couldn't load CSV fixture into %q: %s", table, err)
+	}
+}
+
+// LoadJSON inserts the objects read from r (a JSON array of objects) into the existing table.
+// Object keys are the column names. Nested arrays or objects are inserted as JSON text.
+func LoadJSON(tb testing.TB, db *sqlite.Conn, table string, r io.Reader) {
+	tb.Helper()
+	var rows []map[string]interface{}
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	if err := d.Decode(&rows); err != nil {
+		tb.Fatalf("couldn't read JSON fixture: %s", err)
+	}
+	err := db.Transaction(sqlite.Immediate, func(c *sqlite.Conn) error {
+		for _, row := range rows {
+			columns := make([]string, 0, len(row))
+			for column := range row {
+				columns = append(columns, column)
+			}
+			args := make([]interface{}, len(columns))
+			for i, column := range columns {
+				switch v := row[column].(type) {
+				case json.Number:
+					if n, err := v.Int64(); err == nil {
+						args[i] = n
+					} else if f, err := v.Float64(); err == nil {
+						args[i] = f
+					} else {
+						args[i] = v.String()
+					}
+				case []interface{}, map[string]interface{}:
+					b, err := json.Marshal(v)
+					if err != nil {
+						return err
+					}
+					args[i] = string(b)
+				default:
+					args[i] = v
+				}
+			}
+			if err := c.Exec(insertSQL(table, columns), args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("couldn't load JSON fixture into %q: %s", table, err)
+	}
+}
+
+func insertSQL(table string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = sqlite.QuoteIdentifier(column)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", sqlite.QuoteIdentifier(table), strings.Join(quoted, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+}
+
+// Snapshot is a copy of a table content.
+type Snapshot struct {
+	Table   string
+	Columns []string
+	Rows    [][]interface{} // ordered by all columns
+}
+
+// TakeSnapshot copies the content of the specified table.
+func TakeSnapshot(tb testing.TB, db *sqlite.Conn, table string) Snapshot {
+	tb.Helper()
+	snapshot := Snapshot{Table: table}
+	s, err := db.Prepare(fmt.Sprintf("SELECT * FROM %s", sqlite.QuoteIdentifier(table)))
+	if err != nil {
+		tb.Fatalf("couldn't snapshot %q: %s", table, err)
+	}
+	snapshot.Columns = s.ColumnNames()
+	s.Finalize()
+	orderBy := make([]string, len(snapshot.Columns))
+	for i := range orderBy {
+		orderBy[i] = fmt.Sprintf("%d", i+1)
+	}
+	s, err = db.Prepare(fmt.Sprintf("SELECT * FROM %s ORDER BY %s", sqlite.QuoteIdentifier(table), strings.Join(orderBy, ", ")))
+	if err != nil {
+		tb.Fatalf("couldn't snapshot %q: %s", table, err)
+	}
+	defer s.Finalize()
+	err = s.Select(func(s *sqlite.Stmt) error {
+		row := make([]interface{}, len(snapshot.Columns))
+		s.ScanValues(row)
+		snapshot.Rows = append(snapshot.Rows, row)
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("couldn't snapshot %q: %s", table, err)
+	}
+	return snapshot
+}
+
+// Diff returns the rows removed (prefixed by "-") and added (prefixed by "+") from s to other.
+func (s Snapshot) Diff(other Snapshot) []string {
+	var diff []string
+	if !reflect.DeepEqual(s.Columns, other.Columns) {
+		diff = append(diff, fmt.Sprintf("- columns %v", s.Columns), fmt.Sprintf("+ columns %v", other.Columns))
+	}
+	counts := make(map[string]int)
+	for _, row := range other.Rows {
+		counts[fmt.Sprintf("%#v", row)]++
+	}
+	for _, row := range s.Rows {
+		key := fmt.Sprintf("%#v", row)
+		if counts[key] > 0 {
+			counts[key]--
+		} else {
+			diff = append(diff, fmt.Sprintf("- %v", row))
+		}
+	}
+	for _, row := range other.Rows {
+		key := fmt.Sprintf("%#v", row)
+		if counts[key] > 0 {
+			counts[key]--
+			diff = append(diff, fmt.Sprintf("+ %v", row))
+		}
+	}
+	return diff
+}
+
+// AssertUnchanged reports an error if the table content differs from the snapshot.
+func AssertUnchanged(tb testing.TB, db *sqlite.Conn, snapshot Snapshot) {
+	tb.Helper()
+	if diff := snapshot.Diff(TakeSnapshot(tb, db, snapshot.Table)); len(diff) > 0 {
+		tb.Errorf("table %q changed:\n%s", snapshot.Table, strings.Join(diff, "\n"))
+	}
+}
+
+// Clock is a deterministic clock which can be registered as the SQL function now().
+// It does not freeze the SQLite builtin time: see RegisterDeterministicFunctions.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a clock stopped at the specified time.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// RegisterDeterministicFunctions registers:
+//   - now() which returns the clock time formatted as "YYYY-MM-DD HH:MM:SS" (UTC),
+//   - random() and randomblob(N) which override the builtin functions with a generator seeded by seed.
+//
+// The now() function is not a builtin one: CURRENT_TIMESTAMP, CURRENT_DATE, CURRENT_TIME
+// and the date and time functions with the 'now' argument (like datetime('now')) still use the system time
+// so the SQL under test must call now() (for example, datetime(now(), '+1 day')) to be deterministic.
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+func RegisterDeterministicFunctions(tb testing.TB, db *sqlite.Conn, clock *Clock, seed int64) {
+	tb.Helper()
+	rnd := rand.New(rand.NewSource(seed))
+	err := db.CreateScalarFunction("now", 0, false, nil, func(ctx *sqlite.ScalarContext, nArg int) {
+		ctx.ResultText(clock.Now().UTC().Format("2006-01-02 15:04:05"))
+	}, nil)
+	if err == nil {
+		err = db.CreateScalarFunction("random", 0, false, nil, func(ctx *sqlite.ScalarContext, nArg int) {
+			ctx.ResultInt64(int64(rnd.Uint64()))
+		}, nil)
+	}
+	if err == nil {
+		err = db.CreateScalarFunction("randomblob", 1, false, nil, func(ctx *sqlite.ScalarContext, nArg int) {
+			n := ctx.Int(0)
+			if n < 1 {
+				n = 1
+			}
+			b := make([]byte, n)
+			rnd.Read(b)
+			ctx.ResultBlob(b)
+		}, nil)
+	}
+	if err != nil {
+		tb.Fatalf("couldn't register deterministic functions: %s", err)
+	}
+}
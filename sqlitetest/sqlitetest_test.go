@@ -0,0 +1,77 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build go1.15
+
+package sqlitetest_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite/sqlitetest"
+)
+
+const schema = "CREATE TABLE test (id INTEGER PRIMARY KEY, name TEXT, score REAL, tags TEXT);"
+
+func TestOpenAndFixtures(t *testing.T) {
+	db := Open(t)
+	LoadSQL(t, db, schema)
+	LoadCSV(t, db, "test", strings.NewReader("id,name,score\n1,alice,1.5\n2,,2\n"))
+	LoadJSON(t, db, "test", strings.NewReader(`[{"id": 3, "name": "bob", "tags": ["a", "b"]}]`))
+
+	snapshot := TakeSnapshot(t, db, "test")
+	assert.Equal(t, []string{"id", "name", "score", "tags"}, snapshot.Columns)
+	assert.Equal(t, 3, len(snapshot.Rows))
+	assert.Equal(t, []interface{}{int64(1), "alice", 1.5, nil}, snapshot.Rows[0])
+	assert.Equal(t, []interface{}{int64(2), nil, 2.0, nil}, snapshot.Rows[1])
+	assert.Equal(t, []interface{}{int64(3), "bob", nil, `["a","b"]`}, snapshot.Rows[2])
+
+	AssertUnchanged(t, db, snapshot)
+	LoadSQL(t, db, "UPDATE test SET score = 3 WHERE id = 2")
+	diff := snapshot.Diff(TakeSnapshot(t, db, "test"))
+	assert.Equal(t, []string{"- [2 <nil> 2 <nil>]", "+ [2 <nil> 3 <nil>]"}, diff)
+}
+
+func TestOpenFile(t *testing.T) {
+	db, filename := OpenFile(t)
+	LoadSQL(t, db, schema)
+	_, err := os.Stat(filename)
+	assert.Tf(t, err == nil, "%v", err)
+}
+
+func TestDeterministicFunctions(t *testing.T) {
+	if !strings.Contains(os.Getenv("GODEBUG"), "cgocheck=0") {
+		t.Skip("cgocheck")
+	}
+	var values [2][]int64
+	for i := range values {
+		db := Open(t)
+		clock := NewClock(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+		RegisterDeterministicFunctions(t, db, clock, 42)
+		var now string
+		err := db.OneValue("SELECT now()", &now)
+		assert.Tf(t, err == nil, "%v", err)
+		assert.Equal(t, "2020-01-02 03:04:05", now)
+		clock.Advance(time.Hour)
+		err = db.OneValue("SELECT now()", &now)
+		assert.Tf(t, err == nil, "%v", err)
+		assert.Equal(t, "2020-01-02 04:04:05", now)
+
+		var r int64
+		for j := 0; j < 3; j++ {
+			err = db.OneValue("SELECT random()", &r)
+			assert.Tf(t, err == nil, "%v", err)
+			values[i] = append(values[i], r)
+		}
+		var b []byte
+		err = db.OneValue("SELECT randomblob(4)", &b)
+		assert.Tf(t, err == nil, "%v", err)
+		assert.Equal(t, 4, len(b))
+	}
+	assert.Equal(t, values[0], values[1])
+}
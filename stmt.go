@@ -17,14 +17,37 @@ package sqlite
 // #define SQLITE_STATIC      ((sqlite3_destructor_type)0)
 // #define SQLITE_TRANSIENT   ((sqlite3_destructor_type)-1)
 
-static inline int my_bind_text(sqlite3_stmt *stmt, int pidx, const char *data, int data_len) {
-	return sqlite3_bind_text(stmt, pidx, data, data_len, free);
+static inline int my_bind_text(sqlite3_stmt *stmt, int pidx, const char *data, sqlite3_uint64 data_len) {
+	return sqlite3_bind_text64(stmt, pidx, data, data_len, free, SQLITE_UTF8);
 }
 static inline int my_bind_empty_text(sqlite3_stmt *stmt, int pidx) {
 	return sqlite3_bind_text(stmt, pidx, "", 0, SQLITE_STATIC);
 }
-static inline int my_bind_blob(sqlite3_stmt *stmt, int pidx, void *data, int data_len) {
-	return sqlite3_bind_blob(stmt, pidx, data, data_len, SQLITE_TRANSIENT);
+static inline int my_bind_blob(sqlite3_stmt *stmt, int pidx, void *data, sqlite3_uint64 data_len) {
+	return sqlite3_bind_blob64(stmt, pidx, data, data_len, SQLITE_TRANSIENT);
+}
+
+static inline int my_stmt_isexplain(sqlite3_stmt *stmt) {
+#if SQLITE_VERSION_NUMBER < 3028000
+	return 0;
+#else
+	return sqlite3_stmt_isexplain(stmt);
+#endif
+}
+static inline int my_stmt_explain(sqlite3_stmt *stmt, int eMode) {
+#if SQLITE_VERSION_NUMBER < 3043000
+	return SQLITE_ERROR;
+#else
+	return sqlite3_stmt_explain(stmt, eMode);
+#endif
+}
+
+static inline sqlite3_int64 my_changes64(sqlite3 *db) {
+#if SQLITE_VERSION_NUMBER < 3037000
+	return sqlite3_changes(db);
+#else
+	return sqlite3_changes64(db);
+#endif
 }
 */
 import "C"
@@ -63,10 +86,14 @@ func (s *Stmt) error(rv C.int, details ...string) error {
 	if rv == C.SQLITE_OK {
 		return nil
 	}
+	if err := s.c.vetoedCommit(rv); err != nil {
+		return err
+	}
 	err := ConnError{c: s.c, code: Errno(rv), msg: C.GoString(C.sqlite3_errmsg(s.c.db))}
 	if len(details) > 0 {
 		err.details = details[0]
 	}
+	err.cause = s.c.vtabError(err.msg)
 	return StmtError{err, s}
 }
 
@@ -89,19 +116,27 @@ type Stmt struct {
 	bindParameterCount int
 	params             map[string]int // cached parameter index by name
 	affinities         []Affinity     // cached columns type affinity
+	busyTimeout        time.Duration  // overrides the connection busy timeout while stepping (see WithBusyTimeout)
 	// Tell if the stmt should be cached (default true)
 	Cacheable bool
+	// Tell if the stmt should be reset and its bindings cleared as soon as its execution ends
+	// (Done, error or early stop in Select/SelectOneRow). Default value is Conn.AutoReset.
+	AutoReset bool
 }
 
 func (c *Conn) prepare(sql string, args ...interface{}) (*Stmt, error) {
 	if c == nil {
 		return nil, errors.New("nil sqlite database")
 	}
+	c.checkAffinity("Conn.Prepare")
 	sqlstr := C.CString(sql)
 	defer C.free(unsafe.Pointer(sqlstr))
 	var stmt *C.sqlite3_stmt
 	var tail *C.char
 	rv := C.sqlite3_prepare_v2(c.db, sqlstr, -1, &stmt, &tail)
+	for c.lockedSharedCache(rv) && c.waitForUnlockNotify() == C.SQLITE_OK {
+		rv = C.sqlite3_prepare_v2(c.db, sqlstr, -1, &stmt, &tail)
+	}
 	if rv != C.SQLITE_OK {
 		// C.sqlite3_finalize(stmt) // If there is an error, *stmt is set to NULL
 		return nil, c.error(rv, sql)
@@ -110,7 +145,8 @@ func (c *Conn) prepare(sql string, args ...interface{}) (*Stmt, error) {
 	if tail != nil && *tail != '\000' {
 		t = C.GoString(tail)
 	}
-	s := &Stmt{c: c, stmt: stmt, tail: strings.TrimSpace(t), columnCount: -1, bindParameterCount: -1}
+	s := &Stmt{c: c, stmt: stmt, tail: strings.TrimSpace(t), columnCount: -1, bindParameterCount: -1, AutoReset: c.AutoReset}
+	s.trackLeak()
 	if len(args) > 0 {
 		err := s.Bind(args...)
 		if err != nil {
@@ -155,8 +191,12 @@ func (s *Stmt) Exec(args ...interface{}) error {
 	return s.exec()
 }
 func (s *Stmt) exec() error {
-	rv := C.sqlite3_step(s.stmt)
+	s.c.checkAffinity("Stmt.Exec")
+	rv := s.step()
 	C.sqlite3_reset(s.stmt)
+	if s.AutoReset {
+		C.sqlite3_clear_bindings(s.stmt)
+	}
 	err := Errno(rv)
 	if err != Done {
 		if err == Row {
@@ -181,6 +221,23 @@ func (s *Stmt) ExecDml(args ...interface{}) (changes int, err error) {
 	return s.c.Changes(), nil
 }
 
+// ExecResult is like ExecDml but returns both the number of rows changed and the last inserted rowid
+// (which is left unchanged by statements other than INSERT).
+// Both values are read while holding the connection mutex (when the connection is serialized)
+// so they are not altered by statements executed concurrently on the same connection.
+// Don't use it with SELECT or anything that returns data.
+// The Stmt is reset at each call.
+func (s *Stmt) ExecResult(args ...interface{}) (changes int64, lastRowid int64, err error) {
+	m := C.sqlite3_db_mutex(s.c.db) // nil when the connection is not serialized
+	C.sqlite3_mutex_enter(m)
+	defer C.sqlite3_mutex_leave(m)
+	err = s.Exec(args...)
+	if err != nil {
+		return -1, -1, err
+	}
+	return int64(C.my_changes64(s.c.db)), int64(C.sqlite3_last_insert_rowid(s.c.db)), nil
+}
+
 // Insert is like ExecDml but returns the autoincremented rowid.
 // Don't use it with SELECT or anything that returns data.
 // The Stmt is reset at each call.
@@ -225,6 +282,7 @@ func (s *Stmt) Select(rowCallbackHandler func(s *Stmt) error, args ...interface{
 			break
 		}
 		if err := rowCallbackHandler(s); err != nil {
+			s.autoReset()
 			return err
 		}
 	}
@@ -245,7 +303,9 @@ func (s *Stmt) SelectOneRow(args ...interface{}) (found bool, err error) {
 		}
 		return false, nil
 	}
-	return true, s.Scan(args...)
+	err = s.Scan(args...)
+	s.autoReset()
+	return true, err
 }
 
 // BindParameterCount returns the number of SQL parameters.
@@ -314,6 +374,30 @@ func (s *Stmt) NamedBind(args ...interface{}) error {
 	return nil
 }
 
+// BindNamed binds all the parameters of the statement by their name.
+// Names may be specified with or without their prefix (":id" or "id" for :id, "$id" or "id" for $id).
+// Values not used by the statement are ignored but a missing value or an unnamed parameter (?) is an error.
+func (s *Stmt) BindNamed(args map[string]interface{}) error {
+	for index := 1; index <= s.BindParameterCount(); index++ {
+		cname := C.sqlite3_bind_parameter_name(s.stmt, C.int(index))
+		if cname == nil { // index is valid so the parameter is a nameless one (?)
+			return s.specificError("unnamed parameter at %d", index)
+		}
+		name := C.GoString(cname)
+		value, ok := args[name]
+		if !ok {
+			value, ok = args[name[1:]]
+		}
+		if !ok {
+			return s.specificError("missing value for parameter %q", name)
+		}
+		if err := s.BindByIndex(index, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Bind binds parameters by their index.
 // Calls sqlite3_bind_parameter_count and sqlite3_bind_(blob|double|int|int64|null|text) depending on args type/kind.
 // (See http://sqlite.org/c3ref/bind_blob.html)
@@ -332,10 +416,16 @@ func (s *Stmt) Bind(args ...interface{}) error {
 	return nil
 }
 
-// NullIfEmptyString transforms empty string to null when true (true by default)
+// NullIfEmptyString transforms empty string to null when true (true by default).
+// It is the default value of Conn.NullIfEmptyString for new connections:
+// it is read when a connection is opened so changing it does not affect the connections already opened
+// (use Conn.NullIfEmptyString instead).
 var NullIfEmptyString = true
 
-// NullIfZeroTime transforms zero time (time.Time.IsZero) to null when true (true by default)
+// NullIfZeroTime transforms zero time (time.Time.IsZero) to null when true (true by default).
+// It is the default value of Conn.NullIfZeroTime for new connections:
+// it is read when a connection is opened so changing it does not affect the connections already opened
+// (use Conn.NullIfZeroTime instead).
 var NullIfZeroTime = true
 
 // BindByIndex binds value to the specified host parameter of the prepared statement.
@@ -349,16 +439,16 @@ func (s *Stmt) BindByIndex(index int, value interface{}) error {
 		rv = C.sqlite3_bind_null(s.stmt, i)
 	case string:
 		if len(value) == 0 {
-			if NullIfEmptyString {
+			if s.c.NullIfEmptyString {
 				rv = C.sqlite3_bind_null(s.stmt, i)
 			} else {
 				rv = C.my_bind_empty_text(s.stmt, i)
 			}
 		} else {
-			if i64 && len(value) > math.MaxInt32 {
-				return s.specificError("string too big: %d at index %d", len(value), index)
+			rv = C.my_bind_text(s.stmt, i, C.CString(value), C.sqlite3_uint64(len(value)))
+			if rv == C.SQLITE_TOOBIG {
+				return s.tooBigError("string", len(value), index)
 			}
-			rv = C.my_bind_text(s.stmt, i, C.CString(value), C.int(len(value)))
 		}
 	case int:
 		if i64 {
@@ -379,25 +469,34 @@ func (s *Stmt) BindByIndex(index int, value interface{}) error {
 	case float64:
 		rv = C.sqlite3_bind_double(s.stmt, i, C.double(value))
 	case []byte:
-		if i64 && len(value) > math.MaxInt32 {
-			return s.specificError("blob too big: %d at index %d", len(value), index)
-		}
 		if len(value) == 0 {
-			rv = C.sqlite3_bind_zeroblob(s.stmt, i, 0)
+			if s.c.NullIfEmptyBlob {
+				rv = C.sqlite3_bind_null(s.stmt, i)
+			} else {
+				rv = C.sqlite3_bind_zeroblob(s.stmt, i, 0)
+			}
 		} else {
-			rv = C.my_bind_blob(s.stmt, i, unsafe.Pointer(&value[0]), C.int(len(value)))
+			rv = C.my_bind_blob(s.stmt, i, unsafe.Pointer(&value[0]), C.sqlite3_uint64(len(value)))
+			if rv == C.SQLITE_TOOBIG {
+				return s.tooBigError("blob", len(value), index)
+			}
 		}
 	case time.Time:
-		if NullIfZeroTime && value.IsZero() {
+		if s.c.NullIfZeroTime && value.IsZero() {
 			rv = C.sqlite3_bind_null(s.stmt, i)
 		} else if s.c.DefaultTimeLayout == "" {
 			rv = C.sqlite3_bind_int64(s.stmt, i, C.sqlite3_int64(value.Unix()))
 		} else {
 			v := value.Format(s.c.DefaultTimeLayout)
-			rv = C.my_bind_text(s.stmt, i, C.CString(v), C.int(len(v)))
+			rv = C.my_bind_text(s.stmt, i, C.CString(v), C.sqlite3_uint64(len(v)))
 		}
 	case ZeroBlobLength:
 		rv = C.sqlite3_bind_zeroblob(s.stmt, i, C.int(value))
+	case ZeroBlobLength64:
+		rv = C.sqlite3_bind_zeroblob64(s.stmt, i, C.sqlite3_uint64(value))
+		if rv == C.SQLITE_TOOBIG {
+			return s.tooBigError("zeroblob", int64(value), index)
+		}
 	case driver.Valuer:
 		v, err := value.Value()
 		if err != nil {
@@ -410,8 +509,14 @@ func (s *Stmt) BindByIndex(index int, value interface{}) error {
 	return s.error(rv, "Stmt.Bind")
 }
 
+// tooBigError reports a value exceeding the maximum length of a string or blob (see LimitLength).
+func (s *Stmt) tooBigError(kind string, n interface{}, index int) error {
+	return s.specificError("%s too big: %d bytes > %d (LimitLength) at index %d", kind, n, s.c.Limit(LimitLength), index)
+}
+
 // BindReflect binds value to the specified host parameter of the prepared statement.
 // Value's (reflect) Kind is used to find the storage class.
+// Contrary to BindByIndex, an empty string is bound as an empty text (Conn.NullIfEmptyString is ignored).
 // The leftmost SQL parameter has an index of 1.
 func (s *Stmt) BindReflect(index int, value interface{}) error {
 	i := C.int(index)
@@ -420,7 +525,10 @@ func (s *Stmt) BindReflect(index int, value interface{}) error {
 	switch v.Kind() {
 	case reflect.String:
 		vs := v.String() // TODO NullIfEmptyString
-		rv = C.my_bind_text(s.stmt, i, C.CString(vs), C.int(len(vs)))
+		rv = C.my_bind_text(s.stmt, i, C.CString(vs), C.sqlite3_uint64(len(vs)))
+		if rv == C.SQLITE_TOOBIG {
+			return s.tooBigError("string", len(vs), index)
+		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		rv = C.sqlite3_bind_int64(s.stmt, i, C.sqlite3_int64(v.Int()))
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
@@ -454,12 +562,16 @@ func (s *Stmt) BindReflect(index int, value interface{}) error {
 //
 // (See http://sqlite.org/c3ref/step.html)
 func (s *Stmt) Next() (bool, error) {
-	rv := C.sqlite3_step(s.stmt)
+	s.c.checkAffinity("Stmt.Next")
+	rv := s.step()
 	err := Errno(rv)
 	if err == Row {
 		return true, nil
 	}
 	C.sqlite3_reset(s.stmt) // Release implicit lock as soon as possible (see dbEvalStep in tclsqlite3.c)
+	if s.AutoReset {
+		C.sqlite3_clear_bindings(s.stmt)
+	}
 	if err != Done {
 		return false, s.error(rv, "Stmt.Next")
 	}
@@ -467,6 +579,20 @@ func (s *Stmt) Next() (bool, error) {
 	return false, nil
 }
 
+// step evaluates the statement, waiting for shared-cache table locks to be released when SetUnlockNotify is enabled.
+func (s *Stmt) step() C.int {
+	if s.busyTimeout > 0 {
+		defer s.c.overrideBusyTimeout(s.busyTimeout)()
+	}
+	rv := C.sqlite3_step(s.stmt)
+	for s.c.lockedSharedCache(rv) && s.c.waitForUnlockNotify() == C.SQLITE_OK {
+		C.sqlite3_reset(s.stmt)
+		rv = C.sqlite3_step(s.stmt)
+	}
+	s.c.countBusy(rv)
+	return rv
+}
+
 // Reset terminates the current execution of an SQL statement
 // and reset it back to its starting state so that it can be reused.
 // (See http://sqlite.org/c3ref/reset.html)
@@ -474,12 +600,68 @@ func (s *Stmt) Reset() error {
 	return s.error(C.sqlite3_reset(s.stmt), "Stmt.Reset")
 }
 
-// ClearBindings resets all bindings on a prepared statement.
+// ClearBindings resets all bindings on a prepared statement (to NULL).
+// Reset does not clear the bindings.
 // (See http://sqlite.org/c3ref/clear_bindings.html)
 func (s *Stmt) ClearBindings() error {
 	return s.error(C.sqlite3_clear_bindings(s.stmt), "Stmt.ClearBindings")
 }
 
+// WithBusyTimeout overrides the connection busy timeout (or handler) while this statement is evaluated,
+// the previous one being restored after each step.
+// For example, a long migration statement can wait minutes while other statements keep a short timeout.
+// If d is zero or negative, the override is removed.
+// The override is removed when the statement is returned to the cache.
+func (s *Stmt) WithBusyTimeout(d time.Duration) *Stmt {
+	if d < 0 {
+		d = 0
+	}
+	s.busyTimeout = d
+	return s
+}
+
+// autoReset resets the statement and clears its bindings when AutoReset is enabled.
+func (s *Stmt) autoReset() {
+	if s.AutoReset {
+		C.sqlite3_reset(s.stmt)
+		C.sqlite3_clear_bindings(s.stmt)
+	}
+}
+
+// ExplainMode enumerates statement EXPLAIN modes
+type ExplainMode int32
+
+// Explain modes
+const (
+	ExplainNone      ExplainMode = 0 // ordinary statement
+	Explain          ExplainMode = 1 // EXPLAIN
+	ExplainQueryPlan ExplainMode = 2 // EXPLAIN QUERY PLAN
+)
+
+// IsExplain tells if the statement is an EXPLAIN or EXPLAIN QUERY PLAN statement
+// (always ExplainNone with SQLite < 3.28).
+// (See http://sqlite.org/c3ref/stmt_isexplain.html)
+func (s *Stmt) IsExplain() ExplainMode {
+	return ExplainMode(C.my_stmt_isexplain(s.stmt))
+}
+
+// SetExplain changes the EXPLAIN mode of the statement without re-preparing it (bindings are kept).
+// The statement must be reset. Requires SQLite >= 3.43.
+// (See http://sqlite.org/c3ref/stmt_explain.html)
+func (s *Stmt) SetExplain(mode ExplainMode) error {
+	if !VersionAtLeast(3043000) {
+		return s.specificError("SetExplain requires SQLite 3.43.0 or later")
+	}
+	if rv := C.my_stmt_explain(s.stmt, C.int(mode)); rv != C.SQLITE_OK {
+		return s.error(rv, "Stmt.SetExplain")
+	}
+	// the result columns have changed
+	s.columnCount = -1
+	s.cols = nil
+	s.affinities = nil
+	return nil
+}
+
 // ColumnCount returns the number of columns in the result set for the statement (with or without row).
 // (See http://sqlite.org/c3ref/column_count.html)
 func (s *Stmt) ColumnCount() int {
@@ -852,10 +1034,10 @@ func (s *Stmt) ScanValue(index int, blob bool) (value interface{}, isNull bool)
 	case Null:
 		return nil, true
 	case Text: // does not work as expected if column type affinity is TEXT but inserted value was a numeric
-		if s.c.ScanNumericalAsTime && s.c.DefaultTimeLayout != "" && s.ColumnTypeAffinity(index) == Numerical {
+		if s.c.ScanNumericalAsTime && (s.c.DefaultTimeLayout != "" || len(s.c.ScanTimeLayouts) > 0) && s.ColumnTypeAffinity(index) == Numerical {
 			p := C.sqlite3_column_text(s.stmt, C.int(index))
 			txt := C.GoString((*C.char)(unsafe.Pointer(p)))
-			value, err := time.Parse(s.c.DefaultTimeLayout, txt)
+			value, err := s.c.parseTime(txt)
 			if err == nil {
 				return value, false
 			}
@@ -880,6 +1062,9 @@ func (s *Stmt) ScanValue(index int, blob bool) (value interface{}, isNull bool)
 		// The return value from sqlite3_column_blob() for a zero-length BLOB is a NULL pointer.
 		p := C.sqlite3_column_blob(s.stmt, C.int(index))
 		n := C.sqlite3_column_bytes(s.stmt, C.int(index))
+		if n == 0 && s.c.ScanEmptyBlobAsNil {
+			return []byte(nil), false
+		}
 		// value = (*[1 << 30]byte)(unsafe.Pointer(p))[:n]
 		return C.GoBytes(p, n), false // The memory space used to hold strings and BLOBs is freed automatically.
 	}
@@ -1056,6 +1241,8 @@ func (s *Stmt) ScanRawBytes(index int) (value []byte, isNull bool) {
 }
 
 // ScanTime scans result value from a query.
+// If time is persisted as string, the Conn.ScanTimeLayouts are used when specified
+// (otherwise the layout is guessed from the length of the string).
 // If time is persisted as string without timezone, UTC is used.
 // If time is persisted as numeric, local is used.
 // The leftmost column/index is number 0.
@@ -1069,6 +1256,10 @@ func (s *Stmt) ScanTime(index int) (value time.Time, isNull bool, err error) {
 	case Text: // does not work as expected if column type affinity is TEXT but inserted value was a numeric
 		p := C.sqlite3_column_text(s.stmt, C.int(index))
 		txt := C.GoString((*C.char)(unsafe.Pointer(p)))
+		if len(s.c.ScanTimeLayouts) > 0 {
+			value, err = s.c.parseTime(txt)
+			break
+		}
 		var layout string
 		switch len(txt) {
 		case 5: // HH:MM
@@ -1168,6 +1359,7 @@ func (s *Stmt) finalize() error {
 		Log(C.SQLITE_MISUSE, "sqlite statement with already closed database connection")
 		return errors.New("sqlite statement with already closed database connection")
 	}
+	s.untrackLeak()
 	rv := C.sqlite3_finalize(s.stmt) // must be called only once
 	s.stmt = nil
 	if rv != C.SQLITE_OK {
@@ -5,12 +5,14 @@
 package sqlite_test
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"os"
 	"path"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 	"unsafe"
@@ -394,6 +396,35 @@ func TestStmtSelectWithInsert(t *testing.T) {
 	//println(err.Error())
 }
 
+func TestExecNamed(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	err := db.ExecNamed(`CREATE TABLE test (id INT, name TEXT);
+		INSERT INTO test VALUES (:id, :name);
+		INSERT INTO test VALUES (:id + 2, :name);
+		INSERT INTO test VALUES (@id + 1, $name || '2');`, map[string]interface{}{"id": 1, "name": "a", "unused": 0})
+	checkNoError(t, err, "exec error: %s")
+
+	var rows []string
+	err = db.SelectNamed("SELECT id, name FROM test WHERE id >= :min ORDER BY id", func(s *Stmt) error {
+		var id int
+		var name string
+		if err := s.Scan(&id, &name); err != nil {
+			return err
+		}
+		rows = append(rows, fmt.Sprintf("%d:%s", id, name))
+		return nil
+	}, map[string]interface{}{"min": 1})
+	checkNoError(t, err, "select error: %s")
+	assert.Equal(t, []string{"1:a", "2:a2", "3:a"}, rows)
+
+	err = db.ExecNamed("INSERT INTO test VALUES (:id, :name)", map[string]interface{}{"id": 3})
+	assert.T(t, err != nil, "missing parameter expected")
+	err = db.ExecNamed("INSERT INTO test VALUES (:id, ?)", map[string]interface{}{"id": 3})
+	assert.T(t, err != nil, "unnamed parameter expected")
+	assert.Tf(t, strings.Contains(err.Error(), "unnamed parameter at 2"), "unexpected error: %s", err)
+}
+
 func TestNamedBind(t *testing.T) {
 	db := open(t)
 	defer checkClose(db, t)
@@ -537,12 +568,8 @@ func TestBindEmptyZeroNotTransformedToNull(t *testing.T) {
 	db := open(t)
 	defer checkClose(db, t)
 
-	NullIfEmptyString = false
-	NullIfZeroTime = false
-	defer func() {
-		NullIfEmptyString = true
-		NullIfZeroTime = true
-	}()
+	db.NullIfEmptyString = false
+	db.NullIfZeroTime = false
 
 	var zero time.Time
 	s, err := db.Prepare("SELECT ?, ?", "", zero)
@@ -773,3 +800,66 @@ func TestReadOnly(t *testing.T) {
 	checkFinalize(s, t)
 	assert.T(t, !s.ReadOnly())
 }
+
+func TestStmtAutoReset(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	db.AutoReset = true
+
+	s, err := db.Prepare("SELECT x FROM (SELECT 1 AS x UNION ALL SELECT 2) WHERE ? IS NULL OR x = ?", 1, 1)
+	checkNoError(t, err, "prepare error: %s")
+	defer checkFinalize(s, t)
+	assert.T(t, s.AutoReset, "auto-reset expected")
+
+	var x int
+	found, err := s.SelectOneRow(&x)
+	checkNoError(t, err, "select error: %s")
+	assert.T(t, found)
+	assert.Equal(t, 1, x)
+	assert.T(t, !s.Busy(), "statement should be reset")
+
+	// bindings have been cleared: all rows match
+	n := 0
+	err = s.Select(func(s *Stmt) error {
+		n++
+		return errors.New("stop")
+	})
+	assert.T(t, err != nil, "callback error expected")
+	assert.Equal(t, 1, n)
+	assert.T(t, !s.Busy(), "statement should be reset")
+	n = 0
+	err = s.Select(func(s *Stmt) error {
+		n++
+		return nil
+	})
+	checkNoError(t, err, "select error: %s")
+	assert.Equal(t, 2, n)
+}
+
+func TestStmtExplain(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	s, err := db.Prepare("SELECT ?")
+	checkNoError(t, err, "prepare error: %s")
+	defer checkFinalize(s, t)
+	assert.Equal(t, ExplainNone, s.IsExplain())
+	if !VersionAtLeast(3028000) {
+		t.Skipf("SQLite version too old")
+	}
+	eqp, err := db.Prepare("EXPLAIN QUERY PLAN SELECT 1")
+	checkNoError(t, err, "prepare error: %s")
+	defer checkFinalize(eqp, t)
+	assert.Equal(t, ExplainQueryPlan, eqp.IsExplain())
+
+	err = s.SetExplain(Explain)
+	if !VersionAtLeast(3043000) {
+		assert.T(t, err != nil, "error expected")
+		return
+	}
+	checkNoError(t, err, "explain error: %s")
+	assert.Equal(t, Explain, s.IsExplain())
+	assert.Equal(t, 8, s.ColumnCount())
+	checkNoError(t, s.SetExplain(ExplainNone), "explain error: %s")
+	assert.Equal(t, 1, s.ColumnCount())
+}
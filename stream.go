@@ -0,0 +1,55 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"context"
+)
+
+// RowData contains the values of one row (as returned by Stmt.ScanValues).
+type RowData []interface{}
+
+// streamBufferSize is the number of rows read in advance by Stream.
+const streamBufferSize = 16
+
+// Stream is like StreamContext with a background context.
+func (c *Conn) Stream(query string, args ...interface{}) (<-chan RowData, <-chan error) {
+	return c.StreamContext(context.Background(), query, args...)
+}
+
+// StreamContext executes the query on a dedicated goroutine and sends the rows on the returned channel
+// so that large result sets can be processed while being read.
+// Only a few rows are read in advance (the query waits for the consumer).
+// The rows channel is closed at the end of the iteration and then the error (nil on success)
+// is sent on the error channel: the connection must not be used until it has been received.
+// A consumer stopping before the end must cancel ctx (the query is interrupted and ctx.Err() is returned).
+//
+//	rows, errs := db.StreamContext(ctx, "SELECT * FROM test")
+//	for row := range rows {
+//		// ...
+//	}
+//	err := <-errs
+func (c *Conn) StreamContext(ctx context.Context, query string, args ...interface{}) (<-chan RowData, <-chan error) {
+	rows := make(chan RowData, streamBufferSize)
+	errs := make(chan error, 1)
+	go func() {
+		err := c.withContext(ctx, func() error {
+			return c.Select(query, func(s *Stmt) error {
+				row := make(RowData, s.ColumnCount())
+				s.ScanValues(row)
+				select {
+				case rows <- row:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}, args...)
+		})
+		close(rows)
+		errs <- err
+		close(errs)
+	}()
+	return rows, errs
+}
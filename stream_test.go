@@ -0,0 +1,49 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestStream(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	rows, errs := db.Stream("WITH RECURSIVE cnt(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM cnt LIMIT ?) SELECT x, 'x' || x FROM cnt", 1000)
+	var sum int64
+	var n int
+	for row := range rows {
+		assert.Equal(t, 2, len(row))
+		sum += row[0].(int64)
+		n++
+	}
+	checkNoError(t, <-errs, "stream error: %s")
+	assert.Equal(t, 1000, n)
+	assert.Equal(t, int64(500500), sum)
+	checkNoError(t, db.FastExec("SELECT 1"), "connection unusable after stream: %s")
+}
+
+func TestStreamCancel(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows, errs := db.StreamContext(ctx, infiniteQuery)
+	n := 0
+	for range rows {
+		n++
+		if n == 10 {
+			cancel()
+		}
+	}
+	assert.Equal(t, context.Canceled, <-errs)
+	assert.T(t, n >= 10, "rows expected")
+	checkNoError(t, db.FastExec("SELECT 1"), "connection unusable after cancellation: %s")
+}
@@ -26,6 +26,7 @@ import "C"
 import (
 	"fmt"
 	"io"
+	"math"
 	"time"
 	"unsafe"
 )
@@ -144,6 +145,7 @@ const (
 	Function          Action = C.SQLITE_FUNCTION
 	Savepoint         Action = C.SQLITE_SAVEPOINT
 	Copy              Action = C.SQLITE_COPY
+	Recursive         Action = C.SQLITE_RECURSIVE
 )
 
 func (a Action) String() string {
@@ -214,6 +216,8 @@ func (a Action) String() string {
 		return "Savepoint"
 	case Copy:
 		return "Copy"
+	case Recursive:
+		return "Recursive"
 	}
 	return fmt.Sprintf("Unknown Action: %d", a)
 }
@@ -255,12 +259,15 @@ type BusyHandler func(udp interface{}, count int) bool
 type sqliteBusyHandler struct {
 	f   BusyHandler
 	udp interface{}
+	c   *Conn
 }
 
 //export goXBusy
 func goXBusy(udp unsafe.Pointer, count int) C.int {
 	arg := (*sqliteBusyHandler)(udp)
+	start := time.Now()
 	result := arg.f(arg.udp, count)
+	arg.c.busyCounters.add(0, 1, time.Since(start))
 	return btocint(result)
 }
 
@@ -271,15 +278,34 @@ func goXBusy(udp unsafe.Pointer, count int) C.int {
 // Cannot be used with Go >= 1.6 and cgocheck enabled.
 // (See http://sqlite.org/c3ref/busy_handler.html)
 func (c *Conn) BusyHandler(f BusyHandler, udp interface{}) error {
+	c.busyTimeout = 0
 	if f == nil {
 		c.busyHandler = nil
 		return c.error(C.sqlite3_busy_handler(c.db, nil, nil), "<Conn.BusyHandler")
 	}
 	// To make sure it is not gced, keep a reference in the connection.
-	c.busyHandler = &sqliteBusyHandler{f, udp}
+	c.busyHandler = &sqliteBusyHandler{f, udp, c}
 	return c.error(C.goSqlite3BusyHandler(c.db, unsafe.Pointer(c.busyHandler)), "Conn.BusyHandler")
 }
 
+// overrideBusyTimeout sets a busy timeout without forgetting the timeout or handler
+// set by Conn.BusyTimeout or Conn.BusyHandler, and returns a function restoring the one previously in force
+// (which may be an enclosing override: a statement timeout inside a transaction timeout).
+func (c *Conn) overrideBusyTimeout(d time.Duration) func() {
+	c.busyOverrides = append(c.busyOverrides, d)
+	c.setBusyTimeout(d)
+	return func() {
+		c.busyOverrides = c.busyOverrides[:len(c.busyOverrides)-1]
+		if n := len(c.busyOverrides); n > 0 {
+			c.setBusyTimeout(c.busyOverrides[n-1])
+		} else if c.busyHandler != nil {
+			C.goSqlite3BusyHandler(c.db, unsafe.Pointer(c.busyHandler))
+		} else {
+			c.setBusyTimeout(c.busyTimeout)
+		}
+	}
+}
+
 // ProgressHandler is the signature of query progress callback.
 // Returns true to interrupt.
 // For example, to cancel long-running queries.
@@ -316,6 +342,66 @@ func (c *Conn) ProgressHandler(f ProgressHandler, numOps int32, udp interface{})
 	C.goSqlite3ProgressHandler(c.db, C.int(numOps), unsafe.Pointer(c.progressHandler))
 }
 
+// TimedProgressHandler is the signature of a time-based query progress callback.
+// elapsed is the time since the handler was registered.
+// Returns true to interrupt.
+// See Conn.TimedProgressHandler
+type TimedProgressHandler func(udp interface{}, elapsed time.Duration) (interrupt bool)
+
+const (
+	timedProgressInitialOps = 1000
+	timedProgressMinOps     = 100
+	timedProgressMaxOps     = 1 << 24
+)
+
+type timedProgress struct {
+	c        *Conn
+	f        TimedProgressHandler
+	interval time.Duration
+	numOps   int32
+	start    time.Time
+	last     time.Time
+}
+
+func (p *timedProgress) progress(udp interface{}) bool {
+	now := time.Now()
+	if since := now.Sub(p.last); since > 0 {
+		// Moves numOps halfway (geometrically) toward the value matching the target interval.
+		target := float64(p.numOps) * float64(p.interval) / float64(since)
+		numOps := int64(math.Sqrt(float64(p.numOps) * target))
+		if numOps < timedProgressMinOps {
+			numOps = timedProgressMinOps
+		} else if numOps > timedProgressMaxOps {
+			numOps = timedProgressMaxOps
+		}
+		if int32(numOps) != p.numOps {
+			p.numOps = int32(numOps)
+			// The VDBE reads the period at each check, so the handler can be re-registered from itself.
+			C.goSqlite3ProgressHandler(p.c.db, C.int(p.numOps), unsafe.Pointer(p.c.progressHandler))
+		}
+	}
+	p.last = now
+	return p.f(udp, now.Sub(p.start))
+}
+
+// TimedProgressHandler registers or clears a query progress callback
+// invoked approximately every interval (10ms for example) instead of every numOps opcodes.
+// The number of opcodes between invocations is adjusted at each invocation,
+// so the first invocations may not match the target interval.
+// The callback receives the time elapsed since the handler was registered.
+// It replaces any handler set by ProgressHandler.
+// If f is nil, the current handler is removed.
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+func (c *Conn) TimedProgressHandler(f TimedProgressHandler, interval time.Duration, udp interface{}) {
+	if f == nil {
+		c.ProgressHandler(nil, 0, nil)
+		return
+	}
+	now := time.Now()
+	p := &timedProgress{c: c, f: f, interval: interval, numOps: timedProgressInitialOps, start: now, last: now}
+	c.ProgressHandler(p.progress, p.numOps, udp)
+}
+
 // StmtStatus enumerates status parameters for prepared statements
 type StmtStatus int32
 
@@ -324,8 +410,7 @@ const (
 	StmtStatusFullScanStep StmtStatus = C.SQLITE_STMTSTATUS_FULLSCAN_STEP
 	StmtStatusSort         StmtStatus = C.SQLITE_STMTSTATUS_SORT
 	StmtStatusAutoIndex    StmtStatus = C.SQLITE_STMTSTATUS_AUTOINDEX
-
-//	StmtStatusVmStep       StmtStatus = C.SQLITE_STMTSTATUS_VM_STEP
+	StmtStatusVMStep       StmtStatus = C.SQLITE_STMTSTATUS_VM_STEP
 )
 
 // Status returns the value of a status counter for a prepared statement.
@@ -334,6 +419,35 @@ func (s *Stmt) Status(op StmtStatus, reset bool) int {
 	return int(C.sqlite3_stmt_status(s.stmt, C.int(op), btocint(reset)))
 }
 
+// DbStatus enumerates status parameters for database connections
+type DbStatus int32
+
+// Status parameters for database connections
+const (
+	DbStatusLookasideUsed      DbStatus = C.SQLITE_DBSTATUS_LOOKASIDE_USED
+	DbStatusCacheUsed          DbStatus = C.SQLITE_DBSTATUS_CACHE_USED
+	DbStatusSchemaUsed         DbStatus = C.SQLITE_DBSTATUS_SCHEMA_USED
+	DbStatusStmtUsed           DbStatus = C.SQLITE_DBSTATUS_STMT_USED
+	DbStatusLookasideHit       DbStatus = C.SQLITE_DBSTATUS_LOOKASIDE_HIT
+	DbStatusLookasideMissSize  DbStatus = C.SQLITE_DBSTATUS_LOOKASIDE_MISS_SIZE
+	DbStatusLookasideMissFull  DbStatus = C.SQLITE_DBSTATUS_LOOKASIDE_MISS_FULL
+	DbStatusCacheHit           DbStatus = C.SQLITE_DBSTATUS_CACHE_HIT
+	DbStatusCacheMiss          DbStatus = C.SQLITE_DBSTATUS_CACHE_MISS
+	DbStatusCacheWrite         DbStatus = C.SQLITE_DBSTATUS_CACHE_WRITE
+	DbStatusDeferredForeignKey DbStatus = C.SQLITE_DBSTATUS_DEFERRED_FKS
+)
+
+// Status returns the current value and the highest instantaneous value of a status counter for a database connection.
+// (See http://sqlite.org/c3ref/db_status.html)
+func (c *Conn) Status(op DbStatus, reset bool) (current int, highwater int, err error) {
+	var cur, hiwtr C.int
+	rv := C.sqlite3_db_status(c.db, C.int(op), &cur, &hiwtr, btocint(reset))
+	if rv != C.SQLITE_OK {
+		return -1, -1, c.error(rv, "Conn.Status")
+	}
+	return int(cur), int(hiwtr), nil
+}
+
 // MemoryUsed returns the number of bytes of memory currently outstanding (malloced but not freed).
 // (See sqlite3_memory_used: http://sqlite.org/c3ref/memory_highwater.html)
 func MemoryUsed() int64 {
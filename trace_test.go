@@ -112,6 +112,17 @@ func TestMemory(t *testing.T) {
 	assert.T(t, limit >= 0, "soft heap limit positive")
 }
 
+func TestDbStatus(t *testing.T) {
+	db := open(t)
+	defer checkClose(db, t)
+	createTable(db, t)
+	cur, _, err := db.Status(DbStatusCacheUsed, false)
+	checkNoError(t, err, "error while reading db status: %s")
+	assert.T(t, cur > 0, "expected some cache used")
+	_, _, err = db.Status(DbStatusCacheMiss, true)
+	checkNoError(t, err, "error while resetting db status: %s")
+}
+
 func TestExplainQueryPlan(t *testing.T) {
 	db := open(t)
 	defer checkClose(db, t)
@@ -131,3 +142,26 @@ func TestExplainQueryPlan(t *testing.T) {
 	err = e.ExplainQueryPlan(w)
 	assert.T(t, err != nil)
 }
+
+func TestTimedProgressHandler(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+
+	var calls int
+	var last time.Duration
+	db.TimedProgressHandler(func(udp interface{}, elapsed time.Duration) bool {
+		calls++
+		last = elapsed
+		return elapsed > 50*time.Millisecond
+	}, 5*time.Millisecond, nil)
+	defer db.TimedProgressHandler(nil, 0, nil)
+	var n int64
+	err := db.OneValue("SELECT count(*) FROM (WITH RECURSIVE cnt(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM cnt) SELECT x FROM cnt)", &n)
+	if se, ok := err.(StmtError); !ok || se.Code() != ErrInterrupt {
+		t.Fatalf("got %#v; want interrupt", err)
+	}
+	assert.T(t, last > 50*time.Millisecond, "elapsed")
+	assert.T(t, calls > 1 && calls < 1000, fmt.Sprintf("unexpected number of calls: %d", calls))
+}
@@ -0,0 +1,19 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include <sqlite3.h>
+#include <stdint.h>
+
+extern void goXUnlockNotify(int id);
+
+static void unlockNotifyCb(void **apArg, int nArg) {
+	int i;
+	for (i = 0; i < nArg; i++) {
+		goXUnlockNotify((int)(intptr_t)apArg[i]);
+	}
+}
+
+int goSqlite3UnlockNotify(sqlite3 *db, int id) {
+	return sqlite3_unlock_notify(db, unlockNotifyCb, (void *)(intptr_t)id);
+}
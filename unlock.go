@@ -0,0 +1,85 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+
+int goSqlite3UnlockNotify(sqlite3 *db, int id);
+*/
+import "C"
+
+import (
+	"sync"
+)
+
+// ErrLockedSharedCache is the extended error code returned when a table is locked
+// by another connection sharing the same cache (see ConnError.ExtendedCode and IsLockedSharedCache).
+const ErrLockedSharedCache = int(C.SQLITE_LOCKED_SHAREDCACHE)
+
+// IsLockedSharedCache tells if err is a table lock error caused by another connection
+// sharing the same cache.
+func IsLockedSharedCache(err error) bool {
+	if e, ok := err.(interface {
+		Code() Errno
+		ExtendedCode() int
+	}); ok {
+		return e.Code() == ErrLocked && e.ExtendedCode() == ErrLockedSharedCache
+	}
+	return false
+}
+
+// SetUnlockNotify enables or disables automatic wait/retry on shared-cache table lock errors:
+// when enabled, Prepare, Exec and Next block until the connection holding the lock has concluded its transaction
+// (using sqlite3_unlock_notify) and then retry, instead of failing with ErrLocked.
+// A deadlock (two connections waiting on each other) is still reported as ErrLocked.
+// FastExec is not retried.
+// (See http://sqlite.org/unlock_notify.html)
+func (c *Conn) SetUnlockNotify(on bool) {
+	c.unlockNotify = on
+}
+
+var unlockNotifyRegistry = struct {
+	sync.Mutex
+	next int
+	chs  map[int]chan struct{}
+}{chs: make(map[int]chan struct{})}
+
+//export goXUnlockNotify
+func goXUnlockNotify(id C.int) {
+	unlockNotifyRegistry.Lock()
+	ch := unlockNotifyRegistry.chs[int(id)]
+	delete(unlockNotifyRegistry.chs, int(id))
+	unlockNotifyRegistry.Unlock()
+	if ch != nil {
+		close(ch)
+	}
+}
+
+// lockedSharedCache tells if rv is a shared-cache table lock error which can be waited for.
+func (c *Conn) lockedSharedCache(rv C.int) bool {
+	return c.unlockNotify && rv&0xFF == C.SQLITE_LOCKED && C.sqlite3_extended_errcode(c.db) == C.SQLITE_LOCKED_SHAREDCACHE
+}
+
+// waitForUnlockNotify blocks until the connection holding the lock has concluded its transaction.
+// SQLITE_LOCKED is returned in case of deadlock.
+func (c *Conn) waitForUnlockNotify() C.int {
+	ch := make(chan struct{})
+	unlockNotifyRegistry.Lock()
+	unlockNotifyRegistry.next++
+	id := unlockNotifyRegistry.next
+	unlockNotifyRegistry.chs[id] = ch
+	unlockNotifyRegistry.Unlock()
+	// the callback may be invoked immediately (if the blocking connection has already concluded its transaction)
+	rv := C.goSqlite3UnlockNotify(c.db, C.int(id))
+	if rv != C.SQLITE_OK {
+		unlockNotifyRegistry.Lock()
+		delete(unlockNotifyRegistry.chs, id)
+		unlockNotifyRegistry.Unlock()
+		return rv
+	}
+	<-ch
+	return C.SQLITE_OK
+}
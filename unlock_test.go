@@ -0,0 +1,46 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestUnlockNotify(t *testing.T) {
+	db1, err := Open("file:unlock.db?mode=memory&cache=shared", OpenURI, OpenReadWrite, OpenCreate, OpenFullMutex)
+	checkNoError(t, err, "open error: %s")
+	defer checkClose(db1, t)
+	db2, err := Open("file:unlock.db?mode=memory&cache=shared", OpenURI, OpenReadWrite, OpenCreate, OpenFullMutex)
+	checkNoError(t, err, "open error: %s")
+	defer checkClose(db2, t)
+
+	checkNoError(t, db1.FastExec("CREATE TABLE t (x INT)"), "create error: %s")
+	checkNoError(t, db1.Begin(), "begin error: %s")
+	checkNoError(t, db1.Exec("INSERT INTO t VALUES (1)"), "insert error: %s")
+
+	var count int
+	err = db2.OneValue("SELECT count(*) FROM t", &count)
+	assert.T(t, err != nil, "locked error expected")
+	assert.T(t, IsLockedSharedCache(err), "shared-cache lock error expected")
+
+	db2.SetUnlockNotify(true)
+	done := make(chan error)
+	go func() {
+		err := db2.OneValue("SELECT count(*) FROM t", &count)
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		t.Fatalf("wait expected (%v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	checkNoError(t, db1.Commit(), "commit error: %s")
+	checkNoError(t, <-done, "select error: %s")
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,65 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileURI converts a file path to an URI filename (to be opened with the OpenURI flag)
+// with the specified query parameters (may be nil):
+//
+//	FileURI(`C:\My Documents\test#1.db`, map[string]string{"mode": "ro"})
+//	// file:///C:/My%20Documents/test%231.db?mode=ro
+//
+// Characters with a special meaning in URI ('%', '?', '#'), spaces, control and non-ASCII characters are percent-encoded
+// so that any path (with the OS separators) can be safely used with the driver or when OpenURI is specified.
+// Parameters are sorted by name.
+// (See http://sqlite.org/uri.html)
+func FileURI(path string, params map[string]string) string {
+	var b strings.Builder
+	b.WriteString("file:")
+	path = filepath.ToSlash(path)
+	if vol := filepath.VolumeName(path); len(vol) > 0 && !strings.HasPrefix(vol, "//") { // C:
+		b.WriteString("///")
+	} else if strings.HasPrefix(path, "/") {
+		b.WriteString("//")
+	}
+	uriEscape(&b, path, "")
+	if len(params) > 0 {
+		names := make([]string, 0, len(params))
+		for name := range params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			if i == 0 {
+				b.WriteByte('?')
+			} else {
+				b.WriteByte('&')
+			}
+			uriEscape(&b, name, "&=")
+			b.WriteByte('=')
+			uriEscape(&b, params[name], "&=")
+		}
+	}
+	return b.String()
+}
+
+func uriEscape(b *strings.Builder, s string, reserved string) {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch <= ' ' || ch >= 0x7f || ch == '%' || ch == '?' || ch == '#' || strings.IndexByte(reserved, ch) >= 0 {
+			b.WriteByte('%')
+			b.WriteByte(hex[ch>>4])
+			b.WriteByte(hex[ch&0xf])
+		} else {
+			b.WriteByte(ch)
+		}
+	}
+}
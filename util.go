@@ -12,24 +12,205 @@ package sqlite
 static inline char *my_mprintf(char *zFormat, char *arg) {
 	return sqlite3_mprintf(zFormat, arg);
 }
+
+#if SQLITE_VERSION_NUMBER < 3010000
+static int goSqlite3Strlike(const char *zGlob, const char *zStr, unsigned int cEsc) {
+	return -1;
+}
+#else
+static int goSqlite3Strlike(const char *zGlob, const char *zStr, unsigned int cEsc) {
+	return sqlite3_strlike(zGlob, zStr, cEsc);
+}
+#endif
+#if SQLITE_VERSION_NUMBER < 3007017
+static int goSqlite3Strglob(const char *zGlob, const char *zStr) {
+	return -1;
+}
+#else
+static int goSqlite3Strglob(const char *zGlob, const char *zStr) {
+	return sqlite3_strglob(zGlob, zStr);
+}
+#endif
+
+#if SQLITE_VERSION_NUMBER < 3024000
+static int goSqlite3KeywordCount(void) {
+	return 0;
+}
+static int goSqlite3KeywordName(int i, const char **pz, int *pn) {
+	return SQLITE_ERROR;
+}
+static int goSqlite3KeywordCheck(const char *z, int n) {
+	return 0;
+}
+#else
+static int goSqlite3KeywordCount(void) {
+	return sqlite3_keyword_count();
+}
+static int goSqlite3KeywordName(int i, const char **pz, int *pn) {
+	return sqlite3_keyword_name(i, pz, pn);
+}
+static int goSqlite3KeywordCheck(const char *z, int n) {
+	return sqlite3_keyword_check(z, n);
+}
+#endif
 */
 import "C"
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"unsafe"
 )
 
 // Mprintf is like fmt.Printf but implements some additional formatting options
-// that are useful for constructing SQL statements.
-// (See http://sqlite.org/c3ref/mprintf.html)
-func Mprintf(format string, arg string) string {
-	zSQL := mPrintf(format, arg)
-	defer C.sqlite3_free(unsafe.Pointer(zSQL))
-	return C.GoString(zSQL)
+// that are useful for constructing SQL statements:
+//
+//	%q doubles single quotes,
+//	%Q is like %q but also surrounds the result with single quotes (and renders nil as NULL),
+//	%w doubles double quotes (for identifiers).
+//
+// %s, %z, %c, %%, numeric verbs (%d, %i, %u, %x, %X, %o, %f, %e, %E, %g, %G),
+// flags, width and precision (including '*') are also supported, for any number of arguments.
+// A missing argument is treated as nil.
+// Formatting is done in Go (following the sqlite3_mprintf rules).
+// (See http://sqlite.org/c3ref/mprintf.html and http://sqlite.org/printf.html)
+func Mprintf(format string, args ...interface{}) string {
+	var b bytes.Buffer
+	next := 0
+	arg := func() interface{} {
+		if next >= len(args) {
+			return nil
+		}
+		next++
+		return args[next-1]
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		// flags, width and precision are converted to a fmt spec
+		spec := []byte{'%'}
+		for i++; i < len(format) && strings.IndexByte("-+ #0!,", format[i]) >= 0; i++ {
+			if format[i] != '!' && format[i] != ',' { // alternate-form-2 and thousands separator are ignored
+				spec = append(spec, format[i])
+			}
+		}
+		for ; i < len(format) && (format[i] == '*' || format[i] == '.' || ('0' <= format[i] && format[i] <= '9')); i++ {
+			if format[i] == '*' {
+				spec = strconv.AppendInt(spec, toInt64(arg()), 10)
+			} else {
+				spec = append(spec, format[i])
+			}
+		}
+		for ; i < len(format) && (format[i] == 'l' || format[i] == 'h'); i++ { // length modifiers are ignored
+		}
+		if i >= len(format) {
+			break
+		}
+		verb := format[i]
+		switch verb {
+		case '%':
+			b.WriteByte('%')
+		case 's', 'z':
+			if v := arg(); v != nil {
+				fmt.Fprintf(&b, string(spec)+"s", toString(v))
+			}
+		case 'q', 'Q', 'w':
+			v := arg()
+			var str string
+			if v == nil {
+				if verb == 'Q' {
+					str = "NULL"
+				} else {
+					str = "(NULL)"
+				}
+			} else if verb == 'w' {
+				str = escapeQuote(toString(v))
+			} else if verb == 'q' {
+				str = strings.Replace(toString(v), "'", "''", -1)
+			} else {
+				str = QuoteLiteral(toString(v))
+			}
+			fmt.Fprintf(&b, string(spec)+"s", str)
+		case 'd', 'i', 'u':
+			fmt.Fprintf(&b, string(spec)+"d", toInt64(arg()))
+		case 'x', 'X', 'o':
+			fmt.Fprintf(&b, string(spec)+string(verb), uint64(toInt64(arg())))
+		case 'c':
+			fmt.Fprintf(&b, string(spec)+"c", rune(toInt64(arg())))
+		case 'f', 'e', 'E', 'g', 'G':
+			fmt.Fprintf(&b, string(spec)+string(verb), toFloat64(arg()))
+		default: // unknown verb is output as is
+			b.WriteString(format[strings.LastIndexByte(format[:i], '%') : i+1])
+		}
+	}
+	return b.String()
 }
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func toInt64(v interface{}) int64 {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	case float32:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case bool:
+		if v {
+			return 1
+		}
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	}
+	return 0
+}
+
+func toFloat64(v interface{}) float64 {
+	switch v := v.(type) {
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return float64(toInt64(v))
+}
+
 func mPrintf(format, arg string) *C.char { // TODO may return nil when no memory...
 	cf := C.CString(format)
 	defer C.free(unsafe.Pointer(cf))
@@ -58,6 +239,162 @@ func gostring(cs *C.char) string {
 }
 */
 
+// KeywordCount returns the number of distinct keywords understood by SQLite
+// (zero with SQLite < 3.24).
+// (See http://sqlite.org/c3ref/keyword_check.html)
+func KeywordCount() int {
+	return int(C.goSqlite3KeywordCount())
+}
+
+// KeywordName returns the i-th keyword (0 <= i < KeywordCount()).
+// An empty string is returned when i is out of range.
+// (See http://sqlite.org/c3ref/keyword_check.html)
+func KeywordName(i int) string {
+	var z *C.char
+	var n C.int
+	if C.goSqlite3KeywordName(C.int(i), &z, &n) != C.SQLITE_OK {
+		return ""
+	}
+	return C.GoStringN(z, n)
+}
+
+// IsKeyword checks whether name is an SQL keyword (case insensitive)
+// and must be quoted to be used as an identifier (always false with SQLite < 3.24).
+// (See http://sqlite.org/c3ref/keyword_check.html)
+func IsKeyword(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	cs, l := cstring(name)
+	return C.goSqlite3KeywordCheck(cs, l) != 0
+}
+
+// StrGlob checks whether s matches the GLOB pattern (case sensitive, like the SQL GLOB operator).
+// Always false with SQLite < 3.7.17.
+// (See http://sqlite.org/c3ref/strglob.html)
+func StrGlob(pattern, s string) bool {
+	cp := C.CString(pattern)
+	defer C.free(unsafe.Pointer(cp))
+	cs := C.CString(s)
+	defer C.free(unsafe.Pointer(cs))
+	return C.goSqlite3Strglob(cp, cs) == 0
+}
+
+// StrLike checks whether s matches the LIKE pattern (case insensitive for ASCII characters, like the SQL LIKE operator).
+// escape is the optional escape character (zero when there is none).
+// Always false with SQLite < 3.10.
+// (See http://sqlite.org/c3ref/strlike.html)
+func StrLike(pattern, s string, escape rune) bool {
+	cp := C.CString(pattern)
+	defer C.free(unsafe.Pointer(cp))
+	cs := C.CString(s)
+	defer C.free(unsafe.Pointer(cs))
+	return C.goSqlite3Strlike(cp, cs, C.uint(escape)) == 0
+}
+
+// QuoteIdentifier surrounds identifier with double quotes (embedded double quotes are doubled)
+// to be used as a table/column/... name in dynamically generated SQL (like DDL).
+// SQLite identifiers cannot contain NUL bytes (the SQL text would be truncated).
+func QuoteIdentifier(identifier string) string {
+	return `"` + escapeQuote(identifier) + `"`
+}
+
+// EscapeString doubles the single quotes embedded in s
+// so that it can be put between single quotes as an SQL string literal.
+// NUL bytes are kept as is but SQLite stops reading the SQL text at the first one:
+// use QuoteLiteral when s may contain some.
+func EscapeString(s string) string {
+	return strings.Replace(s, "'", "''", -1)
+}
+
+// QuoteLiteral surrounds s with single quotes (embedded single quotes are doubled)
+// to be used as an SQL string literal.
+// When s contains NUL bytes, an equivalent expression is returned: ('a'||char(0)||'b').
+func QuoteLiteral(s string) string {
+	if strings.IndexByte(s, 0) < 0 {
+		return "'" + EscapeString(s) + "'"
+	}
+	parts := strings.Split(s, "\x00")
+	for i, part := range parts {
+		parts[i] = "'" + EscapeString(part) + "'"
+	}
+	return "(" + strings.Join(parts, "||char(0)||") + ")"
+}
+
+// BlobLiteral returns the SQL blob literal (X'0A1B') matching b.
+func BlobLiteral(b []byte) string {
+	return fmt.Sprintf("X'%X'", b)
+}
+
+// QualifiedName returns the quoted name of an object optionally qualified by a database name:
+// "db"."name" or "name" when the database name is empty.
+func QualifiedName(dbName, name string) string {
+	if len(dbName) == 0 {
+		return QuoteIdentifier(name)
+	}
+	return QuoteIdentifier(dbName) + "." + QuoteIdentifier(name)
+}
+
+// ParseQualifiedName splits a (possibly quoted) qualified name like db.table or "db"."table".
+// The database name is empty when the name is not qualified.
+// Identifiers may be quoted with double quotes, backticks or square brackets.
+func ParseQualifiedName(qualifiedName string) (dbName, name string, err error) {
+	first, rest, err := parseIdentifier(qualifiedName)
+	if err != nil {
+		return "", "", err
+	}
+	if len(rest) == 0 {
+		return "", first, nil
+	}
+	if rest[0] != '.' {
+		return "", "", fmt.Errorf("invalid qualified name: %q", qualifiedName)
+	}
+	second, rest, err := parseIdentifier(rest[1:])
+	if err != nil {
+		return "", "", err
+	}
+	if len(rest) > 0 {
+		return "", "", fmt.Errorf("invalid qualified name: %q", qualifiedName)
+	}
+	return first, second, nil
+}
+
+// parseIdentifier returns the first (unquoted) identifier of s and what follows it.
+func parseIdentifier(s string) (id, rest string, err error) {
+	if len(s) == 0 {
+		return "", "", fmt.Errorf("missing identifier")
+	}
+	var end byte
+	switch s[0] {
+	case '"', '`':
+		end = s[0]
+	case '[':
+		end = ']'
+	default:
+		i := strings.IndexByte(s, '.')
+		if i < 0 {
+			i = len(s)
+		}
+		if i == 0 {
+			return "", "", fmt.Errorf("missing identifier: %q", s)
+		}
+		return s[:i], s[i:], nil
+	}
+	var b []byte
+	for i := 1; i < len(s); i++ {
+		if s[i] == end {
+			if end != ']' && i+1 < len(s) && s[i+1] == end { // escaped quote
+				b = append(b, end)
+				i++
+				continue
+			}
+			return string(b), s[i+1:], nil
+		}
+		b = append(b, s[i])
+	}
+	return "", "", fmt.Errorf("unterminated quoted identifier: %q", s)
+}
+
 func escapeQuote(identifier string) string {
 	if strings.ContainsRune(identifier, '"') { // escape quote by doubling them
 		identifier = strings.Replace(identifier, `"`, `""`, -1)
@@ -68,5 +405,5 @@ func doubleQuote(dbName string) string {
 	if dbName == "main" || dbName == "temp" {
 		return dbName
 	}
-	return fmt.Sprintf(`"%s"`, escapeQuote(dbName)) // surround identifier with quote
+	return QuoteIdentifier(dbName)
 }
@@ -0,0 +1,446 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StringArray is the text counterpart of IntArray:
+// a virtual table in the TEMP database designed to be used as the right-hand side of an IN operator.
+//
+//	p, err := db.CreateStringArray("names")
+//	s, err := db.Prepare("SELECT * FROM t WHERE name IN names")
+//	p.Bind([]string{"a", "b", "c"})
+//
+// The same lifecycle as IntArray applies but, contrary to IntArray, the bound slice is not copied:
+// do not change the bindings nor the content of the array in the middle of a query.
+type StringArray interface {
+	Bind(elements []string)
+	Drop() error
+}
+
+// ValueArray is the generic counterpart of IntArray:
+// bound elements can be nil, string, int, int64, byte, bool, float32, float64 or []byte.
+// The same lifecycle and restrictions as StringArray apply.
+type ValueArray interface {
+	Bind(elements []interface{})
+	Drop() error
+}
+
+// Array is a type-generic counterpart of IntArray: any []int64, []int, []float64, []string,
+// [][]byte or []interface{} (see ValueArray) can be bound to it.
+// The same lifecycle and restrictions as StringArray apply.
+type Array interface {
+	Bind(elements interface{}) error
+	Drop() error
+}
+
+// arrayModule implements a read-only virtual table whose unique column (value)
+// is fed by a Go slice. It is the Go equivalent of intarray.c.
+// Equality constraints (value = expr) are resolved with a value to indexes map
+// built on the first lookup after each Bind.
+type arrayModule struct {
+	c       *Conn
+	name    string
+	len     func() int
+	column  func(ctx *Context, i int) error
+	value   func(i int) interface{}
+	index   map[interface{}][]int
+	dropped bool
+}
+
+type arrayVTab struct {
+	m *arrayModule
+}
+
+type arrayCursor struct {
+	m    *arrayModule
+	rows []int // indexes of the matching elements when an equality constraint is used
+	eq   bool
+	i    int
+}
+
+const arrayIndexEq = 1
+
+// CreateStringArray creates a specific instance of a string array object.
+// Each object corresponds to a virtual table in the TEMP database with the specified name.
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+func (c *Conn) CreateStringArray(name string) (StringArray, error) {
+	sa := &stringArray{}
+	m := &arrayModule{c: c, name: name}
+	m.len = func() int { return len(sa.content) }
+	m.column = func(ctx *Context, i int) error {
+		ctx.ResultText(sa.content[i])
+		return nil
+	}
+	m.value = func(i int) interface{} { return sa.content[i] }
+	sa.m = m
+	if err := m.create(); err != nil {
+		return nil, err
+	}
+	return sa, nil
+}
+
+// CreateValueArray creates a specific instance of a value array object.
+// Each object corresponds to a virtual table in the TEMP database with the specified name.
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+func (c *Conn) CreateValueArray(name string) (ValueArray, error) {
+	va := &valueArray{}
+	m := &arrayModule{c: c, name: name}
+	m.len = func() int { return len(va.content) }
+	m.column = func(ctx *Context, i int) error {
+		return resultValue(ctx, va.content[i])
+	}
+	m.value = func(i int) interface{} { return va.content[i] }
+	va.m = m
+	if err := m.create(); err != nil {
+		return nil, err
+	}
+	return va, nil
+}
+
+// CreateArray creates a specific instance of a type-generic array object.
+// Each object corresponds to a virtual table in the TEMP database with the specified name.
+// Cannot be used with Go >= 1.6 and cgocheck enabled.
+func (c *Conn) CreateArray(name string) (Array, error) {
+	a := &genericArray{}
+	a.m = &arrayModule{c: c, name: name}
+	a.Bind(nil)
+	if err := a.m.create(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (m *arrayModule) create() error {
+	if err := m.c.CreateModule(m.name, m); err != nil {
+		return err
+	}
+	return m.c.FastExec(fmt.Sprintf("CREATE VIRTUAL TABLE temp.%s USING %s", QuoteIdentifier(m.name), QuoteIdentifier(m.name)))
+}
+
+func (m *arrayModule) drop() error {
+	if m == nil {
+		return errors.New("nil sqlite array")
+	}
+	if m.dropped {
+		return nil
+	}
+	if err := m.c.FastExec(fmt.Sprintf("DROP TABLE temp.%s", QuoteIdentifier(m.name))); err != nil {
+		return err
+	}
+	m.dropped = true
+	return nil
+}
+
+type stringArray struct {
+	m       *arrayModule
+	content []string
+}
+
+// Bind a new array of strings to a specific string array object.
+func (a *stringArray) Bind(elements []string) {
+	if a.m.dropped {
+		return
+	}
+	a.content = elements
+	a.m.index = nil
+}
+
+// Drop underlying virtual table.
+func (a *stringArray) Drop() error {
+	if a == nil {
+		return errors.New("nil sqlite string array")
+	}
+	return a.m.drop()
+}
+
+type valueArray struct {
+	m       *arrayModule
+	content []interface{}
+}
+
+// Bind a new array of values to a specific value array object.
+func (a *valueArray) Bind(elements []interface{}) {
+	if a.m.dropped {
+		return
+	}
+	a.content = elements
+	a.m.index = nil
+}
+
+// Drop underlying virtual table.
+func (a *valueArray) Drop() error {
+	if a == nil {
+		return errors.New("nil sqlite value array")
+	}
+	return a.m.drop()
+}
+
+type genericArray struct {
+	m *arrayModule
+}
+
+// Bind a new slice to a specific array object.
+func (a *genericArray) Bind(elements interface{}) error {
+	if a.m.dropped {
+		return nil
+	}
+	m := a.m
+	switch elements := elements.(type) {
+	case nil:
+		m.len = func() int { return 0 }
+		m.column = nil
+		m.value = nil
+	case []int64:
+		m.len = func() int { return len(elements) }
+		m.column = func(ctx *Context, i int) error {
+			ctx.ResultInt64(elements[i])
+			return nil
+		}
+		m.value = func(i int) interface{} { return elements[i] }
+	case []int:
+		m.len = func() int { return len(elements) }
+		m.column = func(ctx *Context, i int) error {
+			ctx.ResultInt(elements[i])
+			return nil
+		}
+		m.value = func(i int) interface{} { return elements[i] }
+	case []float64:
+		m.len = func() int { return len(elements) }
+		m.column = func(ctx *Context, i int) error {
+			ctx.ResultDouble(elements[i])
+			return nil
+		}
+		m.value = func(i int) interface{} { return elements[i] }
+	case []string:
+		m.len = func() int { return len(elements) }
+		m.column = func(ctx *Context, i int) error {
+			ctx.ResultText(elements[i])
+			return nil
+		}
+		m.value = func(i int) interface{} { return elements[i] }
+	case [][]byte:
+		m.len = func() int { return len(elements) }
+		m.column = func(ctx *Context, i int) error {
+			ctx.ResultBlob(elements[i])
+			return nil
+		}
+		m.value = func(i int) interface{} { return elements[i] }
+	case []interface{}:
+		m.len = func() int { return len(elements) }
+		m.column = func(ctx *Context, i int) error {
+			return resultValue(ctx, elements[i])
+		}
+		m.value = func(i int) interface{} { return elements[i] }
+	default:
+		return fmt.Errorf("unsupported type in array: %q", reflect.TypeOf(elements))
+	}
+	m.index = nil
+	return nil
+}
+
+// Drop underlying virtual table.
+func (a *genericArray) Drop() error {
+	if a == nil {
+		return errors.New("nil sqlite array")
+	}
+	return a.m.drop()
+}
+
+func resultValue(ctx *Context, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		ctx.ResultNull()
+	case string:
+		ctx.ResultText(v)
+	case int:
+		ctx.ResultInt(v)
+	case int64:
+		ctx.ResultInt64(v)
+	case byte:
+		ctx.ResultInt(int(v))
+	case bool:
+		ctx.ResultBool(v)
+	case float32:
+		ctx.ResultDouble(float64(v))
+	case float64:
+		ctx.ResultDouble(v)
+	case []byte:
+		ctx.ResultBlob(v)
+	default:
+		return fmt.Errorf("unsupported type in value array: %q", reflect.TypeOf(v))
+	}
+	return nil
+}
+
+func (m *arrayModule) Create(c *Conn, args []string) (VTab, error) {
+	if err := c.DeclareVTabSchema(VTabSchema{Columns: []VTabColumn{{Name: "value"}}}); err != nil {
+		return nil, err
+	}
+	return &arrayVTab{m}, nil
+}
+func (m *arrayModule) Connect(c *Conn, args []string) (VTab, error) {
+	return m.Create(c, args)
+}
+func (m *arrayModule) DestroyModule() {
+}
+
+func (v *arrayVTab) BestIndex() error {
+	return nil
+}
+func (v *arrayVTab) BestIndexInfo(info *IndexInfo) error {
+	info.EstimatedCost = float64(v.m.len())
+	for i, c := range info.Constraints {
+		// SQLite double checks the constraint (Omit is not set) so the lookup may return false positives
+		// but the collation must be BINARY to not miss any element.
+		if c.Usable && c.Column == 0 && c.Op == IndexConstraintEQ && strings.EqualFold(info.Collation(i), "BINARY") {
+			info.Constraints[i].ArgvIndex = 1
+			info.IdxNum = arrayIndexEq
+			info.EstimatedCost = 1
+			info.EstimatedRows = 1
+			break
+		}
+	}
+	return nil
+}
+func (v *arrayVTab) Disconnect() error {
+	return nil
+}
+func (v *arrayVTab) Destroy() error {
+	return nil
+}
+func (v *arrayVTab) Open() (VTabCursor, error) {
+	return &arrayCursor{m: v.m}, nil
+}
+
+func (vc *arrayCursor) Close() error {
+	return nil
+}
+func (vc *arrayCursor) Filter() error {
+	vc.rows, vc.eq = nil, false
+	vc.i = 0
+	return nil
+}
+func (vc *arrayCursor) FilterIndex(idxNum int, idxStr string, args []interface{}) error {
+	if idxNum != arrayIndexEq {
+		return vc.Filter()
+	}
+	vc.rows, vc.eq = vc.m.lookup(args[0]), true
+	vc.i = 0
+	return nil
+}
+func (vc *arrayCursor) Next() error {
+	vc.i++
+	return nil
+}
+func (vc *arrayCursor) EOF() bool {
+	if vc.eq {
+		return vc.i >= len(vc.rows)
+	}
+	return vc.i >= vc.m.len()
+}
+func (vc *arrayCursor) row() int {
+	if vc.eq {
+		return vc.rows[vc.i]
+	}
+	return vc.i
+}
+func (vc *arrayCursor) Column(c *Context, col int) error {
+	if col != 0 {
+		return fmt.Errorf("column index out of bounds: %d", col)
+	}
+	return vc.m.column(c, vc.row())
+}
+func (vc *arrayCursor) Rowid() (int64, error) {
+	return int64(vc.row()), nil
+}
+
+// lookup returns the indexes of the elements which may be equal to v.
+func (m *arrayModule) lookup(v interface{}) []int {
+	if m.index == nil {
+		m.index = make(map[interface{}][]int)
+		for i, n := 0, m.len(); i < n; i++ {
+			for _, k := range arrayKeys(m.value(i)) {
+				m.index[k] = append(m.index[k], i)
+			}
+		}
+	}
+	keys := arrayKeys(v)
+	if len(keys) == 1 {
+		return m.index[keys[0]]
+	}
+	var rows []int
+	for _, k := range keys {
+		rows = append(rows, m.index[k]...)
+	}
+	sort.Ints(rows)
+	// a text element may be found both as text and as a number
+	n := 0
+	for i, row := range rows {
+		if i == 0 || row != rows[n-1] {
+			rows[n] = row
+			n++
+		}
+	}
+	return rows[:n]
+}
+
+type arrayBlobKey string
+
+// arrayKeys returns the map keys of a value: integral numbers (including bool) are indexed as int64
+// and text is also indexed as a number (when it looks like one) because SQLite may apply
+// a numeric (or text) affinity to the operands of an equality.
+func arrayKeys(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case string:
+		if k, ok := arrayNumKey(strings.TrimSpace(v)); ok {
+			return []interface{}{v, k}
+		}
+		return []interface{}{v}
+	case []byte:
+		return []interface{}{arrayBlobKey(v)}
+	case int:
+		return []interface{}{int64(v)}
+	case int64:
+		return []interface{}{v}
+	case byte:
+		return []interface{}{int64(v)}
+	case bool:
+		if v {
+			return []interface{}{int64(1)}
+		}
+		return []interface{}{int64(0)}
+	case float32:
+		return []interface{}{arrayFloatKey(float64(v))}
+	case float64:
+		return []interface{}{arrayFloatKey(v)}
+	}
+	return nil // NULL is never equal to anything
+}
+
+func arrayNumKey(s string) (interface{}, bool) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return arrayFloatKey(f), true
+	}
+	return nil, false
+}
+
+func arrayFloatKey(f float64) interface{} {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return int64(f)
+	}
+	return f
+}
@@ -0,0 +1,154 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestStringArrayModule(t *testing.T) {
+	skipIfCgoCheckActive(t)
+	db := open(t)
+	defer checkClose(db, t)
+
+	err := db.FastExec(`CREATE TABLE t1 (name TEXT);
+		INSERT INTO t1 VALUES ('a'), ('b'), ('c');`)
+	checkNoError(t, err, "couldn't create table: %s")
+
+	p, err := db.CreateStringArray("names")
+	checkNoError(t, err, "couldn't create string array: %s")
+
+	s, err := db.Prepare("SELECT name FROM t1 WHERE name IN names ORDER BY name")
+	checkNoError(t, err, "couldn't prepare: %s")
+	defer checkFinalize(s, t)
+
+	names := func() []string {
+		var result []string
+		err := s.Select(func(s *Stmt) error {
+			name, _ := s.ScanText(0)
+			result = append(result, name)
+			return nil
+		})
+		checkNoError(t, err, "couldn't select: %s")
+		return result
+	}
+	assert.Equal(t, 0, len(names()))
+	p.Bind([]string{"c", "a", "z"})
+	assert.Equal(t, []string{"a", "c"}, names())
+	p.Bind([]string{"b"})
+	assert.Equal(t, []string{"b"}, names())
+
+	checkNoError(t, p.Drop(), "couldn't drop string array: %s")
+	checkNoError(t, p.Drop(), "couldn't drop string array twice: %s")
+}
+
+func TestValueArrayModule(t *testing.T) {
+	skipIfCgoCheckActive(t)
+	db := open(t)
+	defer checkClose(db, t)
+
+	p, err := db.CreateValueArray("vals")
+	checkNoError(t, err, "couldn't create value array: %s")
+	defer p.Drop()
+
+	p.Bind([]interface{}{nil, "text", 1, int64(2), 3.5, []byte{'x'}, true})
+	var types []string
+	err = db.Select("SELECT typeof(value) FROM vals", func(s *Stmt) error {
+		typ, _ := s.ScanText(0)
+		types = append(types, typ)
+		return nil
+	})
+	checkNoError(t, err, "couldn't select: %s")
+	assert.Equal(t, []string{"null", "text", "integer", "integer", "real", "blob", "integer"}, types)
+
+	var n int
+	checkNoError(t, db.OneValue("SELECT count(*) FROM vals WHERE value IN (1, 'text')", &n), "couldn't count: %s")
+	assert.Equal(t, 3, n) // 1, true and "text"
+
+	p.Bind([]interface{}{struct{}{}})
+	err = db.OneValue("SELECT value FROM vals", &n)
+	assert.T(t, err != nil, "error expected")
+}
+
+func TestArrayModule(t *testing.T) {
+	skipIfCgoCheckActive(t)
+	db := open(t)
+	defer checkClose(db, t)
+
+	p, err := db.CreateArray("arr")
+	checkNoError(t, err, "couldn't create array: %s")
+	defer p.Drop()
+
+	count := func(sql string) int {
+		var n int
+		checkNoError(t, db.OneValue(sql, &n), "couldn't count: %s")
+		return n
+	}
+	assert.Equal(t, 0, count("SELECT count(*) FROM arr"))
+	checkNoError(t, p.Bind([]int64{1, 2, 3}), "couldn't bind: %s")
+	assert.Equal(t, 2, count("SELECT count(*) FROM arr WHERE value IN (1, 3)"))
+	checkNoError(t, p.Bind([]int{4, 5}), "couldn't bind: %s")
+	assert.Equal(t, 9, count("SELECT sum(value) FROM arr"))
+	checkNoError(t, p.Bind([]float64{0.5, 1.5}), "couldn't bind: %s")
+	assert.Equal(t, 2, count("SELECT count(*) FROM arr WHERE typeof(value) = 'real'"))
+	checkNoError(t, p.Bind([]string{"a", "b"}), "couldn't bind: %s")
+	assert.Equal(t, 1, count("SELECT count(*) FROM arr WHERE value = 'b'"))
+	checkNoError(t, p.Bind([][]byte{{'x'}}), "couldn't bind: %s")
+	assert.Equal(t, 1, count("SELECT count(*) FROM arr WHERE typeof(value) = 'blob'"))
+	checkNoError(t, p.Bind([]interface{}{nil, 1}), "couldn't bind: %s")
+	assert.Equal(t, 1, count("SELECT count(*) FROM arr WHERE value IS NULL"))
+
+	err = p.Bind([]bool{true})
+	assert.T(t, err != nil, "error expected")
+}
+
+func TestArrayModuleEqLookup(t *testing.T) {
+	skipIfCgoCheckActive(t)
+	db := open(t)
+	defer checkClose(db, t)
+
+	p, err := db.CreateArray("arr")
+	checkNoError(t, err, "couldn't create array: %s")
+	defer p.Drop()
+	checkNoError(t, p.Bind([]interface{}{"a", 1, "b", "a", 2.0, "3", nil}), "couldn't bind: %s")
+
+	var plan string
+	err = db.Select("EXPLAIN QUERY PLAN SELECT rowid FROM arr WHERE value = 'a'", func(s *Stmt) error {
+		plan, _ = s.ScanText(3)
+		return nil
+	})
+	checkNoError(t, err, "couldn't explain: %s")
+	assert.T(t, strings.Contains(plan, "INDEX 1:"), plan)
+
+	rowids := func(sql string, args ...interface{}) []int {
+		var result []int
+		s, err := db.Prepare(sql, args...)
+		checkNoError(t, err, "couldn't prepare: %s")
+		defer checkFinalize(s, t)
+		err = s.Select(func(s *Stmt) error {
+			rowid, _, _ := s.ScanInt(0)
+			result = append(result, rowid)
+			return nil
+		})
+		checkNoError(t, err, "couldn't select: %s")
+		return result
+	}
+	assert.Equal(t, []int{0, 3}, rowids("SELECT rowid FROM arr WHERE value = ?", "a"))
+	assert.Equal(t, []int{4}, rowids("SELECT rowid FROM arr WHERE value = 2"))
+	assert.Equal(t, []int{1, 5}, rowids("SELECT rowid FROM arr WHERE value IN (1, '3')"))
+	assert.Equal(t, []int(nil), rowids("SELECT rowid FROM arr WHERE value = ?", nil))
+	assert.Equal(t, []int{0, 3}, rowids("SELECT rowid FROM arr WHERE value = 'A' COLLATE NOCASE"))
+
+	// affinity of the joined column
+	checkNoError(t, db.FastExec("CREATE TABLE t (i INTEGER); INSERT INTO t VALUES (3)"), "%s")
+	assert.Equal(t, []int{5}, rowids("SELECT arr.rowid FROM t, arr WHERE arr.value = t.i"))
+
+	checkNoError(t, p.Bind([]string{"x", "a"}), "couldn't bind: %s")
+	assert.Equal(t, []int{1}, rowids("SELECT rowid FROM arr WHERE value = 'a'"))
+}
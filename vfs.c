@@ -0,0 +1,202 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include <sqlite3.h>
+#include <stdlib.h>
+#include <string.h>
+
+// Go VFS and files are referenced by handles (not by Go pointers) because they are stored in C memory.
+
+typedef struct goVfs goVfs;
+struct goVfs {
+	sqlite3_vfs base;
+	sqlite3_vfs *pRoot; /* default VFS used for randomness, sleep, time, dynamic loading */
+	int id;             /* Go VFS handle */
+};
+
+typedef struct goVfsFile goVfsFile;
+struct goVfsFile {
+	sqlite3_file base;
+	int id; /* Go file handle */
+};
+
+extern int goVfsOpen(int vfsId, char *zName, int *pFileId, int flags, int *pOutFlags);
+extern int goVfsDelete(int vfsId, char *zName, int syncDir);
+extern int goVfsAccess(int vfsId, char *zName, int flags, int *pResOut);
+extern int goVfsFullPathname(int vfsId, char *zName, int nOut, char *zOut);
+
+extern int goVfsClose(int fileId);
+extern int goVfsRead(int fileId, void *p, int iAmt, sqlite3_int64 iOfst);
+extern int goVfsWrite(int fileId, void *p, int iAmt, sqlite3_int64 iOfst);
+extern int goVfsTruncate(int fileId, sqlite3_int64 size);
+extern int goVfsSync(int fileId, int flags);
+extern int goVfsFileSize(int fileId, sqlite3_int64 *pSize);
+extern int goVfsLock(int fileId, int eLock);
+extern int goVfsUnlock(int fileId, int eLock);
+extern int goVfsCheckReservedLock(int fileId, int *pResOut);
+extern int goVfsSectorSize(int fileId);
+extern int goVfsDeviceCharacteristics(int fileId);
+
+static int cXClose(sqlite3_file *pFile) {
+	return goVfsClose(((goVfsFile *)pFile)->id);
+}
+static int cXRead(sqlite3_file *pFile, void *p, int iAmt, sqlite3_int64 iOfst) {
+	return goVfsRead(((goVfsFile *)pFile)->id, p, iAmt, iOfst);
+}
+static int cXWrite(sqlite3_file *pFile, const void *p, int iAmt, sqlite3_int64 iOfst) {
+	return goVfsWrite(((goVfsFile *)pFile)->id, (void *)p, iAmt, iOfst);
+}
+static int cXTruncate(sqlite3_file *pFile, sqlite3_int64 size) {
+	return goVfsTruncate(((goVfsFile *)pFile)->id, size);
+}
+static int cXSync(sqlite3_file *pFile, int flags) {
+	return goVfsSync(((goVfsFile *)pFile)->id, flags);
+}
+static int cXFileSize(sqlite3_file *pFile, sqlite3_int64 *pSize) {
+	return goVfsFileSize(((goVfsFile *)pFile)->id, pSize);
+}
+static int cXLock(sqlite3_file *pFile, int eLock) {
+	return goVfsLock(((goVfsFile *)pFile)->id, eLock);
+}
+static int cXUnlock(sqlite3_file *pFile, int eLock) {
+	return goVfsUnlock(((goVfsFile *)pFile)->id, eLock);
+}
+static int cXCheckReservedLock(sqlite3_file *pFile, int *pResOut) {
+	return goVfsCheckReservedLock(((goVfsFile *)pFile)->id, pResOut);
+}
+static int cXFileControl(sqlite3_file *pFile, int op, void *pArg) {
+	return SQLITE_NOTFOUND;
+}
+static int cXSectorSize(sqlite3_file *pFile) {
+	return goVfsSectorSize(((goVfsFile *)pFile)->id);
+}
+static int cXDeviceCharacteristics(sqlite3_file *pFile) {
+	return goVfsDeviceCharacteristics(((goVfsFile *)pFile)->id);
+}
+
+static const sqlite3_io_methods goIoMethods = {
+	1,                       /* iVersion (no shared memory: WAL requires exclusive locking mode) */
+	cXClose,
+	cXRead,
+	cXWrite,
+	cXTruncate,
+	cXSync,
+	cXFileSize,
+	cXLock,
+	cXUnlock,
+	cXCheckReservedLock,
+	cXFileControl,
+	cXSectorSize,
+	cXDeviceCharacteristics,
+};
+
+static int cXOpen(sqlite3_vfs *pVfs, const char *zName, sqlite3_file *pFile, int flags, int *pOutFlags) {
+	goVfsFile *p = (goVfsFile *)pFile;
+	int outFlags = flags;
+	int rc = goVfsOpen(((goVfs *)pVfs)->id, (char *)zName, &p->id, flags, &outFlags);
+	if (rc != SQLITE_OK) {
+		p->base.pMethods = 0;
+		return rc;
+	}
+	if (pOutFlags) {
+		*pOutFlags = outFlags;
+	}
+	p->base.pMethods = &goIoMethods;
+	return SQLITE_OK;
+}
+static int cXDelete(sqlite3_vfs *pVfs, const char *zName, int syncDir) {
+	return goVfsDelete(((goVfs *)pVfs)->id, (char *)zName, syncDir);
+}
+static int cXAccess(sqlite3_vfs *pVfs, const char *zName, int flags, int *pResOut) {
+	return goVfsAccess(((goVfs *)pVfs)->id, (char *)zName, flags, pResOut);
+}
+static int cXFullPathname(sqlite3_vfs *pVfs, const char *zName, int nOut, char *zOut) {
+	return goVfsFullPathname(((goVfs *)pVfs)->id, (char *)zName, nOut, zOut);
+}
+
+#define ROOT(pVfs) (((goVfs *)(pVfs))->pRoot)
+
+static void *cXDlOpen(sqlite3_vfs *pVfs, const char *zPath) {
+	return ROOT(pVfs)->xDlOpen(ROOT(pVfs), zPath);
+}
+static void cXDlError(sqlite3_vfs *pVfs, int nByte, char *zErrMsg) {
+	ROOT(pVfs)->xDlError(ROOT(pVfs), nByte, zErrMsg);
+}
+static void (*cXDlSym(sqlite3_vfs *pVfs, void *p, const char *zSym))(void) {
+	return ROOT(pVfs)->xDlSym(ROOT(pVfs), p, zSym);
+}
+static void cXDlClose(sqlite3_vfs *pVfs, void *pHandle) {
+	ROOT(pVfs)->xDlClose(ROOT(pVfs), pHandle);
+}
+static int cXRandomness(sqlite3_vfs *pVfs, int nByte, char *zOut) {
+	return ROOT(pVfs)->xRandomness(ROOT(pVfs), nByte, zOut);
+}
+static int cXSleep(sqlite3_vfs *pVfs, int microseconds) {
+	return ROOT(pVfs)->xSleep(ROOT(pVfs), microseconds);
+}
+static int cXCurrentTime(sqlite3_vfs *pVfs, double *pTime) {
+	return ROOT(pVfs)->xCurrentTime(ROOT(pVfs), pTime);
+}
+static int cXGetLastError(sqlite3_vfs *pVfs, int n, char *zErr) {
+	return ROOT(pVfs)->xGetLastError(ROOT(pVfs), n, zErr);
+}
+static int cXCurrentTimeInt64(sqlite3_vfs *pVfs, sqlite3_int64 *pTime) {
+	if (ROOT(pVfs)->iVersion >= 2 && ROOT(pVfs)->xCurrentTimeInt64) {
+		return ROOT(pVfs)->xCurrentTimeInt64(ROOT(pVfs), pTime);
+	} else {
+		double r;
+		int rc = ROOT(pVfs)->xCurrentTime(ROOT(pVfs), &r);
+		*pTime = (sqlite3_int64)(r * 86400000.0);
+		return rc;
+	}
+}
+
+sqlite3_vfs *goSqlite3RegisterVfs(const char *zName, int id, int makeDflt, int *pRc) {
+	goVfs *pVfs;
+	sqlite3_vfs *pRoot = sqlite3_vfs_find(0);
+	if (pRoot == 0) {
+		*pRc = SQLITE_ERROR;
+		return 0;
+	}
+	pVfs = calloc(1, sizeof(goVfs));
+	if (pVfs == 0) {
+		*pRc = SQLITE_NOMEM;
+		return 0;
+	}
+	pVfs->base.iVersion = 2;
+	pVfs->base.szOsFile = sizeof(goVfsFile);
+	pVfs->base.mxPathname = pRoot->mxPathname;
+	pVfs->base.zName = strdup(zName);
+	pVfs->base.xOpen = cXOpen;
+	pVfs->base.xDelete = cXDelete;
+	pVfs->base.xAccess = cXAccess;
+	pVfs->base.xFullPathname = cXFullPathname;
+	pVfs->base.xDlOpen = cXDlOpen;
+	pVfs->base.xDlError = cXDlError;
+	pVfs->base.xDlSym = cXDlSym;
+	pVfs->base.xDlClose = cXDlClose;
+	pVfs->base.xRandomness = cXRandomness;
+	pVfs->base.xSleep = cXSleep;
+	pVfs->base.xCurrentTime = cXCurrentTime;
+	pVfs->base.xGetLastError = cXGetLastError;
+	pVfs->base.xCurrentTimeInt64 = cXCurrentTimeInt64;
+	pVfs->pRoot = pRoot;
+	pVfs->id = id;
+	*pRc = sqlite3_vfs_register(&pVfs->base, makeDflt);
+	if (*pRc != SQLITE_OK) {
+		free((void *)pVfs->base.zName);
+		free(pVfs);
+		return 0;
+	}
+	return &pVfs->base;
+}
+
+int goSqlite3UnregisterVfs(sqlite3_vfs *pVfs) {
+	int rc = sqlite3_vfs_unregister(pVfs);
+	if (rc == SQLITE_OK) {
+		free((void *)pVfs->zName);
+		free(pVfs);
+	}
+	return rc;
+}
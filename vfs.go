@@ -0,0 +1,393 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+#include <stdlib.h>
+
+sqlite3_vfs *goSqlite3RegisterVfs(const char *zName, int id, int makeDflt, int *pRc);
+int goSqlite3UnregisterVfs(sqlite3_vfs *pVfs);
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"unsafe"
+)
+
+// Flags passed to VFS.Open
+const (
+	OpenDeleteOnClose OpenFlag = C.SQLITE_OPEN_DELETEONCLOSE
+	OpenExclusive     OpenFlag = C.SQLITE_OPEN_EXCLUSIVE
+	OpenMainDB        OpenFlag = C.SQLITE_OPEN_MAIN_DB
+	OpenTempDB        OpenFlag = C.SQLITE_OPEN_TEMP_DB
+	OpenTransientDB   OpenFlag = C.SQLITE_OPEN_TRANSIENT_DB
+	OpenMainJournal   OpenFlag = C.SQLITE_OPEN_MAIN_JOURNAL
+	OpenTempJournal   OpenFlag = C.SQLITE_OPEN_TEMP_JOURNAL
+	OpenSubJournal    OpenFlag = C.SQLITE_OPEN_SUBJOURNAL
+	OpenMasterJournal OpenFlag = C.SQLITE_OPEN_MASTER_JOURNAL
+	OpenWAL           OpenFlag = C.SQLITE_OPEN_WAL
+)
+
+// AccessFlag enumerates the checks performed by VFS.Access
+// (See http://sqlite.org/c3ref/c_access_exists.html)
+type AccessFlag int32
+
+// Flags for VFS.Access
+const (
+	AccessExists    AccessFlag = C.SQLITE_ACCESS_EXISTS
+	AccessReadWrite AccessFlag = C.SQLITE_ACCESS_READWRITE
+	AccessRead      AccessFlag = C.SQLITE_ACCESS_READ
+)
+
+// LockLevel enumerates file locking levels
+// (See http://sqlite.org/c3ref/c_lock_exclusive.html)
+type LockLevel int32
+
+// File locking levels
+const (
+	LockNone      LockLevel = C.SQLITE_LOCK_NONE
+	LockShared    LockLevel = C.SQLITE_LOCK_SHARED
+	LockReserved  LockLevel = C.SQLITE_LOCK_RESERVED
+	LockPending   LockLevel = C.SQLITE_LOCK_PENDING
+	LockExclusive LockLevel = C.SQLITE_LOCK_EXCLUSIVE
+)
+
+// SyncFlag enumerates flags passed to VFSFile.Sync
+// (See http://sqlite.org/c3ref/c_sync_dataonly.html)
+type SyncFlag int32
+
+// Flags for VFSFile.Sync
+const (
+	SyncNormal   SyncFlag = C.SQLITE_SYNC_NORMAL
+	SyncFull     SyncFlag = C.SQLITE_SYNC_FULL
+	SyncDataOnly SyncFlag = C.SQLITE_SYNC_DATAONLY
+)
+
+// DeviceCharacteristic enumerates I/O capabilities
+// (See http://sqlite.org/c3ref/c_iocap_atomic.html)
+type DeviceCharacteristic int32
+
+// I/O capabilities
+const (
+	IocapAtomic              DeviceCharacteristic = C.SQLITE_IOCAP_ATOMIC
+	IocapSafeAppend          DeviceCharacteristic = C.SQLITE_IOCAP_SAFE_APPEND
+	IocapSequential          DeviceCharacteristic = C.SQLITE_IOCAP_SEQUENTIAL
+	IocapUndeletableWhenOpen DeviceCharacteristic = C.SQLITE_IOCAP_UNDELETABLE_WHEN_OPEN
+	IocapPowersafeOverwrite  DeviceCharacteristic = C.SQLITE_IOCAP_POWERSAFE_OVERWRITE
+	IocapImmutable           DeviceCharacteristic = C.SQLITE_IOCAP_IMMUTABLE
+)
+
+// VFS is the Go interface of an OS interface object (a virtual file system).
+// Randomness, sleep, time and dynamic loading are delegated to the default VFS.
+// Methods may return an Errno to specify the SQLite error code.
+// (See http://sqlite.org/c3ref/vfs.html)
+type VFS interface {
+	// Open opens a file (name is empty for temporary files which must be deleted when closed).
+	// Returns the file and the flags actually used (OpenReadOnly when a read/write open fails for example).
+	Open(name string, flags OpenFlag) (VFSFile, OpenFlag, error)
+	Delete(name string, syncDir bool) error
+	Access(name string, flag AccessFlag) (bool, error)
+	FullPathname(name string) (string, error)
+}
+
+// VFSFile is the Go interface of an open file.
+// ReadAt must return io.EOF (or n < len(p)) when the read goes past the end of the file.
+// Methods may return an Errno to specify the SQLite error code (ErrBusy for locks).
+// Shared memory is not supported: WAL journal mode requires the exclusive locking mode.
+// (See http://sqlite.org/c3ref/io_methods.html)
+type VFSFile interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+	Truncate(size int64) error
+	Sync(flags SyncFlag) error
+	FileSize() (int64, error)
+	Lock(level LockLevel) error
+	Unlock(level LockLevel) error
+	CheckReservedLock() (bool, error)
+	SectorSize() int
+	DeviceCharacteristics() DeviceCharacteristic
+}
+
+// VFS and files are stored in C memory so they are referenced by handles.
+var vfsRegistry = struct {
+	sync.Mutex
+	vfs   map[C.int]VFS
+	files map[C.int]VFSFile
+	names map[string]*C.sqlite3_vfs
+	ids   map[string]C.int
+	next  C.int
+}{
+	vfs:   make(map[C.int]VFS),
+	files: make(map[C.int]VFSFile),
+	names: make(map[string]*C.sqlite3_vfs),
+	ids:   make(map[string]C.int),
+}
+
+// RegisterVFS registers a Go VFS with the specified name
+// (to be used with OpenVfs or the vfs URI parameter).
+// A VFS previously registered with the same name is replaced.
+// (See http://sqlite.org/c3ref/vfs_find.html)
+func RegisterVFS(name string, vfs VFS, makeDefault bool) error {
+	if vfs == nil {
+		return errors.New("nil VFS")
+	}
+	if err := UnregisterVFS(name); err != nil {
+		return err
+	}
+	vfsRegistry.Lock()
+	defer vfsRegistry.Unlock()
+	vfsRegistry.next++
+	id := vfsRegistry.next
+	vfsRegistry.vfs[id] = vfs
+	zName := C.CString(name)
+	defer C.free(unsafe.Pointer(zName))
+	var rv C.int
+	p := C.goSqlite3RegisterVfs(zName, id, btocint(makeDefault), &rv)
+	if p == nil {
+		delete(vfsRegistry.vfs, id)
+		return fmt.Errorf("cannot register VFS %q: %s", name, Errno(rv))
+	}
+	vfsRegistry.names[name] = p
+	vfsRegistry.ids[name] = id
+	return nil
+}
+
+// UnregisterVFS unregisters a Go VFS registered with RegisterVFS.
+// The VFS must not be used anymore (by any connection).
+func UnregisterVFS(name string) error {
+	vfsRegistry.Lock()
+	defer vfsRegistry.Unlock()
+	p, ok := vfsRegistry.names[name]
+	if !ok {
+		return nil
+	}
+	if rv := C.goSqlite3UnregisterVfs(p); rv != C.SQLITE_OK {
+		return fmt.Errorf("cannot unregister VFS %q: %s", name, Errno(rv))
+	}
+	delete(vfsRegistry.vfs, vfsRegistry.ids[name])
+	delete(vfsRegistry.names, name)
+	delete(vfsRegistry.ids, name)
+	return nil
+}
+
+func lookupVFS(id C.int) VFS {
+	vfsRegistry.Lock()
+	defer vfsRegistry.Unlock()
+	return vfsRegistry.vfs[id]
+}
+
+func lookupVFSFile(id C.int) VFSFile {
+	vfsRegistry.Lock()
+	defer vfsRegistry.Unlock()
+	return vfsRegistry.files[id]
+}
+
+// vfsErrorCode converts a Go error to an SQLite error code.
+func vfsErrorCode(err error, code C.int) C.int {
+	if err == nil {
+		return C.SQLITE_OK
+	}
+	if errno, ok := err.(Errno); ok {
+		return C.int(errno)
+	}
+	return code
+}
+
+//export goVfsOpen
+func goVfsOpen(vfsID C.int, zName *C.char, pFileID *C.int, flags C.int, pOutFlags *C.int) C.int {
+	vfs := lookupVFS(vfsID)
+	if vfs == nil {
+		return C.SQLITE_CANTOPEN
+	}
+	var name string
+	if zName != nil {
+		name = C.GoString(zName)
+	}
+	f, outFlags, err := vfs.Open(name, OpenFlag(flags))
+	if err != nil {
+		return vfsErrorCode(err, C.SQLITE_CANTOPEN)
+	}
+	vfsRegistry.Lock()
+	vfsRegistry.next++
+	id := vfsRegistry.next
+	vfsRegistry.files[id] = f
+	vfsRegistry.Unlock()
+	*pFileID = id
+	*pOutFlags = C.int(outFlags)
+	return C.SQLITE_OK
+}
+
+//export goVfsDelete
+func goVfsDelete(vfsID C.int, zName *C.char, syncDir C.int) C.int {
+	vfs := lookupVFS(vfsID)
+	if vfs == nil {
+		return C.SQLITE_IOERR_DELETE
+	}
+	return vfsErrorCode(vfs.Delete(C.GoString(zName), syncDir != 0), C.SQLITE_IOERR_DELETE)
+}
+
+//export goVfsAccess
+func goVfsAccess(vfsID C.int, zName *C.char, flags C.int, pResOut *C.int) C.int {
+	vfs := lookupVFS(vfsID)
+	if vfs == nil {
+		return C.SQLITE_IOERR_ACCESS
+	}
+	ok, err := vfs.Access(C.GoString(zName), AccessFlag(flags))
+	if err != nil {
+		return vfsErrorCode(err, C.SQLITE_IOERR_ACCESS)
+	}
+	*pResOut = btocint(ok)
+	return C.SQLITE_OK
+}
+
+//export goVfsFullPathname
+func goVfsFullPathname(vfsID C.int, zName *C.char, nOut C.int, zOut *C.char) C.int {
+	vfs := lookupVFS(vfsID)
+	if vfs == nil {
+		return C.SQLITE_CANTOPEN
+	}
+	path, err := vfs.FullPathname(C.GoString(zName))
+	if err != nil {
+		return vfsErrorCode(err, C.SQLITE_CANTOPEN)
+	}
+	if len(path) >= int(nOut) {
+		return C.SQLITE_CANTOPEN
+	}
+	out := (*[1 << 30]byte)(unsafe.Pointer(zOut))[:nOut:nOut]
+	copy(out, path)
+	out[len(path)] = 0
+	return C.SQLITE_OK
+}
+
+//export goVfsClose
+func goVfsClose(fileID C.int) C.int {
+	vfsRegistry.Lock()
+	f := vfsRegistry.files[fileID]
+	delete(vfsRegistry.files, fileID)
+	vfsRegistry.Unlock()
+	if f == nil {
+		return C.SQLITE_OK
+	}
+	return vfsErrorCode(f.Close(), C.SQLITE_IOERR_CLOSE)
+}
+
+//export goVfsRead
+func goVfsRead(fileID C.int, p unsafe.Pointer, iAmt C.int, iOfst C.sqlite3_int64) C.int {
+	f := lookupVFSFile(fileID)
+	if f == nil {
+		return C.SQLITE_IOERR_READ
+	}
+	buf := (*[1 << 30]byte)(p)[:iAmt:iAmt]
+	n, err := f.ReadAt(buf, int64(iOfst))
+	if n == len(buf) {
+		return C.SQLITE_OK
+	}
+	if err != nil && err != io.EOF {
+		return vfsErrorCode(err, C.SQLITE_IOERR_READ)
+	}
+	// unread part must be zero-filled
+	for i := n; i < len(buf); i++ {
+		buf[i] = 0
+	}
+	return C.SQLITE_IOERR_SHORT_READ
+}
+
+//export goVfsWrite
+func goVfsWrite(fileID C.int, p unsafe.Pointer, iAmt C.int, iOfst C.sqlite3_int64) C.int {
+	f := lookupVFSFile(fileID)
+	if f == nil {
+		return C.SQLITE_IOERR_WRITE
+	}
+	_, err := f.WriteAt((*[1 << 30]byte)(p)[:iAmt:iAmt], int64(iOfst))
+	return vfsErrorCode(err, C.SQLITE_IOERR_WRITE)
+}
+
+//export goVfsTruncate
+func goVfsTruncate(fileID C.int, size C.sqlite3_int64) C.int {
+	f := lookupVFSFile(fileID)
+	if f == nil {
+		return C.SQLITE_IOERR_TRUNCATE
+	}
+	return vfsErrorCode(f.Truncate(int64(size)), C.SQLITE_IOERR_TRUNCATE)
+}
+
+//export goVfsSync
+func goVfsSync(fileID C.int, flags C.int) C.int {
+	f := lookupVFSFile(fileID)
+	if f == nil {
+		return C.SQLITE_IOERR_FSYNC
+	}
+	return vfsErrorCode(f.Sync(SyncFlag(flags)), C.SQLITE_IOERR_FSYNC)
+}
+
+//export goVfsFileSize
+func goVfsFileSize(fileID C.int, pSize *C.sqlite3_int64) C.int {
+	f := lookupVFSFile(fileID)
+	if f == nil {
+		return C.SQLITE_IOERR_FSTAT
+	}
+	size, err := f.FileSize()
+	if err != nil {
+		return vfsErrorCode(err, C.SQLITE_IOERR_FSTAT)
+	}
+	*pSize = C.sqlite3_int64(size)
+	return C.SQLITE_OK
+}
+
+//export goVfsLock
+func goVfsLock(fileID C.int, eLock C.int) C.int {
+	f := lookupVFSFile(fileID)
+	if f == nil {
+		return C.SQLITE_IOERR_LOCK
+	}
+	return vfsErrorCode(f.Lock(LockLevel(eLock)), C.SQLITE_IOERR_LOCK)
+}
+
+//export goVfsUnlock
+func goVfsUnlock(fileID C.int, eLock C.int) C.int {
+	f := lookupVFSFile(fileID)
+	if f == nil {
+		return C.SQLITE_IOERR_UNLOCK
+	}
+	return vfsErrorCode(f.Unlock(LockLevel(eLock)), C.SQLITE_IOERR_UNLOCK)
+}
+
+//export goVfsCheckReservedLock
+func goVfsCheckReservedLock(fileID C.int, pResOut *C.int) C.int {
+	f := lookupVFSFile(fileID)
+	if f == nil {
+		return C.SQLITE_IOERR_CHECKRESERVEDLOCK
+	}
+	ok, err := f.CheckReservedLock()
+	if err != nil {
+		return vfsErrorCode(err, C.SQLITE_IOERR_CHECKRESERVEDLOCK)
+	}
+	*pResOut = btocint(ok)
+	return C.SQLITE_OK
+}
+
+//export goVfsSectorSize
+func goVfsSectorSize(fileID C.int) C.int {
+	f := lookupVFSFile(fileID)
+	if f == nil {
+		return 0
+	}
+	return C.int(f.SectorSize())
+}
+
+//export goVfsDeviceCharacteristics
+func goVfsDeviceCharacteristics(fileID C.int) C.int {
+	f := lookupVFSFile(fileID)
+	if f == nil {
+		return 0
+	}
+	return C.int(f.DeviceCharacteristics())
+}
@@ -38,8 +38,14 @@ static inline int cXConnect(sqlite3 *db, void *pAux, int argc, const char *const
 	return cXInit(db, pAux, argc, argv, ppVTab, pzErr, 0);
 }
 
-static inline int cXBestIndex(sqlite3_vtab *pVTab, sqlite3_index_info *info) {
-	// TODO
+static int cXBestIndex(sqlite3_vtab *pVTab, sqlite3_index_info *info) {
+	char *pzErr = goVBestIndex(((goVTab*)pVTab)->vTab, info);
+	if (pzErr) {
+		if (pVTab->zErrMsg)
+			sqlite3_free(pVTab->zErrMsg);
+		pVTab->zErrMsg = pzErr;
+		return SQLITE_ERROR;
+	}
 	return SQLITE_OK;
 }
 
@@ -72,7 +78,13 @@ struct goVTabCursor {
 };
 
 static int cXOpen(sqlite3_vtab *pVTab, sqlite3_vtab_cursor **ppCursor) {
-	void *vTabCursor = goVOpen(((goVTab*)pVTab)->vTab, &(pVTab->zErrMsg));
+	char *pzErr = 0;
+	void *vTabCursor = goVOpen(((goVTab*)pVTab)->vTab, &pzErr);
+	if (!vTabCursor) {
+		sqlite3_free(pVTab->zErrMsg);
+		pVTab->zErrMsg = pzErr;
+		return SQLITE_ERROR;
+	}
 	goVTabCursor *pCursor = (goVTabCursor *)sqlite3_malloc(sizeof(goVTabCursor));
 	if (!pCursor) {
 		return SQLITE_NOMEM;
@@ -99,8 +111,7 @@ static int cXClose(sqlite3_vtab_cursor *pCursor) {
 	return SQLITE_OK;
 }
 static int cXFilter(sqlite3_vtab_cursor *pCursor, int idxNum, const char *idxStr, int argc, sqlite3_value **argv) {
-	// TODO idxNum, idxStr, argc, argv are useless when cXBestIndex is empty
-	char *pzErr = goVFilter(((goVTabCursor*)pCursor)->vTabCursor);
+	char *pzErr = goVFilter(((goVTabCursor*)pCursor)->vTabCursor, idxNum, (char*)idxStr, argc, argv);
 	if (pzErr) {
 		return setErrMsg(pCursor, pzErr);
 	}
@@ -162,3 +173,7 @@ static sqlite3_module goModule = {
 int goSqlite3CreateModule(sqlite3 *db, const char *zName, void *pClientData) {
 	return sqlite3_create_module_v2(db, zName, &goModule, pClientData, goMDestroy);
 }
+
+int goSqlite3DropModule(sqlite3 *db, const char *zName) {
+	return sqlite3_create_module_v2(db, zName, 0, 0, 0);
+}
@@ -9,12 +9,95 @@ package sqlite
 #include <stdlib.h>
 
 int goSqlite3CreateModule(sqlite3 *db, const char *zName, void *pClientData);
+int goSqlite3DropModule(sqlite3 *db, const char *zName);
+
+// Missing in old sqlite3.h (the constraints are then never reported)
+#ifndef SQLITE_INDEX_CONSTRAINT_LIKE
+#define SQLITE_INDEX_CONSTRAINT_LIKE 65
+#define SQLITE_INDEX_CONSTRAINT_GLOB 66
+#define SQLITE_INDEX_CONSTRAINT_REGEXP 67
+#endif
+#ifndef SQLITE_INDEX_CONSTRAINT_NE
+#define SQLITE_INDEX_CONSTRAINT_NE 68
+#define SQLITE_INDEX_CONSTRAINT_ISNOT 69
+#define SQLITE_INDEX_CONSTRAINT_ISNOTNULL 70
+#define SQLITE_INDEX_CONSTRAINT_ISNULL 71
+#define SQLITE_INDEX_CONSTRAINT_IS 72
+#endif
+#ifndef SQLITE_INDEX_CONSTRAINT_LIMIT
+#define SQLITE_INDEX_CONSTRAINT_LIMIT 73
+#define SQLITE_INDEX_CONSTRAINT_OFFSET 74
+#endif
+
+static inline sqlite3_uint64 my_index_col_used(sqlite3_index_info *info) {
+#if SQLITE_VERSION_NUMBER < 3010000
+	return ~(sqlite3_uint64)0;
+#else
+	return info->colUsed;
+#endif
+}
+static inline sqlite3_int64 my_index_estimated_rows(sqlite3_index_info *info) {
+#if SQLITE_VERSION_NUMBER < 3008002
+	return 0;
+#else
+	return info->estimatedRows;
+#endif
+}
+static inline void my_index_set_estimated_rows(sqlite3_index_info *info, sqlite3_int64 n) {
+#if SQLITE_VERSION_NUMBER >= 3008002
+	info->estimatedRows = n;
+#endif
+}
+static inline void my_index_set_unique(sqlite3_index_info *info) {
+#if SQLITE_VERSION_NUMBER >= 3009000
+	info->idxFlags |= SQLITE_INDEX_SCAN_UNIQUE;
+#endif
+}
+
+static inline int my_vtab_rhs_value(sqlite3_index_info *info, int i, sqlite3_value **ppVal) {
+#if SQLITE_VERSION_NUMBER < 3038000
+	return SQLITE_NOTFOUND;
+#else
+	return sqlite3_vtab_rhs_value(info, i, ppVal);
+#endif
+}
+static inline const char *my_vtab_collation(sqlite3_index_info *info, int i) {
+#if SQLITE_VERSION_NUMBER < 3022000
+	return 0;
+#else
+	return sqlite3_vtab_collation(info, i);
+#endif
+}
+static inline int my_vtab_in(sqlite3_index_info *info, int i, int bHandle) {
+#if SQLITE_VERSION_NUMBER < 3038000
+	return 0;
+#else
+	return sqlite3_vtab_in(info, i, bHandle);
+#endif
+}
+static inline int my_vtab_in_first(sqlite3_value *pVal, sqlite3_value **ppOut) {
+#if SQLITE_VERSION_NUMBER < 3038000
+	return SQLITE_ERROR;
+#else
+	return sqlite3_vtab_in_first(pVal, ppOut);
+#endif
+}
+static inline int my_vtab_in_next(sqlite3_value *pVal, sqlite3_value **ppOut) {
+#if SQLITE_VERSION_NUMBER < 3038000
+	return SQLITE_ERROR;
+#else
+	return sqlite3_vtab_in_next(pVal, ppOut);
+#endif
+}
 */
 import "C"
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"unsafe"
 )
 
@@ -59,7 +142,7 @@ func goMInit(db, pClientData unsafe.Pointer, argc int, argv **C.char, pzErr **C.
 	}
 
 	if err != nil {
-		*pzErr = mPrintf("%s", err.Error())
+		*pzErr = m.c.vtabErrMsg(err)
 		return nil
 	}
 	vt := &sqliteVTab{m, vTab, nil}
@@ -82,7 +165,7 @@ func goVRelease(pVTab unsafe.Pointer, isDestroy int) *C.char {
 		err = vt.vTab.Disconnect()
 	}
 	if err != nil {
-		return mPrintf("%s", err.Error())
+		return vt.module.c.vtabErrMsg(err)
 	}
 	// TODO Check vt.vtcs is empty
 	vt.vtcs = nil
@@ -95,7 +178,7 @@ func goVOpen(pVTab unsafe.Pointer, pzErr **C.char) unsafe.Pointer {
 	vt := (*sqliteVTab)(pVTab)
 	vTabCursor, err := vt.vTab.Open()
 	if err != nil {
-		*pzErr = mPrintf("%s", err.Error())
+		*pzErr = vt.module.c.vtabErrMsg(err)
 		return nil
 	}
 	// prevents 'vt' from being gced
@@ -113,7 +196,7 @@ func goVClose(pCursor unsafe.Pointer) *C.char {
 	vtc := (*sqliteVTabCursor)(pCursor)
 	err := vtc.vTabCursor.Close()
 	if err != nil {
-		return mPrintf("%s", err.Error())
+		return vtc.vTab.module.c.vtabErrMsg(err)
 	}
 	delete(vtc.vTab.vtcs, vtc)
 	return nil
@@ -125,25 +208,76 @@ func goMDestroy(pClientData unsafe.Pointer) {
 	m.module.DestroyModule()
 	// TODO Check m.vts is empty
 	m.vts = nil
-	delete(m.c.modules, m.name)
+	delete(m.c.modules, m)
+}
+
+//export goVBestIndex
+func goVBestIndex(pVTab, pInfo unsafe.Pointer) *C.char {
+	vt := (*sqliteVTab)(pVTab)
+	iv, ok := vt.vTab.(IndexedVTab)
+	if !ok {
+		return nil
+	}
+	info := newIndexInfo((*C.sqlite3_index_info)(pInfo))
+	if err := iv.BestIndexInfo(info); err != nil {
+		return vt.module.c.vtabErrMsg(err)
+	}
+	info.apply()
+	return nil
 }
 
 //export goVFilter
-func goVFilter(pCursor unsafe.Pointer) *C.char {
+func goVFilter(pCursor unsafe.Pointer, idxNum int, idxStr *C.char, argc int, argv unsafe.Pointer) *C.char {
 	vtc := (*sqliteVTabCursor)(pCursor)
-	err := vtc.vTabCursor.Filter()
+	var err error
+	if ic, ok := vtc.vTabCursor.(IndexedVTabCursor); ok {
+		args := make([]interface{}, argc)
+		if argc > 0 {
+			values := (*[1 << 16]*C.sqlite3_value)(argv)[:argc:argc]
+			for i := range values {
+				if args[i], err = filterArg(&values[i]); err != nil {
+					return vtc.vTab.module.c.vtabErrMsg(err)
+				}
+			}
+		}
+		err = ic.FilterIndex(idxNum, C.GoString(idxStr), args)
+	} else {
+		err = vtc.vTabCursor.Filter()
+	}
 	if err != nil {
-		return mPrintf("%s", err.Error())
+		return vtc.vTab.module.c.vtabErrMsg(err)
 	}
 	return nil
 }
 
+// filterArg converts an argument of xFilter.
+// The right-hand operand of an IN constraint processed all at once (see IndexInfo.HandleIn)
+// is converted to a []interface{}.
+func filterArg(pVal **C.sqlite3_value) (interface{}, error) {
+	var v *C.sqlite3_value
+	rv := C.my_vtab_in_first(*pVal, &v)
+	if rv != C.SQLITE_OK && rv != C.SQLITE_DONE {
+		fc := FunctionContext{argv: pVal}
+		return fc.Value(0), nil
+	}
+	values := []interface{}{}
+	fc := FunctionContext{argv: &v}
+	for rv == C.SQLITE_OK {
+		values = append(values, fc.Value(0))
+		rv = C.my_vtab_in_next(*pVal, &v)
+	}
+	if rv != C.SQLITE_DONE {
+		return nil, Errno(rv)
+	}
+	return values, nil
+}
+
 //export goVNext
 func goVNext(pCursor unsafe.Pointer) *C.char {
 	vtc := (*sqliteVTabCursor)(pCursor)
 	err := vtc.vTabCursor.Next()
 	if err != nil {
-		return mPrintf("%s", err.Error())
+		return vtc.vTab.module.c.vtabErrMsg(err)
 	}
 	return nil
 }
@@ -160,7 +294,7 @@ func goVColumn(pCursor, cp unsafe.Pointer, col int) *C.char {
 	c := (*Context)(cp)
 	err := vtc.vTabCursor.Column(c, col)
 	if err != nil {
-		return mPrintf("%s", err.Error())
+		return vtc.vTab.module.c.vtabErrMsg(err)
 	}
 	return nil
 }
@@ -170,7 +304,7 @@ func goVRowid(pCursor unsafe.Pointer, pRowid *C.sqlite3_int64) *C.char {
 	vtc := (*sqliteVTabCursor)(pCursor)
 	rowid, err := vtc.vTabCursor.Rowid()
 	if err != nil {
-		return mPrintf("%s", err.Error())
+		return vtc.vTab.module.c.vtabErrMsg(err)
 	}
 	*pRowid = C.sqlite3_int64(rowid)
 	return nil
@@ -224,6 +358,185 @@ type VTabCursor interface {
 	Rowid() (int64, error)            // See http://sqlite.org/vtab.html#xrowid
 }
 
+// IndexedVTab is implemented by virtual tables which want to use the WHERE clause constraints,
+// the ORDER BY clause or the LIMIT/OFFSET clause of a query (BestIndex is not called).
+// The plan chosen is given to the IndexedVTabCursor.FilterIndex method.
+// (See http://sqlite.org/vtab.html#xbestindex)
+type IndexedVTab interface {
+	VTab
+	BestIndexInfo(info *IndexInfo) error
+}
+
+// IndexedVTabCursor is implemented by the cursors of IndexedVTab (Filter is not called).
+// args contains the right-hand operands of the constraints whose ArgvIndex has been set by BestIndexInfo
+// (a []interface{} for each IN operator processed all at once, see IndexInfo.HandleIn).
+// (See http://sqlite.org/vtab.html#xfilter)
+type IndexedVTabCursor interface {
+	VTabCursor
+	FilterIndex(idxNum int, idxStr string, args []interface{}) error
+}
+
+// IndexConstraintOp is the operator of a constraint (see IndexConstraint).
+// (See http://sqlite.org/c3ref/c_index_constraint_eq.html)
+type IndexConstraintOp uint8
+
+// Virtual table constraint operators
+const (
+	IndexConstraintEQ        IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_EQ
+	IndexConstraintGT        IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_GT
+	IndexConstraintLE        IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_LE
+	IndexConstraintLT        IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_LT
+	IndexConstraintGE        IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_GE
+	IndexConstraintMatch     IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_MATCH
+	IndexConstraintLike      IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_LIKE
+	IndexConstraintGlob      IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_GLOB
+	IndexConstraintRegexp    IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_REGEXP
+	IndexConstraintNE        IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_NE
+	IndexConstraintIsNot     IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_ISNOT
+	IndexConstraintIsNotNull IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_ISNOTNULL
+	IndexConstraintIsNull    IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_ISNULL
+	IndexConstraintIs        IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_IS
+	// LIMIT and OFFSET constraints have no left-hand operand (Column is meaningless).
+	// They can only be used when there is no ORDER BY term or when the order is consumed (OrderByConsumed)
+	// and when all the other constraints are consumed (and omitted): SQLite may offer them even when
+	// some WHERE terms are left to SQLite, which must then see all the candidate rows.
+	// SQLite still applies the LIMIT but the OFFSET is only skipped by SQLite when the constraint is not omitted.
+	IndexConstraintLimit  IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_LIMIT
+	IndexConstraintOffset IndexConstraintOp = C.SQLITE_INDEX_CONSTRAINT_OFFSET
+)
+
+// IndexConstraint is a WHERE clause (or LIMIT/OFFSET) constraint of the form "column OP expr".
+type IndexConstraint struct {
+	Column int // constrained column (-1 for the rowid)
+	Op     IndexConstraintOp
+	Usable bool // whether the constraint can be used by the current plan
+	// ArgvIndex is set by BestIndexInfo to receive the right-hand operand in FilterIndex args[ArgvIndex-1].
+	ArgvIndex int
+	// Omit is set by BestIndexInfo when SQLite does not need to double check the constraint.
+	Omit bool
+}
+
+// IndexOrderBy is an ORDER BY term.
+type IndexOrderBy struct {
+	Column int
+	Desc   bool
+}
+
+// IndexInfo is used to pass information into and receive the reply from the IndexedVTab.BestIndexInfo method.
+// It must not be retained after BestIndexInfo returns.
+// (See http://sqlite.org/c3ref/index_info.html)
+type IndexInfo struct {
+	info *C.sqlite3_index_info
+	// Inputs
+	Constraints []IndexConstraint
+	OrderBy     []IndexOrderBy
+	ColUsed     uint64 // mask of columns used by the statement (all bits set if SQLite < 3.10.0)
+	// Outputs
+	IdxNum          int    // number used to identify the index (passed to FilterIndex)
+	IdxStr          string // string used to identify the index (passed to FilterIndex)
+	OrderByConsumed bool   // true if output is already ordered
+	EstimatedCost   float64
+	EstimatedRows   int64 // ignored if SQLite < 3.8.2
+	Unique          bool  // true if the plan visits at most one row (ignored if SQLite < 3.9.0)
+}
+
+func newIndexInfo(info *C.sqlite3_index_info) *IndexInfo {
+	ii := &IndexInfo{info: info, ColUsed: uint64(C.my_index_col_used(info)),
+		EstimatedCost: float64(info.estimatedCost), EstimatedRows: int64(C.my_index_estimated_rows(info))}
+	if n := int(info.nConstraint); n > 0 {
+		ii.Constraints = make([]IndexConstraint, n)
+		for i, c := range (*[1 << 16]C.struct_sqlite3_index_constraint)(unsafe.Pointer(info.aConstraint))[:n:n] {
+			ii.Constraints[i] = IndexConstraint{Column: int(c.iColumn), Op: IndexConstraintOp(c.op), Usable: c.usable != 0}
+		}
+	}
+	if n := int(info.nOrderBy); n > 0 {
+		ii.OrderBy = make([]IndexOrderBy, n)
+		for i, o := range (*[1 << 16]C.struct_sqlite3_index_orderby)(unsafe.Pointer(info.aOrderBy))[:n:n] {
+			ii.OrderBy[i] = IndexOrderBy{Column: int(o.iColumn), Desc: o.desc != 0}
+		}
+	}
+	return ii
+}
+
+// apply copies the outputs back to SQLite.
+func (ii *IndexInfo) apply() {
+	info := ii.info
+	if n := len(ii.Constraints); n > 0 {
+		usages := (*[1 << 16]C.struct_sqlite3_index_constraint_usage)(unsafe.Pointer(info.aConstraintUsage))[:n:n]
+		for i, c := range ii.Constraints {
+			usages[i].argvIndex = C.int(c.ArgvIndex)
+			usages[i].omit = C.uchar(btocint(c.Omit))
+		}
+	}
+	info.idxNum = C.int(ii.IdxNum)
+	if len(ii.IdxStr) > 0 {
+		info.idxStr = mPrintf("%s", ii.IdxStr)
+		info.needToFreeIdxStr = 1
+	}
+	info.orderByConsumed = btocint(ii.OrderByConsumed)
+	info.estimatedCost = C.double(ii.EstimatedCost)
+	C.my_index_set_estimated_rows(info, C.sqlite3_int64(ii.EstimatedRows))
+	if ii.Unique {
+		C.my_index_set_unique(info)
+	}
+}
+
+// Collation returns the name of the collating sequence to be used when evaluating the i-th constraint
+// (an empty string if SQLite < 3.22.0).
+// (See http://sqlite.org/c3ref/vtab_collation.html)
+func (ii *IndexInfo) Collation(i int) string {
+	return C.GoString(C.my_vtab_collation(ii.info, C.int(i)))
+}
+
+// IsIn tells if the i-th constraint is an IN operator which can be processed all at once (see HandleIn).
+// (See http://sqlite.org/c3ref/vtab_in.html)
+func (ii *IndexInfo) IsIn(i int) bool {
+	return C.my_vtab_in(ii.info, C.int(i), -1) != 0
+}
+
+// HandleIn asks SQLite to give all the values of the right-hand operand of the i-th constraint,
+// an IN operator, in a single FilterIndex call (as a []interface{} arg) instead of one call per value.
+// The constraint ArgvIndex must be set too.
+// It returns false if the constraint cannot be processed all at once (or if SQLite < 3.38.0).
+// (See http://sqlite.org/c3ref/vtab_in.html)
+func (ii *IndexInfo) HandleIn(i int) bool {
+	return C.my_vtab_in(ii.info, C.int(i), 1) != 0
+}
+
+// RHSValue returns the right-hand operand of the i-th constraint when it is known while planning
+// (usually only when it is a literal constant), so that the plan can be computed from it.
+// The value is still given to FilterIndex (when ArgvIndex is set).
+// (See http://sqlite.org/c3ref/vtab_rhs_value.html)
+func (ii *IndexInfo) RHSValue(i int) (interface{}, bool) {
+	var v *C.sqlite3_value
+	if C.my_vtab_rhs_value(ii.info, C.int(i), &v) != C.SQLITE_OK {
+		return nil, false
+	}
+	fc := FunctionContext{argv: &v}
+	return fc.Value(0), true
+}
+
+// vtabErrMsg keeps err so that it can be returned (wrapped) to the caller of the failed statement
+// and converts it to the message reported to SQLite.
+func (c *Conn) vtabErrMsg(err error) *C.char {
+	c.vtabErr = err
+	return mPrintf("%s", err.Error())
+}
+
+// vtabError returns (and forgets) the last error returned by a virtual table method
+// when msg is the error message reported by SQLite for it.
+func (c *Conn) vtabError(msg string) error {
+	err := c.vtabErr
+	if err == nil {
+		return nil
+	}
+	c.vtabErr = nil
+	if !strings.Contains(msg, err.Error()) {
+		return nil
+	}
+	return err
+}
+
 // DeclareVTab declares the Schema of a virtual table.
 // (See http://sqlite.org/c3ref/declare_vtab.html)
 func (c *Conn) DeclareVTab(sql string) error {
@@ -232,18 +545,118 @@ func (c *Conn) DeclareVTab(sql string) error {
 	return c.error(C.sqlite3_declare_vtab(c.db, zSQL), fmt.Sprintf("Conn.DeclareVTab(%q)", sql))
 }
 
+// VTabColumn describes one column of a virtual table schema (see VTabSchema).
+type VTabColumn struct {
+	Name string
+	// Affinity is the declared type of the column.
+	// Any type name can be used (like Affinity("DATE")) but None or "" means no declared type.
+	Affinity   Affinity
+	Hidden     bool // See http://sqlite.org/vtab.html#hidden_columns_in_virtual_tables
+	PrimaryKey bool // Only meaningful for WITHOUT ROWID virtual tables
+}
+
+// VTabSchema is a typed builder for the CREATE TABLE statement passed to DeclareVTab.
+//
+//	err := c.DeclareVTabSchema(VTabSchema{Columns: []VTabColumn{{Name: "value", Affinity: Integral}}})
+type VTabSchema struct {
+	Columns      []VTabColumn
+	WithoutRowID bool // See http://sqlite.org/vtab.html#_without_rowid_virtual_tables_
+}
+
+// SQL validates the schema and generates the matching CREATE TABLE statement.
+func (s VTabSchema) SQL() (string, error) {
+	if len(s.Columns) == 0 {
+		return "", errors.New("no column specified in virtual table schema")
+	}
+	var b bytes.Buffer
+	var pks []string
+	names := make(map[string]bool, len(s.Columns))
+	b.WriteString("CREATE TABLE x(")
+	for i, col := range s.Columns {
+		if len(col.Name) == 0 {
+			return "", fmt.Errorf("no name specified for virtual table column %d", i+1)
+		}
+		if names[strings.ToLower(col.Name)] {
+			return "", fmt.Errorf("duplicate virtual table column name: %q", col.Name)
+		}
+		names[strings.ToLower(col.Name)] = true
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(QuoteIdentifier(col.Name))
+		if col.Affinity != None && len(col.Affinity) > 0 {
+			if !validTypeName(string(col.Affinity)) {
+				return "", fmt.Errorf("invalid type for virtual table column %q: %q", col.Name, col.Affinity)
+			}
+			b.WriteByte(' ')
+			b.WriteString(string(col.Affinity))
+		}
+		if col.Hidden {
+			b.WriteString(" HIDDEN")
+		}
+		if col.PrimaryKey {
+			pks = append(pks, QuoteIdentifier(col.Name))
+		}
+	}
+	if len(pks) > 0 {
+		if !s.WithoutRowID {
+			return "", errors.New("primary key is only supported by WITHOUT ROWID virtual tables")
+		}
+		fmt.Fprintf(&b, ", PRIMARY KEY (%s)", strings.Join(pks, ", "))
+	} else if s.WithoutRowID {
+		return "", errors.New("WITHOUT ROWID virtual table must have a primary key")
+	}
+	b.WriteByte(')')
+	if s.WithoutRowID {
+		b.WriteString(" WITHOUT ROWID")
+	}
+	return b.String(), nil
+}
+
+// validTypeName checks that typ is a type name as defined by the SQLite grammar,
+// like "VARCHAR(10)" or "DECIMAL(10, 2)".
+func validTypeName(typ string) bool {
+	for _, r := range typ {
+		switch {
+		case r == '_', r == ' ', r == '(', r == ')', r == ',', r == '+', r == '-':
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// DeclareVTabSchema declares the Schema of a virtual table (see DeclareVTab).
+func (c *Conn) DeclareVTabSchema(s VTabSchema) error {
+	sql, err := s.SQL()
+	if err != nil {
+		return err
+	}
+	return c.DeclareVTab(sql)
+}
+
 // CreateModule registers a virtual table implementation.
+// Registering a module with the name of an existing one replaces it:
+// the virtual tables already connected keep using the previous module
+// whose DestroyModule method is called once they are all disconnected.
+// If module is nil, the module is unregistered (DestroyModule is called the same way).
+// DestroyModule is also called when the registration fails or when the connection is closed.
 // Cannot be used with Go >= 1.6 and cgocheck enabled.
 // (See http://sqlite.org/c3ref/create_module.html)
 func (c *Conn) CreateModule(moduleName string, module Module) error {
 	mname := C.CString(moduleName)
 	defer C.free(unsafe.Pointer(mname))
-	// To make sure it is not gced, keep a reference in the connection.
+	if module == nil {
+		return c.error(C.goSqlite3DropModule(c.db, mname), fmt.Sprintf("Conn.CreateModule(%q, nil)", moduleName))
+	}
+	// To make sure it is not gced, keep a reference in the connection
+	// (until SQLite releases the module by calling goMDestroy).
 	udm := &sqliteModule{c, moduleName, module, nil}
 	if len(c.modules) == 0 {
-		c.modules = make(map[string]*sqliteModule)
+		c.modules = make(map[*sqliteModule]struct{})
 	}
-	c.modules[moduleName] = udm // FIXME What happens if different modules are registered with the same name?
+	c.modules[udm] = struct{}{}
 	return c.error(C.goSqlite3CreateModule(c.db, mname, unsafe.Pointer(udm)),
 		fmt.Sprintf("Conn.CreateModule(%q)", moduleName))
 }
@@ -264,12 +677,12 @@ goMInit                              |- int (*xCreate)(sqlite3*, void *pAux, int
                                              char **pzErr)
 goMInit                              |- int (*xConnect)(sqlite3*, void *pAux, int argc, char **argv, sqlite3_vtab **ppVTab,
                                              char **pzErr)
-x                                    |- int (*xBestIndex)(sqlite3_vtab *pVTab, sqlite3_index_info*)
+goVBestIndex                         |- int (*xBestIndex)(sqlite3_vtab *pVTab, sqlite3_index_info*)
 goVRelease                           |- int (*xDisconnect)(sqlite3_vtab *pVTab)
 goVRelease                           |- int (*xDestroy)(sqlite3_vtab *pVTab)
 goVOpen                              |- int (*xOpen)(sqlite3_vtab *pVTab, sqlite3_vtab_cursor **ppCursor)
 goVClose                             |- int (*xClose)(sqlite3_vtab_cursor*)
-x                                    |- int (*xFilter)(sqlite3_vtab_cursor*, int idxNum, const char *idxStr, int argc,
+goVFilter                            |- int (*xFilter)(sqlite3_vtab_cursor*, int idxNum, const char *idxStr, int argc,
                                              sqlite3_value **argv)
 x                                    |- int (*xNext)(sqlite3_vtab_cursor*)
 x                                    |- int (*xEof)(sqlite3_vtab_cursor*)
@@ -5,6 +5,7 @@
 package sqlite_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -125,3 +126,342 @@ func TestCreateModule(t *testing.T) {
 	err = db.Exec("DROP TABLE vtab")
 	checkNoError(t, err, "couldn't drop virtual table: %s")
 }
+
+type destroyCountingModule struct {
+	testModule
+	destroyed *int
+}
+
+func (m destroyCountingModule) DestroyModule() {
+	*m.destroyed++
+}
+
+func TestReplaceModule(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	var destroyed1, destroyed2 int
+	err := db.CreateModule("test", destroyCountingModule{testModule{t, []int{1}}, &destroyed1})
+	checkNoError(t, err, "couldn't create module: %s")
+	err = db.Exec("CREATE VIRTUAL TABLE vtab USING test('1', 2, three)")
+	checkNoError(t, err, "couldn't create virtual table: %s")
+
+	err = db.CreateModule("test", destroyCountingModule{testModule{t, []int{2}}, &destroyed2})
+	checkNoError(t, err, "couldn't replace module: %s")
+	assert.Equal(t, 0, destroyed1, "module still used by vtab")
+	var value int
+	err = db.OneValue("SELECT * FROM vtab", &value)
+	checkNoError(t, err, "couldn't select from virtual table: %s")
+	assert.Equal(t, 1, value)
+	err = db.Exec("DROP TABLE vtab")
+	checkNoError(t, err, "couldn't drop virtual table: %s")
+	assert.Equal(t, 1, destroyed1, "replaced module destroyed")
+
+	err = db.Exec("CREATE VIRTUAL TABLE vtab USING test('1', 2, three)")
+	checkNoError(t, err, "couldn't create virtual table: %s")
+	err = db.OneValue("SELECT * FROM vtab", &value)
+	checkNoError(t, err, "couldn't select from virtual table: %s")
+	assert.Equal(t, 2, value)
+	err = db.Exec("DROP TABLE vtab")
+	checkNoError(t, err, "couldn't drop virtual table: %s")
+
+	err = db.CreateModule("test", nil)
+	checkNoError(t, err, "couldn't unregister module: %s")
+	assert.Equal(t, 1, destroyed2, "unregistered module destroyed")
+	err = db.Exec("CREATE VIRTUAL TABLE vtab USING test('1', 2, three)")
+	assert.T(t, err != nil, "no such module expected")
+}
+
+func TestVTabSchema(t *testing.T) {
+	var tests = []struct {
+		schema   VTabSchema
+		expected string
+	}{
+		{VTabSchema{Columns: []VTabColumn{{Name: "value"}}}, `CREATE TABLE x("value")`},
+		{VTabSchema{Columns: []VTabColumn{{Name: "a", Affinity: Integral}, {Name: `b"c`, Affinity: "VARCHAR(10)", Hidden: true}, {Name: "d", Affinity: None}}},
+			`CREATE TABLE x("a" INTEGER, "b""c" VARCHAR(10) HIDDEN, "d")`},
+		{VTabSchema{Columns: []VTabColumn{{Name: "k", Affinity: Textual, PrimaryKey: true}, {Name: "v"}}, WithoutRowID: true},
+			`CREATE TABLE x("k" TEXT, "v", PRIMARY KEY ("k")) WITHOUT ROWID`},
+	}
+	for _, test := range tests {
+		sql, err := test.schema.SQL()
+		checkNoError(t, err, "couldn't generate schema: %s")
+		assert.Equal(t, test.expected, sql)
+	}
+
+	var invalids = []VTabSchema{
+		{},
+		{Columns: []VTabColumn{{}}},
+		{Columns: []VTabColumn{{Name: "a"}, {Name: "A"}}},
+		{Columns: []VTabColumn{{Name: "a", Affinity: "TEXT); DROP TABLE t; --"}}},
+		{Columns: []VTabColumn{{Name: "a"}}, WithoutRowID: true},
+		{Columns: []VTabColumn{{Name: "a", PrimaryKey: true}}},
+	}
+	for _, schema := range invalids {
+		_, err := schema.SQL()
+		assert.Tf(t, err != nil, "error expected for %#v", schema)
+	}
+}
+
+type vtabTestError struct {
+	op string
+}
+
+func (e *vtabTestError) Error() string {
+	return "vtab test error in " + e.op
+}
+
+type failingModule struct {
+	testModule
+	op string
+}
+
+type failingVTab struct {
+	*testVTab
+}
+
+func (m failingModule) Create(c *Conn, args []string) (VTab, error) {
+	if m.op == "Create" {
+		return nil, &vtabTestError{m.op}
+	}
+	vTab, err := m.testModule.Create(c, args)
+	if err != nil {
+		return nil, err
+	}
+	return failingVTab{vTab.(*testVTab)}, nil
+}
+func (m failingModule) Connect(c *Conn, args []string) (VTab, error) {
+	return m.Create(c, args)
+}
+
+func (v failingVTab) Open() (VTabCursor, error) {
+	return nil, &vtabTestError{"Open"}
+}
+
+func TestVTabError(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	err := db.CreateModule("test", failingModule{testModule{t, []int{1}}, "Create"})
+	checkNoError(t, err, "couldn't create module: %s")
+	err = db.Exec("CREATE VIRTUAL TABLE vtab USING test('1', 2, three)")
+	var vErr *vtabTestError
+	assert.Tf(t, errors.As(err, &vErr), "vtab error expected but got %#v", err)
+	assert.Equal(t, "Create", vErr.op)
+
+	err = db.CreateModule("test", failingModule{testModule{t, []int{1}}, "Open"})
+	checkNoError(t, err, "couldn't create module: %s")
+	err = db.Exec("CREATE VIRTUAL TABLE vtab USING test('1', 2, three)")
+	checkNoError(t, err, "couldn't create virtual table: %s")
+	var value int
+	err = db.OneValue("SELECT * FROM vtab", &value)
+	vErr = nil
+	assert.Tf(t, errors.As(err, &vErr), "vtab error expected but got %#v", err)
+	assert.Equal(t, "Open", vErr.op)
+	if cErr, ok := err.(ConnError); ok {
+		assert.Equal(t, ErrError, cErr.Code())
+	}
+
+	err = db.Exec("SELECT * FROM unknown")
+	assert.T(t, err != nil, "error expected")
+	assert.T(t, errors.Unwrap(err) == nil, "no vtab error expected")
+}
+
+// seriesModule generates the integers from 1 to max (value column)
+// and pushes down equality, LIMIT and OFFSET constraints.
+type seriesModule struct {
+	max      int64
+	produced *int64      // number of rows produced by all cursors
+	filters  int         // number of FilterIndex calls
+	limitRHS interface{} // LIMIT value known while planning
+}
+
+type seriesVTab struct {
+	m *seriesModule
+}
+
+type seriesCursor struct {
+	m           *seriesModule
+	value, stop int64
+	in          []int64 // values of an IN constraint processed all at once
+}
+
+func (m *seriesModule) Create(c *Conn, args []string) (VTab, error) {
+	if err := c.DeclareVTabSchema(VTabSchema{Columns: []VTabColumn{{Name: "value", Affinity: Integral}}}); err != nil {
+		return nil, err
+	}
+	return seriesVTab{m}, nil
+}
+func (m *seriesModule) Connect(c *Conn, args []string) (VTab, error) {
+	return m.Create(c, args)
+}
+func (m *seriesModule) DestroyModule() {
+}
+
+func (v seriesVTab) BestIndex() error {
+	return nil
+}
+
+// idxStr lists the kinds of args: '=' (value), 'i' (IN values), 'l' (limit) or 'o' (offset)
+func (v seriesVTab) BestIndexInfo(info *IndexInfo) error {
+	// LIMIT/OFFSET are only pushed down when all other constraints are consumed
+	limitable := len(info.OrderBy) == 0
+	for _, c := range info.Constraints {
+		switch c.Op {
+		case IndexConstraintLimit, IndexConstraintOffset:
+		case IndexConstraintEQ:
+			limitable = limitable && c.Usable
+		default:
+			limitable = false
+		}
+	}
+	var idxStr []byte
+	for i, c := range info.Constraints {
+		if !c.Usable || (!limitable && (c.Op == IndexConstraintLimit || c.Op == IndexConstraintOffset)) {
+			continue
+		}
+		switch c.Op {
+		case IndexConstraintEQ:
+			if info.HandleIn(i) {
+				idxStr = append(idxStr, 'i')
+			} else {
+				idxStr = append(idxStr, '=')
+				info.Unique = true
+			}
+		case IndexConstraintLimit:
+			idxStr = append(idxStr, 'l')
+			v.m.limitRHS, _ = info.RHSValue(i)
+		case IndexConstraintOffset:
+			idxStr = append(idxStr, 'o')
+		default:
+			continue
+		}
+		info.Constraints[i].ArgvIndex = len(idxStr)
+		info.Constraints[i].Omit = true
+	}
+	info.IdxStr = string(idxStr)
+	info.EstimatedCost = float64(v.m.max)
+	if info.Unique {
+		info.EstimatedCost = 1
+	}
+	return nil
+}
+func (v seriesVTab) Disconnect() error {
+	return nil
+}
+func (v seriesVTab) Destroy() error {
+	return nil
+}
+func (v seriesVTab) Open() (VTabCursor, error) {
+	return &seriesCursor{m: v.m}, nil
+}
+
+func (vc *seriesCursor) Close() error {
+	return nil
+}
+func (vc *seriesCursor) Filter() error {
+	return fmt.Errorf("FilterIndex expected")
+}
+func (vc *seriesCursor) FilterIndex(idxNum int, idxStr string, args []interface{}) error {
+	vc.m.filters++
+	vc.value, vc.stop, vc.in = 1, vc.m.max, nil
+	var limit int64 = -1
+	for i, kind := range idxStr {
+		n, _ := args[i].(int64)
+		switch kind {
+		case '=':
+			vc.value, vc.stop = n, n
+		case 'i':
+			vc.in = []int64{}
+			for _, v := range args[i].([]interface{}) {
+				if v := v.(int64); v >= 1 && v <= vc.m.max {
+					vc.in = append(vc.in, v)
+				}
+			}
+			vc.value, vc.stop = 0, -1
+			return vc.Next()
+		case 'l':
+			limit = n
+		case 'o':
+			vc.value += n
+		}
+	}
+	if limit >= 0 && vc.value+limit-1 < vc.stop {
+		vc.stop = vc.value + limit - 1
+	}
+	return nil
+}
+func (vc *seriesCursor) Next() error {
+	if vc.in != nil {
+		if len(vc.in) > 0 {
+			vc.value, vc.stop, vc.in = vc.in[0], vc.in[0], vc.in[1:]
+		} else {
+			vc.value = vc.stop + 1
+		}
+		return nil
+	}
+	vc.value++
+	return nil
+}
+func (vc *seriesCursor) EOF() bool {
+	if vc.value > vc.stop || vc.value < 1 {
+		return true
+	}
+	return false
+}
+func (vc *seriesCursor) Column(c *Context, col int) error {
+	*vc.m.produced++
+	c.ResultInt64(vc.value)
+	return nil
+}
+func (vc *seriesCursor) Rowid() (int64, error) {
+	return vc.value, nil
+}
+
+func TestIndexInfo(t *testing.T) {
+	skipIfCgoCheckActive(t)
+
+	db := open(t)
+	defer checkClose(db, t)
+	var produced int64
+	m := &seriesModule{max: 1000, produced: &produced}
+	err := db.CreateModule("series", m)
+	checkNoError(t, err, "couldn't create module: %s")
+	err = db.Exec("CREATE VIRTUAL TABLE temp.series USING series()")
+	checkNoError(t, err, "couldn't create virtual table: %s")
+
+	var values string
+	err = db.OneValue("SELECT group_concat(value) FROM (SELECT value FROM series LIMIT 3 OFFSET 2)", &values)
+	checkNoError(t, err, "couldn't select from virtual table: %s")
+	assert.Equal(t, "3,4,5", values)
+	assert.Equal(t, int64(3), produced, "LIMIT/OFFSET pushed down")
+	assert.Equal(t, int64(3), m.limitRHS, "LIMIT value known while planning")
+
+	produced = 0
+	var value int64
+	err = db.OneValue("SELECT value FROM series WHERE value = ?", &value, 42)
+	checkNoError(t, err, "couldn't select from virtual table: %s")
+	assert.Equal(t, int64(42), value)
+	assert.Equal(t, int64(1), produced, "equality pushed down")
+
+	err = db.OneValue("SELECT group_concat(value) FROM (SELECT value FROM series WHERE value > 500 LIMIT 3)", &values)
+	checkNoError(t, err, "couldn't select from virtual table: %s")
+	assert.Equal(t, "501,502,503", values, "LIMIT not pushed down with WHERE term left to SQLite")
+
+	produced = 0
+	err = db.OneValue("SELECT count(*) FROM series", &value)
+	checkNoError(t, err, "couldn't select from virtual table: %s")
+	assert.Equal(t, int64(1000), value)
+
+	if VersionNumber() < 3038000 {
+		return
+	}
+	produced, m.filters = 0, 0
+	err = db.OneValue("SELECT sum(value) FROM series WHERE value IN (3, 7, 2000, 5)", &value)
+	checkNoError(t, err, "couldn't select from virtual table: %s")
+	assert.Equal(t, int64(15), value)
+	assert.Equal(t, int64(3), produced, "IN values pushed down")
+	assert.Equal(t, 1, m.filters, "IN values processed all at once")
+}
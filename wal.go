@@ -0,0 +1,106 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+/*
+#include <sqlite3.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"os"
+	"unsafe"
+)
+
+// CheckpointMode enumerates WAL checkpoint modes
+// (See http://sqlite.org/c3ref/wal_checkpoint_v2.html)
+type CheckpointMode int32
+
+// Checkpoint modes
+const (
+	CheckpointPassive  CheckpointMode = C.SQLITE_CHECKPOINT_PASSIVE
+	CheckpointFull     CheckpointMode = C.SQLITE_CHECKPOINT_FULL
+	CheckpointRestart  CheckpointMode = C.SQLITE_CHECKPOINT_RESTART
+	CheckpointTruncate CheckpointMode = C.SQLITE_CHECKPOINT_TRUNCATE
+)
+
+// WalCheckpoint runs a checkpoint on the specified database (all attached databases when dbName is empty)
+// and returns the number of frames in the WAL and the number of frames checkpointed.
+// A passive checkpoint does not wait for readers or writers.
+// (See http://sqlite.org/c3ref/wal_checkpoint_v2.html)
+func (c *Conn) WalCheckpoint(dbName string, mode CheckpointMode) (logFrames, checkpointed int, err error) {
+	var zDb *C.char
+	if len(dbName) > 0 {
+		zDb = C.CString(dbName)
+		defer C.free(unsafe.Pointer(zDb))
+	}
+	var nLog, nCkpt C.int
+	rv := C.sqlite3_wal_checkpoint_v2(c.db, zDb, C.int(mode), &nLog, &nCkpt)
+	if rv != C.SQLITE_OK {
+		return -1, -1, c.error(rv, "Conn.WalCheckpoint")
+	}
+	return int(nLog), int(nCkpt), nil
+}
+
+// JournalFiles gives the paths and sizes on disk (zero when missing) of the files associated to a database.
+type JournalFiles struct {
+	Journal     string // rollback journal (-journal)
+	WAL         string // write-ahead log (-wal)
+	SHM         string // WAL index (-shm)
+	JournalSize int64
+	WALSize     int64
+	SHMSize     int64
+	WALFrames   int // number of frames in the -wal file (-1 when the database is not in WAL mode)
+}
+
+// JournalFiles derives the journal, WAL and shared memory file paths from the database file name
+// and reports their current size, so that WAL growth can be monitored.
+// The WAL frame count is computed from the WAL size and the page size (no checkpoint is run):
+// it may include frames of a previous cycle when the WAL has been reset but not truncated.
+// Database name is optional (default is 'main').
+func (c *Conn) JournalFiles(dbName string) (JournalFiles, error) {
+	if len(dbName) == 0 {
+		dbName = "main"
+	}
+	var jf JournalFiles
+	filename := c.Filename(dbName)
+	if len(filename) == 0 {
+		return jf, c.specificError("no file associated to database %q (temporary or in-memory)", dbName)
+	}
+	jf.Journal, jf.WAL, jf.SHM = filename+"-journal", filename+"-wal", filename+"-shm"
+	jf.JournalSize, jf.WALSize, jf.SHMSize = fileSize(jf.Journal), fileSize(jf.WAL), fileSize(jf.SHM)
+	jf.WALFrames = -1
+	mode, err := c.JournalMode(dbName)
+	if err != nil {
+		return jf, err
+	}
+	if mode == "wal" {
+		var pageSize int64
+		if err = c.oneValue(pragma(dbName, "page_size"), &pageSize); err != nil {
+			return jf, err
+		}
+		jf.WALFrames = walFrames(jf.WALSize, pageSize)
+	}
+	return jf, nil
+}
+
+// walFrames computes the number of frames from the WAL size:
+// a 32-byte header followed by frames made of a 24-byte header and a page.
+// (See http://sqlite.org/fileformat2.html#walformat)
+func walFrames(walSize, pageSize int64) int {
+	if walSize <= 32 || pageSize <= 0 {
+		return 0
+	}
+	return int((walSize - 32) / (24 + pageSize))
+}
+
+func fileSize(path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
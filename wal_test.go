@@ -0,0 +1,62 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlite_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	. "github.com/gwenn/gosqlite"
+)
+
+func TestJournalFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosqlite-wal")
+	checkNoError(t, err, "couldn't create temp dir: %s")
+	defer os.RemoveAll(dir)
+	db, err := Open(filepath.Join(dir, "test.db"))
+	checkNoError(t, err, "open error: %s")
+	defer checkClose(db, t)
+
+	jf, err := db.JournalFiles("")
+	checkNoError(t, err, "journal files error: %s")
+	assert.Equal(t, -1, jf.WALFrames)
+	assert.Equal(t, int64(0), jf.WALSize)
+
+	_, err = db.SetJournalMode("", "wal")
+	checkNoError(t, err, "journal mode error: %s")
+	checkNoError(t, db.FastExec("CREATE TABLE t (x); INSERT INTO t VALUES (1)"), "insert error: %s")
+	dbSize := fileSize(t, filepath.Join(dir, "test.db"))
+	jf, err = db.JournalFiles("main")
+	checkNoError(t, err, "journal files error: %s")
+	assert.Equal(t, filepath.Join(dir, "test.db-wal"), jf.WAL)
+	assert.T(t, jf.WALSize > 0, "WAL expected")
+	assert.T(t, jf.SHMSize > 0, "WAL index expected")
+	assert.T(t, jf.WALFrames > 0, "WAL frames expected")
+	assert.Equal(t, dbSize, fileSize(t, filepath.Join(dir, "test.db")), "no checkpoint expected")
+	logFrames, _, err := db.WalCheckpoint("", CheckpointPassive)
+	checkNoError(t, err, "checkpoint error: %s")
+	assert.Equal(t, logFrames, jf.WALFrames)
+
+	_, _, err = db.WalCheckpoint("", CheckpointTruncate)
+	checkNoError(t, err, "checkpoint error: %s")
+	jf, err = db.JournalFiles("")
+	checkNoError(t, err, "journal files error: %s")
+	assert.Equal(t, 0, jf.WALFrames)
+	assert.Equal(t, int64(0), jf.WALSize)
+
+	mem := open(t)
+	defer checkClose(mem, t)
+	_, err = mem.JournalFiles("")
+	assert.T(t, err != nil, "no file expected")
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	fi, err := os.Stat(path)
+	checkNoError(t, err, "stat error: %s")
+	return fi.Size()
+}